@@ -0,0 +1,394 @@
+package squeakyv
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// GetDel retrieves the value for a key and soft-deletes the key in a single
+// transaction, so that concurrent callers never both receive the same value.
+//
+// Returns nil if the key doesn't exist, was deleted, or has expired.
+//
+// Example:
+//
+//	job, err := client.GetDel("queue:next")
+//	if err != nil {
+//		return err
+//	}
+//	if job != nil {
+//		process(job)
+//	}
+func (c *CacheClient) GetDel(key string) ([]byte, error) {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	var value []byte
+	err = c.withTx(db, func(tx *sql.Tx) error {
+		value = nil
+		v, err := c.readValue(tx, key)
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		value = v
+		return deleteKey(tx, c.tables, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if value != nil {
+		c.notify(key, WatchDelete, nil)
+		err = c.mirror(mirrorWrite{key: key, op: WatchDelete})
+	}
+	return value, err
+}
+
+// GetSet stores a new value for a key and returns the previous one in a
+// single transaction. The overwrite creates a new version exactly as Set does.
+//
+// Returns nil as the previous value if the key didn't exist.
+//
+// Example:
+//
+//	old, err := client.GetSet("config", newConfig)
+//	if err != nil {
+//		return err
+//	}
+//	log.Printf("replaced config: %s", old)
+func (c *CacheClient) GetSet(key string, value []byte) ([]byte, error) {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	stored, err := c.encodeStored(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []byte
+	err = c.withTx(db, func(tx *sql.Tx) error {
+		v, err := c.readValue(tx, key)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return err
+		}
+		previous = v
+		return insertVersion(tx, c.tables, key, stored, sql.NullInt64{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.notify(key, WatchSet, value)
+	return previous, c.mirror(mirrorWrite{key: key, op: WatchSet, value: value})
+}
+
+// SetNX stores a value only if the key has no live value, reporting whether
+// the value was written. It is atomic, so among concurrent callers for the
+// same key exactly one succeeds.
+//
+// A deleted or expired key counts as absent.
+//
+// Example:
+//
+//	won, err := client.SetNX("leader", []byte(hostname))
+//	if err != nil {
+//		return err
+//	}
+//	if won {
+//		runAsLeader()
+//	}
+func (c *CacheClient) SetNX(key string, value []byte) (bool, error) {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return false, err
+	}
+	defer c.release()
+
+	stored, err := c.encodeStored(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	var inserted bool
+	err = c.withTx(db, func(tx *sql.Tx) error {
+		inserted, err = setIfAbsent(tx, c.tables, key, stored, nowMillis())
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if inserted {
+		c.notify(key, WatchSet, value)
+		err = c.mirror(mirrorWrite{key: key, op: WatchSet, value: value})
+	}
+	return inserted, err
+}
+
+// setIfAbsent inserts value for key unless the key is live at now, reporting
+// whether a row was inserted.
+func setIfAbsent(tx querier, t tableNames, key string, value []byte, now int64) (bool, error) {
+	query := `INSERT INTO ` + t.kv + ` (key, value, checksum)
+SELECT ?, ?, ?
+WHERE NOT EXISTS (
+  SELECT 1 FROM ` + t.kv + ` WHERE key = ? AND ` + liveCondition + `
+);`
+
+	result, err := tx.Exec(query, key, value, checksumOf(value), key, now)
+	if err != nil {
+		return false, fmt.Errorf("exec failed: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected failed: %w", err)
+	}
+	return n == 1, nil
+}
+
+// CompareAndSwap replaces the value of a key with new only if its current
+// live value is byte-equal to old, reporting whether the swap happened. The
+// comparison and the write share one transaction.
+//
+// A nil old means "only if the key is absent", which makes CompareAndSwap a
+// superset of SetNX. Note that a non-nil empty slice instead matches a present
+// but empty value. A mismatch returns false, not an error. A successful swap
+// creates a new version exactly as Set does.
+//
+// Example:
+//
+//	for {
+//		current, err := client.Get("counter")
+//		if err != nil {
+//			return err
+//		}
+//		swapped, err := client.CompareAndSwap("counter", current, next(current))
+//		if err != nil || swapped {
+//			return err
+//		}
+//	}
+func (c *CacheClient) CompareAndSwap(key string, old, new []byte) (bool, error) {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return false, err
+	}
+	defer c.release()
+
+	stored, err := c.encodeStored(key, new)
+	if err != nil {
+		return false, err
+	}
+
+	var swapped bool
+	err = c.withTx(db, func(tx *sql.Tx) error {
+		swapped = false
+		if old == nil {
+			swapped, err = setIfAbsent(tx, c.tables, key, stored, nowMillis())
+			return err
+		}
+
+		current, err := c.readValue(tx, key)
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(current, old) {
+			return nil
+		}
+		swapped = true
+		return insertVersion(tx, c.tables, key, stored, sql.NullInt64{})
+	})
+	if err != nil {
+		return false, err
+	}
+	if swapped {
+		c.notify(key, WatchSet, new)
+		err = c.mirror(mirrorWrite{key: key, op: WatchSet, value: new})
+	}
+	return swapped, err
+}
+
+// CompareAndDelete soft-deletes a key only if its current live value is
+// byte-equal to expected, reporting whether the key was deleted. The
+// comparison and the delete share one transaction.
+//
+// A mismatch or a missing key returns false, not an error, so the method can
+// be used directly in retry loops.
+//
+// Example:
+//
+//	deleted, err := client.CompareAndDelete("lock", []byte(myToken))
+func (c *CacheClient) CompareAndDelete(key string, expected []byte) (bool, error) {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return false, err
+	}
+	defer c.release()
+
+	var deleted bool
+	err = c.withTx(db, func(tx *sql.Tx) error {
+		deleted = false
+		current, err := c.readValue(tx, key)
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(current, expected) {
+			return nil
+		}
+		deleted = true
+		return deleteKey(tx, c.tables, key)
+	})
+	if err != nil {
+		return false, err
+	}
+	if deleted {
+		c.notify(key, WatchDelete, nil)
+		err = c.mirror(mirrorWrite{key: key, op: WatchDelete})
+	}
+	return deleted, err
+}
+
+// Append appends data to the value of a key, creating the key if it doesn't
+// exist, and returns the new length of the value in bytes.
+//
+// The concatenation happens inside SQLite, so the existing value is never
+// copied into Go, unless values are transformed, as with WithCompressor, in
+// which case the value is read, extended and rewritten. Append mutates the
+// current version in place rather than writing a new one: an appended log
+// would otherwise retain a full copy of itself in history for every append.
+// The version keeps its write time, as reported by Stat and History, and its
+// expiry, if any; ListKeys orders it as if just written, as Touch does. A
+// deleted or expired key starts afresh.
+//
+// Example:
+//
+//	n, err := client.Append("log", []byte("line\n"))
+func (c *CacheClient) Append(key string, data []byte) (int64, error) {
+	if data == nil {
+		data = []byte{}
+	}
+	if err := c.validateKey(key); err != nil {
+		return 0, err
+	}
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+	defer c.invalidate(key)
+
+	// The value is stored as is, so WithMaxBytes limits it as a whole.
+	limit := c.opts.maxValueSize
+	if c.opts.maxBytes > 0 && (limit <= 0 || c.opts.maxBytes < limit) {
+		limit = c.opts.maxBytes
+	}
+
+	var length int64
+	err = c.withTx(db, func(tx *sql.Tx) error {
+		now := nowMillis()
+		if !c.transformsValues() {
+			n, ok, err := appendInPlace(tx, c.tables, key, data, now, limit)
+			if err != nil || ok {
+				length = n
+				return err
+			}
+		}
+		length, err = c.appendDecoded(tx, key, data, now)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return length, nil
+}
+
+// appendInPlace appends data to the live value of key inside SQLite and
+// returns its new length, which must not exceed limit if it is positive. It
+// reports false, changing nothing, if the key has no live value or its value
+// is stored in an envelope (see valueMagic).
+func appendInPlace(tx *sql.Tx, t tableNames, key string, data []byte, now, limit int64) (int64, bool, error) {
+	query := `SELECT length(value), checksum
+FROM ` + t.kv + `
+WHERE key = ? AND ` + liveCondition + `
+  AND substr(CAST(value AS BLOB), 1, 4) <> ` + valueMagicHex + `;`
+
+	var (
+		length   int64
+		checksum sql.NullInt64
+	)
+	err := tx.QueryRow(query, key, now).Scan(&length, &checksum)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("query failed: %w", err)
+	}
+	if size := length + int64(len(data)); limit > 0 && size > limit {
+		return 0, false, &ValueTooLargeError{Key: key, Size: int(size), Limit: int(limit)}
+	}
+	// CRC32C extends over appended bytes, so the checksum is kept up to date
+	// without reading the value.
+	if checksum.Valid {
+		checksum.Int64 = int64(crc32.Update(uint32(checksum.Int64), castagnoli, data))
+	}
+
+	// || yields TEXT in SQLite; cast back so the value stays a BLOB.
+	query = `UPDATE ` + t.kv + `
+SET value = CAST(value || ? AS BLOB), touched_at = ?, checksum = ?
+WHERE key = ? AND is_active = 1;`
+
+	if _, err := tx.Exec(query, data, now, checksum, key); err != nil {
+		return 0, false, fmt.Errorf("exec failed: %w", err)
+	}
+	return length + int64(len(data)), true, nil
+}
+
+// appendDecoded appends data to the decoded live value of key and stores the
+// result, encoded, in place of the live version. A key without a live value
+// is created.
+func (c *CacheClient) appendDecoded(tx *sql.Tx, key string, data []byte, now int64) (int64, error) {
+	stored, _, err := readLiveVersion(tx, c.tables, key, now)
+	if errors.Is(err, ErrKeyNotFound) {
+		if stored, err = c.encodeStored(key, data); err != nil {
+			return 0, err
+		}
+		if err := insertVersion(tx, c.tables, key, stored, sql.NullInt64{}); err != nil {
+			return 0, err
+		}
+		return int64(len(data)), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := c.decodeStored(key, stored)
+	if err != nil {
+		return 0, err
+	}
+	value = append(value[:len(value):len(value)], data...)
+	if stored, err = c.encodeStored(key, value); err != nil {
+		return 0, err
+	}
+
+	query := `UPDATE ` + c.tables.kv + `
+SET value = ?, touched_at = ?, checksum = ?
+WHERE key = ? AND is_active = 1;`
+	if _, err := tx.Exec(query, stored, now, checksumOf(stored), key); err != nil {
+		return 0, fmt.Errorf("exec failed: %w", err)
+	}
+	return int64(len(value)), nil
+}
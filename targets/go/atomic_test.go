@@ -0,0 +1,491 @@
+package squeakyv
+
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFileClient opens a client with opts on a fresh database file, exercising
+// the multi-connection pool used for file-backed caches.
+func newFileClient(t testing.TB, opts ...Option) *CacheClient {
+	t.Helper()
+	return newTestClientAt(t, filepath.Join(t.TempDir(), "test.db"), opts...)
+}
+
+func TestGetDel(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("value"))
+
+	value, err := client.GetDel("key")
+	if err != nil {
+		t.Fatalf("GetDel failed: %v", err)
+	}
+	if string(value) != "value" {
+		t.Errorf("Expected value, got %s", value)
+	}
+
+	value, err = client.GetDel("key")
+	if err != nil {
+		t.Fatalf("GetDel failed: %v", err)
+	}
+	if value != nil {
+		t.Errorf("Expected nil on second GetDel, got %s", value)
+	}
+
+	if ok, _ := client.Exists("key"); ok {
+		t.Error("Key still exists after GetDel")
+	}
+}
+
+func TestGetDelConcurrent(t *testing.T) {
+	client := newFileClient(t)
+
+	client.Set("job", []byte("payload"))
+
+	var (
+		wg      sync.WaitGroup
+		winners int32
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := client.GetDel("job")
+			if err != nil {
+				t.Errorf("GetDel failed: %v", err)
+				return
+			}
+			if value != nil {
+				atomic.AddInt32(&winners, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if winners != 1 {
+		t.Errorf("Expected exactly one goroutine to receive the value, got %d", winners)
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	client := newTestClient(t)
+
+	previous, err := client.GetSet("key", []byte("v1"))
+	if err != nil {
+		t.Fatalf("GetSet failed: %v", err)
+	}
+	if previous != nil {
+		t.Errorf("Expected nil for missing key, got %s", previous)
+	}
+
+	previous, err = client.GetSet("key", []byte("v2"))
+	if err != nil {
+		t.Fatalf("GetSet failed: %v", err)
+	}
+	if string(previous) != "v1" {
+		t.Errorf("Expected v1, got %s", previous)
+	}
+
+	value, err := client.Get("key")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != "v2" {
+		t.Errorf("Expected v2, got %s", value)
+	}
+	if rows := countRows(t, client, "key"); rows != 2 {
+		t.Errorf("Expected 2 versions, got %d", rows)
+	}
+}
+
+func TestGetSetConcurrent(t *testing.T) {
+	client := newFileClient(t)
+
+	// Every written value must be returned as a previous value exactly once,
+	// except the last one, which remains current.
+	const writers = 20
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		returned = make(map[string]int)
+	)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			previous, err := client.GetSet("key", []byte{byte('a' + id)})
+			if err != nil {
+				t.Errorf("GetSet failed: %v", err)
+				return
+			}
+			mu.Lock()
+			returned[string(previous)]++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	current, err := client.Get("key")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if returned[string(current)] != 0 {
+		t.Errorf("Current value %s was also returned as previous", current)
+	}
+	if returned[""] != 1 {
+		t.Errorf("Expected exactly one nil previous value, got %d", returned[""])
+	}
+	for value, n := range returned {
+		if n != 1 {
+			t.Errorf("Previous value %q returned %d times", value, n)
+		}
+	}
+	if len(returned) != writers {
+		t.Errorf("Expected %d distinct previous values, got %d", writers, len(returned))
+	}
+}
+
+func TestSetNX(t *testing.T) {
+	client := newTestClient(t)
+
+	won, err := client.SetNX("key", []byte("first"))
+	if err != nil {
+		t.Fatalf("SetNX failed: %v", err)
+	}
+	if !won {
+		t.Error("Expected first SetNX to win")
+	}
+
+	won, err = client.SetNX("key", []byte("second"))
+	if err != nil {
+		t.Fatalf("SetNX failed: %v", err)
+	}
+	if won {
+		t.Error("Expected second SetNX to lose")
+	}
+
+	value, _ := client.Get("key")
+	if string(value) != "first" {
+		t.Errorf("Expected first, got %s", value)
+	}
+
+	// A deleted key is absent again
+	client.Delete("key")
+	won, err = client.SetNX("key", []byte("third"))
+	if err != nil {
+		t.Fatalf("SetNX failed: %v", err)
+	}
+	if !won {
+		t.Error("Expected SetNX on deleted key to win")
+	}
+}
+
+func TestSetNXConcurrent(t *testing.T) {
+	client := newFileClient(t)
+
+	var (
+		wg      sync.WaitGroup
+		winners int32
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			won, err := client.SetNX("sentinel", []byte{byte(id)})
+			if err != nil {
+				t.Errorf("SetNX failed: %v", err)
+				return
+			}
+			if won {
+				atomic.AddInt32(&winners, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if winners != 1 {
+		t.Errorf("Expected exactly one winner, got %d", winners)
+	}
+	if rows := countRows(t, client, "sentinel"); rows != 1 {
+		t.Errorf("Expected a single row, got %d", rows)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	client := newTestClient(t)
+
+	// nil old: only if absent
+	swapped, err := client.CompareAndSwap("key", nil, []byte("v1"))
+	if err != nil {
+		t.Fatalf("CompareAndSwap failed: %v", err)
+	}
+	if !swapped {
+		t.Error("Expected swap on absent key")
+	}
+	swapped, _ = client.CompareAndSwap("key", nil, []byte("other"))
+	if swapped {
+		t.Error("Expected nil old to fail on present key")
+	}
+
+	// Mismatch
+	swapped, err = client.CompareAndSwap("key", []byte("wrong"), []byte("v2"))
+	if err != nil {
+		t.Fatalf("CompareAndSwap failed: %v", err)
+	}
+	if swapped {
+		t.Error("Expected mismatch to return false")
+	}
+
+	// Match
+	swapped, err = client.CompareAndSwap("key", []byte("v1"), []byte("v2"))
+	if err != nil {
+		t.Fatalf("CompareAndSwap failed: %v", err)
+	}
+	if !swapped {
+		t.Error("Expected matching swap to succeed")
+	}
+	value, _ := client.Get("key")
+	if string(value) != "v2" {
+		t.Errorf("Expected v2, got %s", value)
+	}
+
+	// Missing key with non-nil old
+	swapped, err = client.CompareAndSwap("missing", []byte("v1"), []byte("v2"))
+	if err != nil {
+		t.Fatalf("CompareAndSwap failed: %v", err)
+	}
+	if swapped {
+		t.Error("Expected swap on missing key to fail")
+	}
+}
+
+func TestCompareAndSwapEmptyValue(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte{})
+
+	swapped, err := client.CompareAndSwap("key", []byte{}, []byte("filled"))
+	if err != nil {
+		t.Fatalf("CompareAndSwap failed: %v", err)
+	}
+	if !swapped {
+		t.Error("Expected empty old to match an empty value")
+	}
+}
+
+func TestCompareAndSwapCounter(t *testing.T) {
+	client := newFileClient(t)
+
+	client.Set("counter", []byte("0"))
+
+	const perWriter = 50
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				for {
+					current, err := client.Get("counter")
+					if err != nil {
+						t.Errorf("Get failed: %v", err)
+						return
+					}
+					n, err := strconv.Atoi(string(current))
+					if err != nil {
+						t.Errorf("Invalid counter %q: %v", current, err)
+						return
+					}
+					swapped, err := client.CompareAndSwap("counter", current, []byte(strconv.Itoa(n+1)))
+					if err != nil {
+						t.Errorf("CompareAndSwap failed: %v", err)
+						return
+					}
+					if swapped {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := client.Get("counter")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != strconv.Itoa(2*perWriter) {
+		t.Errorf("Expected counter %d, got %s", 2*perWriter, value)
+	}
+}
+
+func TestCompareAndDelete(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("lock", []byte("token-a"))
+
+	deleted, err := client.CompareAndDelete("lock", []byte("token-b"))
+	if err != nil {
+		t.Fatalf("CompareAndDelete failed: %v", err)
+	}
+	if deleted {
+		t.Error("Expected mismatch to return false")
+	}
+	if ok, _ := client.Exists("lock"); !ok {
+		t.Error("Key deleted despite mismatch")
+	}
+
+	deleted, err = client.CompareAndDelete("lock", []byte("token-a"))
+	if err != nil {
+		t.Fatalf("CompareAndDelete failed: %v", err)
+	}
+	if !deleted {
+		t.Error("Expected matching delete to succeed")
+	}
+	if ok, _ := client.Exists("lock"); ok {
+		t.Error("Key still exists after CompareAndDelete")
+	}
+
+	deleted, err = client.CompareAndDelete("lock", []byte("token-a"))
+	if err != nil {
+		t.Fatalf("CompareAndDelete failed: %v", err)
+	}
+	if deleted {
+		t.Error("Expected delete of missing key to return false")
+	}
+}
+
+func TestCompareAndDeleteConcurrent(t *testing.T) {
+	client := newFileClient(t)
+
+	client.Set("lock", []byte("token"))
+
+	var (
+		wg      sync.WaitGroup
+		winners int32
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			deleted, err := client.CompareAndDelete("lock", []byte("token"))
+			if err != nil {
+				t.Errorf("CompareAndDelete failed: %v", err)
+				return
+			}
+			if deleted {
+				atomic.AddInt32(&winners, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if winners != 1 {
+		t.Errorf("Expected exactly one successful delete, got %d", winners)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	client := newTestClient(t)
+
+	n, err := client.Append("log", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if n != 5 {
+		t.Errorf("Expected length 5, got %d", n)
+	}
+
+	n, err = client.Append("log", []byte(", world"))
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if n != 12 {
+		t.Errorf("Expected length 12, got %d", n)
+	}
+
+	value, _ := client.Get("log")
+	if string(value) != "hello, world" {
+		t.Errorf("Expected concatenated value, got %q", value)
+	}
+
+	// Appends mutate the current version in place
+	if rows := countRows(t, client, "log"); rows != 1 {
+		t.Errorf("Expected a single version, got %d", rows)
+	}
+}
+
+func TestAppendKeepsWriteTime(t *testing.T) {
+	for _, name := range []string{"plain", "compressed"} {
+		t.Run(name, func(t *testing.T) {
+			client := newTestClient(t)
+			if name == "compressed" {
+				client = newTestClient(t, WithCompressor(GzipCompressor{}))
+			}
+			client.Append("log", []byte("hello"))
+			before, _ := client.Stat("log")
+			time.Sleep(5 * time.Millisecond)
+			client.Append("log", []byte(", world"))
+
+			after, err := client.Stat("log")
+			if err != nil {
+				t.Fatalf("Stat failed: %v", err)
+			}
+			if !after.UpdatedAt.Equal(before.UpdatedAt) {
+				t.Errorf("Expected the write time kept, got %v then %v", before.UpdatedAt, after.UpdatedAt)
+			}
+		})
+	}
+}
+
+func TestAppendBinary(t *testing.T) {
+	client := newTestClient(t)
+
+	first := []byte{0x00, 0xFF, 0x00}
+	second := []byte{0x01, 0x00, 0xFE}
+	client.Set("blob", first)
+
+	n, err := client.Append("blob", second)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if n != 6 {
+		t.Errorf("Expected length 6, got %d", n)
+	}
+
+	value, _ := client.Get("blob")
+	expected := append(append([]byte{}, first...), second...)
+	if !bytes.Equal(value, expected) {
+		t.Errorf("Expected %v, got %v", expected, value)
+	}
+
+	// The value must remain a BLOB so byte lengths stay correct
+	var typ string
+	if err := client.db.QueryRow(`SELECT typeof(value) FROM kv WHERE key = 'blob' AND is_active = 1`).Scan(&typ); err != nil {
+		t.Fatalf("Failed to read type: %v", err)
+	}
+	if typ != "blob" {
+		t.Errorf("Expected blob storage, got %s", typ)
+	}
+}
+
+func TestAppendAfterDelete(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("log", []byte("old"))
+	client.Delete("log")
+
+	n, err := client.Append("log", []byte("new"))
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected deleted key to start afresh, got length %d", n)
+	}
+}
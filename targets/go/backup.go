@@ -0,0 +1,169 @@
+package squeakyv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// backupStepPages is how many pages Backup copies per step. The source is
+	// only locked while a step runs.
+	backupStepPages = 64
+	// backupStepPause is how long Backup yields between steps so writers can
+	// make progress.
+	backupStepPause = time.Millisecond
+	// backupMaxRestarts is how many times a backup may restart because the
+	// source changed before Backup copies the rest in a single step.
+	backupMaxRestarts = 3
+)
+
+// Backup copies the database to destPath using SQLite's online backup API,
+// replacing any database already there. The source stays readable and
+// writable throughout; pages are copied a few at a time and the result is a
+// consistent copy as of the moment the backup finished.
+//
+// If progress is non-nil it is called after every step with the number of
+// pages still to copy and the total page count.
+//
+// A write from another connection restarts the copy. After a few restarts the
+// remaining pages are copied in one step, holding the source's read lock
+// until it completes, so a busy cache still finishes.
+//
+// Example:
+//
+//	err := client.Backup("/backups/cache.db", func(remaining, total int) {
+//		log.Printf("backup: %d/%d pages left", remaining, total)
+//	})
+func (c *CacheClient) Backup(destPath string, progress func(remaining, total int)) error {
+	if destPath == "" {
+		return errors.New("backup: empty destination path")
+	}
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	return backupToFile(db, destPath, progress)
+}
+
+// BackupToWriter writes a consistent copy of the database to w in SQLite's
+// file format and returns the number of bytes written. The stream can be
+// loaded back with NewCacheClientFromReader.
+//
+// The copy is first made with Backup into a temporary file, which is removed
+// afterwards, so the source is never read while torn and w may be slow.
+//
+// Example:
+//
+//	n, err := client.BackupToWriter(uploader)
+func (c *CacheClient) BackupToWriter(w io.Writer) (int64, error) {
+	dir, err := os.MkdirTemp("", "squeakyv-backup-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, "backup.db")
+	if err := c.Backup(tmpPath, nil); err != nil {
+		return 0, err
+	}
+
+	f, err := os.Open(tmpPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+
+	n, err := io.Copy(w, f)
+	if err != nil {
+		return n, fmt.Errorf("failed to write backup: %w", err)
+	}
+	return n, nil
+}
+
+// NewCacheClientFromReader creates a database at path from a stream written
+// by BackupToWriter and opens it with the given options.
+//
+// path must not already exist; ":memory:", or another in-memory path, loads
+// the stream into a new in-memory cache. The stream is written to a temporary
+// file next to path and only renamed into place once it has been opened
+// successfully, so a truncated or corrupt stream never leaves a file at path.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClientFromReader(resp.Body, "restored.db")
+func NewCacheClientFromReader(r io.Reader, path string, opts ...Option) (*CacheClient, error) {
+	dir := os.TempDir()
+	if !isMemoryPath(path) {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("refusing to overwrite existing file %s", path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		dir = filepath.Dir(path)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".squeakyv-restore-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	// SQLite would treat an empty file as a new, empty database.
+	if n == 0 {
+		return nil, errors.New("invalid backup: empty stream")
+	}
+
+	// Opening the copy validates it and brings its schema up to date.
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var validate []Option
+	if o.caseInsensitiveKeys {
+		validate = append(validate, WithCaseInsensitiveKeys())
+	}
+	loaded, err := NewCacheClient(tmpPath, validate...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+
+	if !isMemoryPath(path) {
+		if err := loaded.Close(); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmpPath, path); err != nil {
+			return nil, fmt.Errorf("failed to move backup into place: %w", err)
+		}
+		return NewCacheClient(path, opts...)
+	}
+	defer loaded.Close()
+
+	client, err := NewCacheClient(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := backupDB(client.db, loaded.db, nil); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err := client.remarkEnvelopes(loaded.usesEnvelopes()); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
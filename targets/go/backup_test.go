@@ -0,0 +1,168 @@
+package squeakyv
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	client := newFileClient(t)
+
+	big := bytes.Repeat([]byte("x"), 4096)
+	items := make(map[string][]byte)
+	for i := 0; i < 300; i++ {
+		items[fmt.Sprintf("key%03d", i)] = big
+	}
+	if err := client.SetMany(items); err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		stop    = make(chan struct{})
+		written atomic.Int64
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := client.Set(fmt.Sprintf("during%d", i), []byte("value")); err != nil {
+				t.Errorf("Set during backup failed: %v", err)
+				return
+			}
+			written.Add(1)
+		}
+	}()
+
+	steps := 0
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	err := client.Backup(dest, func(remaining, total int) {
+		steps++
+		if remaining > total {
+			t.Errorf("Remaining %d exceeds total %d", remaining, total)
+		}
+	})
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if steps < 2 {
+		t.Errorf("Expected an incremental backup, got %d steps", steps)
+	}
+	if written.Load() == 0 {
+		t.Error("Expected writes to proceed during the backup")
+	}
+
+	restored, err := NewCacheClient(dest)
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer restored.Close()
+
+	for key, expected := range items {
+		value, err := restored.Get(key)
+		if err != nil {
+			t.Fatalf("Get from backup failed: %v", err)
+		}
+		if !bytes.Equal(value, expected) {
+			t.Fatalf("Backup value mismatch for %s", key)
+		}
+	}
+}
+
+func TestBackupMemory(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("key", []byte("value"))
+
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	if err := client.Backup(dest, nil); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	restored, err := NewCacheClient(dest)
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer restored.Close()
+
+	if value, _ := restored.Get("key"); string(value) != "value" {
+		t.Errorf("Expected value in backup, got %s", value)
+	}
+}
+
+func TestBackupToWriterRoundTrip(t *testing.T) {
+	client := newFileClient(t)
+
+	binary := make([]byte, 256)
+	for i := range binary {
+		binary[i] = byte(i)
+	}
+	client.Set("binary", binary)
+	client.Set("empty", []byte{})
+	client.Set("history", []byte("v1"))
+	client.Set("history", []byte("v2"))
+
+	var buf bytes.Buffer
+	n, err := client.BackupToWriter(&buf)
+	if err != nil {
+		t.Fatalf("BackupToWriter failed: %v", err)
+	}
+	if n != int64(buf.Len()) || n == 0 {
+		t.Errorf("Expected %d bytes reported, got %d", buf.Len(), n)
+	}
+
+	for _, path := range []string{filepath.Join(t.TempDir(), "restored.db"), ":memory:"} {
+		restored, err := NewCacheClientFromReader(bytes.NewReader(buf.Bytes()), path)
+		if err != nil {
+			t.Fatalf("NewCacheClientFromReader(%s) failed: %v", path, err)
+		}
+
+		value, err := restored.GetStrict("binary")
+		if err != nil {
+			t.Fatalf("GetStrict failed: %v", err)
+		}
+		if !bytes.Equal(value, binary) {
+			t.Errorf("%s: binary value did not round-trip", path)
+		}
+		if value, err := restored.GetStrict("empty"); err != nil || len(value) != 0 {
+			t.Errorf("%s: expected empty value, got %v, %v", path, value, err)
+		}
+		if versions, _ := restored.History("history"); len(versions) != 2 {
+			t.Errorf("%s: expected history to round-trip, got %d versions", path, len(versions))
+		}
+		restored.Close()
+	}
+}
+
+func TestNewCacheClientFromReaderRejectsBadInput(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "garbage.db")
+	if _, err := NewCacheClientFromReader(bytes.NewReader([]byte("not a database at all, really not")), path); err == nil {
+		t.Error("Expected error for garbage input")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected no file left behind for garbage input")
+	}
+
+	if _, err := NewCacheClientFromReader(bytes.NewReader(nil), path); err == nil {
+		t.Error("Expected error for empty input")
+	}
+
+	existing := filepath.Join(dir, "existing.db")
+	os.WriteFile(existing, []byte("keep me"), 0o644)
+	if _, err := NewCacheClientFromReader(bytes.NewReader([]byte("x")), existing); err == nil {
+		t.Error("Expected error when the target exists")
+	}
+}
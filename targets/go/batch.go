@@ -0,0 +1,282 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxBatchParams bounds the number of keys bound into a single statement,
+// keeping well under SQLite's default limit of 999 host parameters.
+const maxBatchParams = 500
+
+// uniqueKeys returns keys with duplicates removed, preserving first occurrence.
+func uniqueKeys(keys []string) []string {
+	seen := make(map[string]struct{}, len(keys))
+	unique := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		unique = append(unique, key)
+	}
+	return unique
+}
+
+// chunkKeys splits keys into consecutive slices of at most maxBatchParams.
+func chunkKeys(keys []string) [][]string {
+	var chunks [][]string
+	for len(keys) > maxBatchParams {
+		chunks = append(chunks, keys[:maxBatchParams])
+		keys = keys[maxBatchParams:]
+	}
+	if len(keys) > 0 {
+		chunks = append(chunks, keys)
+	}
+	return chunks
+}
+
+// placeholders returns "?, ?, ..." with n placeholders for an IN clause.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// keyArgs converts keys into query arguments, followed by any extra arguments.
+func keyArgs(keys []string, extra ...interface{}) []interface{} {
+	args := make([]interface{}, 0, len(keys)+len(extra))
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	return append(args, extra...)
+}
+
+// SetMany stores several values atomically: either every key is written or,
+// if any write fails, none are.
+//
+// Each key gets a new version exactly as if Set had been called for it, but
+// all writes share a single transaction, which is much faster than calling
+// Set in a loop on a file-backed database.
+//
+// Example:
+//
+//	err := client.SetMany(map[string][]byte{
+//		"user:1": []byte("alice"),
+//		"user:2": []byte("bob"),
+//	})
+func (c *CacheClient) SetMany(items map[string][]byte) (err error) {
+	if len(items) == 0 {
+		return nil
+	}
+	var size int64
+	for _, value := range items {
+		size += int64(len(value))
+	}
+	n, start := len(items), c.opStart()
+	var wait time.Duration
+	defer func() {
+		c.stats.recordSet(n, size, err)
+		c.logBatchOp(context.Background(), "set_many", n, start, int(size), err)
+		c.reportSlow("set_many", "", n, start, wait)
+	}()
+
+	for key, value := range items {
+		if err := c.validateKey(key); err != nil {
+			return err
+		}
+		if err := c.checkValueSize(key, int64(len(value))); err != nil {
+			return err
+		}
+	}
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	// encodeStored also applies WithMaxBytes, so every value goes through it,
+	// wrapped or not.
+	stored := make(map[string][]byte, len(items))
+	for key, value := range items {
+		encoded, err := c.encodeStored(key, value)
+		if err != nil {
+			return err
+		}
+		stored[key] = encoded
+	}
+
+	err = c.withTxWaiting(db, &wait, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`INSERT INTO ` + c.tables.kv + ` (key, value, checksum)
+VALUES (?, ?, ?);`)
+		if err != nil {
+			return fmt.Errorf("prepare failed: %w", err)
+		}
+		defer stmt.Close()
+
+		for key, value := range stored {
+			if _, err := stmt.Exec(key, value, checksumOf(value)); err != nil {
+				return fmt.Errorf("exec failed for key %q: %w", key, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for key, value := range items {
+		c.notify(key, WatchSet, value)
+	}
+	return c.mirror(mirrorSets(items)...)
+}
+
+// GetMany retrieves the values for several keys using one query per chunk of
+// keys rather than one per key.
+//
+// The result contains only keys that exist; missing, deleted, and expired keys
+// are simply absent. Duplicate input keys are allowed.
+//
+// Example:
+//
+//	values, err := client.GetMany([]string{"user:1", "user:2"})
+//	if err != nil {
+//		return err
+//	}
+//	if value, ok := values["user:1"]; ok {
+//		fmt.Println(string(value))
+//	}
+func (c *CacheClient) GetMany(keys []string) (results map[string][]byte, err error) {
+	start := c.opStart()
+	defer func() {
+		c.stats.recordGetMany(keys, results, err)
+		if c.opts.logger != nil {
+			size := 0
+			for _, value := range results {
+				size += len(value)
+			}
+			c.logBatchOp(context.Background(), "get_many", len(keys), start, size, err)
+		}
+		c.reportSlow("get_many", "", len(keys), start, 0)
+	}()
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	results = make(map[string][]byte, len(keys))
+	now := nowMillis()
+	for _, chunk := range chunkKeys(uniqueKeys(keys)) {
+		query := `SELECT key, value, rowid, checksum
+FROM ` + c.tables.kv + `
+WHERE key IN (` + placeholders(len(chunk)) + `) AND ` + liveCondition + `;`
+
+		if err := scanKeyValues(db, query, keyArgs(chunk, now), results); err != nil {
+			return nil, err
+		}
+	}
+	for key, stored := range results {
+		if results[key], err = c.decodeStored(key, stored); err != nil {
+			return nil, err
+		}
+	}
+	if c.opts.caseInsensitiveKeys {
+		results = byRequestedKey(keys, results)
+	}
+	return results, nil
+}
+
+// scanKeyValues runs a query selecting (key, value, rowid, checksum) rows and
+// stores each verified value in dst.
+func scanKeyValues(db querier, query string, args []interface{}, dst map[string][]byte) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key      string
+			value    []byte
+			version  int64
+			checksum sql.NullInt64
+		)
+		if err := rows.Scan(&key, &value, &version, &checksum); err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		if err := verifyChecksum(key, version, value, checksum); err != nil {
+			return err
+		}
+		if value == nil {
+			value = []byte{}
+		}
+		dst[key] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteMany soft-deletes several keys in a single transaction and returns
+// how many of them were present beforehand.
+//
+// Missing, already deleted, and expired keys are skipped without error. An
+// empty slice is a no-op returning 0.
+//
+// Example:
+//
+//	n, err := client.DeleteMany([]string{"user:1", "user:2"})
+func (c *CacheClient) DeleteMany(keys []string) (n int, err error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	start := c.opStart()
+	var wait time.Duration
+	defer func() {
+		c.stats.recordDelete(len(keys), err)
+		c.logBatchOp(context.Background(), "delete_many", len(keys), start, 0, err)
+		c.reportSlow("delete_many", "", len(keys), start, wait)
+	}()
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	var total int
+	now := nowMillis()
+	err = c.withTxWaiting(db, &wait, func(tx *sql.Tx) error {
+		total = 0
+		for _, chunk := range chunkKeys(uniqueKeys(keys)) {
+			query := `UPDATE ` + c.tables.kv + `
+SET is_active = 0
+WHERE key IN (` + placeholders(len(chunk)) + `) AND ` + liveCondition + `;`
+
+			result, err := tx.Exec(query, keyArgs(chunk, now)...)
+			if err != nil {
+				return fmt.Errorf("exec failed: %w", err)
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("rows affected failed: %w", err)
+			}
+			total += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	unique := uniqueKeys(keys)
+	for _, key := range unique {
+		c.notify(key, WatchDelete, nil)
+	}
+	return total, c.mirror(mirrorDeletes(unique)...)
+}
@@ -0,0 +1,251 @@
+package squeakyv
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetMany(t *testing.T) {
+	client := newTestClient(t)
+
+	items := map[string][]byte{
+		"key1": []byte("value1"),
+		"key2": []byte("value2"),
+		"key3": {0x00, 0xFF},
+	}
+	if err := client.SetMany(items); err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+
+	for key, expected := range items {
+		value, err := client.Get(key)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", key, err)
+		}
+		if !bytes.Equal(value, expected) {
+			t.Errorf("Key %s: expected %v, got %v", key, expected, value)
+		}
+	}
+}
+
+func TestSetManyVersioning(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("old"))
+	if err := client.SetMany(map[string][]byte{"key": []byte("new")}); err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+
+	value, err := client.Get("key")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if string(value) != "new" {
+		t.Errorf("Expected new, got %s", value)
+	}
+	if rows := countRows(t, client, "key"); rows != 2 {
+		t.Errorf("Expected old version to be kept as history, found %d rows", rows)
+	}
+}
+
+func TestSetManyRollsBack(t *testing.T) {
+	client := newTestClient(t)
+
+	// A nil value violates the NOT NULL constraint on kv.value
+	items := map[string][]byte{
+		"good": []byte("value"),
+		"bad":  nil,
+	}
+	if err := client.SetMany(items); err == nil {
+		t.Fatal("Expected SetMany to fail")
+	}
+
+	keys, err := client.ListKeys()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Expected no keys after rollback, got %v", keys)
+	}
+}
+
+func TestSetManyEmpty(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.SetMany(nil); err != nil {
+		t.Errorf("Expected no error for empty input, got %v", err)
+	}
+}
+
+func benchmarkItems(n int) map[string][]byte {
+	items := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		items[fmt.Sprintf("key_%d", i)] = []byte(fmt.Sprintf("value_%d", i))
+	}
+	return items
+}
+
+func BenchmarkSetLoop(b *testing.B) {
+	client, err := NewCacheClient(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	items := benchmarkItems(50)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for key, value := range items {
+			if err := client.Set(key, value); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkSetMany(b *testing.B) {
+	client, err := NewCacheClient(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	items := benchmarkItems(50)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := client.SetMany(items); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key1", []byte("value1"))
+	client.Set("key2", []byte("value2"))
+	client.Set("deleted", []byte("value"))
+	client.Delete("deleted")
+
+	values, err := client.GetMany([]string{"key1", "key2", "key1", "missing", "deleted"})
+	if err != nil {
+		t.Fatalf("Failed to get values: %v", err)
+	}
+
+	if len(values) != 2 {
+		t.Fatalf("Expected 2 values, got %d: %v", len(values), values)
+	}
+	if string(values["key1"]) != "value1" || string(values["key2"]) != "value2" {
+		t.Errorf("Unexpected values: %v", values)
+	}
+	if _, ok := values["missing"]; ok {
+		t.Error("Missing key should be absent from result")
+	}
+}
+
+func TestGetManyChunks(t *testing.T) {
+	client := newTestClient(t)
+
+	items := benchmarkItems(2500)
+	if err := client.SetMany(items); err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+
+	values, err := client.GetMany(keys)
+	if err != nil {
+		t.Fatalf("Failed to get values: %v", err)
+	}
+	if len(values) != len(items) {
+		t.Fatalf("Expected %d values, got %d", len(items), len(values))
+	}
+	for key, expected := range items {
+		if !bytes.Equal(values[key], expected) {
+			t.Errorf("Key %s: expected %s, got %s", key, expected, values[key])
+		}
+	}
+}
+
+func TestGetManyEmpty(t *testing.T) {
+	client := newTestClient(t)
+
+	values, err := client.GetMany(nil)
+	if err != nil {
+		t.Fatalf("Failed to get values: %v", err)
+	}
+	if len(values) != 0 {
+		t.Errorf("Expected empty result, got %v", values)
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key1", []byte("value1"))
+	client.Set("key2", []byte("value2"))
+	client.Set("key3", []byte("value3"))
+
+	n, err := client.DeleteMany([]string{"key1", "key2", "key2", "missing"})
+	if err != nil {
+		t.Fatalf("Failed to delete keys: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 deleted keys, got %d", n)
+	}
+
+	keys, err := client.ListKeys()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "key3" {
+		t.Errorf("Expected only key3 to remain, got %v", keys)
+	}
+
+	// Deleting again finds nothing active
+	n, err = client.DeleteMany([]string{"key1", "key2"})
+	if err != nil {
+		t.Fatalf("Failed to delete keys: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0 deleted keys on repeat, got %d", n)
+	}
+}
+
+func TestDeleteManyEmpty(t *testing.T) {
+	client := newTestClient(t)
+
+	n, err := client.DeleteMany([]string{})
+	if err != nil {
+		t.Fatalf("Expected no error for empty input, got %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0, got %d", n)
+	}
+}
+
+func TestDeleteManyChunks(t *testing.T) {
+	client := newTestClient(t)
+
+	items := benchmarkItems(1200)
+	if err := client.SetMany(items); err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+
+	n, err := client.DeleteMany(keys)
+	if err != nil {
+		t.Fatalf("Failed to delete keys: %v", err)
+	}
+	if n != len(items) {
+		t.Errorf("Expected %d deleted keys, got %d", len(items), n)
+	}
+}
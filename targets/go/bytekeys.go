@@ -0,0 +1,50 @@
+package squeakyv
+
+// Byte-key variants of the basic operations, for keys that are binary, such
+// as hashes.
+//
+// Byte keys are stored in the same key column as string keys, exactly as
+// given: SQLite keeps text with its length and compares it byte by byte, so
+// arbitrary bytes, including zero bytes and invalid UTF-8, round-trip
+// unchanged, and a string key and its byte equivalent are the same key. The
+// column is not switched to BLOB because SQLite never considers a BLOB equal
+// to text, which would split every key in two, and because the other
+// language targets expect text.
+//
+// Prefix matching (ListKeysWithPrefix, DeletePrefix, Namespace) is
+// text-oriented and stops at a zero byte, so it does not suit binary keys.
+// Export writes keys that aren't valid UTF-8 base64-encoded, and ExportCSV
+// writes them as raw bytes, so both keep binary keys exact.
+
+// GetB retrieves the value for a binary key. See Get.
+//
+// Example:
+//
+//	sum := sha256.Sum256(content)
+//	value, err := client.GetB(sum[:])
+func (c *CacheClient) GetB(key []byte) ([]byte, error) {
+	return c.Get(string(key))
+}
+
+// SetB stores a value for a binary key. See Set.
+func (c *CacheClient) SetB(key, value []byte) error {
+	return c.Set(string(key), value)
+}
+
+// DeleteB soft-deletes a binary key. See Delete.
+func (c *CacheClient) DeleteB(key []byte) error {
+	return c.Delete(string(key))
+}
+
+// ListKeysB returns all live keys as byte slices, newest first. See ListKeys.
+func (c *CacheClient) ListKeysB() ([][]byte, error) {
+	keys, err := c.ListKeys()
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]byte, len(keys))
+	for i, key := range keys {
+		out[i] = []byte(key)
+	}
+	return out, nil
+}
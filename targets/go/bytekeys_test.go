@@ -0,0 +1,70 @@
+package squeakyv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestByteKeys(t *testing.T) {
+	client := newTestClient(t)
+
+	binary := []byte{0x00, 0xff, 'a', 0x00, 0xfe}
+	hash := sha256.Sum256([]byte("content"))
+	for _, key := range [][]byte{binary, hash[:], binary[:1]} {
+		if err := client.SetB(key, key); err != nil {
+			t.Fatalf("SetB(%x) failed: %v", key, err)
+		}
+	}
+	for _, key := range [][]byte{binary, hash[:], binary[:1]} {
+		if got, err := client.GetB(key); err != nil || !bytes.Equal(got, key) {
+			t.Errorf("Expected %x to round-trip, got %x, %v", key, got, err)
+		}
+	}
+
+	// Keys differing only after a zero byte stay distinct.
+	if got, _ := client.GetB(binary[:2]); got != nil {
+		t.Errorf("Expected no value for a truncated key, got %x", got)
+	}
+
+	keys, err := client.ListKeysB()
+	if err != nil {
+		t.Fatalf("ListKeysB failed: %v", err)
+	}
+	found := 0
+	for _, key := range keys {
+		if bytes.Equal(key, binary) || bytes.Equal(key, hash[:]) || bytes.Equal(key, binary[:1]) {
+			found++
+		}
+	}
+	if len(keys) != 3 || found != 3 {
+		t.Errorf("Expected the exact keys, got %x", keys)
+	}
+
+	if err := client.DeleteB(binary); err != nil {
+		t.Fatalf("DeleteB failed: %v", err)
+	}
+	if exists, _ := client.Exists(string(binary)); exists {
+		t.Error("Expected the key to be deleted")
+	}
+	if err := client.SetB(nil, []byte("v")); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for an empty key, got %v", err)
+	}
+}
+
+func TestByteKeysInteroperate(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("user:1", []byte("from string"))
+	if got, _ := client.GetB([]byte("user:1")); string(got) != "from string" {
+		t.Errorf("Expected the string key's value, got %q", got)
+	}
+	client.SetB([]byte("user:1"), []byte("from bytes"))
+	if got, _ := client.Get("user:1"); string(got) != "from bytes" {
+		t.Errorf("Expected the byte key's value, got %q", got)
+	}
+	if versions, _ := client.History("user:1"); len(versions) != 2 {
+		t.Errorf("Expected one key with 2 versions, got %d", len(versions))
+	}
+}
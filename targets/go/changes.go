@@ -0,0 +1,281 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// changesPrunedName is the kv_meta entry holding the lowest sequence number
+// PruneChangesBefore has been called with.
+const changesPrunedName = "changes_pruned_before"
+
+// pollBatch is how many changes PollChanges reads at a time.
+const pollBatch = 1000
+
+// ChangesSince returns up to limit changes recorded after sequence number seq,
+// oldest first, together with the sequence number to pass to the next call:
+// that of the last change returned, or seq if there are none. Pass 0 to start
+// from the first change.
+//
+// Every set and delete of a key is recorded in the database, by triggers, so
+// the feed includes writes by other clients and processes, writes in
+// WithTransaction and the other operations Watch doesn't see. A version
+// replaced by a new one is reported only as a set, as are an Undelete and a
+// value rewritten in place, such as by Append or RotateEncryptionKey; a
+// Rename is reported as a delete of the old key and a set of the new one.
+// Expiry and changes to a key's TTL are not reported.
+//
+// Changes are kept for 24 hours, or as set by WithChangeRetention. If changes
+// after seq have been removed, for being older than that or by
+// PruneChangesBefore, ChangesSince returns an error wrapping
+// ErrChangesPruned, together with the earliest sequence number the feed can
+// be read from.
+//
+// Example:
+//
+//	var cursor int64
+//	for {
+//		changes, next, err := client.ChangesSince(cursor, 100)
+//		if err != nil {
+//			return err
+//		}
+//		apply(changes)
+//		if next == cursor {
+//			break
+//		}
+//		cursor = next
+//	}
+func (c *CacheClient) ChangesSince(seq int64, limit int) ([]ChangeEvent, int64, error) {
+	if limit <= 0 {
+		return nil, seq, fmt.Errorf("invalid limit %d: must be positive", limit)
+	}
+
+	db, err := c.acquire()
+	if err != nil {
+		return nil, seq, err
+	}
+	defer c.release()
+
+	// Read the changes and the pruning mark from the same snapshot.
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, seq, fmt.Errorf("begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var prunedBefore int64
+	query := `SELECT COALESCE((SELECT value FROM ` + c.tables.meta + ` WHERE name = ?), 0);`
+	if err := tx.QueryRow(query, changesPrunedName).Scan(&prunedBefore); err != nil {
+		return nil, seq, fmt.Errorf("query failed: %w", err)
+	}
+	if seq+1 < prunedBefore {
+		return nil, prunedBefore - 1, fmt.Errorf("%w: changes before %d were pruned, reading after %d", ErrChangesPruned, prunedBefore, seq)
+	}
+
+	changes, err := readChanges(tx, c.tables, seq, limit)
+	if err != nil {
+		return nil, seq, err
+	}
+	if len(changes) > 0 {
+		seq = changes[len(changes)-1].Seq
+	}
+	return changes, seq, nil
+}
+
+// PollChanges calls fn, in order, for every change recorded from now on,
+// checking for new ones every interval, until ctx is done or fn returns an
+// error. It returns ctx.Err() or the error from fn.
+//
+// Unlike Subscribe, PollChanges sees changes made by other processes sharing
+// the database file, as soon as they commit. Changes are as described for
+// ChangesSince. To resume where a previous reader stopped, call ChangesSince
+// in a loop instead.
+//
+// Example:
+//
+//	err := client.PollChanges(ctx, time.Second, func(ev squeakyv.ChangeEvent) error {
+//		invalidate(ev.Key)
+//		return nil
+//	})
+func (c *CacheClient) PollChanges(ctx context.Context, interval time.Duration, fn func(ChangeEvent) error) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %v: must be positive", interval)
+	}
+
+	seq, err := c.lastChangeSeq()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		for {
+			changes, next, err := c.ChangesSince(seq, pollBatch)
+			if err != nil {
+				return err
+			}
+			for _, ev := range changes {
+				if err := fn(ev); err != nil {
+					return err
+				}
+			}
+			seq = next
+			if len(changes) < pollBatch || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
+
+// PruneChangesBefore physically deletes the recorded changes with a sequence
+// number lower than seq and returns the number removed. Sequence numbers are
+// never reused, and ChangesSince reports readers that fall behind the pruned
+// changes.
+//
+// Example:
+//
+//	// Keep the changes the slowest follower hasn't read
+//	n, err := client.PruneChangesBefore(slowestCursor + 1)
+func (c *CacheClient) PruneChangesBefore(seq int64) (int64, error) {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	var n int64
+	err = c.withTx(db, func(tx *sql.Tx) error {
+		n, err = pruneChangesBefore(tx, c.tables, seq)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// pruneChangesBefore performs PruneChangesBefore inside tx.
+func pruneChangesBefore(tx *sql.Tx, t tableNames, seq int64) (int64, error) {
+	result, err := tx.Exec(`DELETE FROM `+t.changes+` WHERE seq < ?;`, seq)
+	if err != nil {
+		return 0, fmt.Errorf("exec failed: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected failed: %w", err)
+	}
+
+	// Changes not yet recorded are not pruned.
+	last, err := queryLastChangeSeq(tx, t)
+	if err != nil {
+		return 0, err
+	}
+	query := `INSERT INTO ` + t.meta + ` (name, value)
+VALUES (?, ?)
+ON CONFLICT (name) DO UPDATE SET value = max(value, excluded.value);`
+	if _, err := tx.Exec(query, changesPrunedName, min(seq, last+1)); err != nil {
+		return 0, fmt.Errorf("exec failed: %w", err)
+	}
+	return n, nil
+}
+
+// pruneOldChanges prunes the changes recorded longer ago than
+// WithChangeRetention, as PruneChangesBefore does, if there are any.
+func (c *CacheClient) pruneOldChanges(db *sql.DB) error {
+	if c.opts.changeRetention <= 0 {
+		return nil
+	}
+	cutoff := nowMillis() - c.opts.changeRetention.Milliseconds()
+
+	// The feed is in changed_at order, so this stops at the first change
+	// that is kept.
+	var oldest, keep sql.NullInt64
+	query := `SELECT
+  (SELECT seq FROM ` + c.tables.changes + ` ORDER BY seq LIMIT 1),
+  (SELECT seq FROM ` + c.tables.changes + ` WHERE changed_at >= ? ORDER BY seq LIMIT 1);`
+	if err := db.QueryRow(query, cutoff).Scan(&oldest, &keep); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	if !oldest.Valid || (keep.Valid && keep.Int64 <= oldest.Int64) {
+		return nil
+	}
+
+	return c.withTx(db, func(tx *sql.Tx) error {
+		// Without a change to keep, every change recorded so far goes.
+		var before int64
+		query := `SELECT COALESCE(
+  (SELECT seq FROM ` + c.tables.changes + ` WHERE changed_at >= ? ORDER BY seq LIMIT 1),
+  (SELECT seq FROM sqlite_sequence WHERE name = ?) + 1,
+  0);`
+		if err := tx.QueryRow(query, cutoff, c.tables.changes).Scan(&before); err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+		_, err := pruneChangesBefore(tx, c.tables, before)
+		return err
+	})
+}
+
+// lastChangeSeq returns the sequence number of the latest recorded change,
+// including pruned ones, or 0 if there has been none.
+func (c *CacheClient) lastChangeSeq() (int64, error) {
+	db, err := c.acquire()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	return queryLastChangeSeq(db, c.tables)
+}
+
+// queryLastChangeSeq is lastChangeSeq on db.
+func queryLastChangeSeq(db querier, t tableNames) (int64, error) {
+	// AUTOINCREMENT tracks the highest sequence number ever handed out in
+	// sqlite_sequence, which survives pruning.
+	var seq int64
+	query := `SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = ?), 0);`
+	if err := db.QueryRow(query, t.changes).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+	return seq, nil
+}
+
+// readChanges returns up to limit changes recorded after seq, oldest first.
+func readChanges(db querier, t tableNames, seq int64, limit int) ([]ChangeEvent, error) {
+	query := `SELECT seq, key, op, changed_at
+FROM ` + t.changes + `
+WHERE seq > ? AND op <> 0
+ORDER BY seq
+LIMIT ?;`
+
+	rows, err := db.Query(query, seq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []ChangeEvent
+	for rows.Next() {
+		var (
+			ev        ChangeEvent
+			changedAt int64
+		)
+		if err := rows.Scan(&ev.Seq, &ev.Key, &ev.Op, &changedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		ev.Time = time.UnixMilli(changedAt)
+		changes = append(changes, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return changes, nil
+}
@@ -0,0 +1,345 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// changeList formats changes as "op key" strings for comparison.
+func changeList(changes []ChangeEvent) []string {
+	list := make([]string, len(changes))
+	for i, ev := range changes {
+		list[i] = ev.Op.String() + " " + ev.Key
+	}
+	return list
+}
+
+func TestChangesSince(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("a", []byte("1"))
+	client.Set("a", []byte("2"))
+	client.Delete("a")
+	client.Delete("missing")
+	client.WithTransaction(func(tx *Tx) error {
+		return tx.Set("b", []byte("1"))
+	})
+	client.SetWithTTL("c", []byte("1"), time.Hour)
+	client.Clear()
+
+	changes, next, err := client.ChangesSince(0, 100)
+	if err != nil {
+		t.Fatalf("ChangesSince failed: %v", err)
+	}
+	want := []string{"set a", "set a", "delete a", "set b", "set c", "delete b", "delete c"}
+	if got := changeList(changes); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected changes %v, got %v", want, got)
+	}
+	for i, ev := range changes {
+		if ev.Seq != int64(i+1) {
+			t.Errorf("Expected change %d to have sequence number %d, got %d", i, i+1, ev.Seq)
+		}
+		if ev.Time.IsZero() {
+			t.Errorf("Expected change %d to have a time", i)
+		}
+	}
+	if next != changes[len(changes)-1].Seq {
+		t.Errorf("Expected the next sequence number to be %d, got %d", changes[len(changes)-1].Seq, next)
+	}
+
+	if changes, again, err := client.ChangesSince(next, 100); err != nil || len(changes) != 0 || again != next {
+		t.Errorf("Expected no changes after %d, got %v, %d, %v", next, changes, again, err)
+	}
+	if _, _, err := client.ChangesSince(0, 0); err == nil {
+		t.Error("Expected an error for a zero limit")
+	}
+}
+
+func TestChangesSincePages(t *testing.T) {
+	client := newTestClient(t)
+	for i := 0; i < 5; i++ {
+		client.Set(fmt.Sprintf("key%d", i), []byte("v"))
+	}
+
+	var (
+		cursor int64
+		keys   []string
+	)
+	for {
+		changes, next, err := client.ChangesSince(cursor, 2)
+		if err != nil {
+			t.Fatalf("ChangesSince failed: %v", err)
+		}
+		if len(changes) > 2 {
+			t.Fatalf("Expected at most 2 changes, got %d", len(changes))
+		}
+		for _, ev := range changes {
+			keys = append(keys, ev.Key)
+		}
+		if next == cursor {
+			break
+		}
+		cursor = next
+	}
+	if want := "[key0 key1 key2 key3 key4]"; fmt.Sprint(keys) != want {
+		t.Errorf("Expected %s, got %v", want, keys)
+	}
+}
+
+func TestChangesCaseInsensitiveOverwrite(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithCaseInsensitiveKeys())
+	if err != nil {
+		t.Fatalf("NewCacheClient failed: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("Key", []byte("1"))
+	client.Set("KEY", []byte("2"))
+
+	changes, _, err := client.ChangesSince(0, 100)
+	if err != nil {
+		t.Fatalf("ChangesSince failed: %v", err)
+	}
+	if want := "[set Key set KEY]"; fmt.Sprint(changeList(changes)) != want {
+		t.Errorf("Expected %s, got %v", want, changeList(changes))
+	}
+}
+
+func TestPollChangesAcrossClients(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	writer, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("NewCacheClient failed: %v", err)
+	}
+	defer writer.Close()
+	follower, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("NewCacheClient failed: %v", err)
+	}
+	defer follower.Close()
+
+	writer.Set("before", []byte("ignored"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	seen := make(chan ChangeEvent, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- follower.PollChanges(ctx, 5*time.Millisecond, func(ev ChangeEvent) error {
+			seen <- ev
+			return nil
+		})
+	}()
+
+	// Let PollChanges record its starting point.
+	time.Sleep(20 * time.Millisecond)
+	writer.Set("after", []byte("v"))
+
+	select {
+	case ev := <-seen:
+		if ev.Key != "after" || ev.Op != WatchSet {
+			t.Errorf("Expected set after, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the follower to see the change")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestChangesInPlaceWritesAcrossClients(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	writer := newTestClientAt(t, path)
+	reader := newTestClientAt(t, path)
+
+	writer.Set("a", []byte("1"))
+	writer.Set("u", []byte("2"))
+	writer.Delete("u")
+	_, cursor, err := reader.ChangesSince(0, 100)
+	if err != nil {
+		t.Fatalf("ChangesSince failed: %v", err)
+	}
+
+	if err := writer.Rename("a", "b"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := writer.Undelete("u"); err != nil {
+		t.Fatalf("Undelete failed: %v", err)
+	}
+	if _, err := writer.Append("b", []byte("x")); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	changes, _, err := reader.ChangesSince(cursor, 100)
+	if err != nil {
+		t.Fatalf("ChangesSince failed: %v", err)
+	}
+	if want := "[delete a set b set u set b]"; fmt.Sprint(changeList(changes)) != want {
+		t.Errorf("Expected %s, got %v", want, changeList(changes))
+	}
+}
+
+func TestPollChangesCallbackError(t *testing.T) {
+	client := newTestClient(t)
+	stop := errors.New("stop")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.PollChanges(context.Background(), 5*time.Millisecond, func(ChangeEvent) error {
+			return stop
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	client.Set("key", []byte("v"))
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, stop) {
+			t.Errorf("Expected the callback's error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for PollChanges to return")
+	}
+}
+
+func TestPruneChangesBefore(t *testing.T) {
+	client := newTestClient(t)
+	for i := 0; i < 5; i++ {
+		client.Set(fmt.Sprintf("key%d", i), []byte("v"))
+	}
+
+	n, err := client.PruneChangesBefore(4)
+	if err != nil {
+		t.Fatalf("PruneChangesBefore failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 changes pruned, got %d", n)
+	}
+
+	changes, _, err := client.ChangesSince(3, 100)
+	if err != nil {
+		t.Fatalf("ChangesSince failed: %v", err)
+	}
+	if want := "[set key3 set key4]"; fmt.Sprint(changeList(changes)) != want {
+		t.Errorf("Expected %s, got %v", want, changeList(changes))
+	}
+
+	_, resume, err := client.ChangesSince(1, 100)
+	if !errors.Is(err, ErrChangesPruned) {
+		t.Fatalf("Expected ErrChangesPruned, got %v", err)
+	}
+	if resume != 3 {
+		t.Errorf("Expected to resume after 3, got %d", resume)
+	}
+
+	// Sequence numbers are not reused once every change is pruned.
+	client.PruneChangesBefore(100)
+	client.Set("key5", []byte("v"))
+	changes, _, err = client.ChangesSince(5, 100)
+	if err != nil {
+		t.Fatalf("ChangesSince failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Seq != 6 {
+		t.Errorf("Expected one change numbered 6, got %+v", changes)
+	}
+}
+
+func TestChangeRetention(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithChangeRetention(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("old", []byte("v"))
+	time.Sleep(60 * time.Millisecond)
+	client.Set("new", []byte("v"))
+	if _, err := client.SweepNow(); err != nil {
+		t.Fatalf("SweepNow failed: %v", err)
+	}
+
+	_, resume, err := client.ChangesSince(0, 100)
+	if !errors.Is(err, ErrChangesPruned) {
+		t.Fatalf("Expected ErrChangesPruned, got %v", err)
+	}
+	changes, _, err := client.ChangesSince(resume, 100)
+	if err != nil {
+		t.Fatalf("ChangesSince failed: %v", err)
+	}
+	if want := "[set new]"; fmt.Sprint(changeList(changes)) != want {
+		t.Errorf("Expected %s, got %v", want, changeList(changes))
+	}
+
+	// Once every change is old, all of them go.
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if changes, _, err := client.ChangesSince(resume+1, 100); err != nil || len(changes) != 0 {
+		t.Errorf("Expected every change pruned, got %v, %v", changes, err)
+	}
+}
+
+func TestChangesTriggerOrder(t *testing.T) {
+	// SQLite fires the most recently created trigger first, so recreating
+	// each of them in turn tries both orders.
+	for _, name := range []string{"kv_swap_active", "kv_changes_deactivate", "kv_pins_deactivate"} {
+		t.Run(name, func(t *testing.T) {
+			client := newTestClient(t)
+			for _, m := range goTriggers(client.tables) {
+				if m.name != name {
+					continue
+				}
+				if _, err := client.db.Exec(`DROP TRIGGER ` + m.name + `;` + m.create); err != nil {
+					t.Fatalf("Failed to recreate %s: %v", m.name, err)
+				}
+			}
+
+			client.Set("a", []byte("1"))
+			client.Pin("a")
+			client.Set("a", []byte("2"))
+			client.Delete("b")
+			changes, _, err := client.ChangesSince(0, 100)
+			if err != nil {
+				t.Fatalf("ChangesSince failed: %v", err)
+			}
+			if want := "[set a set a]"; fmt.Sprint(changeList(changes)) != want {
+				t.Errorf("Expected %s, got %v", want, changeList(changes))
+			}
+			if pinned, _ := client.ListPinned(); len(pinned) != 1 {
+				t.Errorf("Expected the pin kept on overwrite, got %v", pinned)
+			}
+		})
+	}
+}
+
+func TestChangesSharedSwapTrigger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	db, err := sql.Open(driverName, path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := db.Exec(SchemaSQL); err != nil {
+		t.Fatalf("Failed to initialize schema: %v", err)
+	}
+	db.Close()
+
+	// The shared kv_swap_active is replaced when the database is opened.
+	client := newTestClientAt(t, path)
+	client.Set("a", []byte("1"))
+	client.Set("a", []byte("2"))
+	changes, _, err := client.ChangesSince(0, 100)
+	if err != nil {
+		t.Fatalf("ChangesSince failed: %v", err)
+	}
+	if want := "[set a set a]"; fmt.Sprint(changeList(changes)) != want {
+		t.Errorf("Expected %s, got %v", want, changeList(changes))
+	}
+}
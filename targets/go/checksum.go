@@ -0,0 +1,126 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+	"hash/crc32"
+)
+
+// castagnoli is the CRC32C table, which most CPUs compute in hardware.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// verifyBatchSize is how many rows VerifyAll reads per query.
+const verifyBatchSize = 1000
+
+// checksumOf returns the checksum recorded for a value as stored.
+func checksumOf(stored []byte) int64 {
+	return int64(crc32.Checksum(stored, castagnoli))
+}
+
+// verifyChecksum checks the stored value of a version of key against its
+// recorded checksum. Versions without one, written by older versions of this
+// package or by other language targets, always pass.
+func verifyChecksum(key string, version int64, stored []byte, checksum sql.NullInt64) error {
+	if checksum.Valid && checksum.Int64 != checksumOf(stored) {
+		return &ChecksumMismatchError{Key: key, Version: version}
+	}
+	return nil
+}
+
+// VerifyStats summarizes a VerifyAll scan.
+type VerifyStats struct {
+	// Rows is the number of versions scanned, history included.
+	Rows int64
+	// Verified is the number of versions whose checksum matched.
+	Verified int64
+	// Unchecked is the number of versions without a checksum, written by
+	// older versions of this package or by other language targets.
+	Unchecked int64
+	// Corrupt is the number of versions whose checksum did not match.
+	Corrupt int64
+}
+
+// VerifyAll checks every version in the cache, history included, against the
+// checksum recorded when it was written, and calls fn with a
+// *ChecksumMismatchError for each corrupt one. The scan continues past
+// corrupt versions for as long as fn returns true; fn may be nil.
+//
+// Get, GetStrict, GetMany, GetVersion, snapshots and transactions verify the
+// versions they read; bulk reads such as ForEach and Export do not. The scan
+// reads the database in batches, so it does not block writers, but versions
+// written during the scan may be missed.
+//
+// Example:
+//
+//	stats, err := client.VerifyAll(func(key string, version int64, err error) bool {
+//		log.Printf("corrupt: %v", err)
+//		return true
+//	})
+func (c *CacheClient) VerifyAll(fn func(key string, version int64, err error) bool) (VerifyStats, error) {
+	db, err := c.acquire()
+	if err != nil {
+		return VerifyStats{}, err
+	}
+	defer c.release()
+
+	query := `SELECT rowid, key, value, checksum
+FROM ` + c.tables.kv + `
+WHERE rowid > ?
+ORDER BY rowid
+LIMIT ?;`
+
+	var (
+		stats VerifyStats
+		after int64
+	)
+	for {
+		type failure struct {
+			key     string
+			version int64
+		}
+		var (
+			failures []failure
+			n        int
+		)
+		rows, err := db.Query(query, after, verifyBatchSize)
+		if err != nil {
+			return stats, fmt.Errorf("query failed: %w", err)
+		}
+		for rows.Next() {
+			var (
+				key      string
+				value    []byte
+				checksum sql.NullInt64
+			)
+			if err := rows.Scan(&after, &key, &value, &checksum); err != nil {
+				rows.Close()
+				return stats, fmt.Errorf("scan failed: %w", err)
+			}
+			n++
+			stats.Rows++
+			switch {
+			case !checksum.Valid:
+				stats.Unchecked++
+			case checksum.Int64 == checksumOf(value):
+				stats.Verified++
+			default:
+				stats.Corrupt++
+				failures = append(failures, failure{key, after})
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return stats, fmt.Errorf("rows iteration failed: %w", err)
+		}
+
+		// fn runs once the batch's query is closed, so it may use the client.
+		for _, f := range failures {
+			if fn != nil && !fn(f.key, f.version, &ChecksumMismatchError{Key: f.key, Version: f.version}) {
+				return stats, nil
+			}
+		}
+		if n < verifyBatchSize {
+			return stats, nil
+		}
+	}
+}
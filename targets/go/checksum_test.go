@@ -0,0 +1,135 @@
+package squeakyv
+
+import (
+	"errors"
+	"testing"
+)
+
+// corruptChecksum makes the recorded checksum of every version of key
+// disagree with its value, as if the value had been damaged on disk.
+func corruptChecksum(t *testing.T, client *CacheClient, key string) {
+	t.Helper()
+	if _, err := client.db.Exec(`UPDATE kv SET checksum = checksum + 1 WHERE key = ?;`, key); err != nil {
+		t.Fatalf("Failed to corrupt checksum: %v", err)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("good", []byte("fine"))
+	client.Set("bad", []byte("damaged"))
+	corruptChecksum(t, client, "bad")
+
+	versions, _ := client.History("bad")
+	_, err := client.Get("bad")
+	var mismatch *ChecksumMismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Expected ChecksumMismatchError, got %v", err)
+	}
+	if mismatch.Key != "bad" || mismatch.Version != versions[0].ID {
+		t.Errorf("Expected key bad version %d, got %+v", versions[0].ID, mismatch)
+	}
+
+	if _, err := client.GetMany([]string{"good", "bad"}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected GetMany to fail, got %v", err)
+	}
+	if _, err := client.GetVersion("bad", versions[0].ID); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected GetVersion to fail, got %v", err)
+	}
+	if got, err := client.Get("good"); err != nil || string(got) != "fine" {
+		t.Errorf("Expected fine, got %q, %v", got, err)
+	}
+}
+
+func TestChecksumUnchecked(t *testing.T) {
+	client := newTestClient(t)
+
+	// Rows written without a checksum, as by other language targets, read fine.
+	if _, err := client.db.Exec(`INSERT INTO kv (key, value) VALUES ('legacy', 'old');`); err != nil {
+		t.Fatalf("Failed to insert row: %v", err)
+	}
+	if got, err := client.Get("legacy"); err != nil || string(got) != "old" {
+		t.Errorf("Expected old, got %q, %v", got, err)
+	}
+
+	// Rewriting a value without updating its checksum drops the checksum.
+	client.Set("key", []byte("value"))
+	if _, err := client.db.Exec(`UPDATE kv SET value = 'rewritten' WHERE key = 'key';`); err != nil {
+		t.Fatalf("Failed to update row: %v", err)
+	}
+	if got, err := client.Get("key"); err != nil || string(got) != "rewritten" {
+		t.Errorf("Expected rewritten, got %q, %v", got, err)
+	}
+
+	stats, err := client.VerifyAll(nil)
+	if err != nil {
+		t.Fatalf("VerifyAll failed: %v", err)
+	}
+	if stats != (VerifyStats{Rows: 2, Unchecked: 2}) {
+		t.Errorf("Expected 2 unchecked rows, got %+v", stats)
+	}
+}
+
+func TestChecksumMaintained(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("a", []byte("one"))
+	client.SetWithTTL("b", []byte("two"), 1<<40)
+	client.SetMany(map[string][]byte{"c": []byte("three")})
+	client.SetNX("d", []byte("four"))
+	client.Append("log", []byte("first "))
+	client.Append("log", []byte("second"))
+	client.Increment("counter", 1)
+	client.Copy("a", "copy")
+	versions, _ := client.History("a")
+	client.Set("a", []byte("newer"))
+	client.RestoreVersion("a", versions[0].ID)
+
+	stats, err := client.VerifyAll(nil)
+	if err != nil {
+		t.Fatalf("VerifyAll failed: %v", err)
+	}
+	if stats.Unchecked != 0 || stats.Corrupt != 0 || stats.Verified != stats.Rows {
+		t.Errorf("Expected every row verified, got %+v", stats)
+	}
+	if got, _ := client.Get("log"); string(got) != "first second" {
+		t.Errorf("Expected 'first second', got %q", got)
+	}
+}
+
+func TestVerifyAll(t *testing.T) {
+	client := newTestClient(t)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		client.Set(key, []byte("v1"))
+		client.Set(key, []byte("v2"))
+	}
+	corruptChecksum(t, client, "b")
+	corruptChecksum(t, client, "d")
+
+	var corrupt []string
+	stats, err := client.VerifyAll(func(key string, version int64, err error) bool {
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+		}
+		corrupt = append(corrupt, key)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("VerifyAll failed: %v", err)
+	}
+	if stats != (VerifyStats{Rows: 8, Verified: 4, Corrupt: 4}) {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if len(corrupt) != 4 || corrupt[0] != "b" || corrupt[3] != "d" {
+		t.Errorf("Expected both versions of b and d, got %v", corrupt)
+	}
+
+	// Returning false stops the scan.
+	calls := 0
+	client.VerifyAll(func(string, int64, error) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Expected the scan to stop after 1 call, got %d", calls)
+	}
+}
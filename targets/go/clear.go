@@ -0,0 +1,108 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Clear soft-deletes every active key in a single statement, leaving all
+// values in history. Concurrent readers see either the full cache or an
+// empty one, never a partially cleared state.
+//
+// Use ClearHard to remove history as well.
+func (c *CacheClient) Clear() error {
+	query := `UPDATE ` + c.tables.kv + `
+SET is_active = 0
+WHERE is_active = 1;`
+
+	return c.execAll(query)
+}
+
+// ClearHard physically deletes every row, including history and expired
+// versions, in a single statement. The database file does not shrink until it
+// is vacuumed. Changes older than WithChangeRetention are pruned from the
+// change feed too; the deletes themselves are recorded in it.
+func (c *CacheClient) ClearHard() error {
+	if err := c.execAll(`DELETE FROM ` + c.tables.kv + `;`); err != nil {
+		return err
+	}
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	return c.pruneOldChanges(db)
+}
+
+// Count returns the number of keys with a live value.
+func (c *CacheClient) Count() (int, error) {
+	query := `SELECT COUNT(*)
+FROM ` + c.tables.kv + `
+WHERE ` + liveCondition + `;`
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	var n int
+	if err := db.QueryRow(query, nowMillis()).Scan(&n); err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+	return n, nil
+}
+
+// execAll executes a statement that takes no arguments.
+func (c *CacheClient) execAll(query string) error {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+	defer c.mem.purge()
+
+	if _, err := c.exec(db, query); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
+
+// HardDelete physically removes every row for a key, including its history,
+// in a single transaction. Afterwards the key behaves as if it had never been
+// written. Deleting a key that doesn't exist is not an error.
+//
+// The freed space is reused for new data but the file does not shrink until
+// it is vacuumed. With WithSecureDelete the values are zeroed before removal.
+//
+// Example:
+//
+//	err := client.HardDelete("user:123:profile")
+func (c *CacheClient) HardDelete(key string) error {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+	defer c.invalidate(key)
+
+	return c.withTx(db, func(tx *sql.Tx) error {
+		if c.opts.secureDelete {
+			query := `UPDATE ` + c.tables.kv + `
+SET value = zeroblob(length(value))
+WHERE key = ?;`
+			if _, err := tx.Exec(query, key); err != nil {
+				return fmt.Errorf("exec failed: %w", err)
+			}
+		}
+
+		query := `DELETE FROM ` + c.tables.kv + `
+WHERE key = ?;`
+		if _, err := tx.Exec(query, key); err != nil {
+			return fmt.Errorf("exec failed: %w", err)
+		}
+		return nil
+	})
+}
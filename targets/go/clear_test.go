@@ -0,0 +1,128 @@
+package squeakyv
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClear(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("1"))
+	client.Set("a", []byte("2"))
+	client.Set("b", []byte("3"))
+
+	n, err := client.Count()
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 keys, got %d", n)
+	}
+
+	if err := client.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+
+	keys, err := client.ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys failed: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Expected no keys after Clear, got %v", keys)
+	}
+	if n, _ := client.Count(); n != 0 {
+		t.Errorf("Expected count 0 after Clear, got %d", n)
+	}
+
+	// Soft clear keeps history
+	if n := countRows(t, client, "a"); n != 2 {
+		t.Errorf("Expected history to survive Clear, got %d rows", n)
+	}
+
+	// The cache is usable afterwards
+	client.Set("a", []byte("4"))
+	value, _ := client.Get("a")
+	if string(value) != "4" {
+		t.Errorf("Expected 4, got %s", value)
+	}
+}
+
+func TestClearHard(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("1"))
+	client.Set("a", []byte("2"))
+	client.Delete("a")
+	client.Set("b", []byte("3"))
+
+	if err := client.ClearHard(); err != nil {
+		t.Fatalf("ClearHard failed: %v", err)
+	}
+
+	if n, _ := client.Count(); n != 0 {
+		t.Errorf("Expected count 0 after ClearHard, got %d", n)
+	}
+	var rows int
+	if err := client.db.QueryRow(`SELECT COUNT(*) FROM kv`).Scan(&rows); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if rows != 0 {
+		t.Errorf("Expected no rows after ClearHard, got %d", rows)
+	}
+}
+
+func TestHardDelete(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("v1"))
+	client.Set("key", []byte("v2"))
+	client.Delete("key")
+	client.Set("key", []byte("v3"))
+	client.Set("other", []byte("x"))
+
+	if err := client.HardDelete("key"); err != nil {
+		t.Fatalf("HardDelete failed: %v", err)
+	}
+
+	if versions, _ := client.History("key"); len(versions) != 0 {
+		t.Errorf("Expected no history, got %v", versions)
+	}
+	if _, err := client.Stat("key"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected key to look never written, got %v", err)
+	}
+	if value, _ := client.Get("other"); string(value) != "x" {
+		t.Errorf("Expected other key untouched, got %s", value)
+	}
+
+	if err := client.HardDelete("missing"); err != nil {
+		t.Errorf("Expected no error for missing key, got %v", err)
+	}
+}
+
+func TestHardDeleteSecure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secure.db")
+	client, err := NewCacheClient(path, WithSecureDelete())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	secret := []byte("top-secret-marker-0123456789")
+	client.Set("secret", secret)
+	client.Set("keep", []byte("visible"))
+	if err := client.HardDelete("secret"); err != nil {
+		t.Fatalf("HardDelete failed: %v", err)
+	}
+	client.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read database file: %v", err)
+	}
+	if bytes.Contains(data, secret) {
+		t.Error("Expected deleted value to be overwritten in the database file")
+	}
+}
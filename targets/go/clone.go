@@ -0,0 +1,56 @@
+package squeakyv
+
+// Clone writes an independent copy of the cache, history included, to
+// destPath and returns a client for it. destPath must not already exist;
+// ":memory:", or another in-memory path, clones into an in-memory cache.
+//
+// The copy is taken in a single read transaction with VACUUM INTO (or the
+// backup API for ":memory:" destinations), so it is consistent even while the
+// source keeps taking writes. It is also the simplest way to persist a
+// ":memory:" cache to disk.
+//
+// The clone is opened with the source's options, overridden by opts, except
+// that WithMustExist does not carry over.
+//
+// Example:
+//
+//	saved, err := memClient.Clone("snapshot.db")
+//	if err != nil {
+//		return err
+//	}
+//	defer saved.Close()
+func (c *CacheClient) Clone(destPath string, opts ...Option) (*CacheClient, error) {
+	opts = append([]Option{func(o *options) {
+		*o = c.opts
+		o.mustExist = false
+	}}, opts...)
+
+	if !isMemoryPath(destPath) {
+		if err := c.VacuumInto(destPath); err != nil {
+			return nil, err
+		}
+		return NewCacheClient(destPath, opts...)
+	}
+
+	clone, err := NewCacheClient(destPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		clone.Close()
+		return nil, err
+	}
+	defer c.release()
+
+	if err := backupDB(clone.db, db, nil); err != nil {
+		clone.Close()
+		return nil, err
+	}
+	if err := clone.remarkEnvelopes(c.usesEnvelopes()); err != nil {
+		clone.Close()
+		return nil, err
+	}
+	return clone, nil
+}
@@ -0,0 +1,100 @@
+package squeakyv
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	for _, dest := range []string{"file", ":memory:"} {
+		t.Run(dest, func(t *testing.T) {
+			client := newTestClient(t)
+			client.Set("key", []byte("v1"))
+			client.Set("key", []byte("v2"))
+			client.Set("gone", []byte("x"))
+			client.Delete("gone")
+
+			path := dest
+			if dest == "file" {
+				path = filepath.Join(t.TempDir(), "clone.db")
+			}
+			clone, err := client.Clone(path)
+			if err != nil {
+				t.Fatalf("Clone failed: %v", err)
+			}
+			defer clone.Close()
+
+			if result, _ := client.Diff(clone); !result.Equal() {
+				t.Errorf("Expected clone to match, got %+v", result)
+			}
+			if versions, _ := clone.History("key"); len(versions) != 2 {
+				t.Errorf("Expected history to be cloned, got %d versions", len(versions))
+			}
+			if deleted, _ := clone.ListDeletedKeys(); len(deleted) != 1 {
+				t.Errorf("Expected deleted key to be cloned, got %v", deleted)
+			}
+
+			// The clone is independent
+			clone.Set("key", []byte("v3"))
+			if value, _ := client.Get("key"); string(value) != "v2" {
+				t.Errorf("Expected source untouched, got %s", value)
+			}
+		})
+	}
+}
+
+func TestCloneConsistentUnderWrites(t *testing.T) {
+	client := newFileClient(t)
+
+	// Pairs are always written together, so a consistent clone has both or neither.
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			client.SetMany(map[string][]byte{
+				fmt.Sprintf("a%d", i): []byte("x"),
+				fmt.Sprintf("b%d", i): []byte("x"),
+			})
+		}
+	}()
+
+	clone, err := client.Clone(filepath.Join(t.TempDir(), "clone.db"))
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer clone.Close()
+
+	n, err := clone.Count()
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if n%2 != 0 {
+		t.Errorf("Expected whole pairs in the clone, got %d keys", n)
+	}
+}
+
+func TestCloneExistingPath(t *testing.T) {
+	client := newTestClient(t)
+	path := filepath.Join(t.TempDir(), "clone.db")
+
+	first, err := client.Clone(path)
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	first.Close()
+
+	if _, err := client.Clone(path); err == nil {
+		t.Error("Expected error cloning onto an existing database")
+	}
+}
@@ -0,0 +1,103 @@
+package squeakyv
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOperationsAfterClose(t *testing.T) {
+	client, err := NewCacheClient(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Failed to close client: %v", err)
+	}
+
+	if _, err := client.Get("key"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Get: expected ErrClosed, got %v", err)
+	}
+	if err := client.Set("key", []byte("value")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Set: expected ErrClosed, got %v", err)
+	}
+	if err := client.Delete("key"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Delete: expected ErrClosed, got %v", err)
+	}
+	if _, err := client.ListKeys(); !errors.Is(err, ErrClosed) {
+		t.Errorf("ListKeys: expected ErrClosed, got %v", err)
+	}
+	if err := client.SetWithTTL("key", []byte("value"), time.Minute); !errors.Is(err, ErrClosed) {
+		t.Errorf("SetWithTTL: expected ErrClosed, got %v", err)
+	}
+	if err := client.Expire("key", time.Minute); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expire: expected ErrClosed, got %v", err)
+	}
+	if err := client.Persist("key"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Persist: expected ErrClosed, got %v", err)
+	}
+	if _, _, err := client.TTL("key"); !errors.Is(err, ErrClosed) {
+		t.Errorf("TTL: expected ErrClosed, got %v", err)
+	}
+	if _, err := client.SweepNow(); !errors.Is(err, ErrClosed) {
+		t.Errorf("SweepNow: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestCloseRacingOperations(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithSweepInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			<-start
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key_%d_%d", id, j)
+				if err := client.Set(key, []byte("value")); err != nil && !errors.Is(err, ErrClosed) {
+					t.Errorf("Set: unexpected error %v", err)
+				}
+				if _, err := client.Get(key); err != nil && !errors.Is(err, ErrClosed) {
+					t.Errorf("Get: unexpected error %v", err)
+				}
+			}
+		}(i)
+	}
+
+	close(start)
+	time.Sleep(time.Millisecond)
+	if err := client.Close(); err != nil {
+		t.Errorf("Failed to close client: %v", err)
+	}
+	wg.Wait()
+
+	if _, err := client.Get("key_0_0"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestConcurrentClose(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithSweepInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Close(); err != nil {
+				t.Errorf("Failed to close client: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,65 @@
+package squeakyv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Codec converts Go values to and from the bytes stored in the cache.
+//
+// Implementations must be safe for concurrent use. Any serialization format
+// can be plugged in by wrapping its marshal and unmarshal functions.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is a Codec using encoding/json. It honors json.Marshaler and
+// json.Unmarshaler implementations.
+type JSONCodec struct{}
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// encodeValue marshals the value of key with codec.
+func encodeValue(codec Codec, key string, v any) ([]byte, error) {
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value of key %q: %w", key, err)
+	}
+	return data, nil
+}
+
+// setEncoded stores v for key, marshaled with codec.
+func (c *CacheClient) setEncoded(codec Codec, key string, v any) error {
+	data, err := encodeValue(codec, key, v)
+	if err != nil {
+		return err
+	}
+	return c.Set(key, data)
+}
+
+// getDecoded unmarshals the value of key into dst with codec, reporting
+// whether the key exists. A value that fails to decode returns a
+// *DecodeError.
+func (c *CacheClient) getDecoded(codec Codec, key string, dst any) (bool, error) {
+	data, err := c.GetStrict(key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := codec.Unmarshal(data, dst); err != nil {
+		return true, &DecodeError{Key: key, Err: err}
+	}
+	return true, nil
+}
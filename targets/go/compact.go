@@ -0,0 +1,89 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// CompactStats reports what Compact removed.
+type CompactStats struct {
+	// Rows is the number of inactive rows deleted.
+	Rows int64
+	// Bytes is the total size of the values in those rows.
+	Bytes int64
+}
+
+// Compact physically deletes every inactive row, that is, the versions of
+// deleted keys and every superseded version, and reports how much was
+// removed. Active versions are never touched; expired ones are left to
+// SweepNow. Changes older than WithChangeRetention are pruned from the change
+// feed too.
+//
+// Rows are deleted in batches (see WithSweepBatchSize), each in its own short
+// transaction, so Compact can run while other goroutines keep reading and
+// writing. Afterwards it runs an incremental vacuum, which returns the freed
+// pages to the file system if the database uses auto_vacuum = INCREMENTAL and
+// does nothing otherwise; see Vacuum for other databases.
+//
+// Example:
+//
+//	stats, err := client.Compact()
+//	if err != nil {
+//		return err
+//	}
+//	log.Printf("compacted %d rows (%d bytes)", stats.Rows, stats.Bytes)
+func (c *CacheClient) Compact() (CompactStats, error) {
+	measure := `SELECT COUNT(*), COALESCE(SUM(length(value)), 0)
+FROM (
+  SELECT value FROM ` + c.tables.kv + `
+  WHERE is_active = 0
+  ORDER BY rowid
+  LIMIT ?
+);`
+	remove := `DELETE FROM ` + c.tables.kv + `
+WHERE rowid IN (
+  SELECT rowid FROM ` + c.tables.kv + `
+  WHERE is_active = 0
+  ORDER BY rowid
+  LIMIT ?
+);`
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return CompactStats{}, err
+	}
+	defer c.release()
+
+	var stats CompactStats
+	for {
+		var batch CompactStats
+		err := c.withTx(db, func(tx *sql.Tx) error {
+			if err := tx.QueryRow(measure, c.opts.sweepBatchSize).Scan(&batch.Rows, &batch.Bytes); err != nil {
+				return fmt.Errorf("query failed: %w", err)
+			}
+			if batch.Rows == 0 {
+				return nil
+			}
+			if _, err := tx.Exec(remove, c.opts.sweepBatchSize); err != nil {
+				return fmt.Errorf("exec failed: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return stats, err
+		}
+		stats.Rows += batch.Rows
+		stats.Bytes += batch.Bytes
+		if batch.Rows < int64(c.opts.sweepBatchSize) {
+			break
+		}
+	}
+
+	if err := c.pruneOldChanges(db); err != nil {
+		return stats, err
+	}
+	if _, err := db.Exec(`PRAGMA incremental_vacuum;`); err != nil {
+		return stats, fmt.Errorf("exec failed: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,83 @@
+package squeakyv
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCompact(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithSweepBatchSize(2))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("a", []byte("111"))
+	client.Set("a", []byte("22"))
+	client.Set("a", []byte("3"))
+	client.Set("b", []byte("4444"))
+	client.Delete("b")
+	client.Set("c", []byte("live"))
+
+	stats, err := client.Compact()
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if stats.Rows != 3 {
+		t.Errorf("Expected 3 rows removed, got %d", stats.Rows)
+	}
+	if stats.Bytes != 9 {
+		t.Errorf("Expected 9 bytes removed, got %d", stats.Bytes)
+	}
+
+	if n := countRows(t, client, "a"); n != 1 {
+		t.Errorf("Expected only the active version of a, got %d rows", n)
+	}
+	if n := countRows(t, client, "b"); n != 0 {
+		t.Errorf("Expected deleted key to be purged, got %d rows", n)
+	}
+	for key, expected := range map[string]string{"a": "3", "c": "live"} {
+		if value, _ := client.Get(key); string(value) != expected {
+			t.Errorf("Expected %s=%s, got %s", key, expected, value)
+		}
+	}
+
+	// Nothing left to remove
+	stats, err = client.Compact()
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if stats.Rows != 0 || stats.Bytes != 0 {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
+
+func TestCompactConcurrentWrites(t *testing.T) {
+	client := newFileClient(t)
+
+	for i := 0; i < 200; i++ {
+		client.Set("key", []byte{byte(i)})
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := client.Set("key", []byte("latest")); err != nil {
+				t.Errorf("Set failed: %v", err)
+				return
+			}
+		}
+	}()
+
+	if _, err := client.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	wg.Wait()
+
+	value, _ := client.Get("key")
+	if string(value) != "latest" {
+		t.Errorf("Expected latest, got %s", value)
+	}
+}
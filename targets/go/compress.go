@@ -0,0 +1,217 @@
+package squeakyv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Compressor compresses values before they are stored. Configure one with
+// WithCompressor.
+//
+// Every compressed value records its compressor's ID, so a database written
+// with one compressor stays readable by clients configured with another, or
+// with none, as long as the original compressor has been registered with
+// RegisterCompressor. Implementations must be safe for concurrent use.
+type Compressor interface {
+	// Encode returns the compressed form of data.
+	Encode(data []byte) ([]byte, error)
+	// Decode reverses Encode.
+	Decode(data []byte) ([]byte, error)
+	// ID identifies the compressor, and for dictionary-based compressors the
+	// dictionary, in stored values. It must never change for a given
+	// format, and must not be 0, which marks values stored uncompressed.
+	// IDs 1 to 15 are reserved for compressors shipped with this package.
+	ID() byte
+}
+
+// uncompressedID marks values stored as is by a client with a compressor,
+// because compressing them would not have made them smaller.
+const uncompressedID = 0
+
+// gzipID is the ID of GzipCompressor.
+const gzipID = 1
+
+// compressors holds the compressors available for decoding, by ID.
+var compressors = struct {
+	sync.RWMutex
+	byID map[byte]Compressor
+}{
+	byID: map[byte]Compressor{gzipID: GzipCompressor{}},
+}
+
+// RegisterCompressor makes a compressor available for decoding values it
+// compressed, whichever compressor the reading client is configured with.
+// GzipCompressor is registered already.
+//
+// Like sql.Register, it is meant to be called from an init function, and it
+// panics if c is nil, its ID is 0, or another compressor with the same ID is
+// already registered.
+func RegisterCompressor(c Compressor) {
+	if c == nil {
+		panic("squeakyv: RegisterCompressor compressor is nil")
+	}
+	id := c.ID()
+	if id == uncompressedID {
+		panic("squeakyv: RegisterCompressor compressor ID 0 is reserved")
+	}
+
+	compressors.Lock()
+	defer compressors.Unlock()
+
+	if _, dup := compressors.byID[id]; dup {
+		panic(fmt.Sprintf("squeakyv: RegisterCompressor called twice for compressor ID %d", id))
+	}
+	compressors.byID[id] = c
+}
+
+// lookupCompressor returns the compressor for id: preferred if it has that
+// ID, or else a registered one.
+func lookupCompressor(preferred Compressor, id byte) (Compressor, bool) {
+	if preferred != nil && preferred.ID() == id {
+		return preferred, true
+	}
+	compressors.RLock()
+	defer compressors.RUnlock()
+
+	c, ok := compressors.byID[id]
+	return c, ok
+}
+
+// compressValue returns value compressed with c, or as is if c is nil or
+// compressing doesn't make it smaller.
+func compressValue(c Compressor, key string, value []byte) ([]byte, error) {
+	if c == nil {
+		return appendLayer(layerCompressed, uncompressedID, value), nil
+	}
+	compressed, err := c.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress value of key %q: %w", key, err)
+	}
+	if len(compressed) >= len(value) {
+		return appendLayer(layerCompressed, uncompressedID, value), nil
+	}
+	return appendLayer(layerCompressed, c.ID(), compressed), nil
+}
+
+// decompressValue reverses compressValue for the compressor with the given
+// id, preferring the client's own.
+func decompressValue(preferred Compressor, key string, id byte, payload []byte) ([]byte, error) {
+	if id == uncompressedID {
+		return payload, nil
+	}
+	c, ok := lookupCompressor(preferred, id)
+	if !ok {
+		return nil, fmt.Errorf("failed to decompress value of key %q: %w %d", key, ErrUnknownCompressor, id)
+	}
+	value, err := c.Decode(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress value of key %q: %w", key, err)
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+// GzipCompressor is a Compressor using compress/gzip. It is registered by
+// default, with ID 1.
+type GzipCompressor struct {
+	// Level is the gzip compression level, such as gzip.BestSpeed. Zero
+	// means gzip.DefaultCompression.
+	Level int
+}
+
+// ID implements Compressor.
+func (GzipCompressor) ID() byte {
+	return gzipID
+}
+
+// Encode implements Compressor.
+func (g GzipCompressor) Encode(data []byte) ([]byte, error) {
+	level := g.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode implements Compressor.
+func (GzipCompressor) Decode(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// SampleValues returns up to n live values chosen at random, decoded as Get
+// would return them. It is meant for training compression dictionaries on
+// data representative of the cache, such as with BuildZstdDictionary under
+// the squeakyv_zstd build tag.
+//
+// Example:
+//
+//	samples, err := client.SampleValues(1000)
+func (c *CacheClient) SampleValues(n int) ([][]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	db, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	query := `SELECT key, value
+FROM ` + c.tables.kv + `
+WHERE ` + liveCondition + `
+ORDER BY random()
+LIMIT ?;`
+
+	rows, err := db.Query(query, nowMillis(), n)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var samples [][]byte
+	for rows.Next() {
+		var (
+			key    string
+			stored []byte
+		)
+		if err := rows.Scan(&key, &stored); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		value, err := c.decodeStored(key, stored)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			value = []byte{}
+		}
+		samples = append(samples, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return samples, nil
+}
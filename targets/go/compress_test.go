@@ -0,0 +1,289 @@
+package squeakyv
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// renamedCompressor is GzipCompressor under another ID.
+type renamedCompressor struct {
+	GzipCompressor
+	id byte
+}
+
+func (r renamedCompressor) ID() byte {
+	return r.id
+}
+
+// compressible is a value gzip shrinks a lot.
+var compressible = bytes.Repeat([]byte("squeaky "), 256)
+
+func TestCompressorRoundTrip(t *testing.T) {
+	client := newTestClient(t, WithCompressor(GzipCompressor{}))
+
+	client.Set("key", compressible)
+	if got, _ := client.Get("key"); !bytes.Equal(got, compressible) {
+		t.Errorf("Expected round trip, got %d bytes", len(got))
+	}
+	size, _ := client.SizeOf("key")
+	if size >= int64(len(compressible))/4 {
+		t.Errorf("Expected stored size well under %d, got %d", len(compressible), size)
+	}
+
+	// Values compression doesn't shrink are stored as is, in an envelope.
+	client.Set("small", []byte("x"))
+	client.Set("empty", []byte{})
+	if got, _ := client.GetStrict("small"); string(got) != "x" {
+		t.Errorf("Expected x, got %q", got)
+	}
+	if got, err := client.GetStrict("empty"); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("Expected empty non-nil value, got %q, %v", got, err)
+	}
+}
+
+func TestCompressorOperations(t *testing.T) {
+	client := newTestClient(t, WithCompressor(GzipCompressor{}))
+	client.Set("a", compressible)
+	client.SetWithTTL("b", compressible, 1<<40)
+
+	values, _ := client.GetMany([]string{"a", "b"})
+	if !bytes.Equal(values["a"], compressible) || !bytes.Equal(values["b"], compressible) {
+		t.Error("Expected GetMany to decompress")
+	}
+
+	client.ForEach(func(key string, value []byte) error {
+		if !bytes.Equal(value, compressible) {
+			t.Errorf("Expected ForEach to decompress %s", key)
+		}
+		return nil
+	})
+	for item, err := range client.ItemsErr() {
+		if err != nil || !bytes.Equal(item.Value, compressible) {
+			t.Errorf("Expected ItemsErr to decompress %s, got err %v", item.Key, err)
+		}
+	}
+
+	if swapped, _ := client.CompareAndSwap("a", compressible, []byte("new")); !swapped {
+		t.Error("Expected CompareAndSwap to compare decompressed values")
+	}
+	if previous, _ := client.GetSet("a", compressible); string(previous) != "new" {
+		t.Errorf("Expected GetSet to return new, got %q", previous)
+	}
+	versions, _ := client.History("a")
+	if len(versions) != 3 || string(versions[1].Value) != "new" {
+		t.Fatalf("Expected decompressed history, got %d versions", len(versions))
+	}
+	if old, _ := client.GetVersion("a", versions[2].ID); !bytes.Equal(old, compressible) {
+		t.Error("Expected GetVersion to decompress")
+	}
+
+	if n, _ := client.Append("log", []byte("one ")); n != 4 {
+		t.Errorf("Expected length 4, got %d", n)
+	}
+	if n, _ := client.Append("log", []byte("two")); n != 7 {
+		t.Errorf("Expected length 7, got %d", n)
+	}
+	if got, _ := client.Get("log"); string(got) != "one two" {
+		t.Errorf("Expected 'one two', got %q", got)
+	}
+	if versions, _ := client.History("log"); len(versions) != 1 {
+		t.Errorf("Expected Append to keep a single version, got %d", len(versions))
+	}
+
+	client.Increment("counter", 41)
+	if n, _ := client.Increment("counter", 1); n != 42 {
+		t.Errorf("Expected 42, got %d", n)
+	}
+
+	if deleted, _ := client.CompareAndDelete("b", compressible); !deleted {
+		t.Error("Expected CompareAndDelete to compare decompressed values")
+	}
+
+	err := client.WithTransaction(func(tx *Tx) error {
+		if err := tx.Set("tx", compressible); err != nil {
+			return err
+		}
+		value, err := tx.GetStrict("tx")
+		if !bytes.Equal(value, compressible) {
+			t.Error("Expected Tx.GetStrict to decompress")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction failed: %v", err)
+	}
+}
+
+func TestCompressorExportImport(t *testing.T) {
+	src := newTestClient(t, WithCompressor(GzipCompressor{}))
+	src.Set("key", compressible)
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf, ExportOptions{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	// Exports hold plain values, importable anywhere.
+	dst := newTestClient(t)
+	if _, err := dst.Import(&buf, ImportOptions{}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if got, _ := dst.Get("key"); !bytes.Equal(got, compressible) {
+		t.Error("Expected plain value after import")
+	}
+	if size, _ := dst.SizeOf("key"); size != int64(len(compressible)) {
+		t.Errorf("Expected uncompressed size, got %d", size)
+	}
+}
+
+func TestCompressorSwitch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	plain, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	plain.Set("old", []byte("written before compression"))
+	plain.Close()
+
+	gz := newTestClientAt(t, path, WithCompressor(GzipCompressor{}))
+	if got, _ := gz.Get("old"); string(got) != "written before compression" {
+		t.Errorf("Expected uncompressed value to stay readable, got %q", got)
+	}
+	gz.Set("new", compressible)
+
+	// A client with another compressor, or none, decodes by recorded ID.
+	other := newTestClientAt(t, path, WithCompressor(renamedCompressor{id: 201}))
+	if got, _ := other.Get("new"); !bytes.Equal(got, compressible) {
+		t.Error("Expected gzip value readable with another compressor")
+	}
+	other.Set("other", compressible)
+
+	none, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer none.Close()
+	if got, _ := none.Get("new"); !bytes.Equal(got, compressible) {
+		t.Error("Expected gzip value readable without a compressor")
+	}
+
+	_, err = none.Get("other")
+	if !errors.Is(err, ErrUnknownCompressor) || !strings.Contains(err.Error(), `"other"`) {
+		t.Errorf("Expected ErrUnknownCompressor naming the key, got %v", err)
+	}
+
+	// Appending without a compressor rewrites the decoded value.
+	if n, err := none.Append("new", []byte("!")); err != nil || n != int64(len(compressible))+1 {
+		t.Errorf("Expected length %d, got %d, %v", len(compressible)+1, n, err)
+	}
+}
+
+func TestPlainValueLikeEnvelope(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	lookalike := []byte{0xff, 'S', 'Q', 'V', 'z', 0, 'h', 'i'}
+
+	plain := newTestClientAt(t, path)
+	plain.Set("before", lookalike)
+	if got, _ := plain.Get("before"); !bytes.Equal(got, lookalike) {
+		t.Errorf("Expected the value as set without transforms, got %q", got)
+	}
+
+	// Enabling a transform keeps values already stored as they were, and
+	// clients opened before then wrap what they write from now on.
+	gz := newTestClientAt(t, path, WithCompressor(GzipCompressor{}))
+	plain.Set("after", lookalike)
+	for _, client := range []*CacheClient{plain, gz, newTestClientAt(t, path)} {
+		for _, key := range []string{"before", "after"} {
+			if got, err := client.Get(key); err != nil || !bytes.Equal(got, lookalike) {
+				t.Errorf("Expected the value of %q as set, got %q, %v", key, got, err)
+			}
+		}
+	}
+	if stats, err := plain.VerifyAll(nil); err != nil || stats.Corrupt != 0 {
+		t.Errorf("Expected checksums kept valid, got %+v, %v", stats, err)
+	}
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	writer := newTestClient(t, WithCompressor(renamedCompressor{id: 200}))
+	writer.Set("key", compressible)
+
+	clone, err := writer.Clone(":memory:", WithCompressor(nil))
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer clone.Close()
+	if _, err := clone.Get("key"); !errors.Is(err, ErrUnknownCompressor) {
+		t.Fatalf("Expected ErrUnknownCompressor, got %v", err)
+	}
+
+	RegisterCompressor(renamedCompressor{id: 200})
+	if got, err := clone.Get("key"); err != nil || !bytes.Equal(got, compressible) {
+		t.Errorf("Expected registered compressor to decode, got %v", err)
+	}
+
+	for name, c := range map[string]Compressor{
+		"nil":       nil,
+		"zero":      renamedCompressor{id: 0},
+		"duplicate": GzipCompressor{},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected RegisterCompressor to panic for %s", name)
+				}
+			}()
+			RegisterCompressor(c)
+		}()
+	}
+}
+
+func TestCompressorDiff(t *testing.T) {
+	dir := t.TempDir()
+	plain, err := NewCacheClient(filepath.Join(dir, "plain.db"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer plain.Close()
+	gz := newTestClientAt(t, filepath.Join(dir, "gz.db"), WithCompressor(GzipCompressor{}))
+
+	plain.Set("key", compressible)
+	gz.Set("key", compressible)
+
+	result, err := plain.Diff(gz)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !result.Equal() {
+		t.Errorf("Expected equal decoded values, got %+v", result)
+	}
+}
+
+func TestSampleValues(t *testing.T) {
+	client := newTestClient(t, WithCompressor(GzipCompressor{}))
+	for _, key := range []string{"a", "b", "c"} {
+		client.Set(key, compressible)
+	}
+	client.Delete("c")
+
+	samples, err := client.SampleValues(10)
+	if err != nil {
+		t.Fatalf("SampleValues failed: %v", err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 live samples, got %d", len(samples))
+	}
+	for _, sample := range samples {
+		if !bytes.Equal(sample, compressible) {
+			t.Error("Expected decompressed samples")
+		}
+	}
+
+	if samples, _ := client.SampleValues(1); len(samples) != 1 {
+		t.Errorf("Expected 1 sample, got %d", len(samples))
+	}
+}
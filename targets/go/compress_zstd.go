@@ -0,0 +1,126 @@
+//go:build squeakyv_zstd
+
+package squeakyv
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdID is the ID of a ZstdCompressor without a dictionary.
+const zstdID = 2
+
+// minDictID is the lowest ID accepted for a dictionary compressor, leaving
+// the lower IDs to compressors shipped with this package.
+const minDictID = 16
+
+// ZstdCompressor is a Compressor using zstd from
+// github.com/klauspost/compress, optionally with a dictionary, which greatly
+// improves the ratio on small values that share structure, such as JSON
+// documents with the same fields.
+//
+// It is only built with the squeakyv_zstd tag, so that builds without it
+// don't compile the compression module, though go.mod requires it. Create one
+// with NewZstdCompressor or NewZstdDictCompressor.
+type ZstdCompressor struct {
+	id  byte
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+// NewZstdCompressor returns a zstd compressor without a dictionary. Its ID is
+// 2.
+//
+// Example:
+//
+//	zc, err := squeakyv.NewZstdCompressor()
+//	if err != nil {
+//		return err
+//	}
+//	client, err := squeakyv.NewCacheClient("cache.db", squeakyv.WithCompressor(zc))
+func NewZstdCompressor() (*ZstdCompressor, error) {
+	return newZstdCompressor(zstdID, nil)
+}
+
+// NewZstdDictCompressor returns a zstd compressor using dictionary, such as
+// one built with BuildZstdDictionary. Values compressed with a dictionary can
+// only be decompressed with the same one, so id, which is recorded with every
+// value, must identify the dictionary: give every dictionary its own ID of 16
+// or above, and register the compressors of retired dictionaries with
+// RegisterCompressor so that old values stay readable.
+//
+// Example:
+//
+//	samples, err := client.SampleValues(1000)
+//	if err != nil {
+//		return err
+//	}
+//	dictionary, err := squeakyv.BuildZstdDictionary(samples, 64<<10)
+//	if err != nil {
+//		return err
+//	}
+//	zc, err := squeakyv.NewZstdDictCompressor(16, dictionary)
+func NewZstdDictCompressor(id byte, dictionary []byte) (*ZstdCompressor, error) {
+	if id < minDictID {
+		return nil, fmt.Errorf("invalid zstd dictionary compressor ID %d: must be at least %d", id, minDictID)
+	}
+	if len(dictionary) == 0 {
+		return nil, fmt.Errorf("invalid zstd dictionary: empty")
+	}
+	return newZstdCompressor(id, dictionary)
+}
+
+// newZstdCompressor returns a zstd compressor with the given ID, using
+// dictionary if it is non-nil.
+func newZstdCompressor(id byte, dictionary []byte) (*ZstdCompressor, error) {
+	var (
+		encOpts []zstd.EOption
+		decOpts []zstd.DOption
+	)
+	if dictionary != nil {
+		encOpts = append(encOpts, zstd.WithEncoderDict(dictionary))
+		decOpts = append(decOpts, zstd.WithDecoderDicts(dictionary))
+	}
+
+	enc, err := zstd.NewWriter(nil, encOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	dec, err := zstd.NewReader(nil, decOpts...)
+	if err != nil {
+		enc.Close()
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+	return &ZstdCompressor{id: id, enc: enc, dec: dec}, nil
+}
+
+// ID implements Compressor.
+func (z *ZstdCompressor) ID() byte {
+	return z.id
+}
+
+// Encode implements Compressor.
+func (z *ZstdCompressor) Encode(data []byte) ([]byte, error) {
+	return z.enc.EncodeAll(data, nil), nil
+}
+
+// Decode implements Compressor.
+func (z *ZstdCompressor) Decode(data []byte) ([]byte, error) {
+	return z.dec.DecodeAll(data, nil)
+}
+
+// BuildZstdDictionary trains a zstd dictionary of at most maxSize bytes on
+// samples, such as those returned by CacheClient.SampleValues. A few hundred
+// to a few thousand samples and a size of 16 to 128 KiB are typical.
+func BuildZstdDictionary(samples [][]byte, maxSize int) ([]byte, error) {
+	dictionary, err := dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: maxSize,
+		HashBytes:   6,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build zstd dictionary: %w", err)
+	}
+	return dictionary, nil
+}
@@ -0,0 +1,68 @@
+//go:build squeakyv_zstd
+
+package squeakyv
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestZstdCompressor(t *testing.T) {
+	zc, err := NewZstdCompressor()
+	if err != nil {
+		t.Fatalf("NewZstdCompressor failed: %v", err)
+	}
+	client, err := NewCacheClient(":memory:", WithCompressor(zc))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	want := bytes.Repeat([]byte("zstd "), 200)
+	client.Set("key", want)
+	if got, _ := client.Get("key"); !bytes.Equal(got, want) {
+		t.Errorf("Expected round trip, got %d bytes", len(got))
+	}
+	if size, _ := client.SizeOf("key"); size >= int64(len(want)) {
+		t.Errorf("Expected compressed size, got %d", size)
+	}
+}
+
+func TestZstdDictionary(t *testing.T) {
+	client := newTestClient(t)
+	for i := 0; i < 500; i++ {
+		client.Set(fmt.Sprint(i), []byte(fmt.Sprintf(`{"id":%d,"name":"user-%d","email":"user-%d@example.com","active":true}`, i, i, i)))
+	}
+
+	samples, err := client.SampleValues(500)
+	if err != nil {
+		t.Fatalf("SampleValues failed: %v", err)
+	}
+	dictionary, err := BuildZstdDictionary(samples, 4<<10)
+	if err != nil {
+		t.Fatalf("BuildZstdDictionary failed: %v", err)
+	}
+
+	if _, err := NewZstdDictCompressor(3, dictionary); err == nil {
+		t.Error("Expected reserved ID to be rejected")
+	}
+	zc, err := NewZstdDictCompressor(16, dictionary)
+	if err != nil {
+		t.Fatalf("NewZstdDictCompressor failed: %v", err)
+	}
+
+	value := []byte(`{"id":1000,"name":"user-1000","email":"user-1000@example.com","active":true}`)
+	encoded, err := zc.Encode(value)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	plain, _ := NewZstdCompressor()
+	withoutDict, _ := plain.Encode(value)
+	if len(encoded) >= len(withoutDict) {
+		t.Errorf("Expected the dictionary to help: %d bytes vs %d without", len(encoded), len(withoutDict))
+	}
+	if decoded, err := zc.Decode(encoded); err != nil || !bytes.Equal(decoded, value) {
+		t.Errorf("Expected round trip, got %q, %v", decoded, err)
+	}
+}
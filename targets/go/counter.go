@@ -0,0 +1,74 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ErrNotAnInteger is returned by Increment and Decrement when the stored
+// value is not a decimal-encoded int64. Errors for a specific key wrap it.
+var ErrNotAnInteger = errors.New("squeakyv: value is not an integer")
+
+// Increment adds delta to the integer stored at key and returns the new
+// value, all in one transaction, so concurrent increments are never lost.
+//
+// The value is stored as a decimal string (e.g. "42"). A missing key is
+// treated as 0. The result is written as a new version that keeps the
+// previous version's expiry, so counters with a TTL keep their window.
+// Returns an error wrapping ErrNotAnInteger if the current value doesn't
+// parse, or if the result would overflow an int64.
+//
+// Example:
+//
+//	hits, err := client.Increment("hits:/index.html", 1)
+func (c *CacheClient) Increment(key string, delta int64) (int64, error) {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+	defer c.invalidate(key)
+
+	var result int64
+	err = c.withTx(db, func(tx *sql.Tx) error {
+		current, expiresAt, err := readLiveVersion(tx, c.tables, key, nowMillis())
+		if errors.Is(err, ErrKeyNotFound) {
+			current = []byte("0")
+		} else if err != nil {
+			return err
+		} else if current, err = c.decodeStored(key, current); err != nil {
+			return err
+		}
+
+		n, err := strconv.ParseInt(string(current), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: key %q holds %q", ErrNotAnInteger, key, current)
+		}
+		if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+			return fmt.Errorf("%w: key %q overflows when adding %d to %d", ErrNotAnInteger, key, delta, n)
+		}
+
+		result = n + delta
+		stored, err := c.encodeStored(key, []byte(strconv.FormatInt(result, 10)))
+		if err != nil {
+			return err
+		}
+		return insertVersion(tx, c.tables, key, stored, expiresAt)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result, nil
+}
+
+// Decrement subtracts delta from the integer stored at key and returns the
+// new value. It is equivalent to Increment(key, -delta).
+func (c *CacheClient) Decrement(key string, delta int64) (int64, error) {
+	if delta == math.MinInt64 {
+		return 0, fmt.Errorf("%w: cannot negate delta %d", ErrNotAnInteger, delta)
+	}
+	return c.Increment(key, -delta)
+}
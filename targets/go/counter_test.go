@@ -0,0 +1,96 @@
+package squeakyv
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncrement(t *testing.T) {
+	client := newTestClient(t)
+
+	n, err := client.Increment("counter", 5)
+	if err != nil {
+		t.Fatalf("Increment failed: %v", err)
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 for missing key, got %d", n)
+	}
+
+	n, err = client.Decrement("counter", 7)
+	if err != nil {
+		t.Fatalf("Decrement failed: %v", err)
+	}
+	if n != -2 {
+		t.Errorf("Expected -2, got %d", n)
+	}
+
+	value, _ := client.Get("counter")
+	if string(value) != "-2" {
+		t.Errorf("Expected stored value -2, got %q", value)
+	}
+}
+
+func TestIncrementNotAnInteger(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("text", []byte("hello"))
+	if _, err := client.Increment("text", 1); !errors.Is(err, ErrNotAnInteger) {
+		t.Errorf("Expected ErrNotAnInteger, got %v", err)
+	}
+
+	value, _ := client.Get("text")
+	if string(value) != "hello" {
+		t.Errorf("Value changed after failed increment: %q", value)
+	}
+
+	client.Set("max", []byte(strconv.FormatInt(math.MaxInt64, 10)))
+	if _, err := client.Increment("max", 1); !errors.Is(err, ErrNotAnInteger) {
+		t.Errorf("Expected overflow to return ErrNotAnInteger, got %v", err)
+	}
+}
+
+func TestIncrementKeepsExpiry(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.SetWithTTL("window", []byte("1"), time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if _, err := client.Increment("window", 1); err != nil {
+		t.Fatalf("Increment failed: %v", err)
+	}
+
+	if _, ok, err := client.TTL("window"); err != nil || !ok {
+		t.Errorf("Expected expiry to be kept, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestIncrementConcurrent(t *testing.T) {
+	client := newFileClient(t, WithPragma("synchronous", "OFF"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := client.Increment("counter", 1); err != nil {
+					t.Errorf("Increment failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := client.Get("counter")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != "10000" {
+		t.Errorf("Expected 10000, got %s", value)
+	}
+}
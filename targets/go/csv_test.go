@@ -0,0 +1,121 @@
+package squeakyv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func readCSV(t *testing.T, client *CacheClient, opts CSVOptions) [][]string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := client.ExportCSV(&buf, opts); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	return records
+}
+
+func TestExportCSVAdversarialKeys(t *testing.T) {
+	client := newTestClient(t)
+
+	keys := []string{
+		"plain",
+		"comma,key",
+		`quote"key`,
+		"new\nline",
+		"crlf\r\nkey",
+		" leading space",
+		"",
+		"unicode ✓ ключ",
+		"binary\xff\x00\xfe",
+	}
+	for _, key := range keys[:6] {
+		client.Set(key, []byte(key))
+	}
+	// Empty keys can no longer be written but may predate validation.
+	if _, err := client.db.Exec(`INSERT INTO kv (key, value) VALUES ('', '');`); err != nil {
+		t.Fatalf("Failed to insert empty key: %v", err)
+	}
+	client.Set(keys[7], []byte(keys[7]))
+	client.SetB([]byte(keys[8]), []byte(keys[8]))
+	client.Set("deleted", []byte("x"))
+	client.Delete("deleted")
+
+	records := readCSV(t, client, CSVOptions{})
+	if !reflect.DeepEqual(records[0], csvHeader) {
+		t.Errorf("Expected header %v, got %v", csvHeader, records[0])
+	}
+	if len(records) != len(keys)+1 {
+		t.Fatalf("Expected %d rows, got %d", len(keys)+1, len(records))
+	}
+
+	seen := make(map[string]bool)
+	for _, rec := range records[1:] {
+		seen[rec[0]] = true
+	}
+	for _, key := range keys {
+		// encoding/csv normalizes \r\n inside quoted fields to \n
+		if key == "crlf\r\nkey" {
+			key = "crlf\nkey"
+		}
+		if !seen[key] {
+			t.Errorf("Key %q did not survive CSV round trip", key)
+		}
+	}
+}
+
+func TestExportCSVColumns(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("old"))
+	time.Sleep(5 * time.Millisecond)
+	client.Set("key", []byte{0xde, 0xad, 0xbe, 0xef})
+
+	records := readCSV(t, client, CSVOptions{ValueEncoding: CSVHex})
+	if len(records) != 2 {
+		t.Fatalf("Expected 1 row, got %d", len(records)-1)
+	}
+	row := records[1]
+	if row[1] != "deadbeef" || row[2] != "4" {
+		t.Errorf("Expected hex value and size, got %v", row)
+	}
+	created, err := time.Parse(time.RFC3339, row[3])
+	if err != nil {
+		t.Fatalf("Failed to parse created_at: %v", err)
+	}
+	updated, err := time.Parse(time.RFC3339, row[4])
+	if err != nil {
+		t.Fatalf("Failed to parse updated_at: %v", err)
+	}
+	if !created.Before(updated) {
+		t.Errorf("Expected created_at %v before updated_at %v", created, updated)
+	}
+
+	records = readCSV(t, client, CSVOptions{})
+	if records[1][1] != "3q2+7w==" {
+		t.Errorf("Expected base64 value, got %s", records[1][1])
+	}
+}
+
+func TestExportCSVPrefix(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("user:1", []byte("a"))
+	client.Set("user:2", []byte("b"))
+	client.Set("order:1", []byte("c"))
+
+	records := readCSV(t, client, CSVOptions{Prefix: "user:"})
+	if len(records) != 3 || records[1][0] != "user:1" || records[2][0] != "user:2" {
+		t.Errorf("Expected only user keys, got %v", records)
+	}
+
+	if err := client.ExportCSV(&bytes.Buffer{}, CSVOptions{ValueEncoding: 99}); err == nil {
+		t.Error("Expected error for invalid encoding")
+	}
+}
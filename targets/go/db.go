@@ -0,0 +1,51 @@
+package squeakyv
+
+import "database/sql"
+
+// DBStats returns the statistics of the client's database/sql connection
+// pool, such as open and in-use connections and time spent waiting for one.
+// It returns zero stats once the client is closed.
+//
+// Shared in-memory clients of the same database share one pool, and report
+// its combined stats.
+func (c *CacheClient) DBStats() sql.DBStats {
+	db, err := c.acquire()
+	if err != nil {
+		return sql.DBStats{}
+	}
+	defer c.release()
+
+	return db.Stats()
+}
+
+// UnsafeDB returns the client's underlying *sql.DB, for what the package has
+// no method for: running EXPLAIN QUERY PLAN, ATTACHing another database, or
+// ad-hoc inspection queries. It returns nil once the client is closed.
+//
+// Everything the client normally guarantees is bypassed, so use it with care:
+//
+//   - Writes skip the client's value encoding (compression, encryption),
+//     checksums, key validation and write serialization, and can leave rows
+//     the client cannot read. Prefer read-only queries.
+//   - Statements run as written. Under WithTableName, name the client's
+//     table in place of kv.
+//   - Settings changed on a connection, such as with PRAGMA or ATTACH, apply
+//     only to whichever pooled connection ran the statement. Use db.Conn to
+//     pin one connection for a sequence of statements.
+//   - Don't close the handle; close the client. After Close, statements on a
+//     retained handle fail with "sql: database is closed", except for shared
+//     in-memory databases, whose handle stays open until the last client
+//     using it closes.
+//
+// Example:
+//
+//	rows, err := client.UnsafeDB().Query(`EXPLAIN QUERY PLAN SELECT value FROM kv WHERE key = 'a'`)
+func (c *CacheClient) UnsafeDB() *sql.DB {
+	db, err := c.acquire()
+	if err != nil {
+		return nil
+	}
+	defer c.release()
+
+	return db
+}
@@ -0,0 +1,49 @@
+package squeakyv
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDBStats(t *testing.T) {
+	client, err := NewCacheClient(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Set("a", []byte("value"))
+
+	if stats := client.DBStats(); stats.OpenConnections == 0 {
+		t.Errorf("Expected open connections, got %+v", stats)
+	}
+	client.Close()
+	if stats := client.DBStats(); stats.OpenConnections != 0 {
+		t.Errorf("Expected zero stats after Close, got %+v", stats)
+	}
+}
+
+func TestUnsafeDB(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithTableName("cache"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Set("a", []byte("value"))
+
+	db := client.UnsafeDB()
+	var value string
+	if err := db.QueryRow(`SELECT value FROM cache WHERE key = 'a' AND is_active = 1`).Scan(&value); err != nil || value != "value" {
+		t.Errorf("Expected the client's table to be queried, got %q, %v", value, err)
+	}
+	rows, err := db.Query(`EXPLAIN QUERY PLAN SELECT value FROM cache WHERE key = 'a'`)
+	if err != nil {
+		t.Fatalf("EXPLAIN failed: %v", err)
+	}
+	rows.Close()
+
+	client.Close()
+	if client.UnsafeDB() != nil {
+		t.Error("Expected nil after Close")
+	}
+	if err := db.QueryRow(`SELECT 1`).Scan(new(int)); err == nil {
+		t.Error("Expected a retained handle to fail after Close")
+	}
+}
@@ -0,0 +1,103 @@
+package squeakyv
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeletedKey describes a key that has history but no live version, as
+// returned by ListDeletedKeysDetailed.
+type DeletedKey struct {
+	Key string
+	// DeletedAt is when the key's last version was deleted or expired.
+	DeletedAt time.Time
+}
+
+// ListDeletedKeys returns the keys that have retained history but no live
+// version, because they were deleted or have expired, most recently deleted
+// first.
+//
+// Keys that were deleted and later set again are not included, nor are keys
+// whose history has been purged.
+func (c *CacheClient) ListDeletedKeys() ([]string, error) {
+	deleted, err := c.ListDeletedKeysDetailed()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(deleted))
+	for i, d := range deleted {
+		keys[i] = d.Key
+	}
+	return keys, nil
+}
+
+// ListDeletedKeysDetailed is like ListDeletedKeys but also reports when each
+// key was deleted.
+//
+// Example:
+//
+//	deleted, err := client.ListDeletedKeysDetailed()
+//	if err != nil {
+//		return err
+//	}
+//	for _, d := range deleted {
+//		fmt.Printf("%s deleted at %v\n", d.Key, d.DeletedAt)
+//	}
+func (c *CacheClient) ListDeletedKeysDetailed() ([]DeletedKey, error) {
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	return listDeletedKeys(db, c.tables, nowMillis())
+}
+
+// listDeletedKeys returns keys with no live version at now, most recently
+// deleted first.
+//
+// A key's deletion time is taken from its current version (the active row if
+// it merely expired, otherwise the newest row): the earlier of its expiry and
+// its deactivation. Rows retired before deactivated_at existed fall back to
+// their insertion time.
+func listDeletedKeys(db querier, t tableNames, now int64) ([]DeletedKey, error) {
+	query := `SELECT key, deleted_at
+FROM (
+  SELECT key,
+    CASE
+      WHEN expires_at IS NOT NULL AND expires_at <= COALESCE(deactivated_at, expires_at) THEN expires_at
+      ELSE COALESCE(deactivated_at, inserted_at)
+    END AS deleted_at,
+    ` + liveCondition + ` AS live,
+    ROW_NUMBER() OVER (PARTITION BY key ORDER BY is_active DESC, rowid DESC) AS rank
+  FROM ` + t.kv + `
+)
+WHERE rank = 1 AND NOT live
+ORDER BY deleted_at DESC, key;`
+
+	rows, err := db.Query(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var results []DeletedKey
+	for rows.Next() {
+		var (
+			d         DeletedKey
+			deletedAt int64
+		)
+		if err := rows.Scan(&d.Key, &deletedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		d.DeletedAt = time.UnixMilli(deletedAt)
+		results = append(results, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return results, nil
+}
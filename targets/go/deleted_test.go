@@ -0,0 +1,76 @@
+package squeakyv
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestListDeletedKeys(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("first", []byte("1"))
+	client.Set("second", []byte("2"))
+	client.Set("resurrected", []byte("3"))
+	client.Set("live", []byte("4"))
+
+	client.Delete("first")
+	time.Sleep(5 * time.Millisecond)
+	client.Delete("second")
+	client.Delete("resurrected")
+	client.Set("resurrected", []byte("again"))
+
+	keys, err := client.ListDeletedKeys()
+	if err != nil {
+		t.Fatalf("ListDeletedKeys failed: %v", err)
+	}
+	if expected := []string{"second", "first"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+}
+
+func TestListDeletedKeysDetailed(t *testing.T) {
+	client := newTestClient(t)
+
+	before := time.Now().Add(-time.Second)
+	client.Set("deleted", []byte("1"))
+	client.Delete("deleted")
+	client.SetWithTTL("expired", []byte("2"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	deleted, err := client.ListDeletedKeysDetailed()
+	if err != nil {
+		t.Fatalf("ListDeletedKeysDetailed failed: %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("Expected 2 deleted keys, got %v", deleted)
+	}
+	if deleted[0].Key != "expired" || deleted[1].Key != "deleted" {
+		t.Errorf("Expected expired before deleted, got %v", deleted)
+	}
+	for _, d := range deleted {
+		if d.DeletedAt.Before(before) || d.DeletedAt.After(time.Now()) {
+			t.Errorf("Unexpected deletion time for %s: %v", d.Key, d.DeletedAt)
+		}
+	}
+
+	// Purged history is no longer listed
+	if err := client.ClearHard(); err != nil {
+		t.Fatalf("ClearHard failed: %v", err)
+	}
+	if keys, _ := client.ListDeletedKeys(); len(keys) != 0 {
+		t.Errorf("Expected no deleted keys after purge, got %v", keys)
+	}
+}
+
+func TestUndeleteRemovesFromDeletedKeys(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("value"))
+	client.Delete("key")
+	client.Undelete("key")
+
+	if keys, _ := client.ListDeletedKeys(); len(keys) != 0 {
+		t.Errorf("Expected no deleted keys, got %v", keys)
+	}
+}
@@ -0,0 +1,263 @@
+package squeakyv
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// diffSchema is the name the other database is attached under by DiffFunc.
+const diffSchema = "squeakyv_diff"
+
+// DiffKind says how a key differs between two caches.
+type DiffKind int
+
+const (
+	// DiffOnlyInA marks a key live only in the receiver of Diff.
+	DiffOnlyInA DiffKind = iota
+	// DiffOnlyInB marks a key live only in the other cache.
+	DiffOnlyInB
+	// DiffChanged marks a key live in both with different values.
+	DiffChanged
+)
+
+// String returns a short name for the kind.
+func (k DiffKind) String() string {
+	switch k {
+	case DiffOnlyInA:
+		return "only-in-a"
+	case DiffOnlyInB:
+		return "only-in-b"
+	case DiffChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("DiffKind(%d)", int(k))
+	}
+}
+
+// DiffResult counts the keys that differ between two caches.
+type DiffResult struct {
+	OnlyInA int64
+	OnlyInB int64
+	Changed int64
+}
+
+// Equal reports whether no differences were found.
+func (r DiffResult) Equal() bool {
+	return r.OnlyInA == 0 && r.OnlyInB == 0 && r.Changed == 0
+}
+
+// Diff compares the live keys of the cache (A) with those of other (B) and
+// counts the keys only in A, only in B, and in both with different values.
+// History is not compared. Use DiffFunc to see the keys themselves.
+//
+// Example:
+//
+//	result, err := primary.Diff(replica)
+//	if err != nil {
+//		return err
+//	}
+//	if !result.Equal() {
+//		log.Printf("replica drifted: %+v", result)
+//	}
+func (c *CacheClient) Diff(other *CacheClient) (DiffResult, error) {
+	return c.DiffFunc(other, nil)
+}
+
+// DiffFunc is like Diff but also calls fn, if non-nil, for every differing
+// key in key order. If fn returns an error the comparison stops and the error
+// is returned with the counts so far. fn must not call methods on either
+// client.
+//
+// When other is file-backed its database is attached to this one and values
+// are compared inside SQLite, so they never pass through Go. Otherwise, or
+// if either client transforms values (see WithCompressor), both caches are
+// streamed in key order and their decoded values compared in Go; memory use
+// stays bounded either way.
+func (c *CacheClient) DiffFunc(other *CacheClient, fn func(key string, kind DiffKind) error) (DiffResult, error) {
+	if other == c {
+		return DiffResult{}, nil
+	}
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return DiffResult{}, err
+	}
+	defer c.release()
+
+	otherDB, err := other.acquireFlushed()
+	if err != nil {
+		return DiffResult{}, err
+	}
+	defer other.release()
+
+	d := &differ{fn: fn, tables: c.tables}
+	now := nowMillis()
+	if isMemoryPath(other.path) || c.transformsValues() || other.transformsValues() {
+		err = d.stream(db, otherDB, now, c.decodeStored, other.decodeStored)
+	} else {
+		err = attachTo(db, other.path, diffSchema, func(ctx context.Context, conn *sql.Conn) error {
+			return d.attached(ctx, conn, now)
+		})
+	}
+	return d.result, err
+}
+
+// differ accumulates a DiffResult and reports keys to a callback.
+type differ struct {
+	fn     func(key string, kind DiffKind) error
+	tables tableNames
+	result DiffResult
+}
+
+// report records one differing key.
+func (d *differ) report(key string, kind DiffKind) error {
+	switch kind {
+	case DiffOnlyInA:
+		d.result.OnlyInA++
+	case DiffOnlyInB:
+		d.result.OnlyInB++
+	case DiffChanged:
+		d.result.Changed++
+	}
+	if d.fn != nil {
+		return d.fn(key, kind)
+	}
+	return nil
+}
+
+// liveIn returns liveCondition for the kv table aliased as alias, taking the
+// current time as the named parameter :now.
+func liveIn(alias string) string {
+	return alias + `.is_active = 1 AND (` + alias + `.expires_at IS NULL OR ` + alias + `.expires_at > :now)`
+}
+
+// attached compares main.kv with the kv table attached as diffSchema.
+func (d *differ) attached(ctx context.Context, conn *sql.Conn, now int64) error {
+	query := `SELECT a.key, ` + fmt.Sprint(int(DiffOnlyInA)) + `
+FROM main.` + d.tables.kv + ` a
+WHERE ` + liveIn("a") + ` AND NOT EXISTS (
+  SELECT 1 FROM ` + diffSchema + `.` + d.tables.kv + ` b WHERE b.key = a.key AND ` + liveIn("b") + `
+)
+UNION ALL
+SELECT b.key, ` + fmt.Sprint(int(DiffOnlyInB)) + `
+FROM ` + diffSchema + `.` + d.tables.kv + ` b
+WHERE ` + liveIn("b") + ` AND NOT EXISTS (
+  SELECT 1 FROM main.` + d.tables.kv + ` a WHERE a.key = b.key AND ` + liveIn("a") + `
+)
+UNION ALL
+SELECT a.key, ` + fmt.Sprint(int(DiffChanged)) + `
+FROM main.` + d.tables.kv + ` a
+JOIN ` + diffSchema + `.` + d.tables.kv + ` b ON b.key = a.key
+WHERE ` + liveIn("a") + ` AND ` + liveIn("b") + `
+  AND CAST(a.value AS BLOB) <> CAST(b.value AS BLOB)
+ORDER BY 1;`
+
+	rows, err := conn.QueryContext(ctx, query, sql.Named("now", now))
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key  string
+			kind DiffKind
+		)
+		if err := rows.Scan(&key, &kind); err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		if err := d.report(key, kind); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return nil
+}
+
+// stream compares two databases by walking their live rows in key order,
+// decoding the values of each with its decode function.
+func (d *differ) stream(a, b *sql.DB, now int64, decodeA, decodeB func(key string, stored []byte) ([]byte, error)) error {
+	query := `SELECT key, value
+FROM ` + d.tables.kv + `
+WHERE ` + liveCondition + `
+ORDER BY key;`
+
+	rowsA, err := a.Query(query, now)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	defer rowsA.Close()
+
+	rowsB, err := b.Query(query, now)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	defer rowsB.Close()
+
+	next := func(rows *sql.Rows, decode func(key string, stored []byte) ([]byte, error)) (string, []byte, bool, error) {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return "", nil, false, fmt.Errorf("rows iteration failed: %w", err)
+			}
+			return "", nil, false, nil
+		}
+		var (
+			key   string
+			value []byte
+		)
+		if err := rows.Scan(&key, &value); err != nil {
+			return "", nil, false, fmt.Errorf("scan failed: %w", err)
+		}
+		value, err := decode(key, value)
+		if err != nil {
+			return "", nil, false, err
+		}
+		return key, value, true, nil
+	}
+
+	keyA, valueA, okA, err := next(rowsA, decodeA)
+	if err != nil {
+		return err
+	}
+	keyB, valueB, okB, err := next(rowsB, decodeB)
+	if err != nil {
+		return err
+	}
+
+	for okA || okB {
+		switch {
+		case okA && (!okB || keyA < keyB):
+			if err := d.report(keyA, DiffOnlyInA); err != nil {
+				return err
+			}
+			if keyA, valueA, okA, err = next(rowsA, decodeA); err != nil {
+				return err
+			}
+		case okB && (!okA || keyB < keyA):
+			if err := d.report(keyB, DiffOnlyInB); err != nil {
+				return err
+			}
+			if keyB, valueB, okB, err = next(rowsB, decodeB); err != nil {
+				return err
+			}
+		default:
+			if !bytes.Equal(valueA, valueB) {
+				if err := d.report(keyA, DiffChanged); err != nil {
+					return err
+				}
+			}
+			if keyA, valueA, okA, err = next(rowsA, decodeA); err != nil {
+				return err
+			}
+			if keyB, valueB, okB, err = next(rowsB, decodeB); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package squeakyv
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type diffEntry struct {
+	key  string
+	kind DiffKind
+}
+
+func TestDiff(t *testing.T) {
+	fill := func(a, b *CacheClient) {
+		a.Set("same", []byte("v"))
+		b.Set("same", []byte("v"))
+		a.Set("changed", []byte("a"))
+		b.Set("changed", []byte("b"))
+		a.Set("only-a", []byte("a"))
+		b.Set("only-b", []byte("b"))
+		a.Set("deleted-in-b", []byte("v"))
+		b.Set("deleted-in-b", []byte("v"))
+		b.Delete("deleted-in-b")
+		// Same value, different history
+		a.Set("history", []byte("old"))
+		a.Set("history", []byte("v"))
+		b.Set("history", []byte("v"))
+		a.Set("empty", []byte{})
+		b.Set("empty", []byte{0})
+	}
+	expected := []diffEntry{
+		{"changed", DiffChanged},
+		{"deleted-in-b", DiffOnlyInA},
+		{"empty", DiffChanged},
+		{"only-a", DiffOnlyInA},
+		{"only-b", DiffOnlyInB},
+	}
+
+	clients := map[string]func(t testing.TB, opts ...Option) *CacheClient{
+		"memory": newTestClient,
+		"file":   newFileClient,
+	}
+	for name, newOther := range clients {
+		t.Run(name, func(t *testing.T) {
+			a := newTestClient(t)
+			b := newOther(t)
+			fill(a, b)
+
+			var got []diffEntry
+			result, err := a.DiffFunc(b, func(key string, kind DiffKind) error {
+				got = append(got, diffEntry{key, kind})
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("DiffFunc failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("Expected %v, got %v", expected, got)
+			}
+			if want := (DiffResult{OnlyInA: 2, OnlyInB: 1, Changed: 2}); result != want {
+				t.Errorf("Expected %+v, got %+v", want, result)
+			}
+		})
+	}
+}
+
+func TestDiffEqual(t *testing.T) {
+	a := newTestClient(t)
+	a.Set("key", []byte("value"))
+
+	path := filepath.Join(t.TempDir(), "copy.db")
+	if err := a.VacuumInto(path); err != nil {
+		t.Fatalf("VacuumInto failed: %v", err)
+	}
+	b, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to open copy: %v", err)
+	}
+	defer b.Close()
+
+	result, err := a.Diff(b)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !result.Equal() {
+		t.Errorf("Expected no differences, got %+v", result)
+	}
+
+	if result, _ := a.Diff(a); !result.Equal() {
+		t.Errorf("Expected a cache to equal itself, got %+v", result)
+	}
+}
+
+func TestDiffFuncStops(t *testing.T) {
+	a := newTestClient(t)
+	b := newTestClient(t)
+	a.Set("x", []byte("1"))
+	a.Set("y", []byte("2"))
+
+	stop := errors.New("stop")
+	result, err := a.DiffFunc(b, func(key string, kind DiffKind) error {
+		return stop
+	})
+	if err != stop {
+		t.Errorf("Expected callback error, got %v", err)
+	}
+	if result.OnlyInA != 1 {
+		t.Errorf("Expected counts up to the stop, got %+v", result)
+	}
+}
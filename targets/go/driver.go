@@ -0,0 +1,142 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The SQLite driver is chosen at build time. By default it is
+// github.com/mattn/go-sqlite3, which needs cgo; the squeakyv_purego build tag
+// selects modernc.org/sqlite instead. Each provides, in driver_*.go:
+//
+//	driverName   the name the driver is registered under with database/sql
+//	newDriver    returns a driver.Driver that opens SQLite connections
+//	isBusy       reports whether an error is SQLITE_BUSY or SQLITE_LOCKED
+//	backupToFile copies an open database to a file with the backup API
+//	backupDB     copies an open database over an open in-memory one
+
+// pragmaName and pragmaValue restrict WithPragma to plain identifiers and
+// simple literals, since pragmas cannot be parameterized.
+var (
+	pragmaName  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	pragmaValue = regexp.MustCompile(`^-?[A-Za-z0-9_.]+$`)
+)
+
+// connector opens SQLite connections for a CacheClient, configuring each one
+// with the client's pragmas. database/sql pools connections, and most pragmas
+// are per connection, so they must be applied on every open.
+type connector struct {
+	driver  driver.Driver
+	dsn     string
+	pragmas []pragma
+}
+
+// Connect implements driver.Connector.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("driver connection %T cannot execute statements", conn)
+	}
+	for _, p := range c.pragmas {
+		if _, err := execer.ExecContext(ctx, fmt.Sprintf("PRAGMA %s = %s;", p.name, p.value), nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to set pragma %s: %w", p.name, err)
+		}
+	}
+	return conn, nil
+}
+
+// Driver implements driver.Connector.
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// openDB opens the database at path with every connection configured by o.
+func openDB(path string, o options) (*sql.DB, error) {
+	pragmas := connPragmas(path, o)
+	for _, p := range pragmas {
+		if !pragmaName.MatchString(p.name) {
+			return nil, fmt.Errorf("invalid pragma name %q", p.name)
+		}
+		if !pragmaValue.MatchString(p.value) {
+			return nil, fmt.Errorf("invalid value %q for pragma %s", p.value, p.name)
+		}
+	}
+
+	return sql.OpenDB(&connector{
+		driver:  newDriver(),
+		dsn:     dataSource(path, o),
+		pragmas: pragmas,
+	}), nil
+}
+
+// uriEscaper escapes the characters that would end the path part of a URI
+// filename early.
+var uriEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+// dataSource returns the name the driver opens path by: path itself, or for
+// read-only clients a URI filename carrying mode=ro and, if requested,
+// immutable=1.
+func dataSource(path string, o options) string {
+	if !o.readOnly {
+		return path
+	}
+	params := "mode=ro"
+	if o.immutable {
+		params += "&immutable=1"
+	}
+	return "file:" + uriEscaper.Replace(path) + "?" + params
+}
+
+// connPragmas returns the pragmas applied to each new connection, in order.
+// An explicit journal mode comes first, then the WAL defaults for file-backed
+// databases, then pragmas from WithPragma, which may override them. Read-only
+// connections cannot change the journal mode and get neither.
+func connPragmas(path string, o options) []pragma {
+	var pragmas []pragma
+	// Set first so that the journal mode switch below waits for locks too.
+	if o.busyTimeout > 0 {
+		pragmas = append(pragmas, pragma{"busy_timeout", fmt.Sprint(o.busyTimeout.Milliseconds())})
+	}
+	if o.readOnly {
+		return append(pragmas, o.pragmas...)
+	}
+	switch {
+	case o.journalMode != "":
+		pragmas = append(pragmas, pragma{"journal_mode", o.journalMode})
+	case !isMemoryPath(path):
+		pragmas = append(pragmas, pragma{"journal_mode", "WAL"})
+	}
+	if !isMemoryPath(path) && (o.journalMode == "" || strings.EqualFold(o.journalMode, "WAL")) {
+		pragmas = append(pragmas, pragma{"synchronous", "NORMAL"})
+	}
+	return append(pragmas, o.pragmas...)
+}
+
+// checkJournalMode verifies that an explicitly requested journal mode took
+// effect. Filesystems that cannot support WAL leave the previous mode in
+// place without an error; when WAL was only the default, that is accepted.
+func checkJournalMode(db *sql.DB, o options) error {
+	if o.journalMode == "" {
+		return nil
+	}
+
+	var mode string
+	if err := db.QueryRow(`PRAGMA journal_mode;`).Scan(&mode); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	if !strings.EqualFold(mode, o.journalMode) {
+		return fmt.Errorf("journal_mode %s requested but the database is using %s", strings.ToUpper(o.journalMode), mode)
+	}
+	return nil
+}
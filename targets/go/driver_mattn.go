@@ -0,0 +1,115 @@
+//go:build !squeakyv_purego
+
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// driverName is the name github.com/mattn/go-sqlite3 registers with
+// database/sql.
+const driverName = "sqlite3"
+
+// newDriver returns the driver used to open connections.
+func newDriver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
+
+// isBusy reports whether err is SQLite's SQLITE_BUSY or SQLITE_LOCKED, in
+// any of their extended forms.
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
+// backupToFile copies the main database of src to the file at destPath,
+// replacing any database there.
+func backupToFile(src *sql.DB, destPath string, progress func(remaining, total int)) error {
+	dest, err := sql.Open(driverName, destPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup destination: %w", err)
+	}
+	defer dest.Close()
+
+	return backupDB(dest, src, progress)
+}
+
+// backupDB copies the main database of src over the main database of dest.
+func backupDB(dest, src *sql.DB, progress func(remaining, total int)) error {
+	ctx := context.Background()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get source connection: %w", err)
+	}
+	defer srcConn.Close()
+
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get destination connection: %w", err)
+	}
+	defer destConn.Close()
+
+	return destConn.Raw(func(destRaw interface{}) error {
+		return srcConn.Raw(func(srcRaw interface{}) error {
+			destSQLite, ok := destRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("backup: unexpected driver connection %T", destRaw)
+			}
+			srcSQLite, ok := srcRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("backup: unexpected driver connection %T", srcRaw)
+			}
+			return stepBackup(destSQLite, srcSQLite, progress)
+		})
+	})
+}
+
+// stepBackup runs an incremental backup between two raw connections.
+func stepBackup(dest, src *sqlite3.SQLiteConn, progress func(remaining, total int)) error {
+	backup, err := dest.Backup("main", src, "main")
+	if err != nil {
+		return fmt.Errorf("backup init failed: %w", err)
+	}
+
+	pages, restarts, lastRemaining := backupStepPages, 0, -1
+	for {
+		done, err := backup.Step(pages)
+		if err != nil {
+			backup.Close()
+			return fmt.Errorf("backup step failed: %w", err)
+		}
+
+		remaining := backup.Remaining()
+		if progress != nil {
+			progress(remaining, backup.PageCount())
+		}
+		if done {
+			break
+		}
+
+		if lastRemaining >= 0 && remaining > lastRemaining {
+			restarts++
+			if restarts >= backupMaxRestarts {
+				pages = -1
+			}
+		}
+		lastRemaining = remaining
+		time.Sleep(backupStepPause)
+	}
+
+	if err := backup.Close(); err != nil {
+		return fmt.Errorf("backup finish failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+//go:build squeakyv_purego
+
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// driverName is the name modernc.org/sqlite registers with database/sql.
+const driverName = "sqlite"
+
+// newDriver returns the driver used to open connections.
+func newDriver() driver.Driver {
+	return &sqlite.Driver{}
+}
+
+// isBusy reports whether err is SQLite's SQLITE_BUSY or SQLITE_LOCKED, in
+// any of their extended forms.
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// Code may be an extended result code; the primary code is its low byte.
+	code := sqliteErr.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+// backuper is implemented by modernc.org/sqlite connections.
+type backuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+}
+
+// backupToFile copies the main database of src to the file at destPath,
+// replacing any database there.
+//
+// The driver's backup object does not report how many pages remain, so
+// progress gets an estimate based on the source's page count when the backup
+// started, and restarts caused by concurrent writes are detected by the
+// number of steps taken instead.
+func backupToFile(src *sql.DB, destPath string, progress func(remaining, total int)) error {
+	ctx := context.Background()
+
+	conn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get source connection: %w", err)
+	}
+	defer conn.Close()
+
+	var total int
+	if err := conn.QueryRowContext(ctx, `PRAGMA page_count;`).Scan(&total); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	return conn.Raw(func(raw interface{}) error {
+		b, ok := raw.(backuper)
+		if !ok {
+			return fmt.Errorf("backup: unexpected driver connection %T", raw)
+		}
+		backup, err := b.NewBackup(destPath)
+		if err != nil {
+			return fmt.Errorf("backup init failed: %w", err)
+		}
+
+		// A backup that has not finished after this many steps has been
+		// restarted by writes about backupMaxRestarts times.
+		maxSteps := (total/backupStepPages + 1) * (backupMaxRestarts + 1)
+		pages := int32(backupStepPages)
+		for steps := 1; ; steps++ {
+			// Step reports whether pages remain to be copied.
+			more, err := backup.Step(pages)
+			done := !more
+			if err != nil {
+				backup.Finish()
+				return fmt.Errorf("backup step failed: %w", err)
+			}
+
+			if progress != nil {
+				remaining := max(total-steps*backupStepPages, 0)
+				if done {
+					remaining = 0
+				}
+				progress(remaining, total)
+			}
+			if done {
+				break
+			}
+
+			if steps >= maxSteps {
+				pages = -1
+			}
+			time.Sleep(backupStepPause)
+		}
+
+		if err := backup.Finish(); err != nil {
+			return fmt.Errorf("backup finish failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// restorer is implemented by modernc.org/sqlite connections.
+type restorer interface {
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// backupDB copies the main database of src over the main database of dest,
+// which must be in memory. The driver can only back up to and restore from
+// databases it opens itself by name, so the copy goes through a temporary
+// file: src is backed up to it with backupToFile, which reports progress,
+// and dest restored from it in one step.
+func backupDB(dest, src *sql.DB, progress func(remaining, total int)) error {
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "squeakyv-backup-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, "backup.db")
+	if err := backupToFile(src, tmpPath, progress); err != nil {
+		return err
+	}
+
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get destination connection: %w", err)
+	}
+	defer destConn.Close()
+
+	return destConn.Raw(func(raw interface{}) error {
+		r, ok := raw.(restorer)
+		if !ok {
+			return fmt.Errorf("backup: unexpected driver connection %T", raw)
+		}
+		restore, err := r.NewRestore(tmpPath)
+		if err != nil {
+			return fmt.Errorf("backup init failed: %w", err)
+		}
+		if _, err := restore.Step(-1); err != nil {
+			restore.Finish()
+			return fmt.Errorf("backup step failed: %w", err)
+		}
+		if err := restore.Finish(); err != nil {
+			return fmt.Errorf("backup finish failed: %w", err)
+		}
+		return nil
+	})
+}
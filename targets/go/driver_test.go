@@ -0,0 +1,108 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func queryPragma(t *testing.T, client *CacheClient, name string) string {
+	t.Helper()
+	var value string
+	if err := client.db.QueryRow(`PRAGMA ` + name + `;`).Scan(&value); err != nil {
+		t.Fatalf("Failed to read pragma %s: %v", name, err)
+	}
+	return value
+}
+
+func TestDefaultJournalMode(t *testing.T) {
+	client, err := NewCacheClient(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if mode := queryPragma(t, client, "journal_mode"); mode != "wal" {
+		t.Errorf("Expected WAL by default for files, got %s", mode)
+	}
+	// NORMAL
+	if sync := queryPragma(t, client, "synchronous"); sync != "1" {
+		t.Errorf("Expected synchronous=NORMAL, got %s", sync)
+	}
+
+	memory := newTestClient(t)
+	if mode := queryPragma(t, memory, "journal_mode"); mode != "memory" {
+		t.Errorf("Expected memory journal for :memory:, got %s", mode)
+	}
+}
+
+func TestWithJournalMode(t *testing.T) {
+	client, err := NewCacheClient(filepath.Join(t.TempDir(), "test.db"), WithJournalMode("DELETE"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if mode := queryPragma(t, client, "journal_mode"); mode != "delete" {
+		t.Errorf("Expected delete journal, got %s", mode)
+	}
+
+	// In-memory databases cannot use WAL, which must be reported
+	_, err = NewCacheClient(":memory:", WithJournalMode("WAL"))
+	if err == nil || !strings.Contains(err.Error(), "journal_mode WAL") {
+		t.Errorf("Expected a clear journal mode error, got %v", err)
+	}
+}
+
+func TestWithPragma(t *testing.T) {
+	client, err := NewCacheClient(filepath.Join(t.TempDir(), "test.db"),
+		WithPragma("cache_size", "-4096"),
+		WithPragma("synchronous", "FULL"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	// Every pooled connection is configured, not only the first
+	ctx := context.Background()
+	conns := make([]*sql.Conn, 2)
+	for i := range conns {
+		conn, err := client.db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get connection: %v", err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+	for i, conn := range conns {
+		var size, sync string
+		if err := conn.QueryRowContext(ctx, `PRAGMA cache_size;`).Scan(&size); err != nil {
+			t.Fatalf("Failed to read cache_size: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, `PRAGMA synchronous;`).Scan(&sync); err != nil {
+			t.Fatalf("Failed to read synchronous: %v", err)
+		}
+		if size != "-4096" {
+			t.Errorf("Connection %d: expected cache_size -4096, got %s", i, size)
+		}
+		// FULL
+		if sync != "2" {
+			t.Errorf("Connection %d: expected user pragma to override synchronous, got %s", i, sync)
+		}
+	}
+}
+
+func TestWithPragmaRejectsInjection(t *testing.T) {
+	for _, opt := range []Option{
+		WithPragma("cache_size; DROP TABLE kv", "1"),
+		WithPragma("cache_size", "1; DROP TABLE kv"),
+		WithPragma("cache_size", ""),
+	} {
+		if _, err := NewCacheClient(":memory:", opt); err == nil {
+			t.Error("Expected invalid pragma to be rejected")
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package squeakyv
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// encryptionKeySize is the key size of AES-256.
+const encryptionKeySize = 32
+
+// aesGCMID identifies the first AES-256-GCM format in the layer header of an
+// encrypted value, which authenticates only the header along with the
+// ciphertext. Values in it are still read, but no longer written.
+const aesGCMID = 1
+
+// aesGCMKeyID identifies the AES-256-GCM format that also authenticates the
+// key the value is stored under, so that a value moved to another key fails
+// to decrypt.
+const aesGCMKeyID = 2
+
+// fingerprintSize is the length of the key fingerprint stored with each
+// encrypted value.
+const fingerprintSize = 4
+
+// encryptionCheckName is the kv_meta entry holding the verification record
+// written when encryption is first enabled.
+const encryptionCheckName = "encryption_check"
+
+// encryptionCheckPlaintext is the plaintext of the verification record.
+var encryptionCheckPlaintext = []byte("squeakyv encryption check")
+
+// encryptionKey is an AES-256-GCM key ready for use.
+type encryptionKey struct {
+	// fingerprint identifies the key in stored values without revealing it.
+	fingerprint [fingerprintSize]byte
+	aead        cipher.AEAD
+}
+
+// newEncryptionKey prepares key for use.
+func newEncryptionKey(key []byte) (*encryptionKey, error) {
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("invalid encryption key: must be %d bytes for AES-256, got %d", encryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+
+	k := &encryptionKey{aead: aead}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("squeakyv key fingerprint"))
+	copy(k.fingerprint[:], mac.Sum(nil))
+	return k, nil
+}
+
+// keyring holds the keys a client encrypts and decrypts values with.
+type keyring struct {
+	// current encrypts new values.
+	current *encryptionKey
+	// byFingerprint holds every key that decrypts, current included.
+	byFingerprint map[[fingerprintSize]byte]*encryptionKey
+	// foldKeys is set with WithCaseInsensitiveKeys; see authKey.
+	foldKeys bool
+}
+
+// newKeyring returns the keyring for the encryption options in o, or nil if
+// encryption is off.
+func newKeyring(o options) (*keyring, error) {
+	if len(o.encryptionKeys) == 0 {
+		return nil, nil
+	}
+	r := &keyring{
+		byFingerprint: make(map[[fingerprintSize]byte]*encryptionKey),
+		foldKeys:      o.caseInsensitiveKeys,
+	}
+	for _, raw := range o.encryptionKeys {
+		k, err := newEncryptionKey(raw)
+		if err != nil {
+			return nil, err
+		}
+		if r.current == nil {
+			r.current = k
+		}
+		r.byFingerprint[k.fingerprint] = k
+	}
+	return r, nil
+}
+
+// authKey returns the form of key authenticated with its encrypted values:
+// key itself, or with fold set, as by WithCaseInsensitiveKeys, key in lower
+// case, so that every casing of a key reads its values.
+func authKey(key string, fold bool) string {
+	if fold {
+		return foldKey(key)
+	}
+	return key
+}
+
+// seal encrypts plaintext stored under key with the current key into an
+// encrypted layer.
+func (r *keyring) seal(key string, plaintext []byte) ([]byte, error) {
+	return r.current.seal(authKey(key, r.foldKeys), plaintext)
+}
+
+// seal encrypts plaintext into an encrypted layer bound to key, which is
+// passed through authKey by the caller. The layer header, key fingerprint and
+// key are authenticated along with the ciphertext.
+func (k *encryptionKey) seal(key string, plaintext []byte) ([]byte, error) {
+	nonceSize := k.aead.NonceSize()
+
+	payload := make([]byte, fingerprintSize+nonceSize, fingerprintSize+nonceSize+len(plaintext)+k.aead.Overhead())
+	copy(payload, k.fingerprint[:])
+	nonce := payload[fingerprintSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := k.aead.Seal(payload, nonce, plaintext, k.additionalData(aesGCMKeyID, key))
+	return appendLayer(layerEncrypted, aesGCMKeyID, sealed), nil
+}
+
+// additionalData returns the data authenticated along with a value encrypted
+// by k in format id under key.
+func (k *encryptionKey) additionalData(id byte, key string) []byte {
+	header := appendLayer(layerEncrypted, id, k.fingerprint[:])
+	if id == aesGCMID {
+		return header
+	}
+	return append(header, key...)
+}
+
+// open decrypts the payload of an encrypted layer in format id stored under
+// key with whichever key of the keyring encrypted it. It returns an error
+// wrapping ErrDecrypt if that key is not in the keyring, which may be nil, or
+// the value fails authentication, as it does if it was moved from another key.
+func (r *keyring) open(key string, id byte, payload []byte) ([]byte, error) {
+	k, err := r.find(key, id, payload)
+	if err != nil {
+		return nil, err
+	}
+	return k.open(key, authKey(key, r.foldKeys), id, payload)
+}
+
+// reseal returns the stored value of a key moved from the key from to the key
+// to, re-encrypted with the same key if it is bound to the key it is stored
+// under, so that it decrypts under its new key. Other values are returned
+// unchanged. The error wraps ErrDecrypt if the keyring, which may be nil,
+// can't decrypt the value.
+func (r *keyring) reseal(from, to string, stored []byte) ([]byte, error) {
+	kind, id, payload, ok := parseLayer(stored)
+	if !ok || kind != layerEncrypted || id == aesGCMID {
+		return stored, nil
+	}
+	k, err := r.find(from, id, payload)
+	if err != nil {
+		return nil, err
+	}
+	fromAuth, toAuth := authKey(from, r.foldKeys), authKey(to, r.foldKeys)
+	if fromAuth == toAuth {
+		return stored, nil
+	}
+	plaintext, err := k.open(from, fromAuth, id, payload)
+	if err != nil {
+		return nil, err
+	}
+	return k.seal(toAuth, plaintext)
+}
+
+// find returns the key of the keyring that encrypted the payload of an
+// encrypted layer in format id stored under key.
+func (r *keyring) find(key string, id byte, payload []byte) (*encryptionKey, error) {
+	if r == nil {
+		return nil, decryptError(key, "no encryption key configured")
+	}
+	if id != aesGCMID && id != aesGCMKeyID {
+		return nil, decryptError(key, fmt.Sprintf("unknown format %d", id))
+	}
+	if len(payload) < fingerprintSize {
+		return nil, decryptError(key, "value is truncated")
+	}
+	k, ok := r.byFingerprint[[fingerprintSize]byte(payload)]
+	if !ok {
+		return nil, decryptError(key, "value was encrypted with a different key")
+	}
+	return k, nil
+}
+
+// open decrypts the payload of an encrypted layer in format id stored under
+// key, whose fingerprint must be k's. auth is key passed through authKey.
+func (k *encryptionKey) open(key, auth string, id byte, payload []byte) ([]byte, error) {
+	nonceSize := k.aead.NonceSize()
+	if len(payload) < fingerprintSize+nonceSize+k.aead.Overhead() {
+		return nil, decryptError(key, "value is truncated")
+	}
+
+	nonce := payload[fingerprintSize : fingerprintSize+nonceSize]
+	plaintext, err := k.aead.Open(nil, nonce, payload[fingerprintSize+nonceSize:], k.additionalData(id, auth))
+	if err != nil {
+		return nil, decryptError(key, "value failed authentication")
+	}
+	return plaintext, nil
+}
+
+// decryptError returns an error wrapping ErrDecrypt that names key.
+func decryptError(key, reason string) error {
+	return fmt.Errorf("%w of key %q: %s", ErrDecrypt, key, reason)
+}
+
+// checkEncryption verifies that one of the keyring's keys can decrypt the
+// database's verification record, writing one if there is none yet, so that
+// opening a database with the wrong key fails at once rather than on the
+// first read.
+func checkEncryption(db *sql.DB, t tableNames, keys *keyring, readOnly bool) error {
+	if keys == nil {
+		return nil
+	}
+
+	record, err := readMeta(db, t, encryptionCheckName)
+	if errors.Is(err, sql.ErrNoRows) {
+		if readOnly {
+			return nil
+		}
+		sealed, sealErr := keys.seal(encryptionCheckName, encryptionCheckPlaintext)
+		if sealErr != nil {
+			return sealErr
+		}
+		// Another client may have written its record first; check that one.
+		if err := insertMeta(db, t, encryptionCheckName, sealed); err != nil {
+			return err
+		}
+		record, err = readMeta(db, t, encryptionCheckName)
+	}
+	if err != nil {
+		return err
+	}
+
+	kind, id, payload, ok := parseLayer(record)
+	if !ok || kind != layerEncrypted {
+		return fmt.Errorf("%w: invalid encryption check record", ErrDecrypt)
+	}
+	plaintext, err := keys.open(encryptionCheckName, id, payload)
+	if err != nil {
+		return fmt.Errorf("wrong encryption key for this database: %w", err)
+	}
+	if !bytes.Equal(plaintext, encryptionCheckPlaintext) {
+		return fmt.Errorf("%w: invalid encryption check record", ErrDecrypt)
+	}
+	return nil
+}
+
+// readMeta returns the kv_meta entry called name, or sql.ErrNoRows if there
+// is none, including when the database predates kv_meta.
+func readMeta(db querier, t tableNames, name string) ([]byte, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?);`
+	if err := db.QueryRow(query, t.meta).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	var value []byte
+	err := db.QueryRow(`SELECT value FROM `+t.meta+` WHERE name = ?;`, name).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	return value, nil
+}
+
+// insertMeta stores a kv_meta entry unless one called name already exists.
+func insertMeta(db querier, t tableNames, name string, value []byte) error {
+	query := `INSERT OR IGNORE INTO ` + t.meta + ` (name, value)
+VALUES (?, ?);`
+
+	if _, err := db.Exec(query, name, value); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
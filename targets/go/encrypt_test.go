@@ -0,0 +1,248 @@
+package squeakyv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testKey returns a 32-byte key filled with b.
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, encryptionKeySize)
+}
+
+// rawValue reads the stored bytes of the active version of key.
+func rawValue(t *testing.T, client *CacheClient, key string) []byte {
+	t.Helper()
+	var value []byte
+	if err := client.db.QueryRow(`SELECT value FROM kv WHERE key = ? AND is_active = 1;`, key).Scan(&value); err != nil {
+		t.Fatalf("Failed to read raw value: %v", err)
+	}
+	return value
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithEncryption(testKey(1)))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	secret := []byte("token-1234567890")
+	client.Set("a", secret)
+	client.Set("b", secret)
+
+	if got, _ := client.Get("a"); !bytes.Equal(got, secret) {
+		t.Errorf("Expected round trip, got %q", got)
+	}
+	rawA, rawB := rawValue(t, client, "a"), rawValue(t, client, "b")
+	if bytes.Contains(rawA, secret) {
+		t.Error("Expected the stored value to be encrypted")
+	}
+	if bytes.Equal(rawA, rawB) {
+		t.Error("Expected a fresh nonce per version")
+	}
+
+	// Keys stay in plaintext.
+	if keys, _ := client.ListKeys(); len(keys) != 2 {
+		t.Errorf("Expected 2 keys, got %v", keys)
+	}
+}
+
+func TestEncryptionTampering(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithEncryption(testKey(1)))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("key", []byte("secret"))
+	raw := rawValue(t, client, "key")
+	raw[len(raw)-1] ^= 1
+	client.db.Exec(`UPDATE kv SET value = ? WHERE key = ? AND is_active = 1;`, raw, "key")
+
+	_, err = client.Get("key")
+	if !errors.Is(err, ErrDecrypt) || !strings.Contains(err.Error(), `"key"`) {
+		t.Errorf("Expected ErrDecrypt naming the key, got %v", err)
+	}
+}
+
+func TestEncryptionWrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path, WithEncryption(testKey(1)))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Set("key", []byte("secret"))
+	client.Close()
+
+	if _, err := NewCacheClient(path, WithEncryption(testKey(2))); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt opening with the wrong key, got %v", err)
+	}
+
+	reopened, err := NewCacheClient(path, WithEncryption(testKey(1)))
+	if err != nil {
+		t.Fatalf("Expected the right key to open, got %v", err)
+	}
+	defer reopened.Close()
+	if got, _ := reopened.Get("key"); string(got) != "secret" {
+		t.Errorf("Expected secret, got %q", got)
+	}
+
+	// Without a key, keys are listed but values fail to decrypt.
+	plain, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer plain.Close()
+	if keys, _ := plain.ListKeys(); len(keys) != 1 {
+		t.Errorf("Expected 1 key, got %v", keys)
+	}
+	if _, err := plain.Get("key"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt without a key, got %v", err)
+	}
+}
+
+func TestEncryptionInvalidKey(t *testing.T) {
+	if _, err := NewCacheClient(":memory:", WithEncryption([]byte("short"))); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+}
+
+func TestEncryptionExistingValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	plain, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	plain.Set("old", []byte("plaintext"))
+	plain.Close()
+
+	client, err := NewCacheClient(path, WithEncryption(testKey(1)), WithCompressor(GzipCompressor{}))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if got, _ := client.Get("old"); string(got) != "plaintext" {
+		t.Errorf("Expected value written before encryption, got %q", got)
+	}
+	client.Set("new", compressible)
+	if got, _ := client.Get("new"); !bytes.Equal(got, compressible) {
+		t.Error("Expected compressed and encrypted round trip")
+	}
+	if size, _ := client.SizeOf("new"); size >= int64(len(compressible)) {
+		t.Errorf("Expected compression before encryption, got %d bytes", size)
+	}
+}
+
+func TestEncryptionReadOnlyAndTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path, WithEncryption(testKey(1)), WithTableName("secrets"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Set("key", []byte("secret"))
+	client.Close()
+
+	// Other tables have their own verification record.
+	other, err := NewCacheClient(path, WithEncryption(testKey(2)))
+	if err != nil {
+		t.Fatalf("Expected another table to accept another key, got %v", err)
+	}
+	other.Close()
+
+	ro, err := NewCacheClient(path, WithReadOnly(), WithEncryption(testKey(1)), WithTableName("secrets"))
+	if err != nil {
+		t.Fatalf("Failed to open read-only: %v", err)
+	}
+	defer ro.Close()
+	if got, _ := ro.Get("key"); string(got) != "secret" {
+		t.Errorf("Expected secret, got %q", got)
+	}
+	if _, err := NewCacheClient(path, WithReadOnly(), WithEncryption(testKey(2)), WithTableName("secrets")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt read-only with the wrong key, got %v", err)
+	}
+}
+
+func TestEncryptionBoundToKey(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithEncryption(testKey(1)))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("a", []byte("token-a"))
+	client.Set("b", []byte("token-b"))
+	rawA, rawB := rawValue(t, client, "a"), rawValue(t, client, "b")
+	client.db.Exec(`UPDATE kv SET value = ?, checksum = NULL WHERE key = ? AND is_active = 1;`, rawB, "a")
+	client.db.Exec(`UPDATE kv SET value = ?, checksum = NULL WHERE key = ? AND is_active = 1;`, rawA, "b")
+
+	for _, key := range []string{"a", "b"} {
+		if _, err := client.Get(key); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("Expected ErrDecrypt for a value moved to %q, got %v", key, err)
+		}
+	}
+
+	// Rename and Copy re-encrypt the values they move, history included.
+	client.Set("c", []byte("v1"))
+	client.Set("c", []byte("v2"))
+	_, cursor, _ := client.ChangesSince(0, 100)
+	if err := client.Rename("c", "d"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if changes, _, _ := client.ChangesSince(cursor, 100); fmt.Sprint(changeList(changes)) != "[delete c set d]" {
+		t.Errorf("Expected the rename recorded once, got %v", changeList(changes))
+	}
+	if versions, err := client.History("d"); err != nil || len(versions) != 2 || string(versions[1].Value) != "v1" {
+		t.Errorf("Expected the renamed history to decrypt, got %v, %v", versions, err)
+	}
+	if err := client.Copy("d", "e"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if got, err := client.Get("e"); err != nil || string(got) != "v2" {
+		t.Errorf("Expected the copy to decrypt, got %q, %v", got, err)
+	}
+}
+
+func TestEncryptionCaseInsensitive(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithEncryption(testKey(1)), WithCaseInsensitiveKeys())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("Token", []byte("secret"))
+	if got, err := client.Get("TOKEN"); err != nil || string(got) != "secret" {
+		t.Errorf("Expected any casing to decrypt, got %q, %v", got, err)
+	}
+	if err := client.Rename("Token", "token"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if got, err := client.Get("token"); err != nil || string(got) != "secret" {
+		t.Errorf("Expected the recased key to decrypt, got %q, %v", got, err)
+	}
+}
+
+func TestEncryptionFirstFormat(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithEncryption(testKey(1)))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	// Values written before keys were authenticated still read.
+	k, _ := newEncryptionKey(testKey(1))
+	nonce := make([]byte, k.aead.NonceSize())
+	payload := append(k.fingerprint[:], nonce...)
+	plaintext := appendLayer(layerCompressed, 0, []byte("old"))
+	sealed := appendLayer(layerEncrypted, aesGCMID, k.aead.Seal(payload, nonce, plaintext, k.additionalData(aesGCMID, "")))
+	client.db.Exec(`INSERT INTO kv (key, value) VALUES (?, ?);`, "old", sealed)
+
+	if got, err := client.Get("old"); err != nil || string(got) != "old" {
+		t.Errorf("Expected the first format to decrypt, got %q, %v", got, err)
+	}
+}
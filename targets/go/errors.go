@@ -0,0 +1,175 @@
+package squeakyv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrKeyNotFound is returned by operations that require an existing key when
+// the key has no active, unexpired version.
+//
+// Errors returned for a specific key wrap ErrKeyNotFound, so use errors.Is to
+// test for it.
+var ErrKeyNotFound = errors.New("squeakyv: key not found")
+
+// ErrKeyExists is returned by operations that must not overwrite a key when
+// the target key already has a live value. Errors returned for a specific key
+// wrap ErrKeyExists.
+var ErrKeyExists = errors.New("squeakyv: key already exists")
+
+// ErrClosed is returned by every operation on a CacheClient after Close.
+var ErrClosed = errors.New("squeakyv: client is closed")
+
+// ErrDatabaseNotExist is returned by NewCacheClient when WithMustExist or
+// WithReadOnly is given and there is no database file at the path.
+var ErrDatabaseNotExist = errors.New("squeakyv: database does not exist")
+
+// ErrReadOnly is returned by every operation that would modify the database
+// when the client was opened with WithReadOnly.
+var ErrReadOnly = errors.New("squeakyv: client is read-only")
+
+// ErrUnknownCompressor is wrapped by the error returned when reading a value
+// compressed by a compressor that is neither the client's nor registered with
+// RegisterCompressor.
+var ErrUnknownCompressor = errors.New("squeakyv: unknown compressor")
+
+// ErrDecrypt is wrapped by the error returned when reading a value that
+// cannot be decrypted: it was tampered with, was encrypted with a key the
+// client doesn't have, or the client has no key at all (see WithEncryption).
+// NewCacheClient also returns it when the key doesn't match the database.
+var ErrDecrypt = errors.New("squeakyv: failed to decrypt value")
+
+// ErrInvalidKey is wrapped by the error returned when a write names a key that
+// is empty, longer than WithMaxKeyLength allows, or rejected by the
+// WithKeyValidator function. The error explains which.
+var ErrInvalidKey = errors.New("squeakyv: invalid key")
+
+// ErrChecksumMismatch is matched, via errors.Is, by the ChecksumMismatchError
+// returned when a stored value no longer matches the checksum recorded when it
+// was written.
+var ErrChecksumMismatch = errors.New("squeakyv: checksum mismatch")
+
+// ErrSubscriptionOverflow is wrapped by the error on the last event of a
+// subscription that ended because its receiver fell behind (see Subscribe).
+var ErrSubscriptionOverflow = errors.New("squeakyv: subscription overflowed")
+
+// ErrChangesPruned is wrapped by the error ChangesSince returns when changes
+// after the requested sequence number were removed by PruneChangesBefore.
+var ErrChangesPruned = errors.New("squeakyv: changes pruned")
+
+// ErrMirror is wrapped by the error returned when a write succeeded but could
+// not be replayed onto a MirrorSync mirror (see WithMirror).
+var ErrMirror = errors.New("squeakyv: mirror write failed")
+
+// ErrPipelineUsed is returned by Pipeline.Exec when the pipeline has already
+// been executed.
+var ErrPipelineUsed = errors.New("squeakyv: pipeline already executed")
+
+// ErrCacheFull is returned by a write that would take the cache past
+// WithMaxEntries or WithMaxBytes when only pinned keys are left to evict (see
+// Pin). Nothing is written.
+var ErrCacheFull = errors.New("squeakyv: cache full of pinned keys")
+
+// ErrValueTooLarge is matched, via errors.Is, by the ValueTooLargeError
+// returned when a value exceeds a configured size limit.
+var ErrValueTooLarge = errors.New("squeakyv: value too large")
+
+// ValueTooLargeError reports a write rejected because its value exceeds a
+// size limit, such as WithMaxValueSize. Nothing is written.
+type ValueTooLargeError struct {
+	// Key is the key being written, as stored in the cache.
+	Key string
+	// Size is the length of the rejected value in bytes.
+	Size int
+	// Limit is the maximum allowed length in bytes.
+	Limit int
+}
+
+// Error implements error.
+func (e *ValueTooLargeError) Error() string {
+	return fmt.Sprintf("%v: key %q has %d bytes, limit is %d", ErrValueTooLarge, e.Key, e.Size, e.Limit)
+}
+
+// Is reports whether target is ErrValueTooLarge.
+func (e *ValueTooLargeError) Is(target error) bool {
+	return target == ErrValueTooLarge
+}
+
+// ChecksumMismatchError reports a stored value that was corrupted after it
+// was written, as by a failing disk.
+type ChecksumMismatchError struct {
+	// Key is the key whose value is corrupt, as stored in the cache.
+	Key string
+	// Version is the ID of the corrupt version (see Version.ID).
+	Version int64
+}
+
+// Error implements error.
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%v: key %q version %d", ErrChecksumMismatch, e.Key, e.Version)
+}
+
+// Is reports whether target is ErrChecksumMismatch.
+func (e *ChecksumMismatchError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// DecodeError reports that the value stored for a key could not be decoded
+// into the requested Go type.
+type DecodeError struct {
+	// Key is the key whose value failed to decode, as stored in the cache.
+	Key string
+	// Err is the error from the codec.
+	Err error
+}
+
+// Error implements error.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("squeakyv: failed to decode value of key %q: %v", e.Key, e.Err)
+}
+
+// Unwrap returns the error from the codec.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// keyExists returns an error wrapping ErrKeyExists that names key.
+func keyExists(key string) error {
+	return fmt.Errorf("%w: %q", ErrKeyExists, key)
+}
+
+// invalidKey returns an error wrapping ErrInvalidKey and reason that names key,
+// shortened if it is long.
+func invalidKey(key string, reason error) error {
+	if len(key) > 64 {
+		key = key[:64] + "..."
+	}
+	return fmt.Errorf("%w %q: %w", ErrInvalidKey, key, reason)
+}
+
+// keyNotFound returns an error wrapping ErrKeyNotFound that names key.
+func keyNotFound(key string) error {
+	return fmt.Errorf("%w: %q", ErrKeyNotFound, key)
+}
+
+// expiredError is the error wrapping ErrKeyNotFound returned for a key whose
+// live version getLiveVersion found expired and soft-deleted, so that
+// GetStrict can report it to WithEvictionCallback.
+type expiredError struct {
+	key string
+}
+
+// Error implements error, reading as keyNotFound's error does.
+func (e *expiredError) Error() string {
+	return fmt.Sprintf("%v: %q", ErrKeyNotFound, e.key)
+}
+
+// Is reports whether target is ErrKeyNotFound.
+func (e *expiredError) Is(target error) bool {
+	return target == ErrKeyNotFound
+}
+
+// versionNotFound returns an error wrapping ErrKeyNotFound that names key and version.
+func versionNotFound(key string, version int64) error {
+	return fmt.Errorf("%w: %q version %d", ErrKeyNotFound, key, version)
+}
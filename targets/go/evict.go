@@ -0,0 +1,583 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// evicts reports whether WithMaxEntries or WithMaxBytes set a limit.
+func (o *options) evicts() bool {
+	return o.maxEntries > 0 || o.maxBytes > 0
+}
+
+// lastUsed is the SQL expression ordering rows for LRU eviction: the later of
+// the version's write and its last recorded read or Touch.
+const lastUsed = `max(inserted_at, ifnull(accessed_at, 0))`
+
+// lruSchemaSQL returns the index of active rows by lastUsed for eviction. It
+// is only created by clients with WithMaxEntries or WithMaxBytes, so other
+// writers don't maintain it.
+func lruSchemaSQL(t tableNames) string {
+	return `CREATE INDEX IF NOT EXISTS ` + t.kv + `_lru ON ` + t.kv + `(` + lastUsed + `) WHERE is_active = 1;`
+}
+
+// accessLog collects the keys read by a client that evicts. Rather
+// than making every read a write, the reads are recorded in the accessed_at
+// column by the client's next write transaction, or by Close. Its methods do
+// nothing on a nil *accessLog.
+type accessLog struct {
+	mu sync.Mutex
+	// reads maps the keys read, by watchKey, to the time of their last read
+	// in Unix milliseconds. It holds at most one entry per live key.
+	reads map[string]int64
+}
+
+// newAccessLog returns an empty access log.
+func newAccessLog() *accessLog {
+	return &accessLog{reads: make(map[string]int64)}
+}
+
+// record notes a read of the key cacheKey at now.
+func (a *accessLog) record(cacheKey string, now int64) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reads[cacheKey] = now
+}
+
+// take removes and returns the reads noted so far. If they can't be written,
+// they should be handed back to restore.
+func (a *accessLog) take() map[string]int64 {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.reads) == 0 {
+		return nil
+	}
+	reads := a.reads
+	a.reads = make(map[string]int64)
+	return reads
+}
+
+// restore hands back reads returned by take that weren't written, keeping any
+// later read of the same key noted since.
+func (a *accessLog) restore(reads map[string]int64) {
+	if a == nil || len(reads) == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for key, at := range reads {
+		if at > a.reads[key] {
+			a.reads[key] = at
+		}
+	}
+}
+
+// writeAccess records reads, returned by accessLog.take, in the accessed_at
+// column of the live versions read.
+func writeAccess(tx *sql.Tx, t tableNames, reads map[string]int64) error {
+	if len(reads) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare(`UPDATE ` + t.kv + `
+SET accessed_at = max(ifnull(accessed_at, 0), ?)
+WHERE key = ? AND is_active = 1;`)
+	if err != nil {
+		return fmt.Errorf("prepare failed: %w", err)
+	}
+	defer stmt.Close()
+
+	for key, at := range reads {
+		if _, err := stmt.Exec(at, key); err != nil {
+			return fmt.Errorf("exec failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// usage is the number and total stored size of active rows, from kv_usage.
+type usage struct {
+	entries int
+	bytes   int64
+}
+
+// readUsage returns the totals kept in kv_usage.
+func readUsage(db querier, t tableNames) (usage, error) {
+	var u usage
+	if err := db.QueryRow(`SELECT entries, bytes FROM `+t.usage+`;`).Scan(&u.entries, &u.bytes); err != nil {
+		return usage{}, fmt.Errorf("query failed: %w", err)
+	}
+	return u, nil
+}
+
+// txStart is what evictOverflow needs to know of the database as a write
+// transaction began: its totals and the last rowid, after which rows are new.
+type txStart struct {
+	usage
+	lastRowid int64
+}
+
+// readTxStart returns the txStart for a transaction that hasn't written yet.
+func readTxStart(tx *sql.Tx, t tableNames) (txStart, error) {
+	u, err := readUsage(tx, t)
+	if err != nil {
+		return txStart{}, err
+	}
+	start := txStart{usage: u}
+	if err := tx.QueryRow(`SELECT ifnull(max(rowid), 0) FROM ` + t.kv + `;`).Scan(&start.lastRowid); err != nil {
+		return txStart{}, fmt.Errorf("query failed: %w", err)
+	}
+	return start, nil
+}
+
+// over reports whether u exceeds maxEntries or maxBytes, where zero or less
+// means no limit.
+func (u usage) over(maxEntries int, maxBytes int64) bool {
+	return (maxEntries > 0 && u.entries > maxEntries) || (maxBytes > 0 && u.bytes > maxBytes)
+}
+
+// evictOverflow soft-deletes keys until at most maxEntries rows are active,
+// holding at most maxBytes: first those that have already expired, then the
+// least recently used that aren't pinned. It returns the keys removed and
+// why. Rows the transaction, which began as start, wrote itself come last,
+// and if taking them would leave pinned keys while the transaction took the
+// totals further past a limit, it fails with ErrCacheFull instead.
+func evictOverflow(tx *sql.Tx, t tableNames, maxEntries int, maxBytes int64, start txStart) ([]eviction, error) {
+	u, err := readUsage(tx, t)
+	if err != nil || !u.over(maxEntries, maxBytes) {
+		return nil, err
+	}
+
+	keys, err := deactivateReturning(tx, `UPDATE `+t.kv+`
+SET is_active = 0
+WHERE is_active = 1 AND expires_at IS NOT NULL AND expires_at <= ?
+RETURNING key;`, nowMillis())
+	if err != nil {
+		return nil, err
+	}
+	evictions := make([]eviction, len(keys))
+	for i, key := range keys {
+		evictions[i] = eviction{key: key, reason: Expired}
+	}
+	if u, err = readUsage(tx, t); err != nil || !u.over(maxEntries, maxBytes) {
+		return evictions, err
+	}
+
+	victims, u, err := leastRecentlyUsed(tx, t, `AND rowid <= ?
+ORDER BY `+lastUsed+`, rowid`, start.lastRowid, u, maxEntries, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if u.over(maxEntries, maxBytes) {
+		if (maxEntries > 0 && u.entries > maxEntries && u.entries > start.entries) ||
+			(maxBytes > 0 && u.bytes > maxBytes && u.bytes > start.bytes) {
+			var pinned bool
+			if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM ` + t.pins + `);`).Scan(&pinned); err != nil {
+				return nil, fmt.Errorf("query failed: %w", err)
+			}
+			if pinned {
+				return nil, ErrCacheFull
+			}
+		}
+		var own []victim
+		own, _, err = leastRecentlyUsed(tx, t, `AND rowid > ?
+ORDER BY rowid`, start.lastRowid, u, maxEntries, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		victims = append(victims, own...)
+	}
+	rowids := make([]int64, len(victims))
+	for i, v := range victims {
+		rowids[i] = v.rowid
+	}
+	if keys, err = deactivateRows(tx, t, rowids); err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		evictions = append(evictions, eviction{key: key, reason: victims[i].reason})
+	}
+	return evictions, nil
+}
+
+// victim is a row chosen for eviction by leastRecentlyUsed.
+type victim struct {
+	rowid int64
+	// reason is Evicted if the row is over maxEntries, or else Displaced.
+	reason EvictReason
+}
+
+// leastRecentlyUsed returns the active rows that aren't pinned to remove to
+// bring u within the limits, in the order given by the where clause tail with
+// its argument, and u without them, which is still over the limits if there
+// aren't enough.
+func leastRecentlyUsed(tx *sql.Tx, t tableNames, tail string, arg any, u usage, maxEntries int, maxBytes int64) ([]victim, usage, error) {
+	rows, err := tx.Query(`SELECT rowid, length(value)
+FROM `+t.kv+`
+WHERE is_active = 1
+  AND NOT EXISTS (SELECT 1 FROM `+t.pins+` WHERE `+t.kv+`.key = `+t.pins+`.key)
+  `+tail+`;`, arg)
+	if err != nil {
+		return nil, usage{}, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var victims []victim
+	for u.over(maxEntries, maxBytes) && rows.Next() {
+		v := victim{reason: Displaced}
+		var size int64
+		if err := rows.Scan(&v.rowid, &size); err != nil {
+			return nil, usage{}, fmt.Errorf("scan failed: %w", err)
+		}
+		if maxEntries > 0 && u.entries > maxEntries {
+			v.reason = Evicted
+		}
+		victims = append(victims, v)
+		u.entries--
+		u.bytes -= size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, usage{}, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return victims, u, nil
+}
+
+// deactivateRows soft-deletes the rows with the given rowids and returns
+// their keys.
+func deactivateRows(tx *sql.Tx, t tableNames, rowids []int64) ([]string, error) {
+	if len(rowids) == 0 {
+		return nil, nil
+	}
+	stmt, err := tx.Prepare(`UPDATE ` + t.kv + ` SET is_active = 0 WHERE rowid = ? RETURNING key;`)
+	if err != nil {
+		return nil, fmt.Errorf("prepare failed: %w", err)
+	}
+	defer stmt.Close()
+
+	keys := make([]string, 0, len(rowids))
+	for _, rowid := range rowids {
+		var key string
+		if err := stmt.QueryRow(rowid).Scan(&key); err != nil {
+			return nil, fmt.Errorf("exec failed: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// deactivateReturning runs an UPDATE deactivating rows that returns their
+// keys, and returns them.
+func deactivateReturning(tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("exec failed: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return keys, nil
+}
+
+// evictingTx is withTxWaiting for clients that evict: after fn, the
+// transaction records the reads noted since the last one and evicts keys over
+// the limits. The caller must hold writeMu; the evicted keys are returned, to
+// be passed to notifyEvicted once it is released.
+func (c *CacheClient) evictingTx(db *sql.DB, wait *time.Duration, fn func(tx *sql.Tx) error) ([]eviction, error) {
+	return c.evictingRun(wait, func(fn func(tx *sql.Tx) error) error { return runTx(db, fn) }, fn)
+}
+
+// evictingRun is evictingTx with the transaction started by run, which runs
+// its argument in a new transaction.
+func (c *CacheClient) evictingRun(wait *time.Duration, run func(fn func(tx *sql.Tx) error) error, fn func(tx *sql.Tx) error) ([]eviction, error) {
+	reads := c.access.take()
+
+	var evicted []eviction
+	err := c.retryWaiting(wait, func() error {
+		return run(func(tx *sql.Tx) error {
+			start, err := readTxStart(tx, c.tables)
+			if err != nil {
+				return err
+			}
+			if err := fn(tx); err != nil {
+				return err
+			}
+			if err := writeAccess(tx, c.tables, reads); err != nil {
+				return err
+			}
+			evicted, err = evictOverflow(tx, c.tables, c.opts.maxEntries, c.opts.maxBytes, start)
+			return err
+		})
+	})
+	if err != nil {
+		c.access.restore(reads)
+		return nil, err
+	}
+	return evicted, nil
+}
+
+// notifyEvicted tells watchers, the memory cache and the WithEvictionCallback
+// callback about keys evicted by evictingTx.
+func (c *CacheClient) notifyEvicted(evicted []eviction) {
+	for _, ev := range evicted {
+		c.notify(ev.key, WatchDelete, nil)
+	}
+	c.evictions.add(evicted...)
+}
+
+// writeEvicting runs write, a single statement that may add a key, on db:
+// directly, or for a client that evicts in a transaction that evicts keys over
+// the limits.
+func (c *CacheClient) writeEvicting(db *sql.DB, wait *time.Duration, write func(db querier) error) error {
+	if c.access == nil {
+		return c.retryWaiting(wait, func() error { return write(db) })
+	}
+	return c.withTxWaiting(db, wait, func(tx *sql.Tx) error { return write(tx) })
+}
+
+// writeEvictingConn is writeEvicting for a write in a transaction on conn, a
+// connection pinned by withAttached, whose caller holds writeMu. The evicted
+// keys are returned, to be passed to notifyEvicted once it is released.
+func (c *CacheClient) writeEvictingConn(ctx context.Context, conn *sql.Conn, write func(tx *sql.Tx) error) ([]eviction, error) {
+	run := func(fn func(tx *sql.Tx) error) error { return withConnTx(ctx, conn, fn) }
+	if c.access == nil {
+		return nil, c.retry(func() error { return run(write) })
+	}
+	return c.evictingRun(nil, run, write)
+}
+
+// flushAccess records the reads noted by the access log, for Close. Failures
+// are ignored: the reads only order eviction.
+func (c *CacheClient) flushAccess(db *sql.DB) {
+	if c.access != nil {
+		c.withTx(db, func(tx *sql.Tx) error { return nil })
+	}
+}
+
+// Evict removes up to n of the least recently used live keys, as eviction
+// under WithMaxEntries would, and returns how many it removed. Keys pinned
+// with Pin are skipped. Evicted keys are soft-deleted, seen by watchers as
+// deletes, and reported to the WithEvictionCallback callback as Evicted.
+//
+// Reads only count as uses for clients with WithMaxEntries or WithMaxBytes,
+// which record them in the database, those of other clients and processes
+// only once recorded by their next write or Close. Keys no such client has
+// read are ordered by when they were written.
+//
+// Example:
+//
+//	n, err := client.Evict(1000)
+func (c *CacheClient) Evict(n int) (int, error) {
+	if n < 0 {
+		return 0, fmt.Errorf("invalid count %d: must not be negative", n)
+	}
+	return c.evictWhere(`ORDER BY `+lastUsed+`, rowid
+LIMIT ?`, n)
+}
+
+// EvictIdle removes the live keys not read or written since olderThan, as
+// Evict would, and returns how many it removed. Keys pinned with Pin are
+// skipped.
+//
+// Example:
+//
+//	n, err := client.EvictIdle(time.Now().Add(-24 * time.Hour))
+func (c *CacheClient) EvictIdle(olderThan time.Time) (int, error) {
+	return c.evictWhere(`AND `+lastUsed+` < ?`, olderThan.UnixMilli())
+}
+
+// evictWhere implements Evict and EvictIdle, soft-deleting the live keys that
+// aren't pinned selected by the query tail with its argument.
+func (c *CacheClient) evictWhere(tail string, arg any) (int, error) {
+	query := `SELECT rowid
+FROM ` + c.tables.kv + `
+WHERE ` + liveCondition + `
+  AND NOT EXISTS (SELECT 1 FROM ` + c.tables.pins + ` WHERE ` + c.tables.kv + `.key = ` + c.tables.pins + `.key)
+  ` + tail + `;`
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	c.flushAccess(db)
+	var evicted []eviction
+	err = c.withTx(db, func(tx *sql.Tx) error {
+		rowids, err := queryRowids(tx, query, nowMillis(), arg)
+		if err != nil {
+			return err
+		}
+		keys, err := deactivateRows(tx, c.tables, rowids)
+		if err != nil {
+			return err
+		}
+		evicted = make([]eviction, len(keys))
+		for i, key := range keys {
+			evicted[i] = eviction{key: key, reason: Evicted}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	c.notifyEvicted(evicted)
+	return len(evicted), nil
+}
+
+// queryRowids runs a query selecting rowids and returns them in row order.
+func queryRowids(db querier, query string, args ...interface{}) ([]int64, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var rowids []int64
+	for rows.Next() {
+		var rowid int64
+		if err := rows.Scan(&rowid); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		rowids = append(rowids, rowid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return rowids, nil
+}
+
+// EvictReason is why the cache dropped a key on its own, passed to the
+// WithEvictionCallback callback.
+type EvictReason int
+
+const (
+	// Evicted reports a key removed as least recently used to stay within
+	// WithMaxEntries, or by Evict or EvictIdle.
+	Evicted EvictReason = iota + 1
+	// Expired reports a key removed because its TTL had passed, by SweepNow,
+	// by a Get or GetStrict finding it expired, or to make room under
+	// WithMaxEntries or WithMaxBytes.
+	Expired
+	// Displaced reports a key removed as least recently used to make room
+	// for other values under WithMaxBytes.
+	Displaced
+)
+
+// String returns "evicted", "expired" or "displaced".
+func (r EvictReason) String() string {
+	switch r {
+	case Evicted:
+		return "evicted"
+	case Expired:
+		return "expired"
+	case Displaced:
+		return "displaced"
+	}
+	return "unknown"
+}
+
+// eviction is a key dropped by the cache, for WithEvictionCallback.
+type eviction struct {
+	key    string
+	reason EvictReason
+}
+
+// evictionQueue delivers evictions to the WithEvictionCallback callback, in
+// order, from its own goroutine, so that the callback never runs while the
+// client holds a lock or a transaction. Its methods do nothing on a nil
+// *evictionQueue.
+type evictionQueue struct {
+	fn func(key string, reason EvictReason)
+
+	mu      sync.Mutex
+	pending []eviction
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// startEvictionQueue launches the goroutine calling fn for each eviction
+// added, until shutdown is called.
+func startEvictionQueue(fn func(key string, reason EvictReason)) *evictionQueue {
+	q := &evictionQueue{
+		fn:   fn,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// add queues evictions for the callback.
+func (q *evictionQueue) add(evictions ...eviction) {
+	if q == nil || len(evictions) == 0 {
+		return
+	}
+	q.mu.Lock()
+	q.pending = append(q.pending, evictions...)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take removes and returns the queued evictions.
+func (q *evictionQueue) take() []eviction {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := q.pending
+	q.pending = nil
+	return pending
+}
+
+// run calls the callback for queued evictions until shutdown, then for those
+// still queued.
+func (q *evictionQueue) run() {
+	for {
+		select {
+		case <-q.wake:
+		case <-q.stop:
+			// A final wake may be pending; take catches its evictions.
+			for _, ev := range q.take() {
+				q.fn(ev.key, ev.reason)
+			}
+			return
+		}
+		for _, ev := range q.take() {
+			q.fn(ev.key, ev.reason)
+		}
+	}
+}
+
+// shutdown stops the goroutine once it has delivered the evictions queued so
+// far. It doesn't wait for it, so that a callback can close the client. It is
+// safe to call more than once.
+func (q *evictionQueue) shutdown() {
+	if q == nil {
+		return
+	}
+	q.stopOnce.Do(func() { close(q.stop) })
+}
@@ -0,0 +1,593 @@
+package squeakyv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// sortedKeys returns the live keys of client in order.
+func sortedKeys(t *testing.T, client *CacheClient) []string {
+	t.Helper()
+	keys, err := client.ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys failed: %v", err)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// usageEntries returns the active row count kept in kv_usage.
+func usageEntries(t *testing.T, client *CacheClient) int {
+	t.Helper()
+	var n int
+	if err := client.db.QueryRow(`SELECT entries FROM kv_usage;`).Scan(&n); err != nil {
+		t.Fatalf("Failed to read kv_usage: %v", err)
+	}
+	return n
+}
+
+func TestMaxEntries(t *testing.T) {
+	client := newTestClient(t, WithMaxEntries(3))
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		if err := client.Set(key, []byte("v")); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"c", "d", "e"}) {
+		t.Errorf("Expected the oldest keys evicted, got %v", keys)
+	}
+
+	// Evicted keys are soft-deleted, keeping their history.
+	if versions, _ := client.History("a"); len(versions) != 1 {
+		t.Errorf("Expected the evicted version in history, got %d versions", len(versions))
+	}
+
+	// Overwriting a key doesn't add one.
+	client.Set("c", []byte("v2"))
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"c", "d", "e"}) {
+		t.Errorf("Expected no eviction on overwrite, got %v", keys)
+	}
+}
+
+func TestMaxEntriesLRU(t *testing.T) {
+	client := newTestClient(t, WithMaxEntries(3))
+
+	for _, key := range []string{"a", "b", "c"} {
+		client.Set(key, []byte("v"))
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := client.Get("a"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	client.Set("d", []byte("v"))
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"a", "c", "d"}) {
+		t.Errorf("Expected the least recently read key evicted, got %v", keys)
+	}
+
+	// Touch counts as a use too.
+	client.Touch("c")
+	time.Sleep(5 * time.Millisecond)
+	client.Set("e", []byte("v"))
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"c", "d", "e"}) {
+		t.Errorf("Expected the least recently used key evicted, got %v", keys)
+	}
+}
+
+func TestMaxEntriesExpiredFirst(t *testing.T) {
+	client := newTestClient(t, WithMaxEntries(3))
+
+	client.Set("a", []byte("v"))
+	client.SetWithTTL("b", []byte("v"), time.Millisecond)
+	client.Set("c", []byte("v"))
+	time.Sleep(5 * time.Millisecond)
+
+	client.Set("d", []byte("v"))
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"a", "c", "d"}) {
+		t.Errorf("Expected the expired key evicted first, got %v", keys)
+	}
+}
+
+func TestMaxEntriesBatches(t *testing.T) {
+	client := newTestClient(t, WithMaxEntries(10))
+
+	items := make(map[string][]byte)
+	for i := 0; i < 25; i++ {
+		items[fmt.Sprintf("key%02d", i)] = []byte("v")
+	}
+	if err := client.SetMany(items); err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+	if n := storedCount(t, client); n != 10 {
+		t.Errorf("Expected 10 keys after SetMany, got %d", n)
+	}
+
+	p := client.Pipeline()
+	for i := 0; i < 5; i++ {
+		p.Set(fmt.Sprintf("pipe%d", i), []byte("v"))
+	}
+	if _, err := p.Exec(context.Background()); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	client.Copy("pipe0", "copy")
+	client.WithTransaction(func(tx *Tx) error {
+		return tx.Set("tx", []byte("v"))
+	})
+	if n := storedCount(t, client); n != 10 {
+		t.Errorf("Expected 10 keys, got %d", n)
+	}
+}
+
+func TestMaxEntriesOtherWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	client := newTestClientAt(t, path, WithMaxEntries(2))
+	other := newTestClientAt(t, path)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		other.Set(key, []byte("v"))
+	}
+	if n := storedCount(t, client); n != 4 {
+		t.Fatalf("Expected other clients' writes to be kept, got %d keys", n)
+	}
+	client.Set("e", []byte("v"))
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"d", "e"}) {
+		t.Errorf("Expected the next write to evict down to the limit, got %v", keys)
+	}
+}
+
+func TestMaxEntriesCloseRecordsReads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	client, err := NewCacheClient(path, WithMaxEntries(2))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Set("a", []byte("v"))
+	client.Set("b", []byte("v"))
+	time.Sleep(5 * time.Millisecond)
+	client.Get("a")
+	client.Close()
+
+	reopened := newTestClientAt(t, path, WithMaxEntries(2))
+	reopened.Set("c", []byte("v"))
+	if keys := sortedKeys(t, reopened); !slices.Equal(keys, []string{"a", "c"}) {
+		t.Errorf("Expected the read recorded by Close to count, got %v", keys)
+	}
+}
+
+func TestMaxEntriesNotifies(t *testing.T) {
+	client := newTestClient(t, WithMaxEntries(1), WithMemoryCache(10, 0))
+
+	client.Set("a", []byte("v"))
+	client.Get("a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.Watch(ctx, "a")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	client.Set("b", []byte("v"))
+	select {
+	case ev := <-events:
+		if ev.Op != WatchDelete {
+			t.Errorf("Expected a delete event, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected an event for the evicted key")
+	}
+	if got, _ := client.Get("a"); got != nil {
+		t.Errorf("Expected the evicted key dropped from the memory cache, got %q", got)
+	}
+}
+
+func TestMaxEntriesInvalid(t *testing.T) {
+	if _, err := NewCacheClient(":memory:", WithMaxEntries(-1)); err == nil {
+		t.Error("Expected an error for a negative limit")
+	}
+	if _, err := NewCacheClient(":memory:", WithMaxBytes(-1)); err == nil {
+		t.Error("Expected an error for a negative byte limit")
+	}
+	path := filepath.Join(t.TempDir(), "cache.db")
+	newTestClientAt(t, path)
+	if _, err := NewCacheClient(path, WithReadOnly(), WithMaxEntries(10)); err == nil {
+		t.Error("Expected an error for a read-only client")
+	}
+}
+
+func TestUsageCount(t *testing.T) {
+	client := newTestClient(t)
+
+	check := func(step string) {
+		t.Helper()
+		if got, want := usageEntries(t, client), storedCount(t, client); got != want {
+			t.Errorf("%s: expected kv_usage to count %d keys, got %d", step, want, got)
+		}
+	}
+
+	client.Set("a", []byte("v"))
+	client.Set("b", []byte("v"))
+	client.Set("a", []byte("v2"))
+	check("set")
+	client.Delete("b")
+	check("delete")
+	client.Undelete("b")
+	check("undelete")
+	client.HardDelete("a")
+	check("hard delete")
+	client.Clear()
+	check("clear")
+}
+
+func TestMaxBytes(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithMaxBytes(100))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		client.Set(key, make([]byte, 40))
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"b", "c"}) {
+		t.Errorf("Expected the oldest key evicted to fit 100 bytes, got %v", keys)
+	}
+	if got := client.Stats().StoredBytes; got != 80 {
+		t.Errorf("Expected 80 stored bytes, got %d", got)
+	}
+
+	// One large value evicts several small ones.
+	client.Set("big", make([]byte, 90))
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"big"}) {
+		t.Errorf("Expected only the large value to remain, got %v", keys)
+	}
+
+	err = client.Set("huge", make([]byte, 101))
+	var tooLarge *ValueTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 100 {
+		t.Errorf("Expected a ValueTooLargeError with limit 100, got %v", err)
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"big"}) {
+		t.Errorf("Expected a rejected value to evict nothing, got %v", keys)
+	}
+	if _, err := client.Append("big", make([]byte, 20)); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected Append past the limit to fail, got %v", err)
+	}
+	err = client.SetMany(map[string][]byte{"small": []byte("v"), "huge": make([]byte, 200)})
+	if !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected SetMany past the limit to fail, got %v", err)
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"big"}) {
+		t.Errorf("Expected a rejected SetMany to write and evict nothing, got %v", keys)
+	}
+}
+
+func TestMaxBytesWithMaxEntries(t *testing.T) {
+	client := newTestClient(t, WithMaxEntries(3), WithMaxBytes(1000))
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		client.Set(key, []byte("v"))
+	}
+	if n := storedCount(t, client); n != 3 {
+		t.Errorf("Expected the entry limit to apply, got %d keys", n)
+	}
+	client.Set("e", make([]byte, 1000))
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"e"}) {
+		t.Errorf("Expected the byte limit to apply, got %v", keys)
+	}
+}
+
+func TestUsageBytes(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithMaxBytes(1<<20))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	check := func(step string, want int64) {
+		t.Helper()
+		if got := client.Stats().StoredBytes; got != want {
+			t.Errorf("%s: expected %d stored bytes, got %d", step, want, got)
+		}
+	}
+
+	client.Set("a", make([]byte, 10))
+	client.Set("b", make([]byte, 20))
+	check("set", 30)
+	client.Set("a", make([]byte, 5))
+	check("overwrite", 25)
+	client.Append("b", make([]byte, 3))
+	check("append", 28)
+	client.Delete("b")
+	check("delete", 5)
+	client.Clear()
+	check("clear", 0)
+
+	// Without a limit, StoredBytes isn't read.
+	if got := newTestClient(t).Stats().StoredBytes; got != 0 {
+		t.Errorf("Expected 0 without a limit, got %d", got)
+	}
+}
+
+// evictionRecorder collects the calls of a WithEvictionCallback callback.
+type evictionRecorder struct {
+	calls chan eviction
+}
+
+func newEvictionRecorder() *evictionRecorder {
+	return &evictionRecorder{calls: make(chan eviction, 100)}
+}
+
+func (r *evictionRecorder) callback(key string, reason EvictReason) {
+	r.calls <- eviction{key: key, reason: reason}
+}
+
+// next waits for the next call.
+func (r *evictionRecorder) next(t *testing.T) eviction {
+	t.Helper()
+	select {
+	case ev := <-r.calls:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the eviction callback")
+		return eviction{}
+	}
+}
+
+// none checks that no call is made for a while.
+func (r *evictionRecorder) none(t *testing.T) {
+	t.Helper()
+	select {
+	case ev := <-r.calls:
+		t.Errorf("Expected no eviction callback, got %+v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestEvictionCallback(t *testing.T) {
+	rec := newEvictionRecorder()
+	client := newTestClient(t, WithMaxEntries(2), WithMaxBytes(100), WithEvictionCallback(rec.callback))
+
+	client.Set("a", []byte("v"))
+	client.Set("b", []byte("v"))
+	client.Set("c", []byte("v"))
+	if ev := rec.next(t); ev != (eviction{key: "a", reason: Evicted}) {
+		t.Errorf("Expected a evicted, got %+v", ev)
+	}
+
+	client.Set("big", make([]byte, 100))
+	if ev := rec.next(t); ev != (eviction{key: "b", reason: Evicted}) {
+		t.Errorf("Expected b evicted over the entry limit, got %+v", ev)
+	}
+	if ev := rec.next(t); ev != (eviction{key: "c", reason: Displaced}) {
+		t.Errorf("Expected c displaced over the byte limit, got %+v", ev)
+	}
+
+	// Explicit deletes aren't reported.
+	client.Delete("big")
+	rec.none(t)
+}
+
+func TestEvictionCallbackExpired(t *testing.T) {
+	rec := newEvictionRecorder()
+	client, err := NewCacheClient(":memory:", WithEvictionCallback(rec.callback))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.SetWithTTL("read", []byte("v"), time.Millisecond)
+	client.SetWithTTL("swept", []byte("v"), time.Millisecond)
+	client.Set("kept", []byte("v"))
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := client.GetStrict("read"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+	if ev := rec.next(t); ev != (eviction{key: "read", reason: Expired}) {
+		t.Errorf("Expected the key read expired, got %+v", ev)
+	}
+
+	if _, err := client.SweepNow(); err != nil {
+		t.Fatalf("SweepNow failed: %v", err)
+	}
+	if ev := rec.next(t); ev != (eviction{key: "swept", reason: Expired}) {
+		t.Errorf("Expected only the swept live key expired, got %+v", ev)
+	}
+	rec.none(t)
+
+	// With a limit, expired keys make room first.
+	limited := newTestClient(t, WithMaxEntries(2), WithEvictionCallback(rec.callback))
+	limited.Set("a", []byte("v"))
+	limited.SetWithTTL("b", []byte("v"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	limited.Set("c", []byte("v"))
+	if ev := rec.next(t); ev != (eviction{key: "b", reason: Expired}) {
+		t.Errorf("Expected b expired, got %+v", ev)
+	}
+}
+
+func TestEvictionCallbackReentrant(t *testing.T) {
+	var client *CacheClient
+	done := make(chan struct{})
+	callback := func(key string, reason EvictReason) {
+		// Writing from the callback evicts again, calling it back.
+		if key == "a" {
+			client.Get("b")
+			if err := client.Set("from-callback", []byte("v")); err != nil {
+				t.Errorf("Set from the callback failed: %v", err)
+			}
+			if _, err := client.ListKeys(); err != nil {
+				t.Errorf("ListKeys from the callback failed: %v", err)
+			}
+			return
+		}
+		if err := client.Close(); err != nil {
+			t.Errorf("Close from the callback failed: %v", err)
+		}
+		close(done)
+	}
+	client = newTestClient(t, WithMaxEntries(2), WithEvictionCallback(callback))
+
+	client.Set("a", []byte("v"))
+	client.Set("b", []byte("v"))
+	client.Set("c", []byte("v"))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out: the callback deadlocked")
+	}
+	if _, err := client.Get("b"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected the callback to close the client, got %v", err)
+	}
+}
+
+func TestEvictionCallbackAsync(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	client := newTestClient(t, WithMaxEntries(1), WithEvictionCallback(func(string, EvictReason) {
+		<-block
+	}))
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for i := 0; i < 10; i++ {
+			client.Set(fmt.Sprintf("key%d", i), []byte("v"))
+		}
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a blocked callback not to block writes")
+	}
+}
+
+func TestEvictReasonString(t *testing.T) {
+	for reason, want := range map[EvictReason]string{
+		Evicted:   "evicted",
+		Expired:   "expired",
+		Displaced: "displaced",
+		0:         "unknown",
+	} {
+		if got := reason.String(); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestEvict(t *testing.T) {
+	rec := newEvictionRecorder()
+	client := newTestClient(t, WithMaxEntries(100), WithEvictionCallback(rec.callback))
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		client.Set(key, []byte("v"))
+		time.Sleep(2 * time.Millisecond)
+	}
+	client.Pin("a")
+	if _, err := client.Get("b"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	// The read of b is counted although not yet recorded by a write.
+	n, err := client.Evict(2)
+	if err != nil {
+		t.Fatalf("Evict failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 evicted, got %d", n)
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"a", "b", "e"}) {
+		t.Errorf("Expected c and d evicted, got %v", keys)
+	}
+	for _, want := range []string{"c", "d"} {
+		if ev := rec.next(t); ev.key != want || ev.reason != Evicted {
+			t.Errorf("Expected %s evicted, got %+v", want, ev)
+		}
+	}
+
+	// Only live keys that aren't pinned count.
+	if n, _ := client.Evict(10); n != 2 {
+		t.Errorf("Expected 2 evicted, got %d", n)
+	}
+	if n, _ := client.Evict(0); n != 0 {
+		t.Errorf("Expected none evicted, got %d", n)
+	}
+	if _, err := client.Evict(-1); err == nil {
+		t.Error("Expected an error for a negative count")
+	}
+}
+
+func TestEvictWithoutLimit(t *testing.T) {
+	client := newTestClient(t)
+
+	for _, key := range []string{"a", "b", "c"} {
+		client.Set(key, []byte("v"))
+		time.Sleep(2 * time.Millisecond)
+	}
+	client.Touch("a")
+	if n, err := client.Evict(1); err != nil || n != 1 {
+		t.Fatalf("Expected 1 evicted, got %d, %v", n, err)
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"a", "c"}) {
+		t.Errorf("Expected the oldest write evicted, got %v", keys)
+	}
+	if versions, _ := client.History("b"); len(versions) != 1 {
+		t.Errorf("Expected the evicted version in history, got %d versions", len(versions))
+	}
+}
+
+func TestEvictIdle(t *testing.T) {
+	rec := newEvictionRecorder()
+	client := newTestClient(t, WithMaxEntries(100), WithEvictionCallback(rec.callback))
+
+	for _, key := range []string{"a", "b", "c"} {
+		client.Set(key, []byte("v"))
+	}
+	client.Pin("a")
+	time.Sleep(5 * time.Millisecond)
+	cutoff := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	client.Get("b")
+	client.Set("d", []byte("v"))
+
+	n, err := client.EvictIdle(cutoff)
+	if err != nil {
+		t.Fatalf("EvictIdle failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 evicted, got %d", n)
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"a", "b", "d"}) {
+		t.Errorf("Expected c evicted, got %v", keys)
+	}
+	if ev := rec.next(t); ev.key != "c" || ev.reason != Evicted {
+		t.Errorf("Expected c evicted, got %+v", ev)
+	}
+	rec.none(t)
+}
+
+func TestEvictReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	newTestClientAt(t, path).Set("a", []byte("v"))
+
+	client, err := NewCacheClient(path, WithReadOnly())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	if _, err := client.Evict(1); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if _, err := client.EvictIdle(time.Now()); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Exists reports whether a key has an active, unexpired value, without reading
+// the value itself.
+//
+// Example:
+//
+//	ok, err := client.Exists("mykey")
+func (c *CacheClient) Exists(key string) (bool, error) {
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return false, err
+	}
+	defer c.release()
+
+	return liveKeyExists(db, c.tables, key, nowMillis())
+}
+
+// ExistsMany reports, for each of keys, whether it has an active, unexpired
+// value. Every input key appears in the result. Values are never read.
+//
+// Example:
+//
+//	present, err := client.ExistsMany([]string{"a", "b"})
+//	if err != nil {
+//		return err
+//	}
+//	if !present["a"] {
+//		fmt.Println("a is missing")
+//	}
+func (c *CacheClient) ExistsMany(keys []string) (map[string]bool, error) {
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	results := make(map[string]bool, len(keys))
+	now := nowMillis()
+	for _, chunk := range chunkKeys(uniqueKeys(keys)) {
+		query := `SELECT key
+FROM ` + c.tables.kv + `
+WHERE key IN (` + placeholders(len(chunk)) + `) AND ` + liveCondition + `;`
+
+		found, err := queryStrings(db, query, keyArgs(chunk, now)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range found {
+			results[key] = true
+		}
+	}
+	if c.opts.caseInsensitiveKeys {
+		results = byRequestedKey(keys, results)
+	}
+	for _, key := range keys {
+		if !results[key] {
+			results[key] = false
+		}
+	}
+	return results, nil
+}
+
+// liveKeyExists reports whether key is active and unexpired at now.
+func liveKeyExists(db querier, t tableNames, key string, now int64) (bool, error) {
+	query := `SELECT 1
+FROM ` + t.kv + `
+WHERE key = ? AND ` + liveCondition + `
+LIMIT 1;`
+
+	var one int
+	err := db.QueryRow(query, key, now).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("query failed: %w", err)
+	}
+	return true, nil
+}
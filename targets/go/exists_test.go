@@ -0,0 +1,53 @@
+package squeakyv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExists(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("present", []byte("value"))
+	client.Set("empty", []byte{})
+	client.Set("deleted", []byte("value"))
+	client.Delete("deleted")
+	client.SetWithTTL("expired", []byte("value"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	cases := map[string]bool{
+		"present": true,
+		"empty":   true,
+		"deleted": false,
+		"expired": false,
+		"missing": false,
+	}
+	for key, expected := range cases {
+		ok, err := client.Exists(key)
+		if err != nil {
+			t.Fatalf("Exists(%s) failed: %v", key, err)
+		}
+		if ok != expected {
+			t.Errorf("Exists(%s): expected %v, got %v", key, expected, ok)
+		}
+	}
+}
+
+func TestExistsMany(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("value"))
+	client.Set("b", []byte("value"))
+	client.Delete("b")
+
+	present, err := client.ExistsMany([]string{"a", "b", "c", "a"})
+	if err != nil {
+		t.Fatalf("ExistsMany failed: %v", err)
+	}
+	if len(present) != 3 {
+		t.Errorf("Expected 3 entries, got %v", present)
+	}
+	if !present["a"] || present["b"] || present["c"] {
+		t.Errorf("Unexpected result: %v", present)
+	}
+}
@@ -0,0 +1,146 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// ExportOptions controls what Export writes.
+type ExportOptions struct {
+	// IncludeHistory exports every retained version, including those of
+	// deleted keys, instead of only live values.
+	IncludeHistory bool
+}
+
+// exportRecord is one line of the JSON Lines format written by Export and
+// read by Import. Value is base64-encoded by encoding/json.
+type exportRecord struct {
+	Key       string     `json:"key"`
+	Value     []byte     `json:"value"`
+	WrittenAt time.Time  `json:"written_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Active    bool       `json:"active"`
+}
+
+// MarshalJSON writes a key that isn't valid UTF-8, such as one set with SetB,
+// base64-encoded as key_b in place of key, since encoding/json would replace
+// its invalid bytes.
+func (r exportRecord) MarshalJSON() ([]byte, error) {
+	type plain exportRecord
+	if utf8.ValidString(r.Key) {
+		return json.Marshal(plain(r))
+	}
+	return json.Marshal(struct {
+		plain
+		Key  *string `json:"key,omitempty"`
+		KeyB []byte  `json:"key_b"`
+	}{plain: plain(r), KeyB: []byte(r.Key)})
+}
+
+// exportSummary is the final line written by Export.
+type exportSummary struct {
+	Records int64 `json:"records"`
+	Keys    int64 `json:"keys"`
+}
+
+// exportSummaryLine wraps the summary so it can't be mistaken for a record.
+type exportSummaryLine struct {
+	Summary exportSummary `json:"summary"`
+}
+
+// Export writes the cache to w as JSON Lines: one object per version with its
+// key, base64-encoded value, write time, expiry and whether it is live,
+// followed by a summary line of the form {"summary":{"records":N,"keys":M}}.
+// Keys that aren't valid UTF-8 are written base64-encoded as key_b instead of
+// key, so that binary keys round-trip exactly.
+//
+// Rows are streamed from a single query ordered by key and then by version,
+// so exporting the same data twice produces identical output. Without
+// opts.IncludeHistory only live values are written.
+//
+// Example:
+//
+//	f, err := os.Create("dump.jsonl")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	err = client.Export(f, squeakyv.ExportOptions{IncludeHistory: true})
+func (c *CacheClient) Export(w io.Writer, opts ExportOptions) error {
+	query := `SELECT key, value, inserted_at, expires_at, ` + liveCondition + `
+FROM ` + c.tables.kv + `
+WHERE ` + liveCondition + `
+ORDER BY key, rowid;`
+	if opts.IncludeHistory {
+		query = `SELECT key, value, inserted_at, expires_at, ` + liveCondition + `
+FROM ` + c.tables.kv + `
+ORDER BY key, rowid;`
+	}
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	now := nowMillis()
+	args := []interface{}{now}
+	if !opts.IncludeHistory {
+		args = append(args, now)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	var (
+		summary exportSummary
+		lastKey string
+	)
+	for rows.Next() {
+		var (
+			rec        exportRecord
+			insertedAt int64
+			expiresAt  sql.NullInt64
+		)
+		if err := rows.Scan(&rec.Key, &rec.Value, &insertedAt, &expiresAt, &rec.Active); err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		if rec.Value == nil {
+			rec.Value = []byte{}
+		}
+		if rec.Value, err = c.decodeStored(rec.Key, rec.Value); err != nil {
+			return err
+		}
+		rec.WrittenAt = time.UnixMilli(insertedAt).UTC()
+		if expiresAt.Valid {
+			t := time.UnixMilli(expiresAt.Int64).UTC()
+			rec.ExpiresAt = &t
+		}
+
+		if err := enc.Encode(rec); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		if summary.Records == 0 || rec.Key != lastKey {
+			summary.Keys++
+			lastKey = rec.Key
+		}
+		summary.Records++
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	if err := enc.Encode(exportSummaryLine{Summary: summary}); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package squeakyv
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportLines(t *testing.T, client *CacheClient, opts ExportOptions) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := client.Export(&buf, opts); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func TestExport(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("b", []byte{0x00, 0xff})
+	client.Set("a", []byte("a1"))
+	client.Set("a", []byte("a2"))
+	client.Set("gone", []byte("x"))
+	client.Delete("gone")
+	client.SetWithTTL("ttl", []byte("t"), time.Hour)
+
+	lines := exportLines(t, client, ExportOptions{})
+	if len(lines) != 4 {
+		t.Fatalf("Expected 3 records and a summary, got %d lines: %v", len(lines), lines)
+	}
+
+	var records []exportRecord
+	for _, line := range lines[:3] {
+		var rec exportRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("Failed to decode %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	if records[0].Key != "a" || string(records[0].Value) != "a2" {
+		t.Errorf("Expected a=a2 first, got %+v", records[0])
+	}
+	if records[1].Key != "b" || !bytes.Equal(records[1].Value, []byte{0x00, 0xff}) {
+		t.Errorf("Expected binary b second, got %+v", records[1])
+	}
+	if records[2].Key != "ttl" || records[2].ExpiresAt == nil {
+		t.Errorf("Expected ttl with expiry third, got %+v", records[2])
+	}
+	for _, rec := range records {
+		if !rec.Active || rec.WrittenAt.IsZero() {
+			t.Errorf("Expected active record with a write time, got %+v", rec)
+		}
+	}
+	if !strings.Contains(lines[1], `"value":"AP8="`) {
+		t.Errorf("Expected base64 value, got %s", lines[1])
+	}
+
+	if lines[3] != `{"summary":{"records":3,"keys":3}}` {
+		t.Errorf("Unexpected summary line %s", lines[3])
+	}
+}
+
+func TestExportIncludeHistory(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("a1"))
+	client.Set("a", []byte("a2"))
+	client.Set("gone", []byte("x"))
+	client.Delete("gone")
+
+	lines := exportLines(t, client, ExportOptions{IncludeHistory: true})
+	if len(lines) != 4 {
+		t.Fatalf("Expected 3 records and a summary, got %d lines", len(lines))
+	}
+	if lines[3] != `{"summary":{"records":3,"keys":2}}` {
+		t.Errorf("Unexpected summary line %s", lines[3])
+	}
+
+	var first, last exportRecord
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[2]), &last)
+	if first.Key != "a" || string(first.Value) != "a1" || first.Active {
+		t.Errorf("Expected inactive a1 first, got %+v", first)
+	}
+	if last.Key != "gone" || last.Active {
+		t.Errorf("Expected inactive gone last, got %+v", last)
+	}
+
+	// Exports are deterministic
+	again := exportLines(t, client, ExportOptions{IncludeHistory: true})
+	if strings.Join(lines, "\n") != strings.Join(again, "\n") {
+		t.Error("Expected identical output for identical data")
+	}
+}
+
+func TestExportEmpty(t *testing.T) {
+	client := newTestClient(t)
+
+	lines := exportLines(t, client, ExportOptions{})
+	if len(lines) != 1 || lines[0] != `{"summary":{"records":0,"keys":0}}` {
+		t.Errorf("Expected only a summary line, got %v", lines)
+	}
+}
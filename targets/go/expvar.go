@@ -0,0 +1,83 @@
+package squeakyv
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// published holds the expvar variables created by PublishExpvar, by name.
+//
+// expvar has no way to remove a variable, so each name is published once,
+// as an expvar.Func reading from whichever client currently owns the name.
+var published = struct {
+	sync.Mutex
+	owners map[string]*CacheClient
+}{owners: make(map[string]*CacheClient)}
+
+// PublishExpvar publishes the client's Stats counters and path as the expvar
+// variable name, shown at /debug/vars by servers that serve expvar.Handler.
+// The value is a JSON object with the keys path, gets, hits, misses, sets,
+// deletes, bytes_read, bytes_written and errors, read from Stats each time
+// the variable is.
+//
+// Publishing a name that an earlier client published moves the name to this
+// client, so a process that recreates its clients, say on a config reload,
+// can publish under the same name each time. Closing the client publishes
+// null under its name until another client takes it over. It is an error to
+// use a name published through the expvar package by other code.
+//
+// Example:
+//
+//	if err := client.PublishExpvar("cache"); err != nil {
+//		return err
+//	}
+func (c *CacheClient) PublishExpvar(name string) error {
+	published.Lock()
+	defer published.Unlock()
+
+	if _, ok := published.owners[name]; !ok {
+		if expvar.Get(name) != nil {
+			return fmt.Errorf("expvar %q is already published", name)
+		}
+		expvar.Publish(name, expvar.Func(func() any { return expvarValue(name) }))
+	}
+	published.owners[name] = c
+	return nil
+}
+
+// expvarValue returns the value of the expvar variable name, or nil if no
+// open client owns it.
+func expvarValue(name string) any {
+	published.Lock()
+	c := published.owners[name]
+	published.Unlock()
+	if c == nil {
+		return nil
+	}
+
+	s := c.Stats()
+	return map[string]any{
+		"path":          c.Path(),
+		"gets":          s.Gets,
+		"hits":          s.Hits,
+		"misses":        s.Misses,
+		"sets":          s.Sets,
+		"deletes":       s.Deletes,
+		"bytes_read":    s.BytesRead,
+		"bytes_written": s.BytesWritten,
+		"errors":        s.GetErrors + s.SetErrors + s.DeleteErrors,
+	}
+}
+
+// unpublishExpvar releases the expvar names owned by c.
+func (c *CacheClient) unpublishExpvar() {
+	published.Lock()
+	defer published.Unlock()
+
+	for name, owner := range published.owners {
+		if owner == c {
+			published.owners[name] = nil
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package squeakyv
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+// readExpvar decodes the published expvar variable name.
+func readExpvar(t *testing.T, name string) map[string]any {
+	t.Helper()
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatalf("Expected %s to be published", name)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatalf("Failed to decode %s: %v", name, err)
+	}
+	return got
+}
+
+func TestPublishExpvar(t *testing.T) {
+	client := newTestClient(t)
+	if err := client.PublishExpvar("squeakyv_test"); err != nil {
+		t.Fatalf("PublishExpvar failed: %v", err)
+	}
+	client.Set("a", []byte("v"))
+	client.Get("a")
+	client.Get("missing")
+
+	got := readExpvar(t, "squeakyv_test")
+	if got["path"] != ":memory:" || got["hits"] != 1.0 || got["misses"] != 1.0 || got["sets"] != 1.0 {
+		t.Errorf("Expected the client's counters, got %v", got)
+	}
+
+	// A recreated client takes the name over.
+	client.Close()
+	if v := expvar.Get("squeakyv_test").String(); v != "null" {
+		t.Errorf("Expected null after Close, got %s", v)
+	}
+	next := newTestClient(t)
+	if err := next.PublishExpvar("squeakyv_test"); err != nil {
+		t.Fatalf("PublishExpvar of a reused name failed: %v", err)
+	}
+	if got := readExpvar(t, "squeakyv_test"); got["sets"] != 0.0 {
+		t.Errorf("Expected the new client's counters, got %v", got)
+	}
+
+	expvar.NewInt("squeakyv_test_foreign")
+	if err := next.PublishExpvar("squeakyv_test_foreign"); err == nil {
+		t.Error("Expected an error for a name published elsewhere")
+	}
+}
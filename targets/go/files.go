@@ -0,0 +1,52 @@
+package squeakyv
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// sidecarSuffixes are the suffixes of the files SQLite keeps next to a
+// database, depending on its journal mode.
+var sidecarSuffixes = []string{"-wal", "-shm", "-journal"}
+
+// prepareFile applies WithCreateDirs and WithFileMode to the database file at
+// path before it is opened.
+func prepareFile(path string, o options) error {
+	if isMemoryPath(path) {
+		return nil
+	}
+
+	if o.createDirs {
+		if err := os.MkdirAll(filepath.Dir(path), o.dirMode); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	if o.fileMode == 0 || o.readOnly {
+		return nil
+	}
+
+	// Create the file ourselves so that it never exists with broader
+	// permissions, even briefly; SQLite opens an empty file as a new database.
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, o.fileMode)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	// The umask may have narrowed the mode of a new file, and an existing one
+	// keeps its own until changed.
+	if err := os.Chmod(path, o.fileMode); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	for _, suffix := range sidecarSuffixes {
+		err := os.Chmod(path+suffix, o.fileMode)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to set file mode: %w", err)
+		}
+	}
+	return nil
+}
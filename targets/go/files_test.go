@@ -0,0 +1,104 @@
+package squeakyv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fileMode returns the permission bits of the file at path.
+func fileMode(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", path, err)
+	}
+	return info.Mode().Perm()
+}
+
+func TestWithCreateDirs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "var", "cache", "myapp")
+	path := filepath.Join(dir, "kv.db")
+
+	if _, err := NewCacheClient(path); err == nil {
+		t.Fatal("Expected open to fail without the parent directory")
+	}
+
+	client, err := NewCacheClient(path, WithCreateDirs(0o700))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Set("key", []byte("value")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if mode := fileMode(t, dir); mode != 0o700 {
+		t.Errorf("Expected directory mode 0700, got %o", mode)
+	}
+}
+
+func TestWithFileModeNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.db")
+
+	client, err := NewCacheClient(path, WithFileMode(0o600))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Set("key", []byte("value")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	for _, p := range []string{path, path + "-wal", path + "-shm"} {
+		if mode := fileMode(t, p); mode != 0o600 {
+			t.Errorf("Expected %s to have mode 0600, got %o", filepath.Base(p), mode)
+		}
+	}
+}
+
+func TestWithFileModeExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "existing.db")
+	client, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Set("key", []byte("value"))
+	client.Close()
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+
+	// Without the option, the existing mode is kept
+	client, err = NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to open client: %v", err)
+	}
+	client.Close()
+	if mode := fileMode(t, path); mode != 0o640 {
+		t.Errorf("Expected mode 0640 to be kept, got %o", mode)
+	}
+
+	// Read-only clients never change it
+	client, err = NewCacheClient(path, WithReadOnly(), WithFileMode(0o600))
+	if err != nil {
+		t.Fatalf("Failed to open read-only client: %v", err)
+	}
+	client.Close()
+	if mode := fileMode(t, path); mode != 0o640 {
+		t.Errorf("Expected read-only open to keep mode 0640, got %o", mode)
+	}
+
+	// Asking for a mode applies it
+	client, err = NewCacheClient(path, WithFileMode(0o600))
+	if err != nil {
+		t.Fatalf("Failed to open client: %v", err)
+	}
+	defer client.Close()
+	if mode := fileMode(t, path); mode != 0o600 {
+		t.Errorf("Expected mode 0600, got %o", mode)
+	}
+	if value, _ := client.Get("key"); string(value) != "value" {
+		t.Errorf("Expected existing data to be kept, got %q", value)
+	}
+}
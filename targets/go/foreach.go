@@ -0,0 +1,70 @@
+package squeakyv
+
+import "fmt"
+
+// ForEach calls fn for every active, unexpired key and its value, in
+// ascending key order, without loading all keys into memory first.
+//
+// Iteration stops at the first non-nil error returned by fn, and ForEach
+// returns that error unchanged. The rows come from a single SELECT, so fn
+// sees a consistent snapshot taken when iteration starts; writes made
+// meanwhile are not observed.
+//
+// The read holds a database connection until ForEach returns, so fn must not
+// write through this client (and, for in-memory databases, must not call the
+// client, or another client of the same shared database, at all), or it may
+// block. The value slice is only valid until fn
+// returns; copy it to retain it.
+//
+// Example:
+//
+//	err := client.ForEach(func(key string, value []byte) error {
+//		fmt.Printf("%s=%s\n", key, value)
+//		return nil
+//	})
+func (c *CacheClient) ForEach(fn func(key string, value []byte) error) error {
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	return forEachLive(db, c.tables, nowMillis(), c.decodeEach(fn))
+}
+
+// forEachLive streams rows of kv that are active and unexpired at now to fn,
+// in ascending key order.
+func forEachLive(db querier, t tableNames, now int64, fn func(key string, value []byte) error) error {
+	query := `SELECT key, value
+FROM ` + t.kv + `
+WHERE ` + liveCondition + `
+ORDER BY key;`
+
+	rows, err := db.Query(query, now)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	// Deferred so the cursor is released even if fn panics.
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key   string
+			value []byte
+		)
+		if err := rows.Scan(&key, &value); err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		if value == nil {
+			value = []byte{}
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package squeakyv
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestForEach(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("b", []byte("2"))
+	client.Set("a", []byte("1"))
+	client.Set("c", []byte("3"))
+	client.Delete("c")
+
+	var keys []string
+	values := make(map[string]string)
+	err := client.ForEach(func(key string, value []byte) error {
+		keys = append(keys, key)
+		values[key] = string(value)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEach failed: %v", err)
+	}
+
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Expected [a b], got %v", keys)
+	}
+	if values["a"] != "1" || values["b"] != "2" {
+		t.Errorf("Unexpected values: %v", values)
+	}
+}
+
+func TestForEachStopsOnError(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("1"))
+	client.Set("b", []byte("2"))
+	client.Set("c", []byte("3"))
+
+	sentinel := errors.New("stop")
+	calls := 0
+	err := client.ForEach(func(key string, value []byte) error {
+		calls++
+		if key == "b" {
+			return sentinel
+		}
+		return nil
+	})
+	if err != sentinel {
+		t.Errorf("Expected callback error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls before stopping, got %d", calls)
+	}
+}
+
+func TestForEachReleasesCursorOnPanic(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("1"))
+	client.Set("b", []byte("2"))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic to propagate")
+			}
+		}()
+		client.ForEach(func(key string, value []byte) error {
+			panic("boom")
+		})
+	}()
+
+	// The single in-memory connection must have been released
+	if err := client.Set("c", []byte("3")); err != nil {
+		t.Errorf("Failed to set after panic: %v", err)
+	}
+}
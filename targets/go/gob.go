@@ -0,0 +1,74 @@
+package squeakyv
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sync"
+)
+
+// GobCodec is a Codec using encoding/gob. It round-trips Go-native types that
+// JSON cannot, such as maps with non-string keys, interface-typed fields and
+// exact float and integer types.
+//
+// Every value is encoded as a self-contained gob stream carrying its own type
+// description, because each cached value must decode on its own, in any
+// order, possibly in another process. That description costs a few hundred
+// bytes per value, so for small and medium structs gob is not smaller or
+// faster than JSON; it pays off on large values such as long slices of
+// structs. Run BenchmarkSetGetGob against BenchmarkSetGetJSON with your own
+// types to compare. Concrete types need no registration;
+// types stored in interface-typed fields must be registered once per process
+// with gob.Register, as with any use of gob.
+//
+// Gob data is only readable from Go, and compatibility across versions of a
+// type follows gob's rules: fields are matched by name, so adding or removing
+// fields is safe, but renaming a field silently drops its value, and changing
+// a field's type incompatibly makes decoding fail with a *DecodeError. Prefer
+// JSONCodec for values shared with other languages or long-lived data whose
+// types may be refactored.
+type GobCodec struct{}
+
+// gobBuffers recycles encoding buffers across GobCodec calls.
+var gobBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// Marshal implements Codec.
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	buf := gobBuffers.Get().(*bytes.Buffer)
+	defer gobBuffers.Put(buf)
+	buf.Reset()
+
+	if err := gob.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// Unmarshal implements Codec.
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// SetGob stores v for a key, encoded with GobCodec. A value that cannot be
+// encoded returns an error naming the key, and nothing is written.
+//
+// Example:
+//
+//	err := client.SetGob("user:1", User{Name: "alice"})
+func (c *CacheClient) SetGob(key string, v any) error {
+	return c.setEncoded(GobCodec{}, key, v)
+}
+
+// GetGob decodes the gob value for a key into dst, which must be a non-nil
+// pointer, and reports whether the key exists. Like GetJSON, a missing key
+// returns false and a nil error, and a value that fails to decode returns
+// true and a *DecodeError naming the key.
+//
+// Example:
+//
+//	var user User
+//	found, err := client.GetGob("user:1", &user)
+func (c *CacheClient) GetGob(key string, dst any) (bool, error) {
+	return c.getDecoded(GobCodec{}, key, dst)
+}
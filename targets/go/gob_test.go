@@ -0,0 +1,149 @@
+package squeakyv
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// mediumRecord is a moderately sized struct for the codec benchmarks.
+type mediumRecord struct {
+	ID       int64
+	Name     string
+	Email    string
+	Active   bool
+	Score    float64
+	Tags     []string
+	Counters map[string]int
+	Friends  []int64
+}
+
+func newMediumRecord() mediumRecord {
+	r := mediumRecord{
+		ID:       42,
+		Name:     "Alice Example",
+		Email:    "alice@example.com",
+		Active:   true,
+		Score:    98.6,
+		Counters: make(map[string]int),
+	}
+	for i := 0; i < 10; i++ {
+		r.Tags = append(r.Tags, fmt.Sprintf("tag-%d", i))
+		r.Counters[fmt.Sprintf("counter-%d", i)] = i * 100
+		r.Friends = append(r.Friends, int64(1000+i))
+	}
+	return r
+}
+
+// gobShape is registered to exercise interface-typed fields.
+type gobShape struct {
+	Sides int
+}
+
+func init() {
+	gob.Register(gobShape{})
+}
+
+func TestSetGetGob(t *testing.T) {
+	client := newTestClient(t)
+
+	want := newMediumRecord()
+	if err := client.SetGob("key", want); err != nil {
+		t.Fatalf("SetGob failed: %v", err)
+	}
+
+	var got mediumRecord
+	found, err := client.GetGob("key", &got)
+	if err != nil || !found {
+		t.Fatalf("GetGob failed: found=%v err=%v", found, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestGobNonStringKeys(t *testing.T) {
+	client := newTestClient(t)
+
+	want := map[int]string{1: "one", 2: "two"}
+	client.SetGob("key", want)
+
+	var got map[int]string
+	if _, err := client.GetGob("key", &got); err != nil {
+		t.Fatalf("GetGob failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestGobInterfaceField(t *testing.T) {
+	client := newTestClient(t)
+
+	type holder struct{ Shape any }
+	// Each value is self-contained, so repeated writes decode independently.
+	for i := 1; i <= 3; i++ {
+		if err := client.SetGob(fmt.Sprint(i), holder{Shape: gobShape{Sides: i}}); err != nil {
+			t.Fatalf("SetGob failed: %v", err)
+		}
+	}
+	for i := 3; i >= 1; i-- {
+		var got holder
+		if _, err := client.GetGob(fmt.Sprint(i), &got); err != nil {
+			t.Fatalf("GetGob failed: %v", err)
+		}
+		if got.Shape != (gobShape{Sides: i}) {
+			t.Errorf("Expected %d sides, got %+v", i, got.Shape)
+		}
+	}
+}
+
+func TestGetGobMissingAndInvalid(t *testing.T) {
+	client := newTestClient(t)
+
+	var got mediumRecord
+	if found, err := client.GetGob("missing", &got); found || err != nil {
+		t.Errorf("Expected false, nil, got %v, %v", found, err)
+	}
+
+	client.Set("bad", []byte("not gob"))
+	found, err := client.GetGob("bad", &got)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Key != "bad" || !found {
+		t.Errorf("Expected *DecodeError for key bad, got found=%v err=%v", found, err)
+	}
+}
+
+func BenchmarkSetGetJSON(b *testing.B) {
+	benchmarkCodec(b, JSONCodec{})
+}
+
+func BenchmarkSetGetGob(b *testing.B) {
+	benchmarkCodec(b, GobCodec{})
+}
+
+func benchmarkCodec(b *testing.B, codec Codec) {
+	client, err := NewCacheClient(":memory:")
+	if err != nil {
+		b.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	record := newMediumRecord()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := client.setEncoded(codec, "key", record); err != nil {
+			b.Fatal(err)
+		}
+		var got mediumRecord
+		if _, err := client.getDecoded(codec, "key", &got); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	data, _ := codec.Marshal(record)
+	b.ReportMetric(float64(len(data)), "bytes/value")
+}
@@ -0,0 +1,108 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Ping checks that the database answers a trivial query, SELECT 1, within
+// ctx's deadline. It returns ErrClosed once the client is closed.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+//	defer cancel()
+//	if err := client.Ping(ctx); err != nil {
+//		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+//	}
+func (c *CacheClient) Ping(ctx context.Context) error {
+	db, err := c.acquire()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	return ping(ctx, db)
+}
+
+// ping runs SELECT 1 on db.
+func ping(ctx context.Context, db *sql.DB) error {
+	var one int
+	if err := db.QueryRowContext(ctx, `SELECT 1;`).Scan(&one); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	return nil
+}
+
+// HealthReport is the result of Healthy.
+type HealthReport struct {
+	// Latency is how long the SELECT 1 query of Ping took.
+	Latency time.Duration
+	// SchemaVersion is the schema version recorded when the database was
+	// created.
+	SchemaVersion string
+	// SchemaCurrent reports whether the database has every column this
+	// version of the package adds to the schema. It is false only for
+	// databases that are opened read-only and were never opened read-write
+	// by this version, which NewCacheClient rejects.
+	SchemaCurrent bool
+	// FreeDiskBytes is the space available to the process on the file
+	// system holding the database, or -1 for in-memory databases and on
+	// platforms where it cannot be determined.
+	FreeDiskBytes int64
+}
+
+// Healthy is Ping with a fuller report, for readiness probes: besides the
+// query latency it reads the schema version and, for file databases, the free
+// space on their file system. An error means the database is not usable;
+// what counts as too little free space is left to the caller.
+//
+// Example:
+//
+//	report, err := client.Healthy(ctx)
+//	if err != nil || report.FreeDiskBytes >= 0 && report.FreeDiskBytes < 1<<30 {
+//		w.WriteHeader(http.StatusServiceUnavailable)
+//	}
+func (c *CacheClient) Healthy(ctx context.Context) (HealthReport, error) {
+	db, err := c.acquire()
+	if err != nil {
+		return HealthReport{}, err
+	}
+	defer c.release()
+
+	report := HealthReport{FreeDiskBytes: -1}
+	start := time.Now()
+	if err := ping(ctx, db); err != nil {
+		return report, err
+	}
+	report.Latency = time.Since(start)
+
+	query := `SELECT value FROM __metadata__ WHERE key = 'schema_version';`
+	err = db.QueryRowContext(ctx, query).Scan(&report.SchemaVersion)
+	if err != nil && err != sql.ErrNoRows {
+		return report, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	report.SchemaCurrent = checkSchema(db, c.tables) == nil
+
+	if dir, ok := databaseDir(c.path); ok {
+		if free, err := freeDiskBytes(dir); err == nil {
+			report.FreeDiskBytes = free
+		}
+	}
+	return report, nil
+}
+
+// databaseDir returns the directory holding the database file at path,
+// reporting false for in-memory databases.
+func databaseDir(path string) (string, bool) {
+	if isMemoryPath(path) {
+		return "", false
+	}
+	if name, _, ok := parseURIPath(path); ok {
+		path = name
+	}
+	return filepath.Dir(path), true
+}
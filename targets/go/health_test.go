@@ -0,0 +1,51 @@
+package squeakyv
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	client := newTestClient(t)
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.Ping(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	client.Close()
+	if err := client.Ping(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	client, err := NewCacheClient(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	report, err := client.Healthy(context.Background())
+	if err != nil {
+		t.Fatalf("Healthy failed: %v", err)
+	}
+	if report.SchemaVersion == "" || !report.SchemaCurrent || report.Latency <= 0 || report.Latency > time.Second {
+		t.Errorf("Expected a healthy report, got %+v", report)
+	}
+	if report.FreeDiskBytes <= 0 {
+		t.Errorf("Expected free disk space for a file database, got %d", report.FreeDiskBytes)
+	}
+
+	memory := newTestClient(t)
+	if report, err := memory.Healthy(context.Background()); err != nil || report.FreeDiskBytes != -1 {
+		t.Errorf("Expected no free disk space for a memory database, got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,243 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Version is one retained version of a key, as returned by History.
+type Version struct {
+	// ID identifies the version. IDs are unique across the whole cache and
+	// increase with each write, so they order versions of the same key. They
+	// are kv's INTEGER PRIMARY KEY, so Vacuum never renumbers them.
+	ID int64
+	// Value holds the version's bytes; it is never nil.
+	Value []byte
+	// WrittenAt is when the version was written.
+	WrittenAt time.Time
+	// Active is true for the key's current live version. At most one version
+	// of a key is active, and none is for a deleted or expired key.
+	Active bool
+	// ExpiresAt is when the version expires, or the zero Time if it never does.
+	ExpiresAt time.Time
+}
+
+// History returns every retained version of a key, newest first, including
+// versions written before a Delete.
+//
+// A key that was never written (or whose history has been purged) yields an
+// empty slice and no error.
+//
+// Example:
+//
+//	versions, err := client.History("config")
+//	if err != nil {
+//		return err
+//	}
+//	for _, v := range versions {
+//		fmt.Printf("%d %v %q\n", v.ID, v.WrittenAt, v.Value)
+//	}
+func (c *CacheClient) History(key string) ([]Version, error) {
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	versions, err := keyHistory(db, c.tables, key, nowMillis())
+	if err != nil {
+		return nil, err
+	}
+	for i := range versions {
+		if versions[i].Value, err = c.decodeStored(key, versions[i].Value); err != nil {
+			return nil, err
+		}
+	}
+	return versions, nil
+}
+
+// keyHistory returns the versions of key as of now, newest first.
+func keyHistory(db querier, t tableNames, key string, now int64) ([]Version, error) {
+	query := `SELECT rowid, value, inserted_at, ` + liveCondition + `, expires_at
+FROM ` + t.kv + `
+WHERE key = ?
+ORDER BY rowid DESC;`
+
+	rows, err := db.Query(query, now, key)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []Version
+	for rows.Next() {
+		var (
+			v          Version
+			insertedAt int64
+			expiresAt  sql.NullInt64
+		)
+		if err := rows.Scan(&v.ID, &v.Value, &insertedAt, &v.Active, &expiresAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		if v.Value == nil {
+			v.Value = []byte{}
+		}
+		v.WrittenAt = time.UnixMilli(insertedAt)
+		if expiresAt.Valid {
+			v.ExpiresAt = time.UnixMilli(expiresAt.Int64)
+		}
+		versions = append(versions, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetVersion returns the value of a specific version of a key, whether or not
+// it is current. Version IDs come from History.
+//
+// Returns an error wrapping ErrKeyNotFound if no such version exists or it
+// belongs to a different key.
+//
+// Example:
+//
+//	old, err := client.GetVersion("config", versions[1].ID)
+func (c *CacheClient) GetVersion(key string, version int64) ([]byte, error) {
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	stored, err := readVersion(db, c.tables, key, version)
+	if err != nil {
+		return nil, err
+	}
+	return c.decodeStored(key, stored)
+}
+
+// readVersion returns the value stored in version of key.
+func readVersion(db querier, t tableNames, key string, version int64) ([]byte, error) {
+	query := `SELECT value, checksum
+FROM ` + t.kv + `
+WHERE rowid = ? AND key = ?;`
+
+	var (
+		value    []byte
+		checksum sql.NullInt64
+	)
+	err := db.QueryRow(query, version, key).Scan(&value, &checksum)
+	if err == sql.ErrNoRows {
+		return nil, versionNotFound(key, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	if err := verifyChecksum(key, version, value, checksum); err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+// RestoreVersion makes a past version of a key current again by writing its
+// value as a new version, so the rollback itself is recorded in history. The
+// new version never expires. Restoring a version of a deleted key revives it.
+//
+// The copy happens in a single statement, so it is atomic with respect to
+// concurrent writes to the same key. Returns an error wrapping ErrKeyNotFound
+// if no such version exists or it belongs to a different key.
+//
+// Example:
+//
+//	err := client.RestoreVersion("config", versions[1].ID)
+func (c *CacheClient) RestoreVersion(key string, version int64) error {
+	query := `INSERT INTO ` + c.tables.kv + ` (key, value, checksum)
+SELECT key, value, checksum
+FROM ` + c.tables.kv + `
+WHERE rowid = ? AND key = ?;`
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+	defer c.invalidate(key)
+
+	var result sql.Result
+	err = c.writeEvicting(db, nil, func(db querier) error {
+		result, err = db.Exec(query, version, key)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected failed: %w", err)
+	}
+	if n == 0 {
+		return versionNotFound(key, version)
+	}
+	return nil
+}
+
+// Undelete reactivates the most recent version of a deleted or expired key so
+// that Get and ListKeys see it again. An expiry that has already passed is
+// cleared; one still in the future is kept.
+//
+// Undeleting a key that is already live is a no-op. Returns an error wrapping
+// ErrKeyNotFound if the key was never written or its history has been purged.
+//
+// Example:
+//
+//	client.Delete("config")
+//	err := client.Undelete("config") // oops
+func (c *CacheClient) Undelete(key string) error {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+	defer c.invalidate(key)
+
+	return c.withTx(db, func(tx *sql.Tx) error {
+		now := nowMillis()
+
+		live, err := liveKeyExists(tx, c.tables, key, now)
+		if err != nil {
+			return err
+		}
+		if live {
+			return nil
+		}
+		if err := expireKey(tx, c.tables, key, now); err != nil {
+			return err
+		}
+
+		query := `UPDATE ` + c.tables.kv + `
+SET is_active = 1,
+    deactivated_at = NULL,
+    expires_at = CASE WHEN expires_at <= ? THEN NULL ELSE expires_at END
+WHERE rowid = (SELECT MAX(rowid) FROM ` + c.tables.kv + ` WHERE key = ?);`
+
+		result, err := tx.Exec(query, now, key)
+		if err != nil {
+			return fmt.Errorf("exec failed: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("rows affected failed: %w", err)
+		}
+		if n == 0 {
+			return keyNotFound(key)
+		}
+		return nil
+	})
+}
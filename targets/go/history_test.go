@@ -0,0 +1,205 @@
+package squeakyv
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("v1"))
+	client.Set("key", []byte("v2"))
+	client.Delete("key")
+	client.Set("key", []byte("v3"))
+	client.Set("other", []byte("x"))
+
+	versions, err := client.History("key")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("Expected 3 versions, got %d", len(versions))
+	}
+
+	expected := []string{"v3", "v2", "v1"}
+	for i, v := range versions {
+		if string(v.Value) != expected[i] {
+			t.Errorf("Version %d: expected %s, got %s", i, expected[i], v.Value)
+		}
+		if v.Active != (i == 0) {
+			t.Errorf("Version %d: expected active=%v, got %v", i, i == 0, v.Active)
+		}
+		if v.WrittenAt.IsZero() {
+			t.Errorf("Version %d: expected a write time", i)
+		}
+		if i > 0 && v.ID >= versions[i-1].ID {
+			t.Errorf("Expected IDs to decrease, got %d after %d", v.ID, versions[i-1].ID)
+		}
+	}
+}
+
+func TestHistoryMissingKey(t *testing.T) {
+	client := newTestClient(t)
+
+	versions, err := client.History("missing")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Errorf("Expected no versions, got %d", len(versions))
+	}
+}
+
+func TestHistoryExpiredVersionInactive(t *testing.T) {
+	client := newTestClient(t)
+
+	client.SetWithTTL("key", []byte("value"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	versions, err := client.History("key")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("Expected 1 version, got %d", len(versions))
+	}
+	if versions[0].Active {
+		t.Error("Expected expired version to be inactive")
+	}
+	if versions[0].ExpiresAt.IsZero() {
+		t.Error("Expected expiry to be reported")
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("v1"))
+	client.Set("key", []byte("v2"))
+	client.Set("other", []byte("secret"))
+
+	versions, _ := client.History("key")
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 versions, got %d", len(versions))
+	}
+
+	value, err := client.GetVersion("key", versions[1].ID)
+	if err != nil {
+		t.Fatalf("GetVersion failed: %v", err)
+	}
+	if string(value) != "v1" {
+		t.Errorf("Expected v1, got %s", value)
+	}
+
+	// A version of another key is not visible through this one
+	others, _ := client.History("other")
+	if _, err := client.GetVersion("key", others[0].ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for another key's version, got %v", err)
+	}
+	if _, err := client.GetVersion("key", 9999); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for unknown version, got %v", err)
+	}
+}
+
+func TestRestoreVersion(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("v1"))
+	client.Set("key", []byte("v2"))
+
+	versions, _ := client.History("key")
+	if err := client.RestoreVersion("key", versions[1].ID); err != nil {
+		t.Fatalf("RestoreVersion failed: %v", err)
+	}
+
+	value, _ := client.Get("key")
+	if string(value) != "v1" {
+		t.Errorf("Expected v1 after restore, got %s", value)
+	}
+
+	versions, _ = client.History("key")
+	if len(versions) != 3 {
+		t.Fatalf("Expected 3 versions after restore, got %d", len(versions))
+	}
+	expected := []string{"v1", "v2", "v1"}
+	for i, v := range versions {
+		if string(v.Value) != expected[i] {
+			t.Errorf("Version %d: expected %s, got %s", i, expected[i], v.Value)
+		}
+	}
+}
+
+func TestRestoreVersionRevivesDeletedKey(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("v1"))
+	client.Delete("key")
+
+	versions, _ := client.History("key")
+	if err := client.RestoreVersion("key", versions[0].ID); err != nil {
+		t.Fatalf("RestoreVersion failed: %v", err)
+	}
+
+	value, _ := client.Get("key")
+	if string(value) != "v1" {
+		t.Errorf("Expected v1 after restore, got %s", value)
+	}
+
+	if err := client.RestoreVersion("other", versions[0].ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for another key's version, got %v", err)
+	}
+}
+
+func TestUndelete(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("v1"))
+	client.Set("key", []byte("v2"))
+	client.Delete("key")
+
+	if err := client.Undelete("key"); err != nil {
+		t.Fatalf("Undelete failed: %v", err)
+	}
+
+	value, _ := client.Get("key")
+	if string(value) != "v2" {
+		t.Errorf("Expected v2 after undelete, got %s", value)
+	}
+	keys, _ := client.ListKeys()
+	if len(keys) != 1 || keys[0] != "key" {
+		t.Errorf("Expected key to be listed again, got %v", keys)
+	}
+
+	// Undeleting a live key changes nothing
+	if err := client.Undelete("key"); err != nil {
+		t.Errorf("Expected no-op on live key, got %v", err)
+	}
+	if versions, _ := client.History("key"); len(versions) != 2 {
+		t.Errorf("Expected 2 versions, got %d", len(versions))
+	}
+
+	if err := client.Undelete("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestUndeleteExpiredKey(t *testing.T) {
+	client := newTestClient(t)
+
+	client.SetWithTTL("key", []byte("value"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if err := client.Undelete("key"); err != nil {
+		t.Fatalf("Undelete failed: %v", err)
+	}
+
+	value, _ := client.Get("key")
+	if string(value) != "value" {
+		t.Errorf("Expected value after undelete, got %s", value)
+	}
+	if _, ok, _ := client.TTL("key"); ok {
+		t.Error("Expected the passed expiry to be cleared")
+	}
+}
@@ -0,0 +1,325 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultHTTPMaxBody is the largest PUT body accepted by NewHTTPHandler from
+// a client without WithMaxValueSize.
+const defaultHTTPMaxBody = 32 << 20
+
+// httpKeysPath is the path NewHTTPHandler lists keys under, and the prefix of
+// the paths of single keys.
+const httpKeysPath = "/v1/keys"
+
+// httpErrorHeader names the response header with which NewHTTPHandler
+// marks a 404 Not Found for a missing key, setting it to httpKeyNotFound, so
+// HTTPClient can tell one from a 404 for a path the handler doesn't serve.
+const (
+	httpErrorHeader = "Squeakyv-Error"
+	httpKeyNotFound = "key-not-found"
+)
+
+// httpChunkSize is how much of a value NewHTTPHandler reads per query when
+// streaming a GET response.
+const httpChunkSize = 1 << 20
+
+// NewHTTPHandler returns an http.Handler serving client as a REST API, for
+// processes that can't link against Go:
+//
+//	GET    /v1/keys/{key}        the value, as application/octet-stream
+//	PUT    /v1/keys/{key}        sets the value to the request body
+//	DELETE /v1/keys/{key}        deletes the key
+//	GET    /v1/keys?prefix=...   the live keys with the prefix, as a JSON array
+//
+// Keys are path-escaped, as by url.PathEscape, so "/" in a key is %2F. HEAD
+// works as GET without the body.
+//
+// A missing key is 404 Not Found with the header "Squeakyv-Error:
+// key-not-found", which other 404s lack, and a successful PUT or DELETE is
+// 204 No Content. Other errors are 400 for an invalid key, 403 for a
+// read-only client, 413 for a body over WithMaxValueSize (or 32 MiB), 503
+// for a closed or busy client, 507 for ErrCacheFull and otherwise 500, with
+// the error as a plain text body. GET streams values in 1 MiB chunks, except
+// under WithCompressor, WithEncryption or WithEncryptionKeys and for
+// in-memory databases.
+//
+// The handler serves paths under /v1/keys, answering 404 Not Found to
+// others, so mount it on "/v1/" of a mux, or under a prefix of its own with
+// http.StripPrefix. It does no authentication.
+//
+// Example:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/v1/", squeakyv.NewHTTPHandler(client))
+//	err := http.ListenAndServe("localhost:8080", mux)
+func NewHTTPHandler(client *CacheClient) http.Handler {
+	maxBody := client.opts.maxValueSize
+	if maxBody <= 0 {
+		maxBody = defaultHTTPMaxBody
+	}
+	return &httpHandler{client: client, maxBody: maxBody}
+}
+
+// httpHandler is the http.Handler returned by NewHTTPHandler.
+type httpHandler struct {
+	client *CacheClient
+	// maxBody is the largest PUT body accepted, in bytes.
+	maxBody int64
+}
+
+// ServeHTTP implements http.Handler.
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.EscapedPath()
+	if path == httpKeysPath {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			methodNotAllowed(w, "GET, HEAD")
+			return
+		}
+		h.list(w, r)
+		return
+	}
+
+	escaped, ok := strings.CutPrefix(path, httpKeysPath+"/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	key, err := url.PathUnescape(escaped)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid key %q: %v", escaped, err), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.get(w, r, key)
+	case http.MethodPut:
+		h.put(w, r, key)
+	case http.MethodDelete:
+		h.delete(w, r, key)
+	default:
+		methodNotAllowed(w, "GET, HEAD, PUT, DELETE")
+	}
+}
+
+// list serves GET /v1/keys.
+func (h *httpHandler) list(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.client.ListKeysWithPrefix(r.URL.Query().Get("prefix"))
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// get serves GET /v1/keys/{key}, streaming the value if it can (see
+// stream).
+func (h *httpHandler) get(w http.ResponseWriter, r *http.Request, key string) {
+	if ok := h.stream(w, r, key); ok {
+		return
+	}
+	value, err := h.client.GetContext(r.Context(), key)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	if value == nil {
+		httpError(w, keyNotFound(key))
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(value)))
+	w.Write(value)
+}
+
+// stream serves GET /v1/keys/{key} from a read transaction, writing the
+// value in chunks of httpChunkSize. It reports false, having written
+// nothing, if the value must be read whole instead: if the database is in
+// memory, whose single connection can't be held while the response is
+// written, the value is stored in an envelope, which must be decoded whole,
+// or it is still in the write-behind buffer.
+//
+// SQLite loads the whole value to evaluate substr, so a value of n chunks is
+// read from the database n times, but the handler holds no more than a chunk
+// in memory. The checksum mismatch of a corrupt value shows only once the
+// last chunk is read, so it aborts the response, and the client sees a
+// truncated body rather than a corrupt one.
+func (h *httpHandler) stream(w http.ResponseWriter, r *http.Request, key string) bool {
+	c := h.client
+	if isMemoryPath(c.path) || c.transformsValues() {
+		return false
+	}
+	if _, ok, _ := c.readBehind(key); ok {
+		return false
+	}
+
+	db, err := c.acquire()
+	if err != nil {
+		httpError(w, err)
+		return true
+	}
+	tx, err := db.BeginTx(r.Context(), nil)
+	c.release()
+	if err != nil {
+		httpError(w, fmt.Errorf("begin failed: %w", err))
+		return true
+	}
+	defer tx.Rollback()
+
+	query := `SELECT rowid, length(CAST(value AS BLOB)), checksum,
+  substr(CAST(value AS BLOB), 1, 4) = ` + valueMagicHex + `
+FROM ` + c.tables.kv + `
+WHERE key = ? AND ` + liveCondition + `;`
+
+	var (
+		version  int64
+		length   int64
+		checksum sql.NullInt64
+		wrapped  bool
+	)
+	err = tx.QueryRow(query, key, nowMillis()).Scan(&version, &length, &checksum, &wrapped)
+	if err == sql.ErrNoRows {
+		err = keyNotFound(key)
+	} else if err != nil {
+		err = fmt.Errorf("query failed: %w", err)
+	}
+	if err != nil {
+		c.stats.recordRead(0, err)
+		httpError(w, err)
+		return true
+	}
+	if wrapped && c.usesEnvelopes() {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	c.stats.recordRead(length, nil)
+	c.access.record(c.watchKey(key), nowMillis())
+	if r.Method == http.MethodHead {
+		return true
+	}
+
+	query = `SELECT substr(CAST(value AS BLOB), ?, ?)
+FROM ` + c.tables.kv + `
+WHERE rowid = ?;`
+
+	var sum uint32
+	for offset := int64(0); offset < length; offset += httpChunkSize {
+		var chunk []byte
+		err := tx.QueryRow(query, offset+1, httpChunkSize, version).Scan(&chunk)
+		if err != nil {
+			err = fmt.Errorf("query failed: %w", err)
+		} else if sum = crc32.Update(sum, castagnoli, chunk); offset+int64(len(chunk)) >= length && checksum.Valid && int64(sum) != checksum.Int64 {
+			err = &ChecksumMismatchError{Key: key, Version: version}
+		}
+		if err != nil && offset == 0 {
+			httpError(w, err)
+			return true
+		}
+		if err != nil {
+			// The response has begun, so the error can only be reported by
+			// cutting it short.
+			panic(http.ErrAbortHandler)
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return true
+		}
+	}
+	return true
+}
+
+// put serves PUT /v1/keys/{key}. The body is read whole, into a buffer sized
+// from its Content-Length, before it is written: a streamed write would hold
+// the database's write lock for as long as the client takes to send it,
+// stalling every other writer.
+func (h *httpHandler) put(w http.ResponseWriter, r *http.Request, key string) {
+	if r.ContentLength > h.maxBody {
+		httpError(w, &ValueTooLargeError{Key: key, Size: int(r.ContentLength), Limit: int(h.maxBody)})
+		return
+	}
+
+	value, err := readBody(http.MaxBytesReader(w, r.Body, h.maxBody), r.ContentLength)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("%v: key %q is over the limit of %d bytes", ErrValueTooLarge, key, h.maxBody),
+			http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.client.SetContext(r.Context(), key, value); err != nil {
+		httpError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// delete serves DELETE /v1/keys/{key}.
+func (h *httpHandler) delete(w http.ResponseWriter, r *http.Request, key string) {
+	if err := h.client.DeleteContext(r.Context(), key); err != nil {
+		httpError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readBody reads a request body of size bytes, or of unknown size if size is
+// -1, as for a chunked request. The server stops a body at its declared
+// size, so a known size fills a buffer allocated once.
+func readBody(r io.Reader, size int64) ([]byte, error) {
+	if size < 0 {
+		return io.ReadAll(r)
+	}
+	value := make([]byte, size)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// httpError writes the HTTP response for an error returned by the client.
+func httpError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var busy *BusyError
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		status = http.StatusNotFound
+		w.Header().Set(httpErrorHeader, httpKeyNotFound)
+	case errors.Is(err, ErrInvalidKey):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrValueTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrReadOnly):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrCacheFull):
+		status = http.StatusInsufficientStorage
+	case errors.Is(err, ErrClosed), errors.As(err, &busy):
+		status = http.StatusServiceUnavailable
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// methodNotAllowed writes a 405 Method Not Allowed response listing the
+// allowed methods.
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
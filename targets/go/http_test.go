@@ -0,0 +1,286 @@
+package squeakyv
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newHTTPServer serves client with NewHTTPHandler mounted on "/v1/" of a mux.
+func newHTTPServer(t *testing.T, client *CacheClient) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", NewHTTPHandler(client))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// doHTTP makes a request to server and returns the status and body.
+func doHTTP(t *testing.T, server *httptest.Server, method, path string, body io.Reader) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, body)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return resp.StatusCode, data
+}
+
+func TestHTTPHandler(t *testing.T) {
+	client := newTestClient(t)
+	server := newHTTPServer(t, client)
+
+	if status, _ := doHTTP(t, server, http.MethodGet, "/v1/keys/a", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing key, got %d", status)
+	}
+
+	if status, _ := doHTTP(t, server, http.MethodPut, "/v1/keys/a", strings.NewReader("hello")); status != http.StatusNoContent {
+		t.Fatalf("Expected 204 from PUT, got %d", status)
+	}
+	if value, _ := client.Get("a"); string(value) != "hello" {
+		t.Errorf("Expected hello stored, got %q", value)
+	}
+	status, body := doHTTP(t, server, http.MethodGet, "/v1/keys/a", nil)
+	if status != http.StatusOK || string(body) != "hello" {
+		t.Errorf("Expected 200 hello, got %d %q", status, body)
+	}
+
+	// An empty value is not a missing one.
+	doHTTP(t, server, http.MethodPut, "/v1/keys/empty", nil)
+	if status, body := doHTTP(t, server, http.MethodGet, "/v1/keys/empty", nil); status != http.StatusOK || len(body) != 0 {
+		t.Errorf("Expected 200 with no body, got %d %q", status, body)
+	}
+
+	if status, _ := doHTTP(t, server, http.MethodDelete, "/v1/keys/a", nil); status != http.StatusNoContent {
+		t.Errorf("Expected 204 from DELETE, got %d", status)
+	}
+	if status, _ := doHTTP(t, server, http.MethodGet, "/v1/keys/a", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 after DELETE, got %d", status)
+	}
+	if status, _ := doHTTP(t, server, http.MethodDelete, "/v1/keys/a", nil); status != http.StatusNoContent {
+		t.Errorf("Expected 204 from DELETE of a missing key, got %d", status)
+	}
+}
+
+func TestHTTPHandlerHeaders(t *testing.T) {
+	client := newTestClient(t)
+	server := newHTTPServer(t, client)
+	client.Set("a", []byte("hello"))
+
+	resp, err := http.Head(server.URL + "/v1/keys/a")
+	if err != nil {
+		t.Fatalf("HEAD failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != 5 {
+		t.Errorf("Expected 200 with length 5, got %d with %d", resp.StatusCode, resp.ContentLength)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("Expected application/octet-stream, got %q", ct)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/keys/a", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") == "" {
+		t.Errorf("Expected 405 with Allow, got %d %q", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+
+	if status, _ := doHTTP(t, server, http.MethodGet, "/v1/other", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 outside /v1/keys, got %d", status)
+	}
+}
+
+func TestHTTPHandlerEscapedKeys(t *testing.T) {
+	client := newTestClient(t)
+	server := newHTTPServer(t, client)
+
+	for _, key := range []string{"a/b", "a//b", "../x", "spaces and ?#%", "ключ"} {
+		path := "/v1/keys/" + url.PathEscape(key)
+		if status, _ := doHTTP(t, server, http.MethodPut, path, strings.NewReader(key)); status != http.StatusNoContent {
+			t.Errorf("Expected 204 from PUT of %q, got %d", key, status)
+			continue
+		}
+		if value, _ := client.Get(key); string(value) != key {
+			t.Errorf("Expected %q stored under %q, got %q", key, key, value)
+		}
+		if status, body := doHTTP(t, server, http.MethodGet, path, nil); status != http.StatusOK || string(body) != key {
+			t.Errorf("Expected 200 %q, got %d %q", key, status, body)
+		}
+	}
+
+	// Unescaped slashes are part of the key.
+	if status, body := doHTTP(t, server, http.MethodGet, "/v1/keys/a/b", nil); status != http.StatusOK || string(body) != "a/b" {
+		t.Errorf("Expected 200 a/b, got %d %q", status, body)
+	}
+}
+
+func TestHTTPHandlerList(t *testing.T) {
+	client := newTestClient(t)
+	server := newHTTPServer(t, client)
+
+	status, body := doHTTP(t, server, http.MethodGet, "/v1/keys", nil)
+	if status != http.StatusOK || strings.TrimSpace(string(body)) != "[]" {
+		t.Errorf("Expected an empty array, got %d %q", status, body)
+	}
+
+	for _, key := range []string{"user:1", "user:2", "session:1"} {
+		client.Set(key, []byte("v"))
+	}
+	for query, want := range map[string][]string{
+		"":                    {"session:1", "user:1", "user:2"},
+		"?prefix=user%3A":     {"user:1", "user:2"},
+		"?prefix=missing":     {},
+		"?prefix=session%3A1": {"session:1"},
+	} {
+		status, body := doHTTP(t, server, http.MethodGet, "/v1/keys"+query, nil)
+		if status != http.StatusOK {
+			t.Errorf("Expected 200 for %q, got %d", query, status)
+			continue
+		}
+		var keys []string
+		if err := json.Unmarshal(body, &keys); err != nil {
+			t.Fatalf("Failed to decode %q: %v", body, err)
+		}
+		slices.Sort(keys)
+		if !slices.Equal(keys, want) {
+			t.Errorf("Expected %v for %q, got %v", want, query, keys)
+		}
+	}
+}
+
+func TestHTTPHandlerLimits(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithMaxValueSize(10))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	server := newHTTPServer(t, client)
+
+	if status, _ := doHTTP(t, server, http.MethodPut, "/v1/keys/a", strings.NewReader("0123456789")); status != http.StatusNoContent {
+		t.Errorf("Expected a value at the limit stored, got %d", status)
+	}
+	if status, _ := doHTTP(t, server, http.MethodPut, "/v1/keys/a", strings.NewReader("0123456789a")); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 over the limit, got %d", status)
+	}
+
+	// A chunked body, of unknown length, is cut off at the limit.
+	body := io.MultiReader(strings.NewReader("0123456789"), strings.NewReader("abc"))
+	if status, _ := doHTTP(t, server, http.MethodPut, "/v1/keys/b", body); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a chunked body over the limit, got %d", status)
+	}
+	if exists, _ := client.Exists("b"); exists {
+		t.Error("Expected nothing stored for a rejected body")
+	}
+	if value, _ := client.Get("a"); string(value) != "0123456789" {
+		t.Errorf("Expected the stored value kept, got %q", value)
+	}
+}
+
+func TestHTTPHandlerLargeValue(t *testing.T) {
+	client := newTestClient(t)
+	server := newHTTPServer(t, client)
+
+	value := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	if status, _ := doHTTP(t, server, http.MethodPut, "/v1/keys/big", bytes.NewReader(value)); status != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", status)
+	}
+	if status, body := doHTTP(t, server, http.MethodGet, "/v1/keys/big", nil); status != http.StatusOK || !bytes.Equal(body, value) {
+		t.Errorf("Expected the value back, got %d with %d bytes", status, len(body))
+	}
+}
+
+func TestHTTPHandlerStreamsValues(t *testing.T) {
+	client := newFileClient(t)
+	server := newHTTPServer(t, client)
+
+	// Not a whole number of chunks, so the last one is short.
+	value := bytes.Repeat([]byte("0123456789abcdef"), 5*httpChunkSize/32)
+	client.Set("big", value)
+	client.Set("small", []byte("hello"))
+
+	for key, want := range map[string][]byte{"big": value, "small": []byte("hello")} {
+		resp, err := http.Get(server.URL + "/v1/keys/" + key)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", key, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK || !bytes.Equal(body, want) {
+			t.Errorf("Expected %s back, got %d with %d bytes (%v)", key, resp.StatusCode, len(body), err)
+		}
+		if resp.ContentLength != int64(len(want)) {
+			t.Errorf("Expected Content-Length %d for %s, got %d", len(want), key, resp.ContentLength)
+		}
+	}
+	if stats := client.Stats(); stats.Hits != 2 || stats.BytesRead != int64(len(value)+5) {
+		t.Errorf("Expected 2 hits reading %d bytes, got %+v", len(value)+5, stats)
+	}
+
+	// A corrupt value that fits in one chunk is an error; a longer one cuts
+	// the response short.
+	corruptChecksum(t, client, "small")
+	if status, _ := doHTTP(t, server, http.MethodGet, "/v1/keys/small", nil); status != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for a corrupt value, got %d", status)
+	}
+	corruptChecksum(t, client, "big")
+	resp, err := http.Get(server.URL + "/v1/keys/big")
+	if err != nil {
+		t.Fatalf("GET big failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, err := io.ReadAll(resp.Body); err == nil {
+		t.Errorf("Expected a truncated body, got %d of %d bytes", len(body), len(value))
+	}
+}
+
+func TestHTTPHandlerErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	newTestClientAt(t, path).Set("a", []byte("v"))
+
+	readOnly, err := NewCacheClient(path, WithReadOnly())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer readOnly.Close()
+	server := newHTTPServer(t, readOnly)
+
+	if status, _ := doHTTP(t, server, http.MethodPut, "/v1/keys/a", strings.NewReader("v")); status != http.StatusForbidden {
+		t.Errorf("Expected 403 from a read-only client, got %d", status)
+	}
+	if status, body := doHTTP(t, server, http.MethodGet, "/v1/keys/a", nil); status != http.StatusOK || string(body) != "v" {
+		t.Errorf("Expected reads to work, got %d %q", status, body)
+	}
+
+	pinned := newTestClient(t, WithMaxEntries(1))
+	pinned.Set("a", []byte("v"))
+	pinned.Pin("a")
+	server = newHTTPServer(t, pinned)
+	if status, _ := doHTTP(t, server, http.MethodPut, "/v1/keys/b", strings.NewReader("v")); status != http.StatusInsufficientStorage {
+		t.Errorf("Expected 507 from a full cache, got %d", status)
+	}
+
+	readOnly.Close()
+	if status, _ := doHTTP(t, newHTTPServer(t, readOnly), http.MethodGet, "/v1/keys/a", nil); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 from a closed client, got %d", status)
+	}
+}
@@ -0,0 +1,376 @@
+package squeakyv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Store is the method set shared by CacheClient, ShardedClient and
+// HTTPClient, so code can be written against a cache without caring whether
+// it is embedded, sharded or remote.
+type Store interface {
+	// Get returns the value of key, or nil if it doesn't exist.
+	Get(key string) ([]byte, error)
+	// Set sets the value of key.
+	Set(key string, value []byte) error
+	// Delete removes key; removing a missing key is not an error.
+	Delete(key string) error
+	// ListKeys returns the live keys.
+	ListKeys() ([]string, error)
+}
+
+var (
+	_ Store = (*CacheClient)(nil)
+	_ Store = (*ShardedClient)(nil)
+	_ Store = (*HTTPClient)(nil)
+)
+
+// HTTPClient is a client for a cache served by NewHTTPHandler. Its methods
+// behave as the CacheClient methods of the same name, with errors from the
+// server matching the same sentinel errors (see HTTPError). An HTTPClient is
+// safe for concurrent use.
+type HTTPClient struct {
+	// keysURL is the URL of the key listing, without a trailing slash.
+	keysURL string
+	client  *http.Client
+	opts    httpClientOptions
+}
+
+// HTTPClientOption configures an HTTPClient.
+type HTTPClientOption func(*httpClientOptions)
+
+// httpClientOptions holds the settings assembled from a list of
+// HTTPClientOption values.
+type httpClientOptions struct {
+	transport        http.RoundTripper
+	timeout          time.Duration
+	retryMaxAttempts int
+	retryMaxElapsed  time.Duration
+}
+
+// defaultHTTPClientOptions returns the settings used when no options are
+// given.
+func defaultHTTPClientOptions() httpClientOptions {
+	return httpClientOptions{
+		transport:        http.DefaultTransport,
+		timeout:          10 * time.Second,
+		retryMaxAttempts: 3,
+		retryMaxElapsed:  5 * time.Second,
+	}
+}
+
+// WithHTTPTimeout bounds each attempt of a request by an HTTPClient,
+// including reading the response. The default is 10 seconds; non-positive
+// values keep it.
+func WithHTTPTimeout(d time.Duration) HTTPClientOption {
+	return func(o *httpClientOptions) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// WithHTTPRetry bounds how an HTTPClient retries a request that failed to
+// reach the server, timed out, or got 502 Bad Gateway, 503 Service
+// Unavailable or 504 Gateway Timeout. Every request the client makes is
+// idempotent, so it is safe to repeat. Retries back off exponentially with
+// jitter and stop after maxAttempts tries in total or once maxElapsed has
+// passed, whichever comes first.
+//
+// The default is 3 attempts within 5 seconds. maxAttempts of 1 disables
+// retries; non-positive values keep the defaults.
+func WithHTTPRetry(maxAttempts int, maxElapsed time.Duration) HTTPClientOption {
+	return func(o *httpClientOptions) {
+		if maxAttempts > 0 {
+			o.retryMaxAttempts = maxAttempts
+		}
+		if maxElapsed > 0 {
+			o.retryMaxElapsed = maxElapsed
+		}
+	}
+}
+
+// WithHTTPTransport makes an HTTPClient send its requests through rt, as for
+// TLS settings or authentication, instead of http.DefaultTransport.
+func WithHTTPTransport(rt http.RoundTripper) HTTPClientOption {
+	return func(o *httpClientOptions) {
+		if rt != nil {
+			o.transport = rt
+		}
+	}
+}
+
+// NewHTTPClient returns a client for the cache served by NewHTTPHandler at
+// baseURL, the URL the handler's /v1/keys paths are relative to. No request
+// is made until the first call.
+//
+// Example:
+//
+//	client, err := squeakyv.NewHTTPClient("http://localhost:8080",
+//		squeakyv.WithHTTPTimeout(2*time.Second),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	var cache squeakyv.Store = client
+func NewHTTPClient(baseURL string, opts ...HTTPClientOption) (*HTTPClient, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	o := defaultHTTPClientOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &HTTPClient{
+		keysURL: strings.TrimSuffix(baseURL, "/") + httpKeysPath,
+		client:  &http.Client{Transport: o.transport},
+		opts:    o,
+	}, nil
+}
+
+// Get retrieves the value for a key, or nil if it doesn't exist or has
+// expired.
+func (c *HTTPClient) Get(key string) ([]byte, error) {
+	return c.GetContext(context.Background(), key)
+}
+
+// GetContext is Get with a context, which bounds the request and its
+// retries.
+func (c *HTTPClient) GetContext(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.GetStrictContext(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// GetStrict retrieves the value for a key, returning an error wrapping
+// ErrKeyNotFound if it doesn't exist or has expired.
+func (c *HTTPClient) GetStrict(key string) ([]byte, error) {
+	return c.GetStrictContext(context.Background(), key)
+}
+
+// GetStrictContext is GetStrict with a context, used as GetContext uses it.
+func (c *HTTPClient) GetStrictContext(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := c.do(ctx, http.MethodGet, c.keyURL(key), nil, http.StatusOK, func(body io.Reader) error {
+		var err error
+		value, err = io.ReadAll(body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Set stores a value for a key.
+func (c *HTTPClient) Set(key string, value []byte) error {
+	return c.SetContext(context.Background(), key, value)
+}
+
+// SetContext is Set with a context, used as GetContext uses it.
+func (c *HTTPClient) SetContext(ctx context.Context, key string, value []byte) error {
+	return c.do(ctx, http.MethodPut, c.keyURL(key), value, http.StatusNoContent, nil)
+}
+
+// Delete removes a key. Removing a missing key is not an error.
+func (c *HTTPClient) Delete(key string) error {
+	return c.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext is Delete with a context, used as GetContext uses it.
+func (c *HTTPClient) DeleteContext(ctx context.Context, key string) error {
+	return c.do(ctx, http.MethodDelete, c.keyURL(key), nil, http.StatusNoContent, nil)
+}
+
+// ListKeys returns all live keys.
+func (c *HTTPClient) ListKeys() ([]string, error) {
+	return c.ListKeysWithPrefix("")
+}
+
+// ListKeysWithPrefix returns the live keys starting with prefix.
+func (c *HTTPClient) ListKeysWithPrefix(prefix string) ([]string, error) {
+	return c.ListKeysWithPrefixContext(context.Background(), prefix)
+}
+
+// ListKeysWithPrefixContext is ListKeysWithPrefix with a context, used as
+// GetContext uses it.
+func (c *HTTPClient) ListKeysWithPrefixContext(ctx context.Context, prefix string) ([]string, error) {
+	target := c.keysURL
+	if prefix != "" {
+		target += "?" + url.Values{"prefix": {prefix}}.Encode()
+	}
+	var keys []string
+	err := c.do(ctx, http.MethodGet, target, nil, http.StatusOK, func(body io.Reader) error {
+		if err := json.NewDecoder(body).Decode(&keys); err != nil {
+			return fmt.Errorf("invalid key list: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// keyURL returns the URL of key.
+func (c *HTTPClient) keyURL(key string) string {
+	return c.keysURL + "/" + url.PathEscape(key)
+}
+
+// do makes a request, retrying it as set by WithHTTPRetry. A response with
+// status want is passed to read, if not nil; any other is returned as an
+// *HTTPError.
+func (c *HTTPClient) do(ctx context.Context, method, target string, body []byte, want int, read func(body io.Reader) error) error {
+	start := time.Now()
+	delay := httpRetryBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := c.attempt(ctx, method, target, body, want, read)
+		if err == nil || !retryableHTTP(err) || ctx.Err() != nil {
+			return err
+		}
+
+		elapsed := time.Since(start)
+		sleep := delay/2 + rand.N(delay/2+1)
+		if attempt >= c.opts.retryMaxAttempts || elapsed+sleep > c.opts.retryMaxElapsed {
+			return err
+		}
+
+		select {
+		case <-time.After(sleep):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay = min(delay*2, httpRetryMaxDelay)
+	}
+}
+
+const (
+	// httpRetryBaseDelay and httpRetryMaxDelay bound the exponential backoff
+	// between HTTP attempts, as retryBaseDelay and retryMaxDelay do for the
+	// database.
+	httpRetryBaseDelay = 50 * time.Millisecond
+	httpRetryMaxDelay  = time.Second
+)
+
+// attempt makes a request once, within the timeout set by WithHTTPTimeout.
+func (c *HTTPClient) attempt(ctx context.Context, method, target string, body []byte, want int, read func(body io.Reader) error) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	var reqBody io.Reader
+	if method == http.MethodPut {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reqBody)
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Drain what's left so the connection can be reused.
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode != want {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return &HTTPError{
+			StatusCode:  resp.StatusCode,
+			Message:     strings.TrimSpace(string(message)),
+			keyNotFound: resp.Header.Get(httpErrorHeader) == httpKeyNotFound,
+		}
+	}
+	if read != nil {
+		if err := read(resp.Body); err != nil {
+			return fmt.Errorf("%s %s failed: %w", method, target, err)
+		}
+	}
+	return nil
+}
+
+// retryableHTTP reports whether a request that failed with err may succeed
+// if repeated: the transport failed to reach the server, the attempt timed
+// out, or the response came from an unavailable server or proxy. Failures to
+// build the request or read the response are permanent.
+func retryableHTTP(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var (
+		urlErr *url.Error
+		netErr net.Error
+	)
+	return errors.As(err, &urlErr) || errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// HTTPError reports an unexpected response to an HTTPClient request. It
+// matches, via errors.Is, the error the server's client returned, where the
+// status identifies it: ErrInvalidKey for 400 Bad Request, ErrReadOnly for
+// 403 Forbidden, ErrValueTooLarge for 413 Request Entity Too Large and
+// ErrCacheFull for 507 Insufficient Storage. Only a 404 Not Found the
+// handler marks as being for a missing key matches ErrKeyNotFound; any other,
+// as from a base URL the handler isn't mounted at, is reported as is.
+type HTTPError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// Message is the start of the response body, the server's error.
+	Message string
+	// keyNotFound is set if the response was marked as being for a missing
+	// key (see httpErrorHeader).
+	keyNotFound bool
+}
+
+// Error implements error.
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("squeakyv: server returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is reports whether target is the sentinel error for the status.
+func (e *HTTPError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrInvalidKey
+	case http.StatusForbidden:
+		return target == ErrReadOnly
+	case http.StatusNotFound:
+		return target == ErrKeyNotFound && e.keyNotFound
+	case http.StatusRequestEntityTooLarge:
+		return target == ErrValueTooLarge
+	case http.StatusInsufficientStorage:
+		return target == ErrCacheFull
+	}
+	return false
+}
@@ -0,0 +1,303 @@
+package squeakyv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestHTTPClient returns an HTTPClient for server.
+func newTestHTTPClient(t *testing.T, server *httptest.Server, opts ...HTTPClientOption) *HTTPClient {
+	t.Helper()
+	client, err := NewHTTPClient(server.URL, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create HTTP client: %v", err)
+	}
+	return client
+}
+
+// exerciseStore runs the same calls against any Store, checking that they
+// behave as CacheClient's.
+func exerciseStore(t *testing.T, store Store) {
+	t.Helper()
+
+	if value, err := store.Get("missing"); err != nil || value != nil {
+		t.Errorf("Expected nil for a missing key, got %q, %v", value, err)
+	}
+	for _, key := range []string{"a", "a/b", "../c", "spaces and ?#%"} {
+		if err := store.Set(key, []byte("value of "+key)); err != nil {
+			t.Fatalf("Set(%q) failed: %v", key, err)
+		}
+		if value, err := store.Get(key); err != nil || string(value) != "value of "+key {
+			t.Errorf("Expected the value of %q, got %q, %v", key, value, err)
+		}
+	}
+
+	store.Set("empty", []byte{})
+	if value, err := store.Get("empty"); err != nil || value == nil || len(value) != 0 {
+		t.Errorf("Expected an empty, non-nil value, got %#v, %v", value, err)
+	}
+
+	keys, err := store.ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys failed: %v", err)
+	}
+	slices.Sort(keys)
+	if want := []string{"../c", "a", "a/b", "empty", "spaces and ?#%"}; !slices.Equal(keys, want) {
+		t.Errorf("Expected %v, got %v", want, keys)
+	}
+
+	if err := store.Delete("a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if value, err := store.Get("a"); err != nil || value != nil {
+		t.Errorf("Expected nil after Delete, got %q, %v", value, err)
+	}
+	if err := store.Delete("a"); err != nil {
+		t.Errorf("Expected Delete of a missing key to succeed, got %v", err)
+	}
+}
+
+func TestHTTPClientMatchesCacheClient(t *testing.T) {
+	t.Run("local", func(t *testing.T) {
+		exerciseStore(t, newTestClient(t))
+	})
+	t.Run("remote", func(t *testing.T) {
+		exerciseStore(t, newTestHTTPClient(t, newHTTPServer(t, newTestClient(t))))
+	})
+}
+
+func TestHTTPClientGetStrict(t *testing.T) {
+	client := newTestHTTPClient(t, newHTTPServer(t, newTestClient(t)))
+
+	if _, err := client.GetStrict("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+	client.Set("a", []byte("v"))
+	if value, err := client.GetStrict("a"); err != nil || string(value) != "v" {
+		t.Errorf("Expected v, got %q, %v", value, err)
+	}
+}
+
+func TestHTTPClientListKeysWithPrefix(t *testing.T) {
+	cache := newTestClient(t)
+	client := newTestHTTPClient(t, newHTTPServer(t, cache))
+
+	for _, key := range []string{"user:1", "user:2", "user&x=1", "session:1"} {
+		cache.Set(key, []byte("v"))
+	}
+	keys, err := client.ListKeysWithPrefix("user")
+	if err != nil {
+		t.Fatalf("ListKeysWithPrefix failed: %v", err)
+	}
+	slices.Sort(keys)
+	if want := []string{"user&x=1", "user:1", "user:2"}; !slices.Equal(keys, want) {
+		t.Errorf("Expected %v, got %v", want, keys)
+	}
+	if keys, _ := client.ListKeysWithPrefix("user&"); !slices.Equal(keys, []string{"user&x=1"}) {
+		t.Errorf("Expected the prefix escaped, got %v", keys)
+	}
+}
+
+func TestHTTPClientErrors(t *testing.T) {
+	limited, err := NewCacheClient(":memory:", WithMaxValueSize(4))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer limited.Close()
+	client := newTestHTTPClient(t, newHTTPServer(t, limited))
+
+	err = client.Set("a", []byte("too large"))
+	if !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge, got %v", err)
+	}
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected an HTTPError with 413, got %v", err)
+	}
+
+	pinned := newTestClient(t, WithMaxEntries(1))
+	pinned.Set("a", []byte("v"))
+	pinned.Pin("a")
+	client = newTestHTTPClient(t, newHTTPServer(t, pinned))
+	if err := client.Set("b", []byte("v")); !errors.Is(err, ErrCacheFull) {
+		t.Errorf("Expected ErrCacheFull, got %v", err)
+	}
+
+	if _, err := NewHTTPClient("localhost:8080"); err == nil {
+		t.Error("Expected an error for a base URL without a scheme")
+	}
+}
+
+func TestHTTPClientBasePath(t *testing.T) {
+	cache := newTestClient(t)
+	mux := http.NewServeMux()
+	mux.Handle("/cache/", http.StripPrefix("/cache", NewHTTPHandler(cache)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := NewHTTPClient(server.URL + "/cache/")
+	if err != nil {
+		t.Fatalf("Failed to create HTTP client: %v", err)
+	}
+	if err := client.Set("a/b", []byte("v")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, _ := cache.Get("a/b"); string(value) != "v" {
+		t.Errorf("Expected v stored, got %q", value)
+	}
+}
+
+func TestHTTPClientWrongBaseURL(t *testing.T) {
+	cache := newTestClient(t)
+	cache.Set("a", []byte("v"))
+	mux := http.NewServeMux()
+	mux.Handle("/cache/", http.StripPrefix("/cache", NewHTTPHandler(cache)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The handler is mounted under /cache, so every key path is a 404 from
+	// the mux, not a missing key.
+	client, err := NewHTTPClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create HTTP client: %v", err)
+	}
+	value, err := client.Get("a")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected an HTTPError with 404, got %q, %v", value, err)
+	}
+	if errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected a 404 for an unserved path not to match ErrKeyNotFound")
+	}
+	if _, err := client.GetStrict("a"); err == nil || errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected GetStrict to fail without ErrKeyNotFound, got %v", err)
+	}
+
+	client, err = NewHTTPClient(server.URL + "/cache")
+	if err != nil {
+		t.Fatalf("Failed to create HTTP client: %v", err)
+	}
+	if value, err := client.Get("missing"); value != nil || err != nil {
+		t.Errorf("Expected nil, nil for a missing key, got %q, %v", value, err)
+	}
+	if _, err := client.GetStrict("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+// flakyHandler fails the first failures requests with status before passing
+// them to next, counting requests.
+type flakyHandler struct {
+	next     http.Handler
+	status   int
+	failures int32
+	requests atomic.Int32
+}
+
+func (h *flakyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.requests.Add(1) <= h.failures {
+		http.Error(w, "unavailable", h.status)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+func TestHTTPClientRetry(t *testing.T) {
+	cache := newTestClient(t)
+	flaky := &flakyHandler{next: NewHTTPHandler(cache), status: http.StatusServiceUnavailable, failures: 2}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+	client := newTestHTTPClient(t, server)
+
+	if err := client.Set("a", []byte("v")); err != nil {
+		t.Fatalf("Expected Set to succeed on the third attempt, got %v", err)
+	}
+	if n := flaky.requests.Load(); n != 3 {
+		t.Errorf("Expected 3 requests, got %d", n)
+	}
+
+	// Give up after the configured attempts.
+	flaky.requests.Store(0)
+	flaky.failures = 10
+	client = newTestHTTPClient(t, server, WithHTTPRetry(2, time.Second))
+	err := client.Delete("a")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected the 503, got %v", err)
+	}
+	if n := flaky.requests.Load(); n != 2 {
+		t.Errorf("Expected 2 requests, got %d", n)
+	}
+
+	// Client errors aren't retried.
+	flaky.requests.Store(0)
+	flaky.status = http.StatusBadRequest
+	if err := client.Set("a", []byte("v")); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected the 400, got %v", err)
+	}
+	if n := flaky.requests.Load(); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+}
+
+func TestHTTPClientMalformedResponse(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	client := newTestHTTPClient(t, server, WithHTTPRetry(5, time.Second))
+	if _, err := client.ListKeys(); err == nil || !strings.Contains(err.Error(), "invalid key list") {
+		t.Errorf("Expected an invalid key list, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+}
+
+func TestHTTPClientTimeout(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newTestHTTPClient(t, server, WithHTTPTimeout(20*time.Millisecond), WithHTTPRetry(2, time.Second))
+	start := time.Now()
+	if _, err := client.Get("a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Expected the timed out request retried, got %d requests", n)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected attempts bounded by the timeout, took %v", elapsed)
+	}
+
+	// A canceled context stops retries.
+	requests.Store(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	client = newTestHTTPClient(t, server, WithHTTPRetry(10, 10*time.Second))
+	if _, err := client.GetContext(ctx, "a"); err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Errorf("Expected the context's deadline, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+}
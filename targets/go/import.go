@@ -0,0 +1,366 @@
+package squeakyv
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ConflictPolicy decides what Import does with a key that already has a live
+// value in the cache.
+type ConflictPolicy int
+
+const (
+	// ConflictError stops the import with an error wrapping ErrKeyExists.
+	// It is the default.
+	ConflictError ConflictPolicy = iota
+	// ConflictSkip leaves the existing key alone and skips its records.
+	ConflictSkip
+	// ConflictOverwrite imports the key's records on top of the existing
+	// value, which is kept in history as with Set.
+	ConflictOverwrite
+)
+
+// importBatchSize is how many records Import writes per transaction.
+const importBatchSize = 500
+
+// ImportOptions controls how Import handles existing keys and bad input.
+type ImportOptions struct {
+	// OnConflict applies to keys that are live in the cache when the import
+	// first reaches them.
+	OnConflict ConflictPolicy
+	// SkipMalformed counts malformed lines in ImportStats.Malformed instead of
+	// failing on the first one.
+	SkipMalformed bool
+}
+
+// ImportStats counts the records processed by Import.
+type ImportStats struct {
+	// Inserted counts records written for keys that had no live value.
+	Inserted int64
+	// Overwritten counts records written over an existing key under
+	// ConflictOverwrite.
+	Overwritten int64
+	// Skipped counts records of existing keys skipped under ConflictSkip.
+	Skipped int64
+	// Malformed counts lines ignored under SkipMalformed.
+	Malformed int64
+}
+
+// importLine is a line of the Export format as read by Import. Pointers
+// distinguish missing fields from empty ones.
+type importLine struct {
+	Key       *string        `json:"key"`
+	KeyB      *[]byte        `json:"key_b"`
+	Value     *[]byte        `json:"value"`
+	WrittenAt *time.Time     `json:"written_at"`
+	ExpiresAt *time.Time     `json:"expires_at"`
+	Active    bool           `json:"active"`
+	Summary   *exportSummary `json:"summary"`
+}
+
+// importRecord is a validated record ready to be written.
+type importRecord struct {
+	line int
+	exportRecord
+}
+
+// Import reads the JSON Lines format written by Export and writes its records
+// in batched transactions, preserving write times, expiries and, for exports
+// made with IncludeHistory, inactive versions. Binary keys exported as key_b
+// are restored byte for byte.
+//
+// Each batch is committed as it completes, so on error the returned stats
+// describe the batches already written. Malformed lines, including invalid
+// base64 and lines after the summary, fail the import with an error naming the
+// line number unless opts.SkipMalformed is set. If the summary line is present
+// its record count must match the lines read.
+//
+// Example:
+//
+//	stats, err := client.Import(f, squeakyv.ImportOptions{OnConflict: squeakyv.ConflictSkip})
+//	if err != nil {
+//		return err
+//	}
+//	log.Printf("imported %d, skipped %d", stats.Inserted, stats.Skipped)
+func (c *CacheClient) Import(r io.Reader, opts ImportOptions) (ImportStats, error) {
+	var stats ImportStats
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return stats, err
+	}
+	defer c.release()
+	defer c.mem.purge()
+
+	imp := &importer{
+		client:   c,
+		opts:     opts,
+		outcomes: make(map[string]importOutcome),
+		activeAt: make(map[string]int64),
+	}
+
+	var (
+		batch   []importRecord
+		records int64
+		summary *exportSummary
+		br      = bufio.NewReader(r)
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		batchStats, err := imp.write(db, batch)
+		if err != nil {
+			return err
+		}
+		stats.Inserted += batchStats.Inserted
+		stats.Overwritten += batchStats.Overwritten
+		stats.Skipped += batchStats.Skipped
+		batch = batch[:0]
+		return nil
+	}
+	malformed := func(n int, err error) error {
+		if opts.SkipMalformed {
+			stats.Malformed++
+			return nil
+		}
+		return fmt.Errorf("line %d: %w", n, err)
+	}
+
+	for n := 1; ; n++ {
+		raw, readErr := br.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return stats, fmt.Errorf("failed to read import: %w", readErr)
+		}
+
+		if raw = bytes.TrimSpace(raw); len(raw) > 0 {
+			rec, sum, err := parseImportLine(raw)
+			switch {
+			case err != nil:
+				err = malformed(n, err)
+			case summary != nil:
+				err = malformed(n, errors.New("data after summary line"))
+			case sum != nil:
+				summary = sum
+			default:
+				records++
+				batch = append(batch, importRecord{line: n, exportRecord: *rec})
+				if len(batch) >= importBatchSize {
+					err = flush()
+				}
+			}
+			if err != nil {
+				return stats, err
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if err := flush(); err != nil {
+		return stats, err
+	}
+	if summary != nil && summary.Records != records+stats.Malformed {
+		return stats, fmt.Errorf("import summary lists %d records but %d were read", summary.Records, records+stats.Malformed)
+	}
+	return stats, nil
+}
+
+// parseImportLine decodes and validates one non-empty line, returning either
+// a record or a summary.
+func parseImportLine(raw []byte) (*exportRecord, *exportSummary, error) {
+	var line importLine
+	if err := json.Unmarshal(raw, &line); err != nil {
+		return nil, nil, fmt.Errorf("invalid record: %w", err)
+	}
+	if line.Summary != nil {
+		if line.Key != nil || line.KeyB != nil {
+			return nil, nil, errors.New("invalid record: both key and summary present")
+		}
+		return nil, line.Summary, nil
+	}
+	var key string
+	switch {
+	case line.Key != nil && line.KeyB != nil:
+		return nil, nil, errors.New("invalid record: both key and key_b present")
+	case line.Key != nil:
+		key = *line.Key
+	case line.KeyB != nil:
+		key = string(*line.KeyB)
+	default:
+		return nil, nil, errors.New("invalid record: missing key")
+	}
+	if line.Value == nil {
+		return nil, nil, errors.New("invalid record: missing value")
+	}
+
+	rec := &exportRecord{
+		Key:       key,
+		Value:     *line.Value,
+		ExpiresAt: line.ExpiresAt,
+		Active:    line.Active,
+	}
+	if line.WrittenAt != nil {
+		rec.WrittenAt = *line.WrittenAt
+	}
+	return rec, nil, nil
+}
+
+// importOutcome is what Import does with the records of one key.
+type importOutcome int
+
+const (
+	importInsert importOutcome = iota
+	importOverwrite
+	importSkip
+)
+
+// importer holds the state Import carries across batches.
+type importer struct {
+	client *CacheClient
+	opts   ImportOptions
+	// outcomes is decided for each key when the import first reaches it.
+	outcomes map[string]importOutcome
+	// activeAt maps keys to the rowid of the active version this import
+	// wrote for them.
+	activeAt map[string]int64
+
+	// pendingOutcomes and pendingActiveAt hold the entries added by the batch
+	// being written. They are merged into outcomes and activeAt only once its
+	// transaction commits, since a retried attempt must start from the state
+	// left by the previous batch.
+	pendingOutcomes map[string]importOutcome
+	pendingActiveAt map[string]int64
+}
+
+// write imports one batch of records in a single transaction.
+func (imp *importer) write(db *sql.DB, batch []importRecord) (ImportStats, error) {
+	var stats ImportStats
+	err := imp.client.withTx(db, func(tx *sql.Tx) error {
+		stats = ImportStats{}
+		imp.pendingOutcomes = make(map[string]importOutcome)
+		imp.pendingActiveAt = make(map[string]int64)
+		now := nowMillis()
+
+		for _, rec := range batch {
+			outcome, seen := imp.outcome(rec.Key)
+			if !seen {
+				exists, err := liveKeyExists(tx, imp.client.tables, rec.Key, now)
+				if err != nil {
+					return err
+				}
+				outcome = importInsert
+				if exists {
+					switch imp.opts.OnConflict {
+					case ConflictSkip:
+						outcome = importSkip
+					case ConflictOverwrite:
+						outcome = importOverwrite
+					default:
+						return fmt.Errorf("line %d: %w", rec.line, keyExists(rec.Key))
+					}
+				}
+				imp.pendingOutcomes[rec.Key] = outcome
+			}
+
+			switch outcome {
+			case importSkip:
+				stats.Skipped++
+				continue
+			case importOverwrite:
+				stats.Overwritten++
+			default:
+				stats.Inserted++
+			}
+
+			if err := imp.insert(tx, rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportStats{}, err
+	}
+
+	for key, outcome := range imp.pendingOutcomes {
+		imp.outcomes[key] = outcome
+	}
+	for key, id := range imp.pendingActiveAt {
+		imp.activeAt[key] = id
+	}
+	return stats, nil
+}
+
+// outcome returns the outcome decided for key, if any.
+func (imp *importer) outcome(key string) (importOutcome, bool) {
+	if outcome, ok := imp.pendingOutcomes[key]; ok {
+		return outcome, true
+	}
+	outcome, ok := imp.outcomes[key]
+	return outcome, ok
+}
+
+// activeRow returns the rowid of the active version this import wrote for
+// key, if any.
+func (imp *importer) activeRow(key string) (int64, bool) {
+	if id, ok := imp.pendingActiveAt[key]; ok {
+		return id, true
+	}
+	id, ok := imp.activeAt[key]
+	return id, ok
+}
+
+// insert writes one record. The kv_swap_active trigger retires the key's
+// active version on every insert, so after writing an inactive record the
+// version this import made active, if any, is reactivated.
+func (imp *importer) insert(tx *sql.Tx, rec importRecord) error {
+	query := `INSERT INTO ` + imp.client.tables.kv + ` (inserted_at, is_active, key, value, expires_at, checksum)
+VALUES (?, ?, ?, ?, ?, ?);`
+
+	insertedAt := nowMillis()
+	if !rec.WrittenAt.IsZero() {
+		insertedAt = rec.WrittenAt.UnixMilli()
+	}
+	var expiresAt sql.NullInt64
+	if rec.ExpiresAt != nil {
+		expiresAt = sql.NullInt64{Int64: rec.ExpiresAt.UnixMilli(), Valid: true}
+	}
+
+	value, err := imp.client.encodeStored(rec.Key, rec.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", rec.line, err)
+	}
+
+	result, err := tx.Exec(query, insertedAt, rec.Active, rec.Key, value, expiresAt, checksumOf(value))
+	if err != nil {
+		return fmt.Errorf("line %d: exec failed: %w", rec.line, err)
+	}
+
+	if rec.Active {
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("line %d: last insert id failed: %w", rec.line, err)
+		}
+		imp.pendingActiveAt[rec.Key] = id
+		return nil
+	}
+
+	if id, ok := imp.activeRow(rec.Key); ok {
+		reactivate := `UPDATE ` + imp.client.tables.kv + `
+SET is_active = 1, deactivated_at = NULL
+WHERE rowid = ?;`
+		if _, err := tx.Exec(reactivate, id); err != nil {
+			return fmt.Errorf("line %d: exec failed: %w", rec.line, err)
+		}
+	}
+	return nil
+}
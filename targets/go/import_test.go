@@ -0,0 +1,229 @@
+package squeakyv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportRoundTrip(t *testing.T) {
+	src := newTestClient(t)
+
+	src.Set("a", []byte("a1"))
+	src.Set("a", []byte("a2"))
+	src.Set("bin", []byte{0x00, 0x01, 0xfe, 0xff})
+	src.Set("empty", []byte{})
+	src.Set("gone", []byte("x"))
+	src.Delete("gone")
+	src.SetWithTTL("ttl", []byte("t"), time.Hour)
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf, ExportOptions{IncludeHistory: true}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	dst := newTestClient(t)
+	stats, err := dst.Import(&buf, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if stats.Inserted != 6 || stats.Skipped != 0 || stats.Overwritten != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	for _, key := range []string{"a", "bin", "empty", "ttl"} {
+		expected, _ := src.GetStrict(key)
+		value, err := dst.GetStrict(key)
+		if err != nil {
+			t.Fatalf("GetStrict(%s) failed: %v", key, err)
+		}
+		if !bytes.Equal(value, expected) {
+			t.Errorf("%s: expected %v, got %v", key, expected, value)
+		}
+	}
+	if versions, _ := dst.History("a"); len(versions) != 2 || string(versions[1].Value) != "a1" {
+		t.Errorf("Expected history of a to be imported, got %v", versions)
+	}
+	if deleted, _ := dst.ListDeletedKeys(); !reflect.DeepEqual(deleted, []string{"gone"}) {
+		t.Errorf("Expected gone to be imported as deleted, got %v", deleted)
+	}
+	if _, ok, _ := dst.TTL("ttl"); !ok {
+		t.Error("Expected expiry to be imported")
+	}
+	srcInfo, _ := src.Stat("a")
+	dstInfo, _ := dst.Stat("a")
+	if !srcInfo.UpdatedAt.Equal(dstInfo.UpdatedAt) {
+		t.Errorf("Expected write time %v, got %v", srcInfo.UpdatedAt, dstInfo.UpdatedAt)
+	}
+}
+
+func TestImportActiveBeforeHistory(t *testing.T) {
+	client := newTestClient(t)
+
+	input := `{"key":"k","value":"bGl2ZQ==","written_at":"2024-01-01T00:00:00Z","active":true}
+{"key":"k","value":"b2xk","written_at":"2023-01-01T00:00:00Z","active":false}
+`
+	if _, err := client.Import(strings.NewReader(input), ImportOptions{}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if value, _ := client.Get("k"); string(value) != "live" {
+		t.Errorf("Expected live, got %s", value)
+	}
+	if n := countRows(t, client, "k"); n != 2 {
+		t.Errorf("Expected 2 versions, got %d", n)
+	}
+}
+
+func TestImportConflictPolicies(t *testing.T) {
+	input := `{"key":"existing","value":"bmV3","active":true}
+{"key":"fresh","value":"bmV3","active":true}
+`
+	tests := []struct {
+		policy   ConflictPolicy
+		stats    ImportStats
+		existing string
+	}{
+		{ConflictSkip, ImportStats{Inserted: 1, Skipped: 1}, "old"},
+		{ConflictOverwrite, ImportStats{Inserted: 1, Overwritten: 1}, "new"},
+	}
+
+	for _, tt := range tests {
+		client := newTestClient(t)
+		client.Set("existing", []byte("old"))
+
+		stats, err := client.Import(strings.NewReader(input), ImportOptions{OnConflict: tt.policy})
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if stats != tt.stats {
+			t.Errorf("Policy %d: expected %+v, got %+v", tt.policy, tt.stats, stats)
+		}
+		if value, _ := client.Get("existing"); string(value) != tt.existing {
+			t.Errorf("Policy %d: expected existing=%s, got %s", tt.policy, tt.existing, value)
+		}
+		if value, _ := client.Get("fresh"); string(value) != "new" {
+			t.Errorf("Policy %d: expected fresh=new, got %s", tt.policy, value)
+		}
+	}
+
+	client := newTestClient(t)
+	client.Set("existing", []byte("old"))
+	_, err := client.Import(strings.NewReader(input), ImportOptions{})
+	if !errors.Is(err, ErrKeyExists) {
+		t.Fatalf("Expected ErrKeyExists, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected error to name line 1, got %v", err)
+	}
+	if value, _ := client.Get("fresh"); value != nil {
+		t.Errorf("Expected failed batch to be rolled back, got fresh=%s", value)
+	}
+}
+
+func TestImportMalformed(t *testing.T) {
+	input := `{"key":"a","value":"YQ==","active":true}
+not json
+{"key":"b","value":"!!!not base64!!!","active":true}
+{"value":"YQ==","active":true}
+{"key":"c","value":"Yw==","active":true}
+`
+	client := newTestClient(t)
+	_, err := client.Import(strings.NewReader(input), ImportOptions{})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error naming line 2, got %v", err)
+	}
+
+	client = newTestClient(t)
+	stats, err := client.Import(strings.NewReader(input), ImportOptions{SkipMalformed: true})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if stats.Inserted != 2 || stats.Malformed != 3 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	keys, _ := client.ListKeys()
+	if len(keys) != 2 {
+		t.Errorf("Expected a and c to be imported, got %v", keys)
+	}
+}
+
+func TestImportSummary(t *testing.T) {
+	client := newTestClient(t)
+
+	short := `{"key":"a","value":"YQ==","active":true}
+{"summary":{"records":2,"keys":2}}
+`
+	if _, err := client.Import(strings.NewReader(short), ImportOptions{}); err == nil {
+		t.Error("Expected error for a summary count mismatch")
+	}
+
+	trailing := `{"summary":{"records":0,"keys":0}}
+{"key":"a","value":"YQ==","active":true}
+`
+	if _, err := client.Import(strings.NewReader(trailing), ImportOptions{}); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error for data after the summary, got %v", err)
+	}
+}
+
+func TestImportBatches(t *testing.T) {
+	var buf bytes.Buffer
+	n := importBatchSize*2 + 7
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, `{"key":"key%04d","value":"dg==","active":true}`+"\n", i)
+	}
+
+	client := newTestClient(t)
+	stats, err := client.Import(&buf, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if stats.Inserted != int64(n) {
+		t.Errorf("Expected %d inserted, got %d", n, stats.Inserted)
+	}
+	if count, _ := client.Count(); count != n {
+		t.Errorf("Expected %d keys, got %d", n, count)
+	}
+}
+
+func TestImportBinaryKeys(t *testing.T) {
+	src := newTestClient(t)
+
+	keys := [][]byte{{0xff, 0x01}, {0xfe, 0x01}, {0x00, 0xfe}, []byte("text")}
+	for _, key := range keys {
+		src.SetB(key, key)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf, ExportOptions{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"key":"text"`) || !strings.Contains(buf.String(), `"key_b":"/wE="`) {
+		t.Errorf("Expected text keys as key and binary keys as key_b, got %s", buf.String())
+	}
+
+	dst := newTestClient(t)
+	stats, err := dst.Import(&buf, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if stats.Inserted != int64(len(keys)) {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	for _, key := range keys {
+		if got, err := dst.GetB(key); err != nil || !bytes.Equal(got, key) {
+			t.Errorf("Expected %x to round-trip, got %x, %v", key, got, err)
+		}
+	}
+	if got, _ := dst.ListKeysB(); len(got) != len(keys) {
+		t.Errorf("Expected %d keys, got %x", len(keys), got)
+	}
+
+	_, err = dst.Import(strings.NewReader(`{"key":"a","key_b":"YQ==","value":""}`), ImportOptions{})
+	if err == nil {
+		t.Error("Expected a record with both key and key_b to be rejected")
+	}
+}
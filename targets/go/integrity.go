@@ -0,0 +1,68 @@
+package squeakyv
+
+import (
+	"context"
+	"fmt"
+)
+
+// IntegrityCheck runs SQLite's PRAGMA integrity_check, or quick_check with
+// WithQuickCheck, over the whole database file and returns the problems it
+// reports, at most 100 of them. The result is empty when the database is
+// healthy; an error means the check could not run at all, which on a badly
+// damaged file is itself a sign of corruption.
+//
+// The check reads every page of the file, so it can take minutes on
+// multi-gigabyte databases. It runs in a read transaction on a connection of
+// its own and does not hold the client's write lock, so on file databases in
+// WAL mode reads and writes carry on meanwhile (a WAL checkpoint cannot
+// complete until it finishes). In-memory databases have a single connection,
+// which the check occupies until it is done.
+//
+// Example:
+//
+//	problems, err := client.IntegrityCheck()
+//	if err != nil {
+//		return err
+//	}
+//	for _, p := range problems {
+//		log.Printf("integrity: %s", p)
+//	}
+func (c *CacheClient) IntegrityCheck() ([]string, error) {
+	db, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	pragma := `PRAGMA integrity_check;`
+	if c.opts.quickCheck {
+		pragma = `PRAGMA quick_check;`
+	}
+	rows, err := conn.QueryContext(ctx, pragma)
+	if err != nil {
+		return nil, fmt.Errorf("integrity check failed: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("integrity check failed: %w", err)
+	}
+	return problems, nil
+}
@@ -0,0 +1,89 @@
+package squeakyv
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// breakIndex creates an index on kv whose recorded definition no longer
+// matches its contents, which integrity_check reports and quick_check does
+// not.
+func breakIndex(t *testing.T, path string) {
+	t.Helper()
+	client, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	for _, stmt := range []string{
+		`CREATE INDEX kv_broken ON kv(key);`,
+		`PRAGMA writable_schema = ON;`,
+		`UPDATE sqlite_master SET sql = 'CREATE INDEX kv_broken ON kv(inserted_at)' WHERE name = 'kv_broken';`,
+		`PRAGMA writable_schema = OFF;`,
+	} {
+		if _, err := client.db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to break index: %v", err)
+		}
+	}
+}
+
+func TestIntegrityCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	for i := range 10 {
+		client.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+
+	problems, err := client.IntegrityCheck()
+	if err != nil {
+		t.Fatalf("IntegrityCheck failed: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("Expected a healthy database, got %v", problems)
+	}
+	client.Close()
+
+	breakIndex(t, path)
+
+	full, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer full.Close()
+	problems, err = full.IntegrityCheck()
+	if err != nil {
+		t.Fatalf("IntegrityCheck failed: %v", err)
+	}
+	if len(problems) == 0 || !strings.Contains(strings.Join(problems, "\n"), "kv_broken") {
+		t.Errorf("Expected problems with kv_broken, got %v", problems)
+	}
+
+	quick, err := NewCacheClient(path, WithQuickCheck(), WithReadOnly())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer quick.Close()
+	if problems, err := quick.IntegrityCheck(); err != nil || len(problems) != 0 {
+		t.Errorf("Expected quick_check to skip index contents, got %v, %v", problems, err)
+	}
+}
+
+func TestIntegrityCheckMemory(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("key", []byte("value"))
+
+	problems, err := client.IntegrityCheck()
+	if err != nil || len(problems) != 0 {
+		t.Errorf("Expected a healthy database, got %v, %v", problems, err)
+	}
+	// The connection is released afterwards.
+	if got, _ := client.Get("key"); string(got) != "value" {
+		t.Errorf("Expected value, got %q", got)
+	}
+}
@@ -0,0 +1,144 @@
+package squeakyv
+
+import (
+	"fmt"
+	"iter"
+)
+
+// Item is a key and its current value, as yielded by ItemsErr.
+type Item struct {
+	Key   string
+	Value []byte
+}
+
+// Items returns an iterator over all active, unexpired keys and their values
+// in ascending key order, for use with range:
+//
+//	for key, value := range client.Items() {
+//		fmt.Printf("%s=%s\n", key, value)
+//	}
+//
+// Rows are streamed lazily and the underlying cursor is released as soon as
+// the loop ends, including on break. If an error occurs, iteration simply
+// stops; use ItemsErr to observe errors. The same restrictions as ForEach
+// apply to the loop body.
+func (c *CacheClient) Items() iter.Seq2[string, []byte] {
+	return func(yield func(string, []byte) bool) {
+		for item, err := range c.ItemsErr() {
+			if err != nil || !yield(item.Key, item.Value) {
+				return
+			}
+		}
+	}
+}
+
+// ItemsErr is like Items but reports errors: if the query or a scan fails,
+// the final pair yielded carries the error and a zero Item.
+//
+// Example:
+//
+//	for item, err := range client.ItemsErr() {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Printf("%s=%s\n", item.Key, item.Value)
+//	}
+func (c *CacheClient) ItemsErr() iter.Seq2[Item, error] {
+	return func(yield func(Item, error) bool) {
+		db, err := c.acquireFlushed()
+		if err != nil {
+			yield(Item{}, err)
+			return
+		}
+		defer c.release()
+
+		query := `SELECT key, value
+FROM ` + c.tables.kv + `
+WHERE ` + liveCondition + `
+ORDER BY key;`
+
+		rows, err := db.Query(query, nowMillis())
+		if err != nil {
+			yield(Item{}, fmt.Errorf("query failed: %w", err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var item Item
+			if err := rows.Scan(&item.Key, &item.Value); err != nil {
+				yield(Item{}, fmt.Errorf("scan failed: %w", err))
+				return
+			}
+			if item.Value == nil {
+				item.Value = []byte{}
+			}
+			if item.Value, err = c.decodeStored(item.Key, item.Value); err != nil {
+				yield(Item{}, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(Item{}, fmt.Errorf("rows iteration failed: %w", err))
+		}
+	}
+}
+
+// KeysIter returns an iterator over all active, unexpired keys in ascending
+// key order. Values are never read from disk.
+//
+// As with Items, the cursor is released when the loop ends and iteration
+// stops silently on error; use KeysIterErr to observe errors.
+func (c *CacheClient) KeysIter() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for key, err := range c.KeysIterErr() {
+			if err != nil || !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// KeysIterErr is like KeysIter but reports errors: if the query or a scan
+// fails, the final pair yielded carries the error and an empty key.
+func (c *CacheClient) KeysIterErr() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		db, err := c.acquireFlushed()
+		if err != nil {
+			yield("", err)
+			return
+		}
+		defer c.release()
+
+		query := `SELECT key
+FROM ` + c.tables.kv + `
+WHERE ` + liveCondition + `
+ORDER BY key;`
+
+		rows, err := db.Query(query, nowMillis())
+		if err != nil {
+			yield("", fmt.Errorf("query failed: %w", err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				yield("", fmt.Errorf("scan failed: %w", err))
+				return
+			}
+			if !yield(key, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield("", fmt.Errorf("rows iteration failed: %w", err))
+		}
+	}
+}
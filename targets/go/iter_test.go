@@ -0,0 +1,119 @@
+package squeakyv
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestItems(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("b", []byte("2"))
+	client.Set("a", []byte("1"))
+
+	var keys []string
+	for key, value := range client.Items() {
+		keys = append(keys, key+"="+string(value))
+	}
+	if len(keys) != 2 || keys[0] != "a=1" || keys[1] != "b=2" {
+		t.Errorf("Expected [a=1 b=2], got %v", keys)
+	}
+}
+
+func TestKeysIter(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("b", []byte("2"))
+	client.Set("a", []byte("1"))
+	client.Set("c", []byte("3"))
+	client.Delete("c")
+
+	var keys []string
+	for key := range client.KeysIter() {
+		keys = append(keys, key)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Expected [a b], got %v", keys)
+	}
+}
+
+func TestIteratorsReleaseCursorOnBreak(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("1"))
+	client.Set("b", []byte("2"))
+	client.Set("c", []byte("3"))
+
+	for range client.Items() {
+		break
+	}
+	for range client.KeysIter() {
+		break
+	}
+	for _, err := range client.ItemsErr() {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		break
+	}
+
+	// The single in-memory connection must have been released
+	if err := client.Set("d", []byte("4")); err != nil {
+		t.Errorf("Failed to set after early break: %v", err)
+	}
+}
+
+func TestItemsErrAfterClose(t *testing.T) {
+	client, err := NewCacheClient(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Close()
+
+	calls := 0
+	for _, err := range client.ItemsErr() {
+		calls++
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("Expected ErrClosed, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single error pair, got %d", calls)
+	}
+
+	for range client.Items() {
+		t.Error("Items should yield nothing on a closed client")
+	}
+}
+
+func TestKeysIterErr(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("b", []byte("2"))
+	client.Set("a", []byte("1"))
+
+	var keys []string
+	for key, err := range client.KeysIterErr() {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("Expected [a b], got %v", keys)
+	}
+
+	client.Close()
+	calls := 0
+	for _, err := range client.KeysIterErr() {
+		calls++
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("Expected ErrClosed, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single error pair, got %d", calls)
+	}
+	for range client.KeysIter() {
+		t.Error("KeysIter should yield nothing on a closed client")
+	}
+}
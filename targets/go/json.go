@@ -0,0 +1,32 @@
+package squeakyv
+
+// SetJSON stores v for a key, encoded with encoding/json. Types implementing
+// json.Marshaler are encoded with their MarshalJSON method. A value that
+// cannot be encoded returns an error naming the key, and nothing is written.
+//
+// Example:
+//
+//	err := client.SetJSON("user:1", User{Name: "alice"})
+func (c *CacheClient) SetJSON(key string, v any) error {
+	return c.setEncoded(JSONCodec{}, key, v)
+}
+
+// GetJSON decodes the JSON value for a key into dst, which must be a non-nil
+// pointer, and reports whether the key exists. A missing, deleted or expired
+// key returns false and a nil error, leaving dst untouched. A value that is
+// present but not valid JSON for dst returns true and a *DecodeError naming
+// the key.
+//
+// Example:
+//
+//	var user User
+//	found, err := client.GetJSON("user:1", &user)
+//	if err != nil {
+//		return err
+//	}
+//	if !found {
+//		fmt.Println("Key not found")
+//	}
+func (c *CacheClient) GetJSON(key string, dst any) (bool, error) {
+	return c.getDecoded(JSONCodec{}, key, dst)
+}
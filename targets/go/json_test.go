@@ -0,0 +1,90 @@
+package squeakyv
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// upperName is a json.Marshaler that stores its name upper-cased.
+type upperName struct {
+	Name string
+}
+
+func (u upperName) MarshalJSON() ([]byte, error) {
+	return []byte(`{"Name":"` + strings.ToUpper(u.Name) + `"}`), nil
+}
+
+func TestSetGetJSON(t *testing.T) {
+	client := newTestClient(t)
+
+	want := map[string][]int{"a": {1, 2}, "b": nil}
+	if err := client.SetJSON("key", want); err != nil {
+		t.Fatalf("SetJSON failed: %v", err)
+	}
+
+	var got map[string][]int
+	found, err := client.GetJSON("key", &got)
+	if err != nil || !found {
+		t.Fatalf("GetJSON failed: found=%v err=%v", found, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestSetJSONMarshaler(t *testing.T) {
+	client := newTestClient(t)
+
+	client.SetJSON("key", upperName{Name: "alice"})
+	if value, _ := client.Get("key"); string(value) != `{"Name":"ALICE"}` {
+		t.Errorf("Expected MarshalJSON output, got %s", value)
+	}
+}
+
+func TestGetJSONMissing(t *testing.T) {
+	client := newTestClient(t)
+
+	got := upperName{Name: "unchanged"}
+	found, err := client.GetJSON("missing", &got)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found {
+		t.Error("Expected found=false")
+	}
+	if got.Name != "unchanged" {
+		t.Errorf("Expected dst untouched, got %+v", got)
+	}
+}
+
+func TestGetJSONInvalid(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("key", []byte("{not json"))
+
+	var got map[string]any
+	found, err := client.GetJSON("key", &got)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+	if decodeErr.Key != "key" {
+		t.Errorf("Expected key in error, got %q", decodeErr.Key)
+	}
+	if !found {
+		t.Error("Expected found=true for a present but invalid value")
+	}
+}
+
+func TestSetJSONError(t *testing.T) {
+	client := newTestClient(t)
+
+	err := client.SetJSON("key", make(chan int))
+	if err == nil || !strings.Contains(err.Error(), `"key"`) {
+		t.Errorf("Expected an error naming the key, got %v", err)
+	}
+	if exists, _ := client.Exists("key"); exists {
+		t.Error("Expected nothing written")
+	}
+}
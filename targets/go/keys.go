@@ -0,0 +1,58 @@
+package squeakyv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// defaultMaxKeyLength is the longest key, in bytes, accepted for writes
+// unless WithMaxKeyLength says otherwise.
+const defaultMaxKeyLength = 4096
+
+// validateKey checks a key about to be written against the client's key
+// rules: it must not be empty, must fit WithMaxKeyLength, and must pass the
+// WithKeyValidator function, if any. Keys are only validated on writes, so
+// keys already stored under other rules stay readable and deletable.
+func (c *CacheClient) validateKey(key string) error {
+	if key == "" {
+		return invalidKey(key, errors.New("key is empty"))
+	}
+	if limit := c.opts.maxKeyLength; limit > 0 && len(key) > limit {
+		return invalidKey(key, fmt.Errorf("key has %d bytes, limit is %d", len(key), limit))
+	}
+	if c.opts.keyValidator != nil {
+		if err := c.opts.keyValidator(key); err != nil {
+			return invalidKey(key, err)
+		}
+	}
+	return nil
+}
+
+// foldKey returns key with ASCII letters lowered, the form in which keys are
+// compared by WithCaseInsensitiveKeys.
+func foldKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, key)
+}
+
+// byRequestedKey re-keys results, keyed by keys as stored, by the requested
+// keys they match, for clients with WithCaseInsensitiveKeys. Requested keys
+// with no match are left out.
+func byRequestedKey[V any](requested []string, results map[string]V) map[string]V {
+	folded := make(map[string]V, len(results))
+	for key, v := range results {
+		folded[foldKey(key)] = v
+	}
+	out := make(map[string]V, len(results))
+	for _, key := range requested {
+		if v, ok := folded[foldKey(key)]; ok {
+			out[key] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+package squeakyv
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyValidation(t *testing.T) {
+	client := newTestClient(t)
+	long := strings.Repeat("k", defaultMaxKeyLength+1)
+
+	for name, key := range map[string]string{"empty": "", "long": long} {
+		writes := map[string]func() error{
+			"Set":        func() error { return client.Set(key, []byte("v")) },
+			"SetWithTTL": func() error { return client.SetWithTTL(key, []byte("v"), time.Hour) },
+			"SetMany":    func() error { return client.SetMany(map[string][]byte{key: []byte("v")}) },
+			"SetNX":      func() error { _, err := client.SetNX(key, []byte("v")); return err },
+			"Append":     func() error { _, err := client.Append(key, []byte("v")); return err },
+			"Increment":  func() error { _, err := client.Increment(key, 1); return err },
+			"Copy":       func() error { return client.Copy("src", key) },
+			"Rename":     func() error { return client.Rename("src", key) },
+			"Tx": func() error {
+				return client.WithTransaction(func(tx *Tx) error { return tx.Set(key, []byte("v")) })
+			},
+		}
+		client.Set("src", []byte("v"))
+		for op, write := range writes {
+			if err := write(); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Expected %s of %s key to fail with ErrInvalidKey, got %v", op, name, err)
+			}
+		}
+	}
+
+	_, err := client.SetNX(long, nil)
+	if !strings.Contains(err.Error(), "4097 bytes") || len(err.Error()) > 200 {
+		t.Errorf("Expected a short error naming the length, got %q", err)
+	}
+	if keys, _ := client.ListKeys(); len(keys) != 1 {
+		t.Errorf("Expected only src, got %d keys", len(keys))
+	}
+}
+
+func TestKeyValidatorOption(t *testing.T) {
+	errPrefix := errors.New("keys must start with user:")
+	client, err := NewCacheClient(":memory:",
+		WithMaxKeyLength(16),
+		WithKeyValidator(func(key string) error {
+			if !strings.HasPrefix(key, "user:") {
+				return errPrefix
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Set("user:1", []byte("v")); err != nil {
+		t.Errorf("Expected a valid key to be accepted, got %v", err)
+	}
+	err = client.Set("admin", []byte("v"))
+	if !errors.Is(err, ErrInvalidKey) || !errors.Is(err, errPrefix) {
+		t.Errorf("Expected ErrInvalidKey wrapping the validator's error, got %v", err)
+	}
+	if err := client.Set("user:0123456789abc", []byte("v")); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected the length limit to apply, got %v", err)
+	}
+
+	// Keys written before the rules remain usable.
+	if _, err := client.db.Exec(`INSERT INTO kv (key, value) VALUES ('legacy', 'old');`); err != nil {
+		t.Fatalf("Failed to insert row: %v", err)
+	}
+	if got, err := client.Get("legacy"); err != nil || string(got) != "old" {
+		t.Errorf("Expected old, got %q, %v", got, err)
+	}
+	if err := client.Rename("legacy", "user:legacy"); err != nil {
+		t.Errorf("Expected rename to a valid key to work, got %v", err)
+	}
+
+	unlimited, err := NewCacheClient(":memory:", WithMaxKeyLength(0))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer unlimited.Close()
+	if err := unlimited.Set(strings.Repeat("k", 10000), []byte("v")); err != nil {
+		t.Errorf("Expected no length limit, got %v", err)
+	}
+}
+
+func TestCaseInsensitiveKeys(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithCaseInsensitiveKeys())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("Foo", []byte("v1"))
+	if got, err := client.Get("foo"); err != nil || string(got) != "v1" {
+		t.Errorf("Expected v1 for foo, got %q, %v", got, err)
+	}
+	client.Set("fOO", []byte("v2"))
+	if keys, _ := client.ListKeys(); len(keys) != 1 || keys[0] != "fOO" {
+		t.Errorf("Expected the latest casing, got %v", keys)
+	}
+	if versions, _ := client.History("FOO"); len(versions) != 2 {
+		t.Errorf("Expected history across casings, got %d versions", len(versions))
+	}
+
+	values, err := client.GetMany([]string{"FoO", "missing"})
+	if err != nil || len(values) != 1 || string(values["FoO"]) != "v2" {
+		t.Errorf("Expected GetMany to answer under the requested key, got %v, %v", values, err)
+	}
+	exists, err := client.ExistsMany([]string{"FOO", "missing"})
+	if err != nil || len(exists) != 2 || !exists["FOO"] || exists["missing"] {
+		t.Errorf("Expected ExistsMany to answer under the requested keys, got %v, %v", exists, err)
+	}
+
+	if err := client.Rename("foo", "Foo"); err != nil {
+		t.Fatalf("Rename to a new casing failed: %v", err)
+	}
+	if keys, _ := client.ListKeys(); len(keys) != 1 || keys[0] != "Foo" {
+		t.Errorf("Expected the renamed casing, got %v", keys)
+	}
+
+	if err := client.Delete("FOO"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if got, err := client.Get("Foo"); got != nil || err != nil {
+		t.Errorf("Expected nil after delete, got %q, %v", got, err)
+	}
+}
+
+func TestCaseInsensitiveKeysMismatch(t *testing.T) {
+	dir := t.TempDir()
+	sensitive := filepath.Join(dir, "sensitive.db")
+	insensitive := filepath.Join(dir, "insensitive.db")
+
+	for path, opts := range map[string][]Option{sensitive: nil, insensitive: {WithCaseInsensitiveKeys()}} {
+		client, err := NewCacheClient(path, opts...)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		client.Close()
+	}
+
+	if _, err := NewCacheClient(sensitive, WithCaseInsensitiveKeys()); err == nil {
+		t.Error("Expected an error opening a case-sensitive database with WithCaseInsensitiveKeys")
+	}
+	if _, err := NewCacheClient(insensitive); err == nil {
+		t.Error("Expected an error opening a case-insensitive database without WithCaseInsensitiveKeys")
+	}
+	if _, err := NewCacheClient(insensitive, WithReadOnly()); err == nil {
+		t.Error("Expected an error opening a case-insensitive database read-only without the option")
+	}
+	client, err := NewCacheClient(insensitive, WithCaseInsensitiveKeys(), WithReadOnly())
+	if err != nil {
+		t.Fatalf("Expected a matching read-only open to succeed: %v", err)
+	}
+	client.Close()
+}
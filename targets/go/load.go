@@ -0,0 +1,175 @@
+package squeakyv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// GetOrLoad returns the value of key, calling loader to produce it, and
+// storing the result as Set would, if the key has no live value. Errors from
+// loader are returned as they are and nothing is stored, so the next call
+// tries again.
+//
+// Concurrent GetOrLoad calls for the same missing key share one call to
+// loader: the first caller runs it, with its own ctx, and the others wait for
+// its result or for their ctx to be done. Each caller gets its own copy of the
+// value. Callers in other processes are not coordinated with. A panic in
+// loader is passed on to the caller that ran it, and the others get an error.
+//
+// If the loaded value can't be stored, GetOrLoad returns it together with the
+// error. With WithNegativeTTL, a loader's not-found error is remembered for a
+// while so the loader isn't called again for the key.
+//
+// Example:
+//
+//	user, err := client.GetOrLoad(ctx, "user:42", func(ctx context.Context) ([]byte, error) {
+//		return fetchUser(ctx, 42)
+//	})
+func (c *CacheClient) GetOrLoad(ctx context.Context, key string, loader func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	value, err := c.GetStrict(key)
+	if !errors.Is(err, ErrKeyNotFound) {
+		return value, err
+	}
+	if err := c.checkNegative(key); err != nil {
+		return nil, err
+	}
+
+	call, first := c.loads.start(c.watchKey(key))
+	if !first {
+		select {
+		case <-call.done:
+			return bytes.Clone(call.value), call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			c.loads.finish(c.watchKey(key), call, nil, fmt.Errorf("loader for %q panicked: %v", key, p))
+			panic(p)
+		}
+	}()
+	value, err = c.load(ctx, key, loader)
+	c.loads.finish(c.watchKey(key), call, value, err)
+	return bytes.Clone(value), err
+}
+
+// load is the part of GetOrLoad run by one caller at a time for a key.
+func (c *CacheClient) load(ctx context.Context, key string, loader func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	// A caller that finished loading just before this one started may
+	// already have stored the value.
+	value, err := c.GetStrict(key)
+	if !errors.Is(err, ErrKeyNotFound) {
+		return value, err
+	}
+	if err := c.checkNegative(key); err != nil {
+		return nil, err
+	}
+
+	value, err = loader(ctx)
+	if errors.Is(err, ErrKeyNotFound) && c.opts.negativeTTL > 0 {
+		// Failing to record the miss only costs another call to the loader.
+		c.recordNegative(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Set(key, value); err != nil {
+		return value, fmt.Errorf("failed to store loaded value: %w", err)
+	}
+	return value, nil
+}
+
+// checkNegative returns an error wrapping ErrKeyNotFound if, with
+// WithNegativeTTL, key has an unexpired tombstone.
+func (c *CacheClient) checkNegative(key string) error {
+	if c.opts.negativeTTL <= 0 {
+		return nil
+	}
+
+	db, err := c.acquire()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	var found bool
+	query := `SELECT EXISTS (SELECT 1 FROM ` + c.tables.tombstones + ` WHERE key = ? AND expires_at > ?);`
+	if err := db.QueryRow(query, c.watchKey(key), nowMillis()).Scan(&found); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	if found {
+		return keyNotFound(key)
+	}
+	return nil
+}
+
+// recordNegative stores a tombstone for key expiring after WithNegativeTTL.
+func (c *CacheClient) recordNegative(key string) error {
+	expiresAt, err := expiryMillis(c.opts.negativeTTL)
+	if err != nil {
+		return err
+	}
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	query := `INSERT OR REPLACE INTO ` + c.tables.tombstones + ` (key, expires_at)
+VALUES (?, ?);`
+	if _, err := c.exec(db, query, c.watchKey(key), expiresAt); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
+
+// loadGroup tracks the GetOrLoad calls running loaders, by key.
+type loadGroup struct {
+	mu    sync.Mutex
+	calls map[string]*loadCall
+}
+
+// loadCall is one loader call, whose result is shared by every caller waiting
+// for it.
+type loadCall struct {
+	// done is closed once value and err are set.
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// start returns the call running for key, and whether the caller must run it
+// because there was none.
+func (g *loadGroup) start(key string) (*loadCall, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if call, ok := g.calls[key]; ok {
+		return call, false
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*loadCall)
+	}
+	call := &loadCall{done: make(chan struct{})}
+	g.calls[key] = call
+	return call, true
+}
+
+// finish records the result of call for key and releases its waiters.
+func (g *loadGroup) finish(key string, call *loadCall, value []byte, err error) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	call.value, call.err = value, err
+	close(call.done)
+}
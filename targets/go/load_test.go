@@ -0,0 +1,246 @@
+package squeakyv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	calls := 0
+	loader := func(ctx context.Context) ([]byte, error) {
+		calls++
+		return []byte("loaded"), nil
+	}
+
+	value, err := client.GetOrLoad(ctx, "key", loader)
+	if err != nil || string(value) != "loaded" {
+		t.Fatalf("Expected the loaded value, got %q, %v", value, err)
+	}
+	if stored, _ := client.Get("key"); string(stored) != "loaded" {
+		t.Errorf("Expected the loaded value to be stored, got %q", stored)
+	}
+
+	value, err = client.GetOrLoad(ctx, "key", loader)
+	if err != nil || string(value) != "loaded" {
+		t.Fatalf("Expected the cached value, got %q, %v", value, err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the loader to run once, ran %d times", calls)
+	}
+
+	client.Set("existing", []byte("cached"))
+	value, _ = client.GetOrLoad(ctx, "existing", loader)
+	if string(value) != "cached" || calls != 1 {
+		t.Errorf("Expected a hit without loading, got %q after %d calls", value, calls)
+	}
+}
+
+func TestGetOrLoadErrorNotCached(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	failure := errors.New("upstream down")
+
+	calls := 0
+	loader := func(ctx context.Context) ([]byte, error) {
+		calls++
+		if calls == 1 {
+			return nil, failure
+		}
+		return []byte("recovered"), nil
+	}
+
+	if _, err := client.GetOrLoad(ctx, "key", loader); !errors.Is(err, failure) {
+		t.Fatalf("Expected the loader's error, got %v", err)
+	}
+	if exists, _ := client.Exists("key"); exists {
+		t.Error("Expected nothing stored after a loader error")
+	}
+	value, err := client.GetOrLoad(ctx, "key", loader)
+	if err != nil || string(value) != "recovered" {
+		t.Errorf("Expected the loader to run again, got %q, %v", value, err)
+	}
+}
+
+func TestGetOrLoadSingleflight(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte("shared"), nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	values := make([][]byte, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, err := client.GetOrLoad(ctx, "key", loader)
+			if err != nil {
+				t.Errorf("GetOrLoad failed: %v", err)
+			}
+			values[i] = value
+		}(i)
+	}
+
+	// Let every caller reach the loader or start waiting for it.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected the loader to run once, ran %d times", n)
+	}
+	for i, value := range values {
+		if string(value) != "shared" {
+			t.Errorf("Expected caller %d to get the shared value, got %q", i, value)
+		}
+	}
+	values[0][0] = 'X'
+	if string(values[1]) != "shared" {
+		t.Error("Expected each caller to get its own copy of the value")
+	}
+}
+
+func TestGetOrLoadWaiterCancelled(t *testing.T) {
+	client := newTestClient(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	go client.GetOrLoad(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		<-release
+		return []byte("slow"), nil
+	})
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.GetOrLoad(ctx, "key", func(ctx context.Context) ([]byte, error) {
+		t.Error("Expected the waiting caller not to run the loader")
+		return nil, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the waiter to give up with its context, got %v", err)
+	}
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	client := newTestClient(t)
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("Expected the loader's panic to propagate, got %v", p)
+			}
+		}()
+		client.GetOrLoad(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+			panic("boom")
+		})
+	}()
+
+	// The key is not left marked as loading.
+	value, err := client.GetOrLoad(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		return []byte("ok"), nil
+	})
+	if err != nil || string(value) != "ok" {
+		t.Errorf("Expected a later load to succeed, got %q, %v", value, err)
+	}
+}
+
+func TestGetOrLoadNegativeTTL(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithNegativeTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	calls := 0
+	missing := func(ctx context.Context) ([]byte, error) {
+		calls++
+		return nil, fmt.Errorf("no such user: %w", ErrKeyNotFound)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetOrLoad(ctx, "user:1", missing); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the loader to run once while the miss is cached, ran %d times", calls)
+	}
+
+	// The tombstone is never read as a value.
+	if value, err := client.Get("user:1"); value != nil || err != nil {
+		t.Errorf("Expected Get to miss, got %q, %v", value, err)
+	}
+	if n, _ := client.Count(); n != 0 {
+		t.Errorf("Expected no keys, got %d", n)
+	}
+
+	// Storing a value replaces the tombstone.
+	client.Set("user:1", []byte{})
+	client.Delete("user:1")
+	value, err := client.GetOrLoad(ctx, "user:1", func(ctx context.Context) ([]byte, error) {
+		return []byte("alice"), nil
+	})
+	if err != nil || string(value) != "alice" {
+		t.Errorf("Expected the loader to run after a write, got %q, %v", value, err)
+	}
+}
+
+func TestGetOrLoadNegativeTTLExpires(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithNegativeTTL(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	calls := 0
+	missing := func(ctx context.Context) ([]byte, error) {
+		calls++
+		return nil, ErrKeyNotFound
+	}
+	client.GetOrLoad(ctx, "key", missing)
+	time.Sleep(30 * time.Millisecond)
+	client.GetOrLoad(ctx, "key", missing)
+	if calls != 2 {
+		t.Errorf("Expected the loader to run again once the miss expired, ran %d times", calls)
+	}
+
+	client.SweepNow()
+	var n int
+	client.db.QueryRow(`SELECT COUNT(*) FROM kv_tombstones;`).Scan(&n)
+	if n != 1 {
+		t.Errorf("Expected only the unexpired tombstone after a sweep, got %d", n)
+	}
+}
+
+func TestGetOrLoadNotFoundWithoutNegativeTTL(t *testing.T) {
+	client := newTestClient(t)
+
+	calls := 0
+	missing := func(ctx context.Context) ([]byte, error) {
+		calls++
+		return nil, ErrKeyNotFound
+	}
+	client.GetOrLoad(context.Background(), "key", missing)
+	client.GetOrLoad(context.Background(), "key", missing)
+	if calls != 2 {
+		t.Errorf("Expected misses not to be cached by default, loader ran %d times", calls)
+	}
+}
@@ -0,0 +1,113 @@
+package squeakyv
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// slowOpLogThreshold is the duration above which WithLogger logs an
+// operation at warn rather than debug level.
+const slowOpLogThreshold = 500 * time.Millisecond
+
+// opStart returns the start time of an operation to be logged by logOp or
+// reported by reportSlow, or the zero time, without reading the clock, if the
+// client has neither a logger nor a slow operation callback.
+func (c *CacheClient) opStart() time.Time {
+	if c.opts.logger == nil && c.opts.slowOp == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// logOp logs the outcome of a single-key operation started at start, moving
+// size bytes of values. It logs at debug level, or at warn level if the
+// operation took longer than slowOpLogThreshold.
+func (c *CacheClient) logOp(ctx context.Context, op, key string, start time.Time, size int, err error) {
+	if c.opts.logger == nil {
+		return
+	}
+	c.logOpAttrs(ctx, op, start, size, err, slog.String("key", key))
+}
+
+// logBatchOp is logOp for operations on n keys at once.
+func (c *CacheClient) logBatchOp(ctx context.Context, op string, n int, start time.Time, size int, err error) {
+	if c.opts.logger == nil {
+		return
+	}
+	c.logOpAttrs(ctx, op, start, size, err, slog.Int("keys", n))
+}
+
+func (c *CacheClient) logOpAttrs(ctx context.Context, op string, start time.Time, size int, err error, target slog.Attr) {
+	elapsed := time.Since(start)
+	level, msg := slog.LevelDebug, "squeakyv operation"
+	if elapsed > slowOpLogThreshold {
+		level, msg = slog.LevelWarn, "squeakyv slow operation"
+	}
+	if !c.opts.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("op", op),
+		target,
+		slog.Duration("duration", elapsed),
+		slog.Int("bytes", size),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	c.opts.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logRetry logs, at warn level, that a write failed with a busy error on the
+// given attempt and will be retried after delay.
+func (c *CacheClient) logRetry(attempt int, delay time.Duration, err error) {
+	if c.opts.logger == nil || !c.opts.logger.Enabled(context.Background(), slog.LevelWarn) {
+		return
+	}
+	c.opts.logger.LogAttrs(context.Background(), slog.LevelWarn, "squeakyv retrying busy write",
+		slog.String("path", c.path),
+		slog.Int("attempt", attempt),
+		slog.Duration("delay", delay),
+		slog.Any("error", err),
+	)
+}
+
+// logBusy logs, at warn level, that a write gave up with err after retrying.
+func (c *CacheClient) logBusy(err *BusyError) {
+	if c.opts.logger == nil || !c.opts.logger.Enabled(context.Background(), slog.LevelWarn) {
+		return
+	}
+	c.opts.logger.LogAttrs(context.Background(), slog.LevelWarn, "squeakyv busy timeout",
+		slog.String("path", c.path),
+		slog.Int("attempts", err.Attempts),
+		slog.Duration("elapsed", err.Elapsed),
+		slog.Any("error", err.Err),
+	)
+}
+
+// logMirrorRetry logs, at warn level, that replaying a write onto the mirror
+// failed with err and will be retried after delay.
+func (c *CacheClient) logMirrorRetry(err error, delay time.Duration) {
+	if c.opts.logger == nil || !c.opts.logger.Enabled(context.Background(), slog.LevelWarn) {
+		return
+	}
+	c.opts.logger.LogAttrs(context.Background(), slog.LevelWarn, "squeakyv retrying mirror write",
+		slog.String("path", c.path),
+		slog.Duration("delay", delay),
+		slog.Any("error", err),
+	)
+}
+
+// logWriteBehindFailure logs, at warn level, that writing a batch buffered by
+// WithWriteBehind failed with err.
+func (c *CacheClient) logWriteBehindFailure(err error) {
+	if c.opts.logger == nil || !c.opts.logger.Enabled(context.Background(), slog.LevelWarn) {
+		return
+	}
+	c.opts.logger.LogAttrs(context.Background(), slog.LevelWarn, "squeakyv write-behind batch failed",
+		slog.String("path", c.path),
+		slog.Any("error", err),
+	)
+}
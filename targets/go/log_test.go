@@ -0,0 +1,110 @@
+package squeakyv
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logEvents decodes the JSON log lines written to buf.
+func logEvents(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client, err := NewCacheClient(":memory:", WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("a", []byte("value"))
+	client.Get("a")
+	client.GetMany([]string{"a", "b"})
+	client.Delete("a")
+	client.Set("", []byte("x"))
+
+	events := logEvents(t, &buf)
+	if len(events) != 5 {
+		t.Fatalf("Expected 5 events, got %v", events)
+	}
+	for i, want := range []struct {
+		op    string
+		bytes float64
+	}{{"set", 5}, {"get", 5}, {"get_many", 5}, {"delete", 0}, {"set", 1}} {
+		e := events[i]
+		if e["level"] != "DEBUG" || e["op"] != want.op || e["bytes"] != want.bytes {
+			t.Errorf("Event %d: expected a debug %s event of %v bytes, got %v", i, want.op, want.bytes, e)
+		}
+		if _, ok := e["duration"]; !ok {
+			t.Errorf("Event %d: expected a duration, got %v", i, e)
+		}
+	}
+	if events[1]["key"] != "a" || events[2]["keys"] != 2.0 {
+		t.Errorf("Expected the key and key count, got %v and %v", events[1], events[2])
+	}
+	if events[4]["error"] == nil || events[0]["error"] != nil {
+		t.Errorf("Expected only failed operations to carry an error, got %v", events)
+	}
+}
+
+func TestWithLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	client, err := NewCacheClient(":memory:", WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("a", []byte("value"))
+	client.Get("a")
+	if buf.Len() != 0 {
+		t.Errorf("Expected no events above debug level, got %s", buf.String())
+	}
+}
+
+func TestWithLoggerRetries(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path, WithBusyTimeout(time.Millisecond), WithRetry(3, time.Minute), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	lockDatabase(t, path)
+	client.Set("a", []byte("value"))
+
+	var retries, timeouts int
+	for _, e := range logEvents(t, &buf) {
+		switch e["msg"] {
+		case "squeakyv retrying busy write":
+			retries++
+		case "squeakyv busy timeout":
+			timeouts++
+		}
+	}
+	if retries != 2 || timeouts != 1 {
+		t.Errorf("Expected 2 retries and a timeout logged, got %d and %d: %s", retries, timeouts, buf.String())
+	}
+}
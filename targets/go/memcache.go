@@ -0,0 +1,274 @@
+package squeakyv
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+)
+
+// memCacheRefresh is how often a memory cache reads the change feed for
+// writes made by other clients and processes.
+const memCacheRefresh = 100 * time.Millisecond
+
+// memCacheMaxAge is how long a memory cache serves a value before reading it
+// from the database again, bounding how stale a value can get if a change is
+// missed, such as a change of TTL alone, which the feed doesn't record.
+const memCacheMaxAge = time.Second
+
+// memCache is the in-process LRU enabled by WithMemoryCache. Its methods do
+// nothing on a nil *memCache, so call sites need not check whether it is
+// enabled.
+//
+// Entries are keyed by watchKey. A value read from the database is added only
+// if no key was invalidated while it was being read: otherwise a write
+// committed in between could be overwritten by the value it replaced.
+type memCache struct {
+	maxEntries int
+	maxBytes   int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds *memEntry values, most recently used first.
+	order *list.List
+	bytes int64
+	// gen counts invalidations.
+	gen uint64
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// memEntry is a cached value.
+type memEntry struct {
+	key   string
+	value []byte
+	// expiresAt is the value's expiry in Unix milliseconds, or 0 for none.
+	expiresAt int64
+	// staleAt is when, in Unix milliseconds, the entry is dropped whatever
+	// its expiry, memCacheMaxAge after it was added.
+	staleAt int64
+}
+
+// size is what the entry counts towards WithMemoryCache's byte limit.
+func (e *memEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// newMemCache returns an empty memory cache with the given limits, where zero
+// or less means no limit.
+func newMemCache(maxEntries int, maxBytes int64) *memCache {
+	return &memCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// get returns a copy of the cached value of key if it is unexpired and not
+// stale at now.
+func (m *memCache) get(key string, now int64) ([]byte, bool) {
+	if m == nil {
+		return nil, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*memEntry)
+	if (entry.expiresAt != 0 && entry.expiresAt <= now) || entry.staleAt <= now {
+		m.remove(elem)
+		return nil, false
+	}
+	m.order.MoveToFront(elem)
+	return bytes.Clone(entry.value), true
+}
+
+// generation returns the number of invalidations so far, to pass to add.
+func (m *memCache) generation() uint64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gen
+}
+
+// add caches a copy of value for key, read from the database after
+// generation returned gen, evicting the least recently used entries to stay
+// within the limits. It does nothing if anything was invalidated since, or if
+// the entry alone exceeds the byte limit.
+func (m *memCache) add(key string, value []byte, expiresAt int64, gen uint64) {
+	if m == nil {
+		return
+	}
+	entry := &memEntry{
+		key:       key,
+		value:     bytes.Clone(value),
+		expiresAt: expiresAt,
+		staleAt:   nowMillis() + memCacheMaxAge.Milliseconds(),
+	}
+	if m.maxBytes > 0 && entry.size() > m.maxBytes {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if gen != m.gen {
+		return
+	}
+	if elem, ok := m.entries[key]; ok {
+		m.remove(elem)
+	}
+	m.entries[key] = m.order.PushFront(entry)
+	m.bytes += entry.size()
+
+	for (m.maxEntries > 0 && m.order.Len() > m.maxEntries) || (m.maxBytes > 0 && m.bytes > m.maxBytes) {
+		m.remove(m.order.Back())
+	}
+}
+
+// invalidate drops the cached values of keys, which have been written. It
+// must be called after the write commits.
+func (m *memCache) invalidate(keys ...string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gen++
+	for _, key := range keys {
+		if elem, ok := m.entries[key]; ok {
+			m.remove(elem)
+		}
+	}
+}
+
+// purge drops every cached value, after writes to keys that aren't known
+// individually.
+func (m *memCache) purge() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gen++
+	clear(m.entries)
+	m.order.Init()
+	m.bytes = 0
+}
+
+// remove drops elem from the cache. m.mu must be held.
+func (m *memCache) remove(elem *list.Element) {
+	entry := m.order.Remove(elem).(*memEntry)
+	delete(m.entries, entry.key)
+	m.bytes -= entry.size()
+}
+
+// startRefresh launches a goroutine that invalidates the keys of every change
+// recorded in c's change feed, every memCacheRefresh, until shutdown is
+// called. This catches writes that c doesn't make itself.
+func (m *memCache) startRefresh(c *CacheClient) {
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	// Without a starting point, reading the whole feed once only costs time.
+	seq, _ := c.lastChangeSeq()
+
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(memCacheRefresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				// Failures are retried on the next tick.
+				seq = m.refresh(c, seq)
+			}
+		}
+	}()
+}
+
+// refresh invalidates the keys changed after seq and returns the sequence
+// number to continue from.
+func (m *memCache) refresh(c *CacheClient, seq int64) int64 {
+	for {
+		changes, next, err := c.ChangesSince(seq, pollBatch)
+		if errors.Is(err, ErrChangesPruned) {
+			// The changes missed can't be known.
+			m.purge()
+			seq = next
+			continue
+		}
+		if err != nil {
+			return seq
+		}
+
+		if len(changes) > 0 {
+			keys := make([]string, len(changes))
+			for i, ev := range changes {
+				keys[i] = c.watchKey(ev.Key)
+			}
+			m.invalidate(keys...)
+		}
+		seq = next
+		if len(changes) < pollBatch {
+			return seq
+		}
+	}
+}
+
+// shutdown stops the refresh goroutine, if any, waits for it to exit and
+// drops every cached value. It is safe to call more than once.
+func (m *memCache) shutdown() {
+	if m == nil {
+		return
+	}
+	if m.stop != nil {
+		m.stopOnce.Do(func() { close(m.stop) })
+		<-m.done
+	}
+	m.purge()
+}
+
+// readCached is readValue for Get and GetStrict under WithMemoryCache,
+// serving the value from memory when it can and caching it otherwise.
+func (c *CacheClient) readCached(db querier, key string) ([]byte, error) {
+	cacheKey := c.watchKey(key)
+	if value, ok := c.mem.get(cacheKey, nowMillis()); ok {
+		return value, nil
+	}
+
+	gen := c.mem.generation()
+	value, expiresAt, err := c.readVersion(db, key)
+	if err != nil {
+		return nil, err
+	}
+	c.mem.add(cacheKey, value, expiresAt.Int64, gen)
+	return value, nil
+}
+
+// invalidate drops the memory cache entries of keys after they are written.
+func (c *CacheClient) invalidate(keys ...string) {
+	if c.mem == nil {
+		return
+	}
+	cacheKeys := make([]string, len(keys))
+	for i, key := range keys {
+		cacheKeys[i] = c.watchKey(key)
+	}
+	c.mem.invalidate(cacheKeys...)
+}
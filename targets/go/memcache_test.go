@@ -0,0 +1,396 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// overwriteBehind changes the stored value of key without the client, or the
+// change feed, noticing: the trigger recording the rewrite is dropped for the
+// duration.
+func overwriteBehind(t *testing.T, client *CacheClient, key string, value []byte) {
+	t.Helper()
+	err := runTx(client.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DROP TRIGGER kv_changes_rewrite;`); err != nil {
+			return err
+		}
+		query := `UPDATE kv SET value = ?, checksum = NULL WHERE key = ? AND is_active = 1;`
+		if _, err := tx.Exec(query, value, key); err != nil {
+			return err
+		}
+		for _, m := range goTriggers(client.tables) {
+			if m.name == "kv_changes_rewrite" {
+				_, err := tx.Exec(m.create)
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to overwrite %q: %v", key, err)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	client := newTestClient(t, WithMemoryCache(100, 0))
+
+	client.Set("key", []byte("v1"))
+	if value, _ := client.Get("key"); string(value) != "v1" {
+		t.Fatalf("Expected v1, got %q", value)
+	}
+
+	overwriteBehind(t, client, "key", []byte("behind"))
+	if value, _ := client.Get("key"); string(value) != "v1" {
+		t.Errorf("Expected the value to be served from memory, got %q", value)
+	}
+
+	client.Set("key", []byte("v2"))
+	if value, _ := client.Get("key"); string(value) != "v2" {
+		t.Errorf("Expected the write to replace the cached value, got %q", value)
+	}
+
+	value, _ := client.GetStrict("key")
+	value[0] = 'X'
+	if value, _ := client.Get("key"); string(value) != "v2" {
+		t.Errorf("Expected callers to get their own copy, got %q", value)
+	}
+
+	client.Set("empty", []byte{})
+	client.Get("empty")
+	if value, err := client.GetStrict("empty"); value == nil || err != nil {
+		t.Errorf("Expected a cached empty value to be non-nil, got %v, %v", value, err)
+	}
+}
+
+func TestMemoryCacheWrites(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(c *CacheClient) error
+		want  []byte
+	}{
+		{"Set", func(c *CacheClient) error { return c.Set("k", []byte("new")) }, []byte("new")},
+		{"SetWithTTL", func(c *CacheClient) error { return c.SetWithTTL("k", []byte("new"), time.Hour) }, []byte("new")},
+		{"Delete", func(c *CacheClient) error { return c.Delete("k") }, nil},
+		{"SetMany", func(c *CacheClient) error { return c.SetMany(map[string][]byte{"k": []byte("new")}) }, []byte("new")},
+		{"DeleteMany", func(c *CacheClient) error { _, err := c.DeleteMany([]string{"k"}); return err }, nil},
+		{"GetSet", func(c *CacheClient) error { _, err := c.GetSet("k", []byte("new")); return err }, []byte("new")},
+		{"GetDel", func(c *CacheClient) error { _, err := c.GetDel("k"); return err }, nil},
+		{"CompareAndSwap", func(c *CacheClient) error { _, err := c.CompareAndSwap("k", []byte("1"), []byte("new")); return err }, []byte("new")},
+		{"Append", func(c *CacheClient) error { _, err := c.Append("k", []byte("0")); return err }, []byte("10")},
+		{"Increment", func(c *CacheClient) error { _, err := c.Increment("k", 1); return err }, []byte("2")},
+		{"Expire", func(c *CacheClient) error {
+			err := c.Expire("k", time.Millisecond)
+			time.Sleep(5 * time.Millisecond)
+			return err
+		}, nil},
+		{"Rename", func(c *CacheClient) error { return c.Rename("k", "moved") }, nil},
+		{"Copy", func(c *CacheClient) error { return c.Copy("other", "k") }, []byte("o")},
+		{"RestoreVersion", func(c *CacheClient) error {
+			versions, err := c.History("k")
+			if err != nil {
+				return err
+			}
+			return c.RestoreVersion("k", versions[len(versions)-1].ID)
+		}, []byte("0")},
+		{"HardDelete", func(c *CacheClient) error { return c.HardDelete("k") }, nil},
+		{"DeletePrefix", func(c *CacheClient) error { _, err := c.DeletePrefix("k"); return err }, nil},
+		{"Clear", func(c *CacheClient) error { return c.Clear() }, nil},
+		{"WithTransaction", func(c *CacheClient) error {
+			return c.WithTransaction(func(tx *Tx) error { return tx.Set("k", []byte("new")) })
+		}, []byte("new")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, WithMemoryCache(100, 0))
+			client.Set("k", []byte("0"))
+			client.Set("k", []byte("1"))
+			client.Set("other", []byte("o"))
+			client.Get("k")
+
+			if err := tt.write(client); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			value, err := client.Get("k")
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			if string(value) != string(tt.want) || (value == nil) != (tt.want == nil) {
+				t.Errorf("Expected %q after the write, got %q", tt.want, value)
+			}
+		})
+	}
+}
+
+func TestMemoryCacheUndelete(t *testing.T) {
+	client := newTestClient(t, WithMemoryCache(100, 0))
+	client.Set("key", []byte("v"))
+	client.Delete("key")
+	client.Get("key")
+
+	if err := client.Undelete("key"); err != nil {
+		t.Fatalf("Undelete failed: %v", err)
+	}
+	if value, _ := client.Get("key"); string(value) != "v" {
+		t.Errorf("Expected the undeleted value, got %q", value)
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	client := newTestClient(t, WithMemoryCache(100, 0))
+
+	client.SetWithTTL("key", []byte("v"), 20*time.Millisecond)
+	if value, _ := client.Get("key"); string(value) != "v" {
+		t.Fatalf("Expected v, got %q", value)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if value, _ := client.Get("key"); value != nil {
+		t.Errorf("Expected the cached value to expire, got %q", value)
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithMemoryCache(2, 0))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		client.Set(key, []byte(key))
+	}
+	client.Get("a")
+	client.Get("b")
+	client.Get("a")
+	client.Get("c")
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := client.mem.get(key, nowMillis()); ok != want {
+			t.Errorf("Expected %q cached: %v, got %v", key, want, ok)
+		}
+	}
+
+	bySize, err := NewCacheClient(":memory:", WithMemoryCache(0, 10))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer bySize.Close()
+
+	// Each entry counts its key and value: 5 bytes for a, b and c.
+	for _, key := range []string{"a", "b", "c"} {
+		bySize.Set(key, []byte("1234"))
+	}
+	bySize.Set("big", []byte("0123456789"))
+	bySize.Get("a")
+	bySize.Get("b")
+	bySize.Get("c")
+	bySize.Get("big")
+	if n, size := bySize.mem.order.Len(), bySize.mem.bytes; n != 2 || size != 10 {
+		t.Errorf("Expected 2 entries of 10 bytes cached, got %d entries, %d bytes", n, size)
+	}
+	if _, ok := bySize.mem.get("a", nowMillis()); ok {
+		t.Error("Expected a to be evicted to make room for c")
+	}
+	if value, _ := bySize.Get("big"); string(value) != "0123456789" {
+		t.Errorf("Expected values over the limit to be read from the database, got %q", value)
+	}
+}
+
+func TestMemoryCacheOtherWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	client := newTestClientAt(t, path, WithMemoryCache(100, 0))
+	other := newTestClientAt(t, path, WithMemoryCache(100, 0))
+
+	client.Set("key", []byte("v1"))
+	client.Get("key")
+	other.Set("key", []byte("v2"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if value, _ := client.Get("key"); string(value) == "v2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for another client's write to be seen")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMemoryCacheOtherWritersInPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	client := newTestClientAt(t, path, WithMemoryCache(100, 0))
+	other := newTestClientAt(t, path)
+
+	client.Set("appended", []byte("v1"))
+	client.Set("renamed", []byte("rv"))
+	client.Get("appended")
+	client.Get("renamed")
+
+	other.Append("appended", []byte("+"))
+	other.Rename("renamed", "moved")
+
+	waitFor := func(key, want string) {
+		t.Helper()
+		deadline := time.Now().Add(memCacheMaxAge / 2)
+		for {
+			value, _ := client.Get(key)
+			if string(value) == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %s=%q from the change feed, still got %q", key, want, value)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("appended", "v1+")
+	waitFor("renamed", "")
+}
+
+func TestMemoryCacheMaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	client := newTestClientAt(t, path, WithMemoryCache(100, 0))
+
+	client.Set("key", []byte("v1"))
+	// Let the refresh pass the Set in the feed before caching.
+	time.Sleep(2 * memCacheRefresh)
+	client.Get("key")
+	overwriteBehind(t, client, "key", []byte("v2"))
+
+	time.Sleep(2 * memCacheRefresh)
+	if value, _ := client.Get("key"); string(value) != "v1" {
+		t.Fatalf("Expected the cached v1 before the entry is stale, got %q", value)
+	}
+	time.Sleep(memCacheMaxAge)
+	if value, _ := client.Get("key"); string(value) != "v2" {
+		t.Errorf("Expected v2 once the entry is stale, got %q", value)
+	}
+}
+
+func TestMemoryCacheMirror(t *testing.T) {
+	secondary := newTestClient(t, WithMemoryCache(100, 0))
+	primary, err := NewCacheClient(":memory:", WithMirror(secondary, MirrorSync))
+	if err != nil {
+		t.Fatalf("Failed to create primary: %v", err)
+	}
+	defer primary.Close()
+
+	primary.Set("key", []byte("v1"))
+	secondary.Get("key")
+	primary.Set("key", []byte("v2"))
+	if value, _ := secondary.Get("key"); string(value) != "v2" {
+		t.Errorf("Expected mirrored writes to update the mirror's cache, got %q", value)
+	}
+}
+
+func TestMemoryCacheCaseInsensitive(t *testing.T) {
+	client := newTestClient(t, WithMemoryCache(100, 0), WithCaseInsensitiveKeys())
+
+	client.Set("Key", []byte("v1"))
+	client.Get("KEY")
+	client.Set("key", []byte("v2"))
+	if value, _ := client.Get("KEY"); string(value) != "v2" {
+		t.Errorf("Expected a write in any case to invalidate, got %q", value)
+	}
+}
+
+func TestMemoryCacheConcurrent(t *testing.T) {
+	client := newTestClientAt(t, filepath.Join(t.TempDir(), "cache.db"), WithMemoryCache(100, 0), WithPragma("synchronous", "OFF"))
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	// Readers keep filling the cache while the writers write.
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					client.Get(fmt.Sprintf("key%d", rand.Intn(4)))
+				}
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			key := fmt.Sprintf("key%d", w)
+			for i := 0; i < 100; i++ {
+				want := strconv.Itoa(i)
+				if err := client.Set(key, []byte(want)); err != nil {
+					t.Errorf("Set failed: %v", err)
+					return
+				}
+				if value, _ := client.Get(key); string(value) != want {
+					t.Errorf("Expected %q to read its own write %q, got %q", key, want, value)
+					return
+				}
+			}
+		}(w)
+	}
+	writers.Wait()
+	close(stop)
+	wg.Wait()
+}
+
+func TestMemoryCacheInvalidLimits(t *testing.T) {
+	if _, err := NewCacheClient(":memory:", WithMemoryCache(0, 0)); err == nil {
+		t.Error("Expected an error for a memory cache without limits")
+	}
+}
+
+// BenchmarkGetZipf reads keys with a zipfian distribution, as hot keys are
+// typically read, from the database alone and through a memory cache holding
+// a tenth of them.
+func BenchmarkGetZipf(b *testing.B) {
+	const keys = 10000
+	for _, bench := range []struct {
+		name string
+		opts []Option
+	}{
+		{"sqlite", nil},
+		{"memory", []Option{WithMemoryCache(keys/10, 0)}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			client, err := NewCacheClient(filepath.Join(b.TempDir(), "bench.db"), bench.opts...)
+			if err != nil {
+				b.Fatalf("Failed to create client: %v", err)
+			}
+			defer client.Close()
+
+			items := make(map[string][]byte, keys)
+			for i := 0; i < keys; i++ {
+				items["key"+strconv.Itoa(i)] = make([]byte, 100)
+			}
+			if err := client.SetMany(items); err != nil {
+				b.Fatalf("SetMany failed: %v", err)
+			}
+
+			zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, keys-1)
+			names := make([]string, b.N)
+			for i := range names {
+				names[i] = "key" + strconv.FormatUint(zipf.Uint64(), 10)
+			}
+
+			b.ResetTimer()
+			for _, key := range names {
+				if _, err := client.Get(key); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// NewSharedMemoryClient opens a client for the in-memory database called name.
+// Unlike ":memory:", every client opened with the same name in this process
+// sees the same data, which makes it easy to wire several components to one
+// cache in tests. The database is freed when the last client for the name is
+// closed.
+//
+// It is shorthand for NewCacheClient with the URI
+// "file:<name>?mode=memory&cache=shared", which can also be passed directly.
+// Clients of the same database and table share a single connection,
+// configured by the options of whichever client opened it first.
+//
+// Example:
+//
+//	producer, err := squeakyv.NewSharedMemoryClient("jobs")
+//	consumer, err := squeakyv.NewSharedMemoryClient("jobs")
+func NewSharedMemoryClient(name string, opts ...Option) (*CacheClient, error) {
+	if name == "" {
+		return nil, errors.New("shared memory database name is empty")
+	}
+	return NewCacheClient(sharedMemoryPath(name), opts...)
+}
+
+// sharedMemoryPath returns the URI filename of the shared in-memory database
+// called name.
+func sharedMemoryPath(name string) string {
+	return "file:" + url.PathEscape(name) + "?mode=memory&cache=shared"
+}
+
+// isMemoryPath reports whether path names an in-memory database: ":memory:"
+// or a URI filename with mode=memory or the name ":memory:".
+func isMemoryPath(path string) bool {
+	if path == ":memory:" {
+		return true
+	}
+	name, query, ok := parseURIPath(path)
+	return ok && (name == ":memory:" || query.Get("mode") == "memory")
+}
+
+// sharedMemoryName returns the name identifying path if it is an in-memory
+// database in SQLite's shared cache, which every connection in the process
+// opening the same name shares.
+func sharedMemoryName(path string) (string, bool) {
+	name, query, ok := parseURIPath(path)
+	if !ok || !isMemoryPath(path) || query.Get("cache") != "shared" {
+		return "", false
+	}
+	return name, true
+}
+
+// parseURIPath splits a "file:" URI filename into its unescaped name and its
+// query parameters. ok is false if path is a plain file name.
+func parseURIPath(path string) (name string, query url.Values, ok bool) {
+	rest, ok := strings.CutPrefix(path, "file:")
+	if !ok {
+		return "", nil, false
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rawName, rawQuery, _ := strings.Cut(rest, "?")
+
+	name, err := url.PathUnescape(rawName)
+	if err != nil {
+		return "", nil, false
+	}
+	query, err = url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", nil, false
+	}
+	return name, query, true
+}
+
+// sharedMemory holds the open handles of shared in-memory databases.
+//
+// Clients of the same database share one handle rather than each opening a
+// connection: connections to a shared cache lock whole tables against each
+// other and fail with SQLITE_LOCKED instead of waiting.
+var sharedMemory = struct {
+	sync.Mutex
+	dbs map[sharedKey]*sharedDB
+}{dbs: make(map[sharedKey]*sharedDB)}
+
+// sharedKey identifies a shared handle. Handles are per table as well as per
+// database, since the table a connection works on is fixed when it opens.
+type sharedKey struct {
+	name  string
+	table string
+}
+
+// sharedDB is a handle shared by the clients of one in-memory database.
+type sharedDB struct {
+	db   *sql.DB
+	refs int
+	// envelopes is the mark of values stored in envelopes, shared by the
+	// clients (see openEnvelopes).
+	envelopes atomic.Bool
+}
+
+// acquireShared returns the shared in-memory database handle for key,
+// calling open to create it if no client holds it. Each call must be matched
+// by a call to releaseShared.
+func acquireShared(key sharedKey, open func() (*sql.DB, error)) (*sql.DB, error) {
+	sharedMemory.Lock()
+	defer sharedMemory.Unlock()
+
+	if s, ok := sharedMemory.dbs[key]; ok {
+		s.refs++
+		return s.db, nil
+	}
+
+	db, err := open()
+	if err != nil {
+		return nil, err
+	}
+	sharedMemory.dbs[key] = &sharedDB{db: db, refs: 1}
+	return db, nil
+}
+
+// sharedEnvelopes returns the envelope mark of the shared handle for key,
+// which must be held.
+func sharedEnvelopes(key sharedKey) *atomic.Bool {
+	sharedMemory.Lock()
+	defer sharedMemory.Unlock()
+	return &sharedMemory.dbs[key].envelopes
+}
+
+// releaseShared gives up one reference to the handle for key, closing it once
+// none are left. The database itself is freed when its last handle closes.
+func releaseShared(key sharedKey) error {
+	sharedMemory.Lock()
+	defer sharedMemory.Unlock()
+
+	s, ok := sharedMemory.dbs[key]
+	if !ok {
+		return fmt.Errorf("shared memory database %q is not open", key.name)
+	}
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+	delete(sharedMemory.dbs, key)
+	return s.db.Close()
+}
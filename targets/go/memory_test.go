@@ -0,0 +1,132 @@
+package squeakyv
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSharedMemoryClient(t *testing.T) {
+	a := newTestClientAt(t, sharedMemoryPath(t.Name()))
+	b := newTestClientAt(t, sharedMemoryPath(t.Name()))
+	other := newTestClientAt(t, sharedMemoryPath(t.Name()+"-other"))
+	private := newTestClient(t)
+
+	if err := a.Set("key", []byte("value")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, _ := b.Get("key"); string(value) != "value" {
+		t.Errorf("Expected the second client to see the write, got %q", value)
+	}
+	if value, _ := other.Get("key"); value != nil {
+		t.Errorf("Expected a different name to be isolated, got %q", value)
+	}
+	if value, _ := private.Get("key"); value != nil {
+		t.Errorf("Expected :memory: to be isolated, got %q", value)
+	}
+
+	// The URI form names the same database
+	uri, err := NewCacheClient("file:" + t.Name() + "?cache=shared&mode=memory")
+	if err != nil {
+		t.Fatalf("Failed to open URI: %v", err)
+	}
+	defer uri.Close()
+	if value, _ := uri.Get("key"); string(value) != "value" {
+		t.Errorf("Expected the URI client to see the write, got %q", value)
+	}
+}
+
+func TestSharedMemoryReleasedOnLastClose(t *testing.T) {
+	a, err := NewSharedMemoryClient(t.Name())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	b, err := NewSharedMemoryClient(t.Name())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	a.Set("key", []byte("value"))
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if value, _ := b.Get("key"); string(value) != "value" {
+		t.Errorf("Expected data to survive while a client is open, got %q", value)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	sharedMemory.Lock()
+	_, open := sharedMemory.dbs[sharedKey{t.Name(), defaultTable}]
+	sharedMemory.Unlock()
+	if open {
+		t.Error("Expected the shared handle to be released")
+	}
+
+	c := newTestClientAt(t, sharedMemoryPath(t.Name()))
+	if value, _ := c.Get("key"); value != nil {
+		t.Errorf("Expected a fresh database after the last close, got %q", value)
+	}
+}
+
+func TestSharedMemoryConcurrentWrites(t *testing.T) {
+	a := newTestClientAt(t, sharedMemoryPath(t.Name()))
+	b := newTestClientAt(t, sharedMemoryPath(t.Name()))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for i := 0; i < 100; i++ {
+		for _, client := range []*CacheClient{a, b} {
+			wg.Add(1)
+			go func(client *CacheClient, i int) {
+				defer wg.Done()
+				if _, err := client.Increment("counter", 1); err != nil {
+					errs <- err
+				}
+				if _, err := client.Get(fmt.Sprintf("key%d", i)); err != nil {
+					errs <- err
+				}
+			}(client, i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Concurrent operation failed: %v", err)
+	}
+
+	if value, _ := a.Get("counter"); string(value) != "200" {
+		t.Errorf("Expected 200, got %s", value)
+	}
+}
+
+func TestIsMemoryPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		memory bool
+		shared bool
+	}{
+		{":memory:", true, false},
+		{"cache.db", false, false},
+		{"file:cache.db", false, false},
+		{"file:cache.db?cache=shared", false, false},
+		{"file::memory:", true, false},
+		{"file::memory:?cache=shared", true, true},
+		{"file:name?mode=memory", true, false},
+		{"file:name?mode=memory&cache=shared", true, true},
+		{sharedMemoryPath("a b/c"), true, true},
+	}
+	for _, tt := range tests {
+		if got := isMemoryPath(tt.path); got != tt.memory {
+			t.Errorf("isMemoryPath(%q) = %v, want %v", tt.path, got, tt.memory)
+		}
+		if _, got := sharedMemoryName(tt.path); got != tt.shared {
+			t.Errorf("sharedMemoryName(%q) ok = %v, want %v", tt.path, got, tt.shared)
+		}
+	}
+
+	if name, _ := sharedMemoryName(sharedMemoryPath("a b/c")); name != "a b/c" {
+		t.Errorf("Expected name to round-trip, got %q", name)
+	}
+}
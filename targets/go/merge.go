@@ -0,0 +1,211 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// mergeSchema is the name the source database is attached under by MergeFrom.
+const mergeSchema = "squeakyv_merge"
+
+// MergePolicy decides which value MergeFrom keeps for a key that is live in
+// both databases.
+type MergePolicy int
+
+const (
+	// MergeError fails the merge, changing nothing, with an error wrapping
+	// ErrKeyExists. It is the default.
+	MergeError MergePolicy = iota
+	// MergePreferNewer keeps whichever value was written later, keeping the
+	// destination's on a tie.
+	MergePreferNewer
+	// MergePreferSource always takes the source's value.
+	MergePreferSource
+	// MergePreferDestination always keeps the destination's value.
+	MergePreferDestination
+)
+
+// MergeOptions controls how MergeFrom resolves conflicts.
+type MergeOptions struct {
+	// OnConflict applies to keys live in both the source and the client.
+	OnConflict MergePolicy
+	// IncludeHistory also copies the source's inactive versions of every key
+	// whose value is taken from the source.
+	IncludeHistory bool
+}
+
+// MergeStats counts the keys considered by MergeFrom.
+type MergeStats struct {
+	// Added counts keys live in the source but not in the client.
+	Added int64
+	// Replaced counts conflicting keys whose value was taken from the source.
+	Replaced int64
+	// Kept counts conflicting keys whose destination value was kept.
+	Kept int64
+}
+
+// MergeFrom copies the live keys of another squeakyv database file into the
+// client, resolving keys live in both according to opts.OnConflict. Values
+// taken from the source become new versions, keeping their original write
+// times and expiries; replaced values stay in the client's history.
+//
+// The source is attached to the client's database and copied with
+// INSERT ... SELECT in a single transaction, so values never pass through Go
+// and a failed merge changes nothing. Writes from this client wait until the
+// merge is done. Under WithMaxEntries or WithMaxBytes the same transaction
+// evicts keys over the limits, as a Set would.
+//
+// Example:
+//
+//	stats, err := client.MergeFrom("host-b.db", squeakyv.MergeOptions{
+//		OnConflict: squeakyv.MergePreferNewer,
+//	})
+func (c *CacheClient) MergeFrom(srcPath string, opts MergeOptions) (MergeStats, error) {
+	var (
+		stats     MergeStats
+		merged    []string
+		envelopes bool
+		evicted   []eviction
+	)
+	defer func() { c.notifyEvicted(evicted) }()
+	err := c.withAttached(srcPath, mergeSchema, func(ctx context.Context, conn *sql.Conn) error {
+		columns, err := attachedColumns(ctx, conn, c.tables, mergeSchema)
+		if err != nil {
+			return fmt.Errorf("invalid merge source: %w", err)
+		}
+		srcExpires := "NULL"
+		if columns["expires_at"] {
+			srcExpires = "s.expires_at"
+		}
+		srcChecksum := "NULL"
+		if columns["checksum"] {
+			srcChecksum = "s.checksum"
+		}
+
+		evicted, err = c.writeEvictingConn(ctx, conn, func(tx *sql.Tx) error {
+			var err error
+			stats, merged, err = mergeFrom(tx, c.tables, opts, srcExpires, srcChecksum)
+			if err != nil {
+				return err
+			}
+			envelopes, err = mergeEnvelopes(tx, c.tables)
+			return err
+		})
+		return err
+	})
+	if err != nil {
+		return MergeStats{}, err
+	}
+	c.invalidate(merged...)
+	if envelopes {
+		c.envelopes.Store(true)
+	}
+	return stats, nil
+}
+
+// mergeEnvelopes marks the client's database as storing values in envelopes
+// if the source is marked, since its values were copied as stored. It
+// reports whether the client's database is marked.
+func mergeEnvelopes(tx *sql.Tx, t tableNames) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM ` + mergeSchema + `.sqlite_master WHERE type = 'table' AND name = ?);`
+	if err := tx.QueryRow(query, t.meta).Scan(&exists); err != nil {
+		return false, fmt.Errorf("query failed: %w", err)
+	}
+	if exists {
+		insert := `INSERT OR IGNORE INTO main.` + t.meta + ` (name, value)
+SELECT name, value FROM ` + mergeSchema + `.` + t.meta + ` WHERE name = ?;`
+		if _, err := tx.Exec(insert, valueEnvelopesName); err != nil {
+			return false, fmt.Errorf("exec failed: %w", err)
+		}
+	}
+
+	var marked bool
+	query = `SELECT EXISTS (SELECT 1 FROM main.` + t.meta + ` WHERE name = ?);`
+	if err := tx.QueryRow(query, valueEnvelopesName).Scan(&marked); err != nil {
+		return false, fmt.Errorf("query failed: %w", err)
+	}
+	return marked, nil
+}
+
+// mergeFrom performs MergeFrom inside tx and returns the keys whose value was
+// taken from the source. srcExpires and srcChecksum are the expressions for a
+// source row's expiry and checksum: the column, or NULL for sources without
+// it.
+func mergeFrom(tx *sql.Tx, t tableNames, opts MergeOptions, srcExpires, srcChecksum string) (MergeStats, []string, error) {
+	// Conditions on a source row s. Unqualified columns in the subqueries
+	// refer to main.kv.
+	srcLive := `(s.is_active = 1 AND (` + srcExpires + ` IS NULL OR ` + srcExpires + ` > :now))`
+	dstRow := `SELECT 1 FROM main.` + t.kv + ` WHERE key = s.key AND is_active = 1 AND (expires_at IS NULL OR expires_at > :now)`
+	dstLive := `EXISTS (` + dstRow + `)`
+
+	var dstWins string
+	switch opts.OnConflict {
+	case MergeError, MergePreferDestination:
+		dstWins = dstLive
+	case MergePreferNewer:
+		dstWins = `EXISTS (` + dstRow + ` AND inserted_at >= s.inserted_at)`
+	case MergePreferSource:
+		dstWins = `0`
+	default:
+		return MergeStats{}, nil, fmt.Errorf("invalid merge policy %d", opts.OnConflict)
+	}
+	now := sql.Named("now", nowMillis())
+
+	count := `SELECT COUNT(*), COALESCE(SUM(` + dstLive + `), 0), COALESCE(SUM(` + dstWins + `), 0)
+FROM ` + mergeSchema + `.` + t.kv + ` s
+WHERE ` + srcLive + `;`
+
+	var total, conflicts, kept int64
+	if err := tx.QueryRow(count, now).Scan(&total, &conflicts, &kept); err != nil {
+		return MergeStats{}, nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	if opts.OnConflict == MergeError && conflicts > 0 {
+		first := `SELECT s.key
+FROM ` + mergeSchema + `.` + t.kv + ` s
+WHERE ` + srcLive + ` AND ` + dstLive + `
+ORDER BY s.key
+LIMIT 1;`
+
+		var key string
+		if err := tx.QueryRow(first, now).Scan(&key); err != nil {
+			return MergeStats{}, nil, fmt.Errorf("query failed: %w", err)
+		}
+		return MergeStats{}, nil, fmt.Errorf("%d conflicting keys: %w", conflicts, keyExists(key))
+	}
+
+	winners := `SELECT s.key
+  FROM ` + mergeSchema + `.` + t.kv + ` s
+  WHERE ` + srcLive + ` AND NOT ` + dstWins
+	keys, err := queryStrings(tx, winners+`;`, now)
+	if err != nil {
+		return MergeStats{}, nil, err
+	}
+
+	rows := srcLive
+	if opts.IncludeHistory {
+		rows = `(` + srcLive + ` OR s.is_active = 0)`
+	}
+	// History goes in before the live version, which kv_swap_active would
+	// otherwise retire.
+	insert := `WITH winners AS (
+  ` + winners + `
+)
+INSERT INTO main.` + t.kv + ` (inserted_at, is_active, key, value, expires_at, checksum)
+SELECT s.inserted_at, ` + srcLive + ` AS live, s.key, s.value, ` + srcExpires + `, ` + srcChecksum + `
+FROM ` + mergeSchema + `.` + t.kv + ` s
+WHERE ` + rows + ` AND s.key IN (SELECT key FROM winners)
+ORDER BY live, s.rowid;`
+
+	if _, err := tx.Exec(insert, now); err != nil {
+		return MergeStats{}, nil, fmt.Errorf("exec failed: %w", err)
+	}
+
+	return MergeStats{
+		Added:    total - conflicts,
+		Replaced: conflicts - kept,
+		Kept:     kept,
+	}, keys, nil
+}
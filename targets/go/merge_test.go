@@ -0,0 +1,177 @@
+package squeakyv
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newMergeSource writes a source database for MergeFrom and returns its path.
+func newMergeSource(t *testing.T, fill func(src *CacheClient)) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "source.db")
+	src, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+	fill(src)
+	if err := src.Close(); err != nil {
+		t.Fatalf("Failed to close source: %v", err)
+	}
+	return path
+}
+
+func TestMergeFromPolicies(t *testing.T) {
+	// dst writes "older" before the source writes, "newer" after.
+	tests := []struct {
+		policy MergePolicy
+		older  string
+		newer  string
+		stats  MergeStats
+	}{
+		{MergePreferNewer, "src", "dst", MergeStats{Added: 1, Replaced: 1, Kept: 1}},
+		{MergePreferSource, "src", "src", MergeStats{Added: 1, Replaced: 2}},
+		{MergePreferDestination, "dst", "dst", MergeStats{Added: 1, Kept: 2}},
+	}
+
+	for _, tt := range tests {
+		client := newTestClient(t)
+		client.Set("older", []byte("dst"))
+		time.Sleep(5 * time.Millisecond)
+
+		path := newMergeSource(t, func(src *CacheClient) {
+			src.Set("older", []byte("src"))
+			src.Set("newer", []byte("src"))
+			src.Set("only-src", []byte("src"))
+			src.Set("deleted", []byte("src"))
+			src.Delete("deleted")
+		})
+
+		time.Sleep(5 * time.Millisecond)
+		client.Set("newer", []byte("dst"))
+		client.Set("only-dst", []byte("dst"))
+
+		stats, err := client.MergeFrom(path, MergeOptions{OnConflict: tt.policy})
+		if err != nil {
+			t.Fatalf("Policy %d: MergeFrom failed: %v", tt.policy, err)
+		}
+		if stats != tt.stats {
+			t.Errorf("Policy %d: expected %+v, got %+v", tt.policy, tt.stats, stats)
+		}
+
+		expected := map[string]string{
+			"older":    tt.older,
+			"newer":    tt.newer,
+			"only-src": "src",
+			"only-dst": "dst",
+		}
+		for key, want := range expected {
+			if value, _ := client.Get(key); string(value) != want {
+				t.Errorf("Policy %d: expected %s=%s, got %s", tt.policy, key, want, value)
+			}
+		}
+		if exists, _ := client.Exists("deleted"); exists {
+			t.Errorf("Policy %d: expected deleted source key to be ignored", tt.policy)
+		}
+	}
+}
+
+func TestMergeFromError(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("shared", []byte("dst"))
+
+	path := newMergeSource(t, func(src *CacheClient) {
+		src.Set("shared", []byte("src"))
+		src.Set("new", []byte("src"))
+	})
+
+	_, err := client.MergeFrom(path, MergeOptions{})
+	if !errors.Is(err, ErrKeyExists) {
+		t.Fatalf("Expected ErrKeyExists, got %v", err)
+	}
+	if exists, _ := client.Exists("new"); exists {
+		t.Error("Expected a failed merge to change nothing")
+	}
+}
+
+func TestMergeFromHistory(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("key", []byte("dst"))
+
+	path := newMergeSource(t, func(src *CacheClient) {
+		src.Set("key", []byte("v1"))
+		src.Set("key", []byte("v2"))
+		src.SetWithTTL("ttl", []byte("t"), time.Hour)
+	})
+
+	if _, err := client.MergeFrom(path, MergeOptions{OnConflict: MergePreferSource, IncludeHistory: true}); err != nil {
+		t.Fatalf("MergeFrom failed: %v", err)
+	}
+
+	versions, _ := client.History("key")
+	if len(versions) != 3 {
+		t.Fatalf("Expected 3 versions, got %d", len(versions))
+	}
+	for i, want := range []string{"v2", "v1", "dst"} {
+		if string(versions[i].Value) != want {
+			t.Errorf("Version %d: expected %s, got %s", i, want, versions[i].Value)
+		}
+	}
+	if !versions[0].Active {
+		t.Error("Expected merged version to be active")
+	}
+	if _, ok, _ := client.TTL("ttl"); !ok {
+		t.Error("Expected expiry to be merged")
+	}
+}
+
+func TestMergeFromInvalidSource(t *testing.T) {
+	client := newTestClient(t)
+
+	if _, err := client.MergeFrom(filepath.Join(t.TempDir(), "missing.db"), MergeOptions{}); err == nil {
+		t.Error("Expected error for a missing source")
+	}
+}
+
+func TestMergeFromEvicts(t *testing.T) {
+	path := newMergeSource(t, func(src *CacheClient) {
+		src.Set("b", []byte("v"))
+		src.Set("c", []byte("v"))
+		src.Set("d", []byte("v"))
+	})
+	rec := newEvictionRecorder()
+	client := newTestClientAt(t, filepath.Join(t.TempDir(), "dst.db"), WithMaxEntries(2), WithEvictionCallback(rec.callback))
+	client.Set("a", []byte("v"))
+
+	if _, err := client.MergeFrom(path, MergeOptions{}); err != nil {
+		t.Fatalf("MergeFrom failed: %v", err)
+	}
+	if n, _ := client.Count(); n != 2 {
+		t.Errorf("Expected the merge to evict down to 2 keys, got %d", n)
+	}
+	for i := 0; i < 2; i++ {
+		if ev := rec.next(t); ev.reason != Evicted {
+			t.Errorf("Expected an eviction, got %+v", ev)
+		}
+	}
+	rec.none(t)
+}
+
+func TestMergeFromMemoryCache(t *testing.T) {
+	path := newMergeSource(t, func(src *CacheClient) {
+		src.Set("k", []byte("source"))
+	})
+	client := newTestClientAt(t, filepath.Join(t.TempDir(), "dst.db"), WithMemoryCache(100, 0))
+	client.Set("k", []byte("dest"))
+	if value, _ := client.Get("k"); string(value) != "dest" {
+		t.Fatalf("Expected dest, got %q", value)
+	}
+
+	if _, err := client.MergeFrom(path, MergeOptions{OnConflict: MergePreferSource}); err != nil {
+		t.Fatalf("MergeFrom failed: %v", err)
+	}
+	if value, _ := client.Get("k"); string(value) != "source" {
+		t.Errorf("Expected the merged value, not the cached one, got %q", value)
+	}
+}
@@ -0,0 +1,295 @@
+package squeakyv
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// mirrorBuffer is how many writes an asynchronous mirror holds before it
+// starts dropping new ones.
+const mirrorBuffer = 1024
+
+// Delays between attempts to apply a write to an asynchronous mirror that
+// keeps failing.
+const (
+	mirrorRetryBaseDelay = 50 * time.Millisecond
+	mirrorRetryMaxDelay  = 5 * time.Second
+)
+
+// MirrorMode selects how WithMirror replays writes.
+type MirrorMode int
+
+const (
+	// MirrorSync replays each write before the operation returns, and fails
+	// the operation if the mirror fails.
+	MirrorSync MirrorMode = iota + 1
+	// MirrorAsync queues writes and replays them in the background, retrying
+	// while the mirror fails.
+	MirrorAsync
+)
+
+// String returns "sync" or "async".
+func (m MirrorMode) String() string {
+	switch m {
+	case MirrorSync:
+		return "sync"
+	case MirrorAsync:
+		return "async"
+	}
+	return "unknown"
+}
+
+// MirrorDrop describes a write an asynchronous mirror gave up on, passed to
+// the WithMirrorDropped callback.
+type MirrorDrop struct {
+	// Key is the key written.
+	Key string
+	// Op is the kind of write.
+	Op WatchOp
+	// Err is the mirror's latest error, or nil if it had not failed yet, as
+	// when the client closes with writes still queued.
+	Err error
+}
+
+// mirrorWrite is a committed write to replay onto the mirror.
+type mirrorWrite struct {
+	key   string
+	op    WatchOp
+	value []byte
+	// expires is when a value written with a TTL expires, or zero.
+	expires time.Time
+}
+
+// mirror replays writes, committed together, onto the client's mirror, if it
+// has one. In MirrorSync mode it returns an error wrapping ErrMirror if the
+// mirror fails; in MirrorAsync mode it only queues them.
+func (c *CacheClient) mirror(writes ...mirrorWrite) error {
+	switch {
+	case c.opts.mirror == nil:
+		return nil
+	case c.mirrorQueue != nil:
+		c.mirrorQueue.enqueue(writes)
+		return nil
+	}
+	if err := applyMirrorWrites(c.opts.mirror, writes); err != nil {
+		return fmt.Errorf("%w: %w", ErrMirror, err)
+	}
+	return nil
+}
+
+// mirrorSets returns the mirror writes for values set without a TTL.
+func mirrorSets(items map[string][]byte) []mirrorWrite {
+	writes := make([]mirrorWrite, 0, len(items))
+	for key, value := range items {
+		writes = append(writes, mirrorWrite{key: key, op: WatchSet, value: value})
+	}
+	return writes
+}
+
+// mirrorDeletes returns the mirror writes for deleted keys.
+func mirrorDeletes(keys []string) []mirrorWrite {
+	writes := make([]mirrorWrite, 0, len(keys))
+	for _, key := range keys {
+		writes = append(writes, mirrorWrite{key: key, op: WatchDelete})
+	}
+	return writes
+}
+
+// applyMirrorWrites writes writes to dst, batching plain sets and deletes
+// made together as SetMany and DeleteMany do. A value whose TTL has run out
+// by the time it is replayed is deleted instead.
+func applyMirrorWrites(dst *CacheClient, writes []mirrorWrite) error {
+	if len(writes) > 1 {
+		sets := make(map[string][]byte, len(writes))
+		var deletes []string
+		for _, w := range writes {
+			switch {
+			case w.op == WatchSet && w.expires.IsZero():
+				sets[w.key] = w.value
+			case w.op == WatchDelete:
+				deletes = append(deletes, w.key)
+			}
+		}
+		switch len(writes) {
+		case len(sets):
+			return dst.SetMany(sets)
+		case len(deletes):
+			_, err := dst.DeleteMany(deletes)
+			return err
+		}
+	}
+
+	for _, w := range writes {
+		var err error
+		switch {
+		case w.op == WatchDelete:
+			err = dst.Delete(w.key)
+		case w.expires.IsZero():
+			err = dst.Set(w.key, w.value)
+		default:
+			if ttl := time.Until(w.expires); ttl > 0 {
+				err = dst.SetWithTTL(w.key, w.value, ttl)
+			} else {
+				err = dst.Delete(w.key)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mirrorQueue replays writes onto an asynchronous mirror, in the order they
+// were queued, from a background goroutine.
+type mirrorQueue struct {
+	dst     *CacheClient
+	dropped func(MirrorDrop)
+	logf    func(err error, delay time.Duration)
+
+	mu sync.Mutex
+	// batches holds the queued writes, each batch committed together;
+	// queued counts the writes in them.
+	batches [][]mirrorWrite
+	queued  int
+	// lastErr is the error of the latest failed attempt, cleared on success.
+	lastErr error
+
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// startMirrorQueue launches the goroutine replaying writes onto dst until the
+// returned queue is shut down.
+func startMirrorQueue(dst *CacheClient, dropped func(MirrorDrop), logf func(error, time.Duration)) *mirrorQueue {
+	q := &mirrorQueue{
+		dst:     dst,
+		dropped: dropped,
+		logf:    logf,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// enqueue queues writes, or drops them all if they don't fit. A batch larger
+// than the whole buffer is still queued when nothing else is. Values are
+// copied, so the caller keeps ownership of them.
+func (q *mirrorQueue) enqueue(writes []mirrorWrite) {
+	q.mu.Lock()
+	if q.queued > 0 && q.queued+len(writes) > mirrorBuffer {
+		err := q.lastErr
+		q.mu.Unlock()
+		q.drop(writes, err)
+		return
+	}
+	batch := make([]mirrorWrite, len(writes))
+	for i, w := range writes {
+		w.value = bytes.Clone(w.value)
+		batch[i] = w
+	}
+	q.batches = append(q.batches, batch)
+	q.queued += len(batch)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// front returns the oldest queued batch without removing it.
+func (q *mirrorQueue) front() ([]mirrorWrite, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.batches) == 0 {
+		return nil, false
+	}
+	return q.batches[0], true
+}
+
+// finish records the outcome of applying the oldest queued batch, removing
+// it if err is nil.
+func (q *mirrorQueue) finish(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.lastErr = err
+	if err == nil {
+		q.queued -= len(q.batches[0])
+		q.batches[0] = nil
+		q.batches = q.batches[1:]
+	}
+}
+
+// run applies queued batches in order, retrying a failing one with backoff
+// until it succeeds, until the queue is shut down.
+func (q *mirrorQueue) run() {
+	defer close(q.done)
+
+	delay := mirrorRetryBaseDelay
+	for {
+		batch, ok := q.front()
+		if !ok {
+			select {
+			case <-q.wake:
+				continue
+			case <-q.stop:
+				return
+			}
+		}
+
+		err := applyMirrorWrites(q.dst, batch)
+		q.finish(err)
+		if err == nil {
+			delay = mirrorRetryBaseDelay
+			continue
+		}
+
+		if q.logf != nil {
+			q.logf(err, delay)
+		}
+		select {
+		case <-time.After(delay):
+			delay = min(delay*2, mirrorRetryMaxDelay)
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// shutdown stops the goroutine, then makes one last attempt at each queued
+// batch, dropping those that fail. It is safe to call more than once.
+func (q *mirrorQueue) shutdown() {
+	q.stopOnce.Do(func() { close(q.stop) })
+	<-q.done
+
+	for {
+		batch, ok := q.front()
+		if !ok {
+			return
+		}
+		err := applyMirrorWrites(q.dst, batch)
+		if err != nil {
+			q.drop(batch, err)
+		}
+		q.finish(nil)
+	}
+}
+
+// drop reports writes given up on to the callback, if there is one.
+func (q *mirrorQueue) drop(writes []mirrorWrite, err error) {
+	if q.dropped == nil {
+		return
+	}
+	for _, w := range writes {
+		q.dropped(MirrorDrop{Key: w.key, Op: w.op, Err: err})
+	}
+}
@@ -0,0 +1,189 @@
+package squeakyv
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newMirrorPair returns a primary client mirroring onto a file-backed
+// secondary, which fails fast while its database is locked, and the
+// secondary's path.
+func newMirrorPair(t *testing.T, mode MirrorMode, opts ...Option) (primary, secondary *CacheClient, path string) {
+	t.Helper()
+	path = filepath.Join(t.TempDir(), "mirror.db")
+	secondary, err := NewCacheClient(path, WithBusyTimeout(time.Millisecond), WithRetry(1, time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create secondary: %v", err)
+	}
+	t.Cleanup(func() { secondary.Close() })
+
+	primary, err = NewCacheClient(":memory:", append([]Option{WithMirror(secondary, mode)}, opts...)...)
+	if err != nil {
+		t.Fatalf("Failed to create primary: %v", err)
+	}
+	t.Cleanup(func() { primary.Close() })
+	return primary, secondary, path
+}
+
+// expectMirrored fails the test unless client has value for key, or no value
+// if value is nil.
+func expectMirrored(t *testing.T, client *CacheClient, key string, value []byte) {
+	t.Helper()
+	got, err := client.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q) failed: %v", key, err)
+	}
+	if string(got) != string(value) || (got == nil) != (value == nil) {
+		t.Errorf("Expected %q on the mirror for %q, got %q", value, key, got)
+	}
+}
+
+func TestMirrorSync(t *testing.T) {
+	primary, secondary, _ := newMirrorPair(t, MirrorSync)
+
+	primary.Set("a", []byte("1"))
+	primary.SetMany(map[string][]byte{"b": []byte("2"), "c": []byte("3")})
+	primary.SetWithTTL("d", []byte("4"), time.Hour)
+	primary.Delete("a")
+	primary.DeleteMany([]string{"b"})
+	primary.GetSet("e", []byte("5"))
+	primary.SetNX("f", []byte("6"))
+	primary.CompareAndSwap("e", []byte("5"), []byte("7"))
+	primary.GetDel("c")
+
+	expectMirrored(t, secondary, "a", nil)
+	expectMirrored(t, secondary, "b", nil)
+	expectMirrored(t, secondary, "c", nil)
+	expectMirrored(t, secondary, "d", []byte("4"))
+	expectMirrored(t, secondary, "e", []byte("7"))
+	expectMirrored(t, secondary, "f", []byte("6"))
+	if _, ok, err := secondary.TTL("d"); err != nil || !ok {
+		t.Errorf("Expected the mirrored value to keep its TTL, got %v, %v", ok, err)
+	}
+
+	// Reads only use the primary.
+	secondary.Set("only-on-mirror", []byte("x"))
+	if value, _ := primary.Get("only-on-mirror"); value != nil {
+		t.Errorf("Expected reads to ignore the mirror, got %q", value)
+	}
+}
+
+func TestMirrorSyncFailure(t *testing.T) {
+	primary, secondary, _ := newMirrorPair(t, MirrorSync)
+	secondary.Close()
+
+	err := primary.Set("key", []byte("value"))
+	if !errors.Is(err, ErrMirror) || !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrMirror wrapping ErrClosed, got %v", err)
+	}
+	if value, _ := primary.Get("key"); string(value) != "value" {
+		t.Errorf("Expected the primary write to have committed, got %q", value)
+	}
+}
+
+func TestMirrorAsyncDowntime(t *testing.T) {
+	primary, secondary, path := newMirrorPair(t, MirrorAsync)
+
+	release := lockDatabase(t, path)
+	for i := 0; i < 10; i++ {
+		if err := primary.Set("key", []byte(fmt.Sprint(i))); err != nil {
+			t.Fatalf("Set failed while the mirror was down: %v", err)
+		}
+	}
+	primary.Delete("gone")
+	primary.Set("other", []byte("v"))
+
+	time.Sleep(50 * time.Millisecond)
+	release()
+
+	// Wait for the queue to drain after the mirror comes back.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		value, err := secondary.Get("other")
+		if err == nil && value != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the mirror to catch up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Every version arrived, in the order it was written.
+	versions, err := secondary.History("key")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(versions) != 10 {
+		t.Fatalf("Expected 10 versions on the mirror, got %d", len(versions))
+	}
+	for i, v := range versions {
+		if want := fmt.Sprint(9 - i); string(v.Value) != want {
+			t.Errorf("Expected version %d to be %q, got %q", i, want, v.Value)
+		}
+	}
+}
+
+func TestMirrorAsyncDrops(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		dropped []MirrorDrop
+	)
+	primary, _, path := newMirrorPair(t, MirrorAsync, WithMirrorDropped(func(d MirrorDrop) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped = append(dropped, d)
+	}))
+
+	lockDatabase(t, path)
+	for i := 0; i < mirrorBuffer+10; i++ {
+		primary.Set(fmt.Sprintf("key%d", i), []byte("v"))
+	}
+
+	mu.Lock()
+	if len(dropped) != 10 {
+		t.Errorf("Expected 10 writes dropped while the queue was full, got %d", len(dropped))
+	}
+	if len(dropped) > 0 && (dropped[0].Key != fmt.Sprintf("key%d", mirrorBuffer) || dropped[0].Op != WatchSet) {
+		t.Errorf("Expected the first write past the buffer to be dropped, got %+v", dropped[0])
+	}
+	mu.Unlock()
+
+	// Closing while the mirror is still down drops the rest.
+	primary.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dropped) != mirrorBuffer+10 {
+		t.Errorf("Expected every write dropped after Close, got %d", len(dropped))
+	}
+	if last := dropped[len(dropped)-1]; last.Err == nil {
+		t.Errorf("Expected writes dropped on Close to carry the mirror's error, got %+v", last)
+	}
+}
+
+func TestMirrorAsyncCloseFlushes(t *testing.T) {
+	primary, secondary, _ := newMirrorPair(t, MirrorAsync)
+	for i := 0; i < 100; i++ {
+		primary.Set(fmt.Sprintf("key%d", i), []byte("v"))
+	}
+	primary.Close()
+
+	keys, err := secondary.ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys failed: %v", err)
+	}
+	if len(keys) != 100 {
+		t.Errorf("Expected every queued write on the mirror after Close, got %d", len(keys))
+	}
+}
+
+func TestMirrorInvalidMode(t *testing.T) {
+	secondary := newTestClient(t)
+	if _, err := NewCacheClient(":memory:", WithMirror(secondary, MirrorMode(0))); err == nil {
+		t.Error("Expected an error for an invalid mirror mode")
+	}
+}
@@ -0,0 +1,161 @@
+package squeakyv
+
+import (
+	"strings"
+	"time"
+)
+
+// namespaceSeparator joins a namespace's prefix to the keys inside it.
+const namespaceSeparator = ":"
+
+// Namespace is a view of a CacheClient in which every key is transparently
+// prefixed with the namespace's name and a colon, giving isolated keyspaces,
+// for example one per tenant, on top of a single database.
+//
+// Keys passed to a Namespace are relative to it, and keys it returns have
+// the prefix stripped. A Namespace is cheap to create, holds no resources of
+// its own, and is safe for concurrent use; closing the client closes it too.
+type Namespace struct {
+	client *CacheClient
+	// prefix is the full prefix of keys in the namespace, separator included.
+	prefix string
+	opts   NamespaceOptions
+}
+
+// NamespaceOptions configures the writes made through a Namespace. The zero
+// value imposes nothing.
+type NamespaceOptions struct {
+	// DefaultTTL, if positive, is the expiry Set gives every value.
+	// SetWithTTL overrides it.
+	DefaultTTL time.Duration
+	// MaxValueBytes, if positive, is the largest value Set and SetWithTTL
+	// accept. Larger values are rejected with a *ValueTooLargeError without
+	// touching the database.
+	MaxValueBytes int
+}
+
+// Namespace returns a view of the cache in which every key is prefixed with
+// prefix + ":". Namespaces nest: c.Namespace("a").Namespace("b") stores key
+// "k" as "a:b:k".
+//
+// Example:
+//
+//	tenant := client.Namespace("tenant:42")
+//	err := tenant.Set("settings", data) // stored as "tenant:42:settings"
+func (c *CacheClient) Namespace(prefix string) *Namespace {
+	return &Namespace{client: c, prefix: prefix + namespaceSeparator}
+}
+
+// NamespaceWithOptions is like Namespace, but writes through the returned
+// namespace follow opts. The options apply only to that namespace and those
+// nested inside it, never to the client or to sibling namespaces.
+//
+// Example:
+//
+//	sessions := client.NamespaceWithOptions("sessions", squeakyv.NamespaceOptions{
+//		DefaultTTL:    time.Hour,
+//		MaxValueBytes: 1 << 20,
+//	})
+func (c *CacheClient) NamespaceWithOptions(prefix string, opts NamespaceOptions) *Namespace {
+	return &Namespace{client: c, prefix: prefix + namespaceSeparator, opts: opts}
+}
+
+// Namespace returns a namespace nested inside n, with n's options.
+func (n *Namespace) Namespace(prefix string) *Namespace {
+	return n.NamespaceWithOptions(prefix, n.opts)
+}
+
+// NamespaceWithOptions returns a namespace nested inside n that follows opts
+// instead of n's options.
+func (n *Namespace) NamespaceWithOptions(prefix string, opts NamespaceOptions) *Namespace {
+	return &Namespace{client: n.client, prefix: n.prefix + prefix + namespaceSeparator, opts: opts}
+}
+
+// Prefix returns the prefix added to every key in the namespace, including
+// the trailing separator.
+func (n *Namespace) Prefix() string {
+	return n.prefix
+}
+
+// key returns the key under which key is stored in the underlying cache.
+func (n *Namespace) key(key string) string {
+	return n.prefix + key
+}
+
+// Get retrieves the value for a key in the namespace. See CacheClient.Get.
+func (n *Namespace) Get(key string) ([]byte, error) {
+	return n.client.Get(n.key(key))
+}
+
+// GetStrict retrieves the value for a key in the namespace, returning an
+// error wrapping ErrKeyNotFound if it is missing. See CacheClient.GetStrict.
+func (n *Namespace) GetStrict(key string) ([]byte, error) {
+	return n.client.GetStrict(n.key(key))
+}
+
+// Set stores a value for a key in the namespace, with the namespace's default
+// TTL if it has one. See CacheClient.Set.
+func (n *Namespace) Set(key string, value []byte) error {
+	if n.opts.DefaultTTL > 0 {
+		return n.SetWithTTL(key, value, n.opts.DefaultTTL)
+	}
+	if err := n.checkSize(key, value); err != nil {
+		return err
+	}
+	return n.client.Set(n.key(key), value)
+}
+
+// SetWithTTL stores a value for a key in the namespace that expires after
+// ttl, regardless of the namespace's default TTL. See CacheClient.SetWithTTL.
+func (n *Namespace) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	if err := n.checkSize(key, value); err != nil {
+		return err
+	}
+	return n.client.SetWithTTL(n.key(key), value, ttl)
+}
+
+// checkSize enforces the namespace's MaxValueBytes.
+func (n *Namespace) checkSize(key string, value []byte) error {
+	if n.opts.MaxValueBytes > 0 && len(value) > n.opts.MaxValueBytes {
+		return &ValueTooLargeError{Key: n.key(key), Size: len(value), Limit: n.opts.MaxValueBytes}
+	}
+	return nil
+}
+
+// Delete soft-deletes a key in the namespace. See CacheClient.Delete.
+func (n *Namespace) Delete(key string) error {
+	return n.client.Delete(n.key(key))
+}
+
+// Exists reports whether a key in the namespace has a live value. See
+// CacheClient.Exists.
+func (n *Namespace) Exists(key string) (bool, error) {
+	return n.client.Exists(n.key(key))
+}
+
+// ListKeys returns the live keys in the namespace, including those of nested
+// namespaces, without the namespace's prefix and ordered by insertion time
+// (newest first).
+func (n *Namespace) ListKeys() ([]string, error) {
+	return n.ListKeysWithPrefix("")
+}
+
+// ListKeysWithPrefix returns the live keys in the namespace that start with
+// prefix, without the namespace's prefix. See CacheClient.ListKeysWithPrefix.
+func (n *Namespace) ListKeysWithPrefix(prefix string) ([]string, error) {
+	keys, err := n.client.ListKeysWithPrefix(n.key(prefix))
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, n.prefix)
+	}
+	return keys, nil
+}
+
+// DeletePrefix soft-deletes every live key in the namespace that starts with
+// prefix and returns the number deleted. An empty prefix empties the
+// namespace. See CacheClient.DeletePrefix.
+func (n *Namespace) DeletePrefix(prefix string) (int64, error) {
+	return n.client.DeletePrefix(n.key(prefix))
+}
@@ -0,0 +1,182 @@
+package squeakyv
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNamespace(t *testing.T) {
+	client := newTestClient(t)
+	ns := client.Namespace("tenant1")
+
+	if err := ns.Set("settings", []byte("dark")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, _ := client.Get("tenant1:settings"); string(value) != "dark" {
+		t.Errorf("Expected key stored with prefix, got %q", value)
+	}
+	if value, _ := ns.Get("settings"); string(value) != "dark" {
+		t.Errorf("Expected dark, got %q", value)
+	}
+	if ok, _ := ns.Exists("settings"); !ok {
+		t.Error("Expected key to exist")
+	}
+
+	ns.SetWithTTL("session", []byte("s"), time.Hour)
+	keys, err := ns.ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys failed: %v", err)
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"session", "settings"}) {
+		t.Errorf("Expected stripped keys, got %v", keys)
+	}
+
+	if err := ns.Delete("settings"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := ns.GetStrict("settings"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestNamespaceIsolation(t *testing.T) {
+	client := newTestClient(t)
+	a := client.Namespace("a")
+	ab := client.Namespace("ab")
+
+	a.Set("key", []byte("from a"))
+	ab.Set("key", []byte("from ab"))
+	client.Set("key", []byte("root"))
+	client.Set("a", []byte("root a"))
+
+	if value, _ := a.Get("key"); string(value) != "from a" {
+		t.Errorf("Expected a's value, got %q", value)
+	}
+	if value, _ := ab.Get("key"); string(value) != "from ab" {
+		t.Errorf("Expected ab's value, got %q", value)
+	}
+	if value, _ := client.Namespace("b").Get("key"); value != nil {
+		t.Errorf("Expected an unrelated namespace to see nothing, got %q", value)
+	}
+
+	// A namespace whose name is a prefix of another's doesn't list its keys
+	if keys, _ := a.ListKeys(); !reflect.DeepEqual(keys, []string{"key"}) {
+		t.Errorf("Expected only a's key, got %v", keys)
+	}
+
+	n, err := a.DeletePrefix("")
+	if err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 key deleted, got %d", n)
+	}
+	if value, _ := ab.Get("key"); string(value) != "from ab" {
+		t.Errorf("Expected ab untouched, got %q", value)
+	}
+	if value, _ := client.Get("a"); string(value) != "root a" {
+		t.Errorf("Expected root key untouched, got %q", value)
+	}
+}
+
+func TestNamespaceNested(t *testing.T) {
+	client := newTestClient(t)
+	tenant := client.Namespace("tenant")
+	users := tenant.Namespace("users")
+
+	if users.Prefix() != "tenant:users:" {
+		t.Errorf("Expected tenant:users:, got %q", users.Prefix())
+	}
+
+	users.Set("1", []byte("alice"))
+	tenant.Set("plan", []byte("pro"))
+
+	if value, _ := client.Get("tenant:users:1"); string(value) != "alice" {
+		t.Errorf("Expected nested key to compose, got %q", value)
+	}
+	if value, _ := tenant.Get("users:1"); string(value) != "alice" {
+		t.Errorf("Expected parent to see nested key, got %q", value)
+	}
+	if keys, _ := users.ListKeys(); !reflect.DeepEqual(keys, []string{"1"}) {
+		t.Errorf("Expected [1], got %v", keys)
+	}
+	if keys, _ := tenant.ListKeysWithPrefix("users:"); !reflect.DeepEqual(keys, []string{"users:1"}) {
+		t.Errorf("Expected [users:1], got %v", keys)
+	}
+}
+
+func TestNamespaceDefaultTTL(t *testing.T) {
+	client := newTestClient(t)
+	ns := client.NamespaceWithOptions("cache", NamespaceOptions{DefaultTTL: time.Hour})
+
+	ns.Set("default", []byte("v"))
+	ttl, ok, err := client.TTL("cache:default")
+	if err != nil || !ok {
+		t.Fatalf("Expected an expiry, got ok=%v err=%v", ok, err)
+	}
+	if ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("Expected the default TTL, got %v", ttl)
+	}
+
+	ns.SetWithTTL("override", []byte("v"), time.Minute)
+	if ttl, _, _ := client.TTL("cache:override"); ttl > time.Minute {
+		t.Errorf("Expected SetWithTTL to override the default, got %v", ttl)
+	}
+
+	// Nested namespaces inherit the options
+	ns.Namespace("sub").Set("key", []byte("v"))
+	if _, ok, _ := client.TTL("cache:sub:key"); !ok {
+		t.Error("Expected nested namespace to inherit the default TTL")
+	}
+
+	// The parent client and siblings are unaffected
+	client.Set("plain", []byte("v"))
+	client.Namespace("cache").Set("sibling", []byte("v"))
+	for _, key := range []string{"plain", "cache:sibling"} {
+		if _, ok, _ := client.TTL(key); ok {
+			t.Errorf("Expected %s to have no expiry", key)
+		}
+	}
+}
+
+func TestNamespaceMaxValueBytes(t *testing.T) {
+	client := newTestClient(t)
+	ns := client.NamespaceWithOptions("small", NamespaceOptions{MaxValueBytes: 4})
+
+	if err := ns.Set("ok", []byte("1234")); err != nil {
+		t.Fatalf("Expected a value at the limit to be accepted, got %v", err)
+	}
+
+	err := ns.Set("big", []byte("12345"))
+	if !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("Expected ErrValueTooLarge, got %v", err)
+	}
+	var tooLarge *ValueTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("Expected *ValueTooLargeError, got %T", err)
+	}
+	if tooLarge.Key != "small:big" || tooLarge.Size != 5 || tooLarge.Limit != 4 {
+		t.Errorf("Unexpected error details: %+v", tooLarge)
+	}
+	if err := ns.SetWithTTL("big", []byte("12345"), time.Hour); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected SetWithTTL to enforce the limit, got %v", err)
+	}
+	if n := countRows(t, client, "small:big"); n != 0 {
+		t.Errorf("Expected nothing written, got %d rows", n)
+	}
+
+	// Options don't leak
+	if err := client.Set("big", []byte("12345")); err != nil {
+		t.Errorf("Expected the client to be unlimited, got %v", err)
+	}
+	if err := client.Namespace("small").Set("big", []byte("12345")); err != nil {
+		t.Errorf("Expected a plain namespace to be unlimited, got %v", err)
+	}
+	if err := ns.NamespaceWithOptions("big", NamespaceOptions{}).Set("x", []byte("12345")); err != nil {
+		t.Errorf("Expected explicit nested options to replace the parent's, got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package squeakyv
+
+// SetObject stores v for a key, encoded with the client's codec (see
+// WithCodec). A value that cannot be encoded returns an error naming the key,
+// and nothing is written.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithCodec(squeakyv.GobCodec{}),
+//	)
+//	...
+//	err = client.SetObject("user:1", User{Name: "alice"})
+func (c *CacheClient) SetObject(key string, v any) error {
+	return c.setEncoded(c.opts.codec, key, v)
+}
+
+// GetObject decodes the value for a key into dst with the client's codec and
+// reports whether the key exists. Like GetJSON, a missing key returns false
+// and a nil error, and a value that fails to decode returns true and a
+// *DecodeError naming the key.
+//
+// Example:
+//
+//	var user User
+//	found, err := client.GetObject("user:1", &user)
+func (c *CacheClient) GetObject(key string, dst any) (bool, error) {
+	return c.getDecoded(c.opts.codec, key, dst)
+}
@@ -0,0 +1,107 @@
+package squeakyv
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// upperCodec is a toy Codec that stores strings upper-cased, standing in
+// for third-party formats such as msgpack.
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v any) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New("upperCodec only encodes strings")
+	}
+	return []byte(strings.ToUpper(s)), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v any) error {
+	p, ok := v.(*string)
+	if !ok {
+		return errors.New("upperCodec only decodes into *string")
+	}
+	*p = string(data)
+	return nil
+}
+
+func TestObjectDefaultsToJSON(t *testing.T) {
+	client := newTestClient(t)
+
+	client.SetObject("key", map[string]int{"a": 1})
+	if value, _ := client.Get("key"); string(value) != `{"a":1}` {
+		t.Errorf("Expected JSON, got %s", value)
+	}
+
+	var got map[string]int
+	found, err := client.GetObject("key", &got)
+	if err != nil || !found || got["a"] != 1 {
+		t.Errorf("Expected map[a:1], got %v, %v, %v", got, found, err)
+	}
+}
+
+func TestWithCodec(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithCodec(GobCodec{}))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	want := map[int]string{1: "one"}
+	if err := client.SetObject("key", want); err != nil {
+		t.Fatalf("SetObject failed: %v", err)
+	}
+	if value, _ := client.Get("key"); bytes.HasPrefix(value, []byte("{")) {
+		t.Errorf("Expected gob, got %s", value)
+	}
+
+	var got map[int]string
+	if _, err := client.GetObject("key", &got); err != nil {
+		t.Fatalf("GetObject failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestWithCustomCodec(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithCodec(upperCodec{}))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.SetObject("key", "hello")
+	if value, _ := client.Get("key"); string(value) != "HELLO" {
+		t.Errorf("Expected HELLO, got %s", value)
+	}
+
+	// Typed views without their own codec use the client's.
+	got, _, err := Typed[string](client, nil).Get("key")
+	if err != nil || got != "HELLO" {
+		t.Errorf("Expected HELLO from typed view, got %q, %v", got, err)
+	}
+
+	if err := client.SetObject("other", 42); err == nil {
+		t.Error("Expected encode error")
+	}
+}
+
+func TestGetObjectMissingAndInvalid(t *testing.T) {
+	client := newTestClient(t)
+
+	var got struct{ Name string }
+	if found, err := client.GetObject("missing", &got); found || err != nil {
+		t.Errorf("Expected false, nil, got %v, %v", found, err)
+	}
+
+	client.Set("bad", []byte("{"))
+	var decodeErr *DecodeError
+	if _, err := client.GetObject("bad", &got); !errors.As(err, &decodeErr) || decodeErr.Key != "bad" {
+		t.Errorf("Expected *DecodeError for key bad, got %v", err)
+	}
+}
@@ -3,12 +3,6 @@
 
 package squeakyv
 
-import (
-	"database/sql"
-	"fmt"
-)
-
-
 // SchemaSQL contains the embedded database schema
 const SchemaSQL = `/*
  * Table: __metadata__
@@ -67,82 +61,3 @@ CREATE VIEW IF NOT EXISTS kv_current AS
   WHERE is_active = 1;
 
 `
-
-
-// _deleteKey executes the delete_key query
-func _deleteKey(db *sql.DB, key string) error {
-	query := `UPDATE kv
-SET is_active = 0
-WHERE key = ? AND is_active = 1;`
-	args := []interface{}{key}
-
-	_, err := db.Exec(query, args...)
-	if err != nil {
-		return fmt.Errorf("exec failed: %w", err)
-	}
-	return nil
-}
-
-
-// _getCurrentValue executes the get_current_value query
-func _getCurrentValue(db *sql.DB, key string) ([]byte, error) {
-	query := `SELECT value -- , inserted_at
-FROM kv
-WHERE key = ? AND is_active = 1;`
-	args := []interface{}{key}
-
-	var value []byte
-	err := db.QueryRow(query, args...).Scan(&value)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
-	}
-	return value, nil
-}
-
-
-// _listActiveKeys executes the list_active_keys query
-func _listActiveKeys(db *sql.DB) ([]string, error) {
-	query := `SELECT key -- , inserted_at
-FROM kv
-WHERE is_active = 1
-ORDER BY inserted_at DESC;`
-	args := []interface{}{}
-
-	rows, err := db.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
-	}
-	defer rows.Close()
-
-	var results []string
-	for rows.Next() {
-		var value string
-		if err := rows.Scan(&value); err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
-		}
-		results = append(results, value)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration failed: %w", err)
-	}
-
-	return results, nil
-}
-
-
-// _setValue executes the set_value query
-func _setValue(db *sql.DB, key string, value []byte) error {
-	query := `INSERT INTO kv (key, value)
-VALUES (?, ?);`
-	args := []interface{}{key, value}
-
-	_, err := db.Exec(query, args...)
-	if err != nil {
-		return fmt.Errorf("exec failed: %w", err)
-	}
-	return nil
-}
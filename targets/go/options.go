@@ -0,0 +1,776 @@
+package squeakyv
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Option configures a CacheClient at construction time.
+//
+// Options are passed to NewCacheClient:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithSweepInterval(time.Minute),
+//	)
+type Option func(*options)
+
+// options holds the settings assembled from a list of Option values.
+type options struct {
+	sweepInterval   time.Duration
+	sweepBatchSize  int
+	changeRetention time.Duration
+	slidingExpiry   bool
+	secureDelete    bool
+	journalMode     string
+	pragmas         []pragma
+
+	busyTimeout      time.Duration
+	retryMaxAttempts int
+	retryMaxElapsed  time.Duration
+
+	readOnly  bool
+	immutable bool
+	mustExist bool
+
+	createDirs bool
+	dirMode    os.FileMode
+	fileMode   os.FileMode
+
+	table string
+
+	codec      Codec
+	compressor Compressor
+
+	encryptionKeys [][]byte
+
+	quickCheck bool
+
+	maxValueSize int64
+
+	maxKeyLength int
+	keyValidator func(key string) error
+
+	caseInsensitiveKeys bool
+
+	tracer Tracer
+	logger *slog.Logger
+
+	slowOpThreshold time.Duration
+	slowOp          func(SlowOp)
+
+	mirror        *CacheClient
+	mirrorMode    MirrorMode
+	mirrorDropped func(MirrorDrop)
+
+	shardHash func(key string) uint64
+
+	negativeTTL time.Duration
+
+	memCache        bool
+	memCacheEntries int
+	memCacheBytes   int64
+
+	writeBehind        bool
+	writeBehindBatch   int
+	writeBehindDelay   time.Duration
+	writeBehindDropped func(WriteBehindDrop)
+
+	maxEntries int
+	maxBytes   int64
+
+	evictionCallback func(key string, reason EvictReason)
+}
+
+// pragma is a PRAGMA statement applied to every connection.
+type pragma struct {
+	name  string
+	value string
+}
+
+// defaultOptions returns the settings used when no Option overrides them.
+func defaultOptions() options {
+	return options{
+		sweepBatchSize:   500,
+		changeRetention:  24 * time.Hour,
+		busyTimeout:      5 * time.Second,
+		retryMaxAttempts: 5,
+		retryMaxElapsed:  2 * time.Second,
+		table:            defaultTable,
+		codec:            JSONCodec{},
+		maxKeyLength:     defaultMaxKeyLength,
+	}
+}
+
+// WithSweepInterval starts a background sweeper that physically deletes
+// expired rows every interval. The sweeper stops when the client is closed.
+//
+// A zero or negative interval disables the sweeper, which is the default.
+func WithSweepInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.sweepInterval = interval
+	}
+}
+
+// WithSweepBatchSize sets how many rows the sweeper, and batched deletes such
+// as PruneAllVersions, remove per transaction, and how many RotateEncryptionKey
+// scans. Smaller batches hold the write lock for less time.
+//
+// The default is 500. Non-positive values are ignored.
+func WithSweepBatchSize(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.sweepBatchSize = n
+		}
+	}
+}
+
+// WithChangeRetention sets how long changes recorded in the change feed (see
+// ChangesSince) are kept. Older ones are pruned, as by PruneChangesBefore, by
+// SweepNow and so the background sweeper, Compact, ClearHard, PruneVersions
+// and PruneAllVersions.
+//
+// The default is 24 hours. A zero or negative d keeps every change until
+// PruneChangesBefore removes it.
+func WithChangeRetention(d time.Duration) Option {
+	return func(o *options) {
+		o.changeRetention = d
+	}
+}
+
+// WithSlidingExpiry makes Touch extend a key's expiry as well as its
+// recency, so that the key lives for its TTL measured from the touch rather
+// than from when it was written or its expiry was last set.
+func WithSlidingExpiry() Option {
+	return func(o *options) {
+		o.slidingExpiry = true
+	}
+}
+
+// WithSecureDelete makes HardDelete overwrite a key's values with zeros before
+// removing its rows, so the bytes are not left behind in freed pages of the
+// main database file.
+//
+// Copies may still survive in a rollback journal or WAL file until it is
+// checkpointed or reset.
+func WithSecureDelete() Option {
+	return func(o *options) {
+		o.secureDelete = true
+	}
+}
+
+// WithJournalMode sets SQLite's journal mode, for example "WAL", "DELETE" or
+// "TRUNCATE". NewCacheClient fails if the database does not end up in the
+// requested mode.
+//
+// File-backed caches use WAL by default, with synchronous=NORMAL, so readers
+// never block on a writer; if the filesystem cannot support WAL the default
+// quietly falls back to the rollback journal. In-memory caches keep SQLite's
+// default unless a mode is given.
+func WithJournalMode(mode string) Option {
+	return func(o *options) {
+		o.journalMode = mode
+	}
+}
+
+// WithPragma runs "PRAGMA name = value" on every connection the client opens,
+// after the journal mode settings, so it can override them.
+//
+// name must be an identifier and value a plain literal such as NORMAL, ON or
+// -2000; NewCacheClient rejects anything else.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithPragma("cache_size", "-65536"),
+//	)
+func WithPragma(name, value string) Option {
+	return func(o *options) {
+		o.pragmas = append(o.pragmas, pragma{name: name, value: value})
+	}
+}
+
+// WithBusyTimeout sets how long SQLite itself waits for a lock held by
+// another connection before failing with SQLITE_BUSY. The default is five
+// seconds, under either driver.
+func WithBusyTimeout(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.busyTimeout = d
+		}
+	}
+}
+
+// WithRetry bounds how write operations are retried when they still fail with
+// SQLITE_BUSY or SQLITE_LOCKED, which the busy timeout cannot always prevent
+// (for example when a WAL read transaction cannot be upgraded). Retries back
+// off exponentially with jitter and stop after maxAttempts tries in total or
+// once maxElapsed has passed, whichever comes first, returning a *BusyError.
+//
+// The default is 5 attempts within 2 seconds. maxAttempts of 1 disables
+// retries; non-positive values keep the defaults.
+func WithRetry(maxAttempts int, maxElapsed time.Duration) Option {
+	return func(o *options) {
+		if maxAttempts > 0 {
+			o.retryMaxAttempts = maxAttempts
+		}
+		if maxElapsed > 0 {
+			o.retryMaxElapsed = maxElapsed
+		}
+	}
+}
+
+// WithReadOnly opens the database in read-only mode. Every method that would
+// modify it returns ErrReadOnly without touching the file, reads no longer
+// soft-delete expired keys as a side effect, and the background sweeper is
+// not started.
+//
+// The file must already exist, as with WithMustExist, and have been opened
+// read-write by this package at least once, since a read-only client cannot
+// initialize or migrate the schema. The journal mode is left as it is;
+// WithJournalMode only verifies it.
+func WithReadOnly() Option {
+	return func(o *options) {
+		o.readOnly = true
+	}
+}
+
+// WithMustExist makes NewCacheClient fail with ErrDatabaseNotExist instead of
+// creating a new, empty database when there is no file at the path, so a
+// mistyped path is caught at startup. In-memory paths are rejected.
+func WithMustExist() Option {
+	return func(o *options) {
+		o.mustExist = true
+	}
+}
+
+// WithImmutable is WithReadOnly for a file that no process will modify while
+// it is open, such as a snapshot on read-only media. SQLite then skips all
+// locking and change detection. Opening a file that is in fact being written
+// this way can return stale or corrupt results.
+func WithImmutable() Option {
+	return func(o *options) {
+		o.readOnly = true
+		o.immutable = true
+	}
+}
+
+// WithCreateDirs creates the database file's parent directory, and any
+// missing ancestors, with permissions perm (before umask) if it does not
+// exist. A zero perm means 0755. It has no effect on in-memory databases.
+func WithCreateDirs(perm os.FileMode) Option {
+	return func(o *options) {
+		if perm == 0 {
+			perm = 0o755
+		}
+		o.createDirs = true
+		o.dirMode = perm
+	}
+}
+
+// WithFileMode sets the permissions of the database file, whether it is new
+// or already exists, before SQLite opens it. SQLite gives the -wal, -shm and
+// rollback journal files it creates the same permissions as the database;
+// any already present are changed too. Without this option the database is
+// created according to the umask and existing files are left alone.
+//
+// It has no effect on in-memory databases or read-only clients.
+func WithFileMode(mode os.FileMode) Option {
+	return func(o *options) {
+		o.fileMode = mode.Perm()
+	}
+}
+
+// WithTableName stores the cache in the named table instead of the default
+// "kv", so several independent caches can share one database file. The
+// indexes, triggers and view that go with the table are named after it too,
+// e.g. sessions_current for the view of table sessions.
+//
+// Clients opened on the same file with different table names never see each
+// other's keys. Operations that involve another database, such as Restore,
+// MergeFrom and Diff, expect the same table name there. The name must be a
+// plain identifier of at most 64 characters, not starting with "sqlite_" and
+// not ending in a suffix used for the objects named after a table, such as
+// "_meta" or "_changes"; NewCacheClient rejects anything else.
+func WithTableName(name string) Option {
+	return func(o *options) {
+		o.table = name
+	}
+}
+
+// WithCodec sets the Codec used by SetObject and GetObject, and by Typed
+// views created without one. The raw byte methods such as Set and Get are
+// unaffected.
+//
+// The default is JSONCodec; GobCodec ships too, and any other format, such as
+// msgpack or CBOR, can be plugged in by implementing Codec. A nil codec is
+// ignored.
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		if codec != nil {
+			o.codec = codec
+		}
+	}
+}
+
+// WithCompressor compresses every value written by the client with c. Values
+// that compressing would not shrink are stored uncompressed.
+//
+// Reads decode values by the compressor ID recorded with each of them, so
+// values written with another compressor, or before compression was enabled,
+// stay readable; compressors other than c must be registered with
+// RegisterCompressor. Compressed values are only readable by Go clients, and
+// sizes reported by methods such as Size, SizeOf and Stat are of the stored,
+// compressed bytes. Append reads and rewrites the whole value instead of
+// appending inside SQLite. A nil c turns compression off for new writes.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithCompressor(squeakyv.GzipCompressor{}),
+//	)
+func WithCompressor(c Compressor) Option {
+	return func(o *options) {
+		o.compressor = c
+	}
+}
+
+// WithEncryption encrypts every value written by the client with AES-256-GCM
+// under key, which must be 32 bytes long. Each version gets its own random
+// nonce, stored with the ciphertext, and reads fail with an error wrapping
+// ErrDecrypt if a value was tampered with.
+//
+// Only values are encrypted. Keys stay in plaintext, so ListKeys and prefix
+// operations keep working, but anyone with the file can read them, along
+// with write times, expiries and value sizes: do not put secrets in keys.
+// Each value is authenticated together with the key it is stored under, so
+// a value moved to another key, as by swapping two values in the file, fails
+// to decrypt too; Rename and Copy re-encrypt the values they move.
+//
+// The first client to open a database with encryption stores an encrypted
+// verification record; afterwards NewCacheClient fails with an error wrapping
+// ErrDecrypt if given a different key. Values written before encryption was
+// enabled stay readable. Encrypted values are only readable by Go clients.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithEncryption(key),
+//	)
+func WithEncryption(key []byte) Option {
+	return WithEncryptionKeys(key)
+}
+
+// WithEncryptionKeys is WithEncryption with fallback keys: values are
+// encrypted with primary, and values encrypted with any of the keys decrypt.
+// NewCacheClient accepts the database if any of the keys matches its
+// verification record.
+//
+// It is the transitional mode for rotating keys without downtime: configure
+// every client with the new key as primary and the old one as a fallback,
+// run RotateEncryptionKey once, then drop the fallback.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithEncryptionKeys(newKey, oldKey),
+//	)
+func WithEncryptionKeys(primary []byte, fallbacks ...[]byte) Option {
+	keys := [][]byte{bytes.Clone(primary)}
+	for _, key := range fallbacks {
+		keys = append(keys, bytes.Clone(key))
+	}
+	return func(o *options) {
+		o.encryptionKeys = keys
+	}
+}
+
+// WithQuickCheck makes IntegrityCheck run PRAGMA quick_check instead of the
+// full integrity_check. It skips verifying that indexes match their tables,
+// which makes it several times faster on large files.
+func WithQuickCheck() Option {
+	return func(o *options) {
+		o.quickCheck = true
+	}
+}
+
+// WithMaxValueSize makes every write of a value longer than n bytes fail
+// with a *ValueTooLargeError, matching ErrValueTooLarge, before any SQL runs.
+// It covers Set, SetWithTTL, SetMany, the atomic operations, transactions and
+// Import, and Append, which checks the length the value would grow to. Sizes
+// are measured before compression and encryption.
+//
+// Zero, the default, means no limit. Negative values are ignored.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithMaxValueSize(16<<20),
+//	)
+func WithMaxValueSize(n int64) Option {
+	return func(o *options) {
+		if n >= 0 {
+			o.maxValueSize = n
+		}
+	}
+}
+
+// WithMaxKeyLength sets the longest key, in bytes, that writes accept; longer
+// keys fail with an error wrapping ErrInvalidKey. Zero removes the limit.
+//
+// The default is 4096. Negative values are ignored.
+func WithMaxKeyLength(n int) Option {
+	return func(o *options) {
+		if n >= 0 {
+			o.maxKeyLength = n
+		}
+	}
+}
+
+// WithKeyValidator adds a rule that every key must pass to be written, on top
+// of the built-in ones (not empty, no longer than WithMaxKeyLength). A non-nil
+// error from fn rejects the write with an error wrapping both ErrInvalidKey
+// and the error from fn.
+//
+// Only writes are checked: keys stored before the rule was introduced can
+// still be read, deleted and renamed away.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithKeyValidator(func(key string) error {
+//			if !strings.HasPrefix(key, "user:") {
+//				return errors.New("keys must start with user:")
+//			}
+//			return nil
+//		}),
+//	)
+func WithKeyValidator(fn func(key string) error) Option {
+	return func(o *options) {
+		o.keyValidator = fn
+	}
+}
+
+// WithCaseInsensitiveKeys makes keys that differ only in the case of ASCII
+// letters the same key, so Get("foo") finds a value stored with
+// Set("Foo", ...). Keys are stored as written, not normalized: ListKeys and
+// the other listings return the casing of each key's most recent write.
+//
+// The option works by declaring the key column COLLATE NOCASE when the table
+// is created, so it applies to every client of the database, in any
+// language. It must therefore be given when the database is created, and
+// every time it is opened afterwards; NewCacheClient rejects a database
+// created in the other mode. Prefix matching (ListKeysWithPrefix,
+// DeletePrefix, Namespace) stays case-sensitive, and letters outside ASCII
+// are compared exactly, as with SQLite's NOCASE.
+func WithCaseInsensitiveKeys() Option {
+	return func(o *options) {
+		o.caseInsensitiveKeys = true
+	}
+}
+
+// WithLogger logs the client's operations to logger. Get, Set, Delete,
+// ListKeys, SetWithTTL and the batch operations each log a debug event with
+// the operation, key (or number of keys), duration, bytes of values and any
+// error; those taking longer than 500ms log at warn level instead. Writes
+// retried because the database was busy, and writes that gave up, log warn
+// events.
+//
+// Events are only built if logger is enabled for their level, and without a
+// logger the only cost is a nil check per operation.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithLogger(slog.Default()),
+//	)
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithSlowOpThreshold calls fn with a SlowOp for every operation that takes
+// longer than d, saying how much of that time went to waiting for the
+// database lock rather than executing, so lock contention on a file database
+// can be found without logging every operation. The operations covered are
+// those WithLogger logs.
+//
+// fn runs synchronously on the goroutine that made the call, after the
+// operation has completed, so it should return quickly. A non-positive d or a
+// nil fn disables the callback.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithSlowOpThreshold(100*time.Millisecond, func(op squeakyv.SlowOp) {
+//			log.Printf("slow %s of %q: %v, %v waiting for the lock", op.Op, op.Key, op.Duration, op.LockWait)
+//		}),
+//	)
+func WithSlowOpThreshold(d time.Duration, fn func(SlowOp)) Option {
+	return func(o *options) {
+		if d <= 0 || fn == nil {
+			o.slowOpThreshold, o.slowOp = 0, nil
+			return
+		}
+		o.slowOpThreshold, o.slowOp = d, fn
+	}
+}
+
+// WithMirror replays every write this client makes with Set, SetWithTTL,
+// SetMany, Delete, DeleteMany, GetDel, GetSet, SetNX, CompareAndSwap or
+// CompareAndDelete, including through a Namespace, onto secondary, for
+// example to keep a warm standby on another disk. These are the writes Watch
+// sees; others, such as those in WithTransaction, are not mirrored. Reads
+// never use the mirror. The mirror is not closed with the client.
+//
+// Values are replayed as given, before encoding, so secondary applies its own
+// codec, compression and encryption. A value written with a TTL keeps its
+// expiry time.
+//
+// With MirrorSync, each write is replayed before the operation returns. If
+// that fails, the operation returns an error wrapping ErrMirror, although its
+// write to this client has committed, and its other results describe that
+// write.
+//
+// With MirrorAsync, writes are queued and replayed in order by a background
+// goroutine, which retries a failing write with backoff until it succeeds.
+// The queue holds up to 1024 writes; while it is full, new writes are dropped
+// and reported to the WithMirrorDropped callback. Close makes one last
+// attempt at the writes still queued, dropping those that fail.
+//
+// Writes to a key made one after another reach the mirror in the same order;
+// concurrent writes to the same key may reach it in either order. A nil
+// secondary disables mirroring.
+//
+// Example:
+//
+//	standby, err := squeakyv.NewCacheClient("/mnt/backup/cache.db")
+//	if err != nil {
+//		return err
+//	}
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithMirror(standby, squeakyv.MirrorAsync),
+//		squeakyv.WithMirrorDropped(func(d squeakyv.MirrorDrop) {
+//			log.Printf("standby missed %s of %q: %v", d.Op, d.Key, d.Err)
+//		}),
+//	)
+func WithMirror(secondary *CacheClient, mode MirrorMode) Option {
+	return func(o *options) {
+		o.mirror, o.mirrorMode = secondary, mode
+	}
+}
+
+// WithMirrorDropped calls fn for every write a MirrorAsync mirror drops,
+// either because its queue is full or because the client is closing. fn runs
+// synchronously, on the goroutine making the write or on the one closing the
+// client, so it should return quickly.
+func WithMirrorDropped(fn func(MirrorDrop)) Option {
+	return func(o *options) {
+		o.mirrorDropped = fn
+	}
+}
+
+// WithShardHash makes NewShardedClient assign each key to shard
+// fn(key) % number of shards, instead of using 64-bit FNV-1a of the key (of
+// the key in lower case with WithCaseInsensitiveKeys). fn must be
+// deterministic and stable across releases, since changing the shard of
+// stored keys makes them unreachable. With WithCaseInsensitiveKeys, fn must
+// hash keys differing only in case alike. Other clients ignore the option.
+//
+// Example:
+//
+//	client, err := squeakyv.NewShardedClient(paths,
+//		squeakyv.WithShardHash(func(key string) uint64 {
+//			return xxhash.Sum64String(key)
+//		}),
+//	)
+func WithShardHash(fn func(key string) uint64) Option {
+	return func(o *options) {
+		o.shardHash = fn
+	}
+}
+
+// WithNegativeTTL makes GetOrLoad remember, for d, that its loader reported a
+// key as not found by returning an error wrapping ErrKeyNotFound. Until then,
+// GetOrLoad for the key returns an error wrapping ErrKeyNotFound without
+// calling the loader, unless a value has been stored for the key meanwhile.
+//
+// The misses are recorded as tombstones in a table of their own, so they are
+// never mistaken for values, empty or not, by any read. A zero or negative d
+// disables negative caching, which is the default.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithNegativeTTL(time.Minute),
+//	)
+func WithNegativeTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = d
+	}
+}
+
+// WithMemoryCache keeps recently read values in memory, in front of the
+// database, so that Get and GetStrict for hot keys skip SQLite altogether.
+// The cache holds at most maxEntries values and maxBytes bytes of keys and
+// values, evicting the least recently used; zero or less means no limit on
+// that count, but at least one limit must be set. Values are cached with
+// their expiry and never returned once it has passed.
+//
+// Writes made through the client, including in WithTransaction, update the
+// cache before they return, so a client always reads its own writes. Writes
+// by other clients and processes are picked up from the database's change
+// feed (see ChangesSince) every 100ms: until then, Get may return the value
+// they replaced. The feed doesn't record changes of TTL alone, so an Expire
+// or Persist by another client only takes effect here once the entry is
+// evicted, rewritten or a second old: no value is served from memory for
+// longer than that. Other reads, such as GetMany, Exists and iteration,
+// always use the database.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithMemoryCache(10000, 64<<20),
+//	)
+func WithMemoryCache(maxEntries int, maxBytes int64) Option {
+	return func(o *options) {
+		o.memCache = true
+		o.memCacheEntries = maxEntries
+		o.memCacheBytes = maxBytes
+	}
+}
+
+// WithWriteBehind makes Set and Delete buffer their writes in memory and
+// return at once, leaving a background goroutine to write them in batches of
+// up to maxBatch, each in a single transaction, at the latest maxDelay after
+// the oldest buffered write. This trades durability for throughput: writes
+// still buffered are lost if the process dies. Close and Flush write the
+// buffered writes before returning.
+//
+// Get and GetStrict see buffered writes, so a client reads its own writes,
+// and the client's other reads of keys and values, such as GetMany, Exists,
+// Stat, TTL, History, Count, Size, ListKeys, ListKeysPage, ForEach, Items,
+// Export, Backup, Clone, VacuumInto, Diff and Snapshot, flush the buffer
+// first. Other clients and processes, and the change feed, only see buffered
+// writes once flushed. Every other write, such as SetWithTTL or
+// WithTransaction, flushes the buffer first, so it never overtakes a
+// buffered write. Buffered writes
+// keep their order, and each one still creates its own version.
+//
+// Set only fails if the client is closed or the key or value is rejected, as
+// by WithMaxValueSize, and Delete only if the client is closed. If a batch
+// fails to commit, its writes are dropped and reported to
+// WithWriteBehindDropped. maxBatch must be positive and maxDelay must not be
+// negative. A read-only client can't use the option.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithWriteBehind(500, 10*time.Millisecond),
+//	)
+func WithWriteBehind(maxBatch int, maxDelay time.Duration) Option {
+	return func(o *options) {
+		o.writeBehind = true
+		o.writeBehindBatch = maxBatch
+		o.writeBehindDelay = maxDelay
+	}
+}
+
+// WithWriteBehindDropped calls fn for each write buffered by WithWriteBehind
+// whose batch failed to commit, since the Set or Delete that made it has
+// already returned. fn is called synchronously, on the goroutine writing the
+// batch, so it should return quickly.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithWriteBehind(500, 10*time.Millisecond),
+//		squeakyv.WithWriteBehindDropped(func(d squeakyv.WriteBehindDrop) {
+//			log.Printf("lost write of %q: %v", d.Key, d.Err)
+//		}),
+//	)
+func WithWriteBehindDropped(fn func(WriteBehindDrop)) Option {
+	return func(o *options) {
+		o.writeBehindDropped = fn
+	}
+}
+
+// WithMaxEntries bounds the cache to n live keys. A write that takes the
+// number of active keys past n evicts, in the same transaction, keys that
+// have expired and then the least recently used, by soft-deleting them as
+// Delete would. Watchers see evictions as deletes. Keys pinned with Pin are
+// never evicted; a write that would need them to be fails with ErrCacheFull.
+//
+// A key is used when it is written, touched, or read with Get or GetStrict.
+// Reads are not written to the database one by one: the client notes them in
+// memory and records them in its next write transaction, or when it is
+// closed, so another process evicting from the same file only sees them
+// then.
+//
+// The limit holds after every write made by this client, but not for the
+// writes of other clients and processes, nor those buffered by
+// WithWriteBehind: keys they add are evicted by this client's next write.
+// Expired keys not yet swept count towards n. It can't be combined with
+// WithReadOnly.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithMaxEntries(100000),
+//	)
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
+	}
+}
+
+// WithMaxBytes bounds the total size of the live values to n bytes, as
+// stored, after any compression or encryption. A write that takes the total
+// past n evicts keys as WithMaxEntries does, until it is back within n; the
+// two can be combined. A value larger than n on its own is rejected with a
+// ValueTooLargeError rather than evicting everything else.
+//
+// The total is kept up to date by triggers as values are written and
+// removed, rather than summed on every write, and is reported by Stats as
+// StoredBytes. It can't be combined with WithReadOnly.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithMaxBytes(1<<30),
+//	)
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// WithEvictionCallback calls fn for each key the client drops on its own:
+// keys evicted under WithMaxEntries (Evicted) or WithMaxBytes (Displaced),
+// and expired keys removed by SweepNow, by the background sweeper, by a Get
+// or GetStrict that finds them expired, or to make room (Expired). Keys
+// removed by Delete and other explicit calls, or by other clients and
+// processes, are not reported.
+//
+// fn is called asynchronously, in order, from a goroutine of its own, after
+// the transaction that dropped the key has committed and without any of the
+// client's locks held. It may therefore call back into the client, even to
+// write and cause further evictions, or to close it. A slow fn delays later
+// calls but never the client's operations. Close doesn't wait for calls
+// still pending; those made after it returns find the client closed.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyv.WithMaxEntries(10000),
+//		squeakyv.WithEvictionCallback(func(key string, reason squeakyv.EvictReason) {
+//			index.Remove(key)
+//		}),
+//	)
+func WithEvictionCallback(fn func(key string, reason EvictReason)) Option {
+	return func(o *options) {
+		o.evictionCallback = fn
+	}
+}
@@ -0,0 +1,81 @@
+package squeakyv
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// ListKeysPage returns up to limit active, unexpired keys following cursor,
+// together with the cursor for the next page. Pass an empty cursor to start
+// from the beginning; an empty nextCursor means there are no more keys.
+//
+// Pages are ordered by key (ascending) rather than by insertion time, so the
+// order is stable while other goroutines write: overwriting a key does not
+// move it, and paging never returns a key twice. Keys added or removed during
+// pagination appear or not depending on whether the cursor has passed them.
+//
+// The cursor is opaque and only valid for this method.
+//
+// Example:
+//
+//	cursor := ""
+//	for {
+//		keys, next, err := client.ListKeysPage(100, cursor)
+//		if err != nil {
+//			return err
+//		}
+//		process(keys)
+//		if next == "" {
+//			break
+//		}
+//		cursor = next
+//	}
+func (c *CacheClient) ListKeysPage(limit int, cursor string) (keys []string, nextCursor string, err error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("invalid page limit %d: must be positive", limit)
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return nil, "", err
+	}
+	defer c.release()
+
+	// Fetch one extra key to learn whether another page follows.
+	query := `SELECT key
+FROM ` + c.tables.kv + `
+WHERE key > ? AND ` + liveCondition + `
+ORDER BY key
+LIMIT ?;`
+
+	keys, err = queryStrings(db, query, after, nowMillis(), limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(keys) <= limit {
+		return keys, "", nil
+	}
+	keys = keys[:limit]
+	return keys, encodeCursor(keys[limit-1]), nil
+}
+
+// encodeCursor turns the last key of a page into an opaque cursor.
+func encodeCursor(lastKey string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(lastKey))
+}
+
+// decodeCursor recovers the last key of the previous page from a cursor.
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	lastKey, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", fmt.Errorf("invalid cursor %q: %w", cursor, err)
+	}
+	return string(lastKey), nil
+}
@@ -0,0 +1,87 @@
+package squeakyv
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestListKeysPage(t *testing.T) {
+	client := newTestClient(t)
+
+	const total = 10000
+	items := make(map[string][]byte, total)
+	for i := 0; i < total; i++ {
+		items[fmt.Sprintf("key_%05d", i)] = []byte("value")
+	}
+	if err := client.SetMany(items); err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+
+	seen := make(map[string]bool, total)
+	cursor := ""
+	pages := 0
+	for {
+		keys, next, err := client.ListKeysPage(100, cursor)
+		if err != nil {
+			t.Fatalf("Failed to list page %d: %v", pages, err)
+		}
+		pages++
+		if len(keys) > 100 {
+			t.Fatalf("Page %d exceeds limit: %d keys", pages, len(keys))
+		}
+		for _, key := range keys {
+			if seen[key] {
+				t.Fatalf("Duplicate key %s on page %d", key, pages)
+			}
+			seen[key] = true
+		}
+
+		// Overwrites between pages must not disturb pagination
+		if err := client.Set("key_00000", []byte("updated")); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if len(seen) != total {
+		t.Errorf("Expected %d keys, saw %d", total, len(seen))
+	}
+	if pages != total/100 {
+		t.Errorf("Expected %d pages, got %d", total/100, pages)
+	}
+}
+
+func TestListKeysPageSkipsDeleted(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("value"))
+	client.Set("b", []byte("value"))
+	client.Set("c", []byte("value"))
+	client.Delete("b")
+
+	keys, next, err := client.ListKeysPage(10, "")
+	if err != nil {
+		t.Fatalf("Failed to list page: %v", err)
+	}
+	if next != "" {
+		t.Errorf("Expected no next cursor, got %q", next)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "c" {
+		t.Errorf("Expected [a c], got %v", keys)
+	}
+}
+
+func TestListKeysPageInvalidInput(t *testing.T) {
+	client := newTestClient(t)
+
+	if _, _, err := client.ListKeysPage(0, ""); err == nil {
+		t.Error("Expected error for zero limit")
+	}
+	if _, _, err := client.ListKeysPage(10, "not a cursor!"); err == nil {
+		t.Error("Expected error for malformed cursor")
+	}
+}
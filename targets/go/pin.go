@@ -0,0 +1,130 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Pin exempts a live key from eviction under WithMaxEntries and WithMaxBytes,
+// for entries such as feature flags that must stay however full the cache
+// gets. A pinned key can still be deleted, and still expires at the end of
+// its TTL; either ends the pin, but overwriting the key keeps it. See PinHard
+// for a pin that also stops the key expiring.
+//
+// A write that would take the cache past its limits when only pinned keys
+// are left to evict fails with ErrCacheFull. Pinning a pinned key replaces its
+// pin. Returns an error wrapping ErrKeyNotFound if the key has no live value.
+//
+// Example:
+//
+//	if err := client.Pin("flags:checkout"); err != nil {
+//		return err
+//	}
+func (c *CacheClient) Pin(key string) error {
+	return c.pin(key, false)
+}
+
+// PinHard is Pin for a key that must not expire either: its TTL is removed,
+// and any TTL it is later given, by SetWithTTL, Expire or another client, is
+// ignored for as long as the pin lasts. Delete still removes the key, ending
+// the pin.
+//
+// Example:
+//
+//	err := client.PinHard("keys:signing")
+func (c *CacheClient) PinHard(key string) error {
+	return c.pin(key, true)
+}
+
+// pin implements Pin and PinHard.
+func (c *CacheClient) pin(key string, hard bool) error {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+	defer c.invalidate(key)
+
+	return c.withTx(db, func(tx *sql.Tx) error {
+		live, err := liveKeyExists(tx, c.tables, key, nowMillis())
+		if err != nil {
+			return err
+		}
+		if !live {
+			return keyNotFound(key)
+		}
+
+		query := `INSERT OR REPLACE INTO ` + c.tables.pins + ` (key, hard)
+VALUES (?, ?);`
+		if _, err := tx.Exec(query, c.watchKey(key), hard); err != nil {
+			return fmt.Errorf("exec failed: %w", err)
+		}
+		if hard {
+			query := `UPDATE ` + c.tables.kv + `
+SET expires_at = NULL
+WHERE key = ? AND is_active = 1;`
+			if _, err := tx.Exec(query, key); err != nil {
+				return fmt.Errorf("exec failed: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Unpin removes the pin of a key set by Pin or PinHard, making it subject to
+// eviction again. A hard-pinned key's removed TTL is not restored. Unpinning
+// a key that isn't pinned is not an error.
+//
+// Example:
+//
+//	err := client.Unpin("flags:checkout")
+func (c *CacheClient) Unpin(key string) error {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	if _, err := c.exec(db, `DELETE FROM `+c.tables.pins+` WHERE key = ?;`, c.watchKey(key)); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
+
+// ListPinned returns the live keys pinned by Pin or PinHard, in key order.
+//
+// Example:
+//
+//	pinned, err := client.ListPinned()
+func (c *CacheClient) ListPinned() ([]string, error) {
+	query := `SELECT ` + c.tables.kv + `.key
+FROM ` + c.tables.kv + `
+JOIN ` + c.tables.pins + ` ON ` + c.tables.kv + `.key = ` + c.tables.pins + `.key
+WHERE ` + liveCondition + `
+ORDER BY ` + c.tables.kv + `.key;`
+
+	db, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	rows, err := db.Query(query, nowMillis())
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return keys, nil
+}
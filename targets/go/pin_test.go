@@ -0,0 +1,220 @@
+package squeakyv
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPin(t *testing.T) {
+	client := newTestClient(t, WithMaxEntries(3))
+
+	for _, key := range []string{"a", "b", "c"} {
+		client.Set(key, []byte("v"))
+	}
+	if err := client.Pin("a"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	for _, key := range []string{"d", "e", "f"} {
+		if err := client.Set(key, []byte("v")); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"a", "e", "f"}) {
+		t.Errorf("Expected the pinned key kept, got %v", keys)
+	}
+
+	pinned, err := client.ListPinned()
+	if err != nil {
+		t.Fatalf("ListPinned failed: %v", err)
+	}
+	if !slices.Equal(pinned, []string{"a"}) {
+		t.Errorf("Expected [a] pinned, got %v", pinned)
+	}
+
+	// Overwriting keeps the pin.
+	client.Set("a", []byte("v2"))
+	if pinned, _ := client.ListPinned(); !slices.Equal(pinned, []string{"a"}) {
+		t.Errorf("Expected the pin kept on overwrite, got %v", pinned)
+	}
+
+	if err := client.Unpin("a"); err != nil {
+		t.Fatalf("Unpin failed: %v", err)
+	}
+	if err := client.Unpin("a"); err != nil {
+		t.Errorf("Expected Unpin of an unpinned key to succeed, got %v", err)
+	}
+	for _, key := range []string{"g", "h", "i"} {
+		client.Set(key, []byte("v"))
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"g", "h", "i"}) {
+		t.Errorf("Expected the unpinned key evicted, got %v", keys)
+	}
+}
+
+func TestPinMissing(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.Pin("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+	if err := client.PinHard("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestPinDelete(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("v"))
+	client.Pin("a")
+	if err := client.Delete("a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// The pin doesn't come back with the key.
+	client.Set("a", []byte("v"))
+	if pinned, _ := client.ListPinned(); len(pinned) != 0 {
+		t.Errorf("Expected the pin ended by Delete, got %v", pinned)
+	}
+}
+
+func TestPinExpires(t *testing.T) {
+	client := newTestClient(t)
+
+	client.SetWithTTL("a", []byte("v"), 20*time.Millisecond)
+	if err := client.Pin("a"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	if _, err := client.GetStrict("a"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected the pinned key to expire, got %v", err)
+	}
+	if pinned, _ := client.ListPinned(); len(pinned) != 0 {
+		t.Errorf("Expected no live pinned keys, got %v", pinned)
+	}
+
+	// Setting the key again, before the sweeper has run, doesn't pin it.
+	client.Set("a", []byte("v"))
+	if pinned, _ := client.ListPinned(); len(pinned) != 0 {
+		t.Errorf("Expected the pin ended by expiry, got %v", pinned)
+	}
+}
+
+func TestPinHard(t *testing.T) {
+	client := newTestClient(t)
+
+	client.SetWithTTL("a", []byte("v"), time.Minute)
+	if err := client.PinHard("a"); err != nil {
+		t.Fatalf("PinHard failed: %v", err)
+	}
+	if _, ok, err := client.TTL("a"); err != nil || ok {
+		t.Errorf("Expected the TTL removed, got ok=%v err=%v", ok, err)
+	}
+
+	client.Expire("a", time.Minute)
+	client.SetWithTTL("a", []byte("v2"), time.Minute)
+	if _, ok, _ := client.TTL("a"); ok {
+		t.Error("Expected later TTLs ignored while hard-pinned")
+	}
+
+	client.Unpin("a")
+	client.Expire("a", time.Minute)
+	if _, ok, _ := client.TTL("a"); !ok {
+		t.Error("Expected TTLs honored once unpinned")
+	}
+}
+
+func TestPinCacheFull(t *testing.T) {
+	client := newTestClient(t, WithMaxEntries(2))
+
+	for _, key := range []string{"a", "b"} {
+		client.Set(key, []byte("v"))
+		client.Pin(key)
+	}
+	if err := client.Set("c", []byte("v")); !errors.Is(err, ErrCacheFull) {
+		t.Fatalf("Expected ErrCacheFull, got %v", err)
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("Expected nothing written, got %v", keys)
+	}
+
+	// Writes that don't add a key still succeed.
+	if err := client.Set("a", []byte("v2")); err != nil {
+		t.Errorf("Expected an overwrite to succeed, got %v", err)
+	}
+	client.Unpin("b")
+	if err := client.Set("c", []byte("v")); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"a", "c"}) {
+		t.Errorf("Expected the unpinned key evicted, got %v", keys)
+	}
+}
+
+func TestPinCaseInsensitive(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithCaseInsensitiveKeys())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("Flag", []byte("v"))
+	if err := client.Pin("FLAG"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if pinned, _ := client.ListPinned(); !slices.Equal(pinned, []string{"Flag"}) {
+		t.Errorf("Expected [Flag] pinned, got %v", pinned)
+	}
+	client.Unpin("flag")
+	if pinned, _ := client.ListPinned(); len(pinned) != 0 {
+		t.Errorf("Expected the pin removed, got %v", pinned)
+	}
+}
+
+func TestPinRename(t *testing.T) {
+	for _, hard := range []bool{false, true} {
+		client := newTestClient(t, WithMaxEntries(2))
+
+		client.Set("a", []byte("v"))
+		pin := client.Pin
+		if hard {
+			pin = client.PinHard
+		}
+		if err := pin("a"); err != nil {
+			t.Fatalf("Pin failed: %v", err)
+		}
+		if err := client.Rename("a", "b"); err != nil {
+			t.Fatalf("Rename failed: %v", err)
+		}
+		if pinned, _ := client.ListPinned(); !slices.Equal(pinned, []string{"b"}) {
+			t.Errorf("hard=%v: expected [b] pinned, got %v", hard, pinned)
+		}
+
+		// The renamed key survives eviction.
+		for _, key := range []string{"c", "d", "e"} {
+			client.Set(key, []byte("v"))
+		}
+		if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"b", "e"}) {
+			t.Errorf("hard=%v: expected the renamed key kept, got %v", hard, keys)
+		}
+		if hard {
+			client.Expire("b", time.Minute)
+			if _, ok, _ := client.TTL("b"); ok {
+				t.Error("Expected TTLs on the renamed key ignored")
+			}
+		}
+
+		// The old name is no longer pinned.
+		client.SetWithTTL("a", []byte("v"), time.Minute)
+		if _, ok, _ := client.TTL("a"); !ok {
+			t.Errorf("hard=%v: expected the old name to keep its TTL", hard)
+		}
+		client.Set("f", []byte("v"))
+		if keys := sortedKeys(t, client); !slices.Equal(keys, []string{"b", "f"}) {
+			t.Errorf("hard=%v: expected the old name evicted, got %v", hard, keys)
+		}
+	}
+}
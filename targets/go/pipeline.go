@@ -0,0 +1,244 @@
+package squeakyv
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Pipeline records Gets, Sets and Deletes to run together, in order, in a
+// single transaction when Exec is called. Create one with
+// CacheClient.Pipeline.
+//
+// A Pipeline can be executed only once, and is not safe for concurrent use.
+type Pipeline struct {
+	client *CacheClient
+	cmds   []pipelineCmd
+	used   bool
+}
+
+// pipelineCmd is a command recorded by a Pipeline.
+type pipelineCmd struct {
+	op    pipelineOp
+	key   string
+	value []byte
+}
+
+// pipelineOp is the kind of a pipelineCmd.
+type pipelineOp int
+
+const (
+	pipelineGet pipelineOp = iota
+	pipelineSet
+	pipelineDelete
+)
+
+// PipelineResult is the outcome of one command of a Pipeline.
+type PipelineResult struct {
+	// Key is the key of the command.
+	Key string
+	// Value is the value read by a Get, or nil for other commands and for
+	// a key without a live value. A present but empty value is a non-nil
+	// empty slice.
+	Value []byte
+	// Err is the error of the command alone, such as an invalid key or a
+	// value that fails its checksum. The other commands are unaffected.
+	Err error
+}
+
+// Pipeline returns an empty Pipeline for the client.
+//
+// Example:
+//
+//	p := client.Pipeline()
+//	p.Set("user:1", []byte("alice"))
+//	p.Delete("user:2")
+//	i := p.Get("user:3")
+//	results, err := p.Exec(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	user3 := results[i].Value
+func (c *CacheClient) Pipeline() *Pipeline {
+	return &Pipeline{client: c}
+}
+
+// Get records a read of key, which sees the writes recorded before it, and
+// returns the index of its result in those returned by Exec.
+func (p *Pipeline) Get(key string) int {
+	return p.add(pipelineCmd{op: pipelineGet, key: key})
+}
+
+// Set records a write of value to key, as CacheClient.Set makes, and returns
+// the index of its result in those returned by Exec. value is copied.
+func (p *Pipeline) Set(key string, value []byte) int {
+	value = bytes.Clone(value)
+	if value == nil {
+		value = []byte{}
+	}
+	return p.add(pipelineCmd{op: pipelineSet, key: key, value: value})
+}
+
+// Delete records a soft delete of key, as CacheClient.Delete makes, and
+// returns the index of its result in those returned by Exec.
+func (p *Pipeline) Delete(key string) int {
+	return p.add(pipelineCmd{op: pipelineDelete, key: key})
+}
+
+// Len returns the number of commands recorded.
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+func (p *Pipeline) add(cmd pipelineCmd) int {
+	p.cmds = append(p.cmds, cmd)
+	return len(p.cmds) - 1
+}
+
+// Exec runs the recorded commands in order in a single transaction and
+// returns one result per command, in the order they were recorded.
+//
+// A command failing on its own, with an invalid key, a value rejected by
+// WithMaxValueSize or a stored value that can't be read back, reports the
+// error in its result and changes nothing, while the others still run. Any
+// other failure rolls back the whole transaction, so that none of the writes
+// are made, and is returned with no results. ctx is checked before the
+// transaction starts and between commands; if it is done, the transaction is
+// rolled back and ctx.Err() returned.
+//
+// A pipeline of Gets alone also runs on a read-only client. Exec returns
+// ErrPipelineUsed if the pipeline has already been executed, and ErrClosed if
+// the client is closed. As with Set, an error from a MirrorSync mirror is
+// returned together with the results, the writes having been made.
+func (p *Pipeline) Exec(ctx context.Context) (results []PipelineResult, err error) {
+	if p.used {
+		return nil, ErrPipelineUsed
+	}
+	p.used = true
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c := p.client
+
+	writes := 0
+	var size int64
+	for _, cmd := range p.cmds {
+		if cmd.op != pipelineGet {
+			writes++
+			size += int64(len(cmd.value))
+		}
+	}
+	n, start := len(p.cmds), c.opStart()
+	var wait time.Duration
+	defer func() {
+		c.logBatchOp(ctx, "pipeline", n, start, int(size), err)
+		c.reportSlow("pipeline", "", n, start, wait)
+	}()
+
+	var db *sql.DB
+	if writes > 0 || c.behind != nil {
+		db, err = c.acquireWrite()
+	} else {
+		db, err = c.acquire()
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	if len(p.cmds) == 0 {
+		return []PipelineResult{}, nil
+	}
+
+	// Encode values before the transaction, as SetMany does.
+	stored := make([][]byte, len(p.cmds))
+	encodeErrs := make([]error, len(p.cmds))
+	for i, cmd := range p.cmds {
+		if cmd.op == pipelineSet {
+			stored[i], encodeErrs[i] = c.encodeStored(cmd.key, cmd.value)
+		}
+	}
+
+	err = c.withTxWaiting(db, &wait, func(tx *sql.Tx) error {
+		results = make([]PipelineResult, len(p.cmds))
+		for i, cmd := range p.cmds {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			results[i] = PipelineResult{Key: cmd.key}
+
+			switch cmd.op {
+			case pipelineGet:
+				value, err := c.pipelineGet(tx, cmd.key)
+				if err != nil {
+					return err
+				}
+				results[i] = value
+			case pipelineSet:
+				if encodeErrs[i] != nil {
+					results[i].Err = encodeErrs[i]
+					continue
+				}
+				if err := insertVersion(tx, p.client.tables, cmd.key, stored[i], sql.NullInt64{}); err != nil {
+					return err
+				}
+			case pipelineDelete:
+				if err := deleteKey(tx, p.client.tables, cmd.key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var mirrored []mirrorWrite
+	for i, cmd := range p.cmds {
+		res := results[i]
+		switch {
+		case cmd.op == pipelineGet:
+			c.stats.recordGet(res.Value, res.Err)
+		case cmd.op == pipelineSet:
+			c.stats.recordSet(1, int64(len(cmd.value)), res.Err)
+			if res.Err == nil {
+				c.notify(cmd.key, WatchSet, cmd.value)
+				mirrored = append(mirrored, mirrorWrite{key: cmd.key, op: WatchSet, value: cmd.value})
+			}
+		case cmd.op == pipelineDelete:
+			c.stats.recordDelete(1, nil)
+			c.notify(cmd.key, WatchDelete, nil)
+			mirrored = append(mirrored, mirrorWrite{key: cmd.key, op: WatchDelete})
+		}
+	}
+	return results, c.mirror(mirrored...)
+}
+
+// pipelineGet runs a pipeline's Get of key inside tx. Errors reading the
+// database are returned, to abort the transaction; a value that fails its
+// checksum or can't be decoded is reported in the result.
+func (c *CacheClient) pipelineGet(tx *sql.Tx, key string) (PipelineResult, error) {
+	var (
+		stored []byte
+		err    error
+	)
+	if c.opts.readOnly {
+		stored, _, err = readLiveVersion(tx, c.tables, key, nowMillis())
+	} else {
+		stored, _, err = getLiveVersion(tx, c.tables, key)
+	}
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		return PipelineResult{Key: key}, nil
+	case errors.Is(err, ErrChecksumMismatch):
+		return PipelineResult{Key: key, Err: err}, nil
+	case err != nil:
+		return PipelineResult{}, err
+	}
+
+	value, err := c.decodeStored(key, stored)
+	return PipelineResult{Key: key, Value: value, Err: err}, nil
+}
@@ -0,0 +1,250 @@
+package squeakyv
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPipeline(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("old", []byte("gone"))
+
+	p := client.Pipeline()
+	set := p.Set("a", []byte("1"))
+	get := p.Get("a")
+	p.Delete("old")
+	deleted := p.Get("old")
+	missing := p.Get("missing")
+	if p.Len() != 5 {
+		t.Errorf("Expected 5 commands, got %d", p.Len())
+	}
+
+	results, err := p.Exec(context.Background())
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("Expected 5 results, got %d", len(results))
+	}
+	if results[set].Key != "a" || results[set].Err != nil {
+		t.Errorf("Expected the Set to succeed, got %+v", results[set])
+	}
+	if string(results[get].Value) != "1" {
+		t.Errorf("Expected the Get to see the earlier Set, got %q", results[get].Value)
+	}
+	if results[deleted].Value != nil || results[deleted].Err != nil {
+		t.Errorf("Expected the Get to see the earlier Delete, got %+v", results[deleted])
+	}
+	if results[missing].Value != nil || results[missing].Err != nil {
+		t.Errorf("Expected a missing key to give nil, got %+v", results[missing])
+	}
+
+	if got, _ := client.Get("a"); string(got) != "1" {
+		t.Errorf("Expected the Set to be committed, got %q", got)
+	}
+	if exists, _ := client.Exists("old"); exists {
+		t.Error("Expected the Delete to be committed")
+	}
+}
+
+func TestPipelineCopiesValues(t *testing.T) {
+	client := newTestClient(t)
+
+	p := client.Pipeline()
+	value := []byte("v1")
+	p.Set("key", value)
+	value[0] = 'X'
+	p.Set("empty", nil)
+	empty := p.Get("empty")
+
+	results, err := p.Exec(context.Background())
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got, _ := client.Get("key"); string(got) != "v1" {
+		t.Errorf("Expected the value as it was recorded, got %q", got)
+	}
+	if results[empty].Value == nil {
+		t.Error("Expected an empty value to be non-nil")
+	}
+}
+
+func TestPipelinePerCommandErrors(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithMaxValueSize(4))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	p := client.Pipeline()
+	big := p.Set("big", []byte(strings.Repeat("x", 10)))
+	invalid := p.Set("", []byte("v"))
+	p.Set("ok", []byte("v"))
+
+	results, err := p.Exec(context.Background())
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if !errors.Is(results[big].Err, ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge, got %v", results[big].Err)
+	}
+	if results[invalid].Err == nil {
+		t.Error("Expected an error for an empty key")
+	}
+	if exists, _ := client.Exists("big"); exists {
+		t.Error("Expected a failed Set to write nothing")
+	}
+	if got, _ := client.Get("ok"); string(got) != "v" {
+		t.Errorf("Expected the other Set to be committed, got %q", got)
+	}
+}
+
+func TestPipelineSingleUse(t *testing.T) {
+	client := newTestClient(t)
+
+	p := client.Pipeline()
+	p.Set("key", []byte("v"))
+	if _, err := p.Exec(context.Background()); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if _, err := p.Exec(context.Background()); !errors.Is(err, ErrPipelineUsed) {
+		t.Errorf("Expected ErrPipelineUsed, got %v", err)
+	}
+
+	results, err := client.Pipeline().Exec(context.Background())
+	if err != nil || len(results) != 0 {
+		t.Errorf("Expected an empty pipeline to do nothing, got %v, %v", results, err)
+	}
+}
+
+func TestPipelineClosed(t *testing.T) {
+	client, err := NewCacheClient(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	p := client.Pipeline()
+	p.Get("key")
+	client.Close()
+
+	if _, err := p.Exec(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestPipelineCancelled(t *testing.T) {
+	client := newTestClient(t)
+
+	p := client.Pipeline()
+	p.Set("key", []byte("v"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Exec(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if exists, _ := client.Exists("key"); exists {
+		t.Error("Expected nothing written")
+	}
+}
+
+func TestPipelineAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	client, err := NewCacheClient(path, WithBusyTimeout(time.Millisecond), WithRetry(1, time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	release := lockDatabase(t, path)
+	p := client.Pipeline()
+	p.Set("a", []byte("1"))
+	p.Set("b", []byte("2"))
+	if results, err := p.Exec(context.Background()); err == nil || results != nil {
+		t.Fatalf("Expected Exec to fail while the database is locked, got %v, %v", results, err)
+	}
+	release()
+
+	if n := storedCount(t, client); n != 0 {
+		t.Errorf("Expected no writes from a failed pipeline, got %d keys", n)
+	}
+}
+
+func TestPipelineReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	newTestClientAt(t, path).Set("key", []byte("v"))
+
+	client, err := NewCacheClient(path, WithReadOnly())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	p := client.Pipeline()
+	get := p.Get("key")
+	results, err := p.Exec(context.Background())
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if string(results[get].Value) != "v" {
+		t.Errorf("Expected %q, got %q", "v", results[get].Value)
+	}
+
+	p = client.Pipeline()
+	p.Set("key", []byte("w"))
+	if _, err := p.Exec(context.Background()); err == nil {
+		t.Error("Expected a write to fail on a read-only client")
+	}
+}
+
+func TestPipelineNotifies(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithMemoryCache(100, 0))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("key", []byte("v1"))
+	client.Get("key")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.Watch(ctx, "key")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	p := client.Pipeline()
+	p.Set("key", []byte("v2"))
+	if _, err := p.Exec(context.Background()); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got, _ := client.Get("key"); string(got) != "v2" {
+		t.Errorf("Expected the cached value to be invalidated, got %q", got)
+	}
+	select {
+	case ev := <-events:
+		if ev.Op != WatchSet || string(ev.Value) != "v2" {
+			t.Errorf("Expected a set event, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected an event for the pipeline's Set")
+	}
+}
+
+func TestPipelineWriteBehind(t *testing.T) {
+	client := newTestClient(t, WithWriteBehind(100, time.Hour))
+
+	client.Set("key", []byte("buffered"))
+	p := client.Pipeline()
+	get := p.Get("key")
+	results, err := p.Exec(context.Background())
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if string(results[get].Value) != "buffered" {
+		t.Errorf("Expected the pipeline to see the buffered write, got %q", results[get].Value)
+	}
+}
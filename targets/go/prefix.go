@@ -0,0 +1,91 @@
+package squeakyv
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// prefixCondition restricts a query on kv to keys starting with a prefix. It
+// expects the arguments returned by prefixArgs.
+//
+// LIKE narrows the scan but is case-insensitive for ASCII in SQLite, so the
+// substr comparison enforces an exact, case-sensitive match.
+const prefixCondition = `key LIKE ? ESCAPE '\' AND substr(key, 1, ?) = ?`
+
+// prefixArgs returns the arguments for prefixCondition.
+func prefixArgs(prefix string) []interface{} {
+	return []interface{}{escapeLike(prefix) + "%", utf8.RuneCountInString(prefix), prefix}
+}
+
+// likeEscaper escapes the LIKE metacharacters %, _ and the escape character itself.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike escapes s for literal use in a LIKE pattern with ESCAPE '\'.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// ListKeysWithPrefix returns all active, unexpired keys that start with
+// prefix, ordered by insertion time (newest first).
+//
+// Matching is exact and case-sensitive; % and _ in prefix have no special
+// meaning. An empty prefix returns the same keys as ListKeys.
+//
+// Example:
+//
+//	keys, err := client.ListKeysWithPrefix("user:123:")
+func (c *CacheClient) ListKeysWithPrefix(prefix string) ([]string, error) {
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	if prefix == "" {
+		return listLiveKeys(db, c.tables, nowMillis())
+	}
+	return listLiveKeysWithPrefix(db, c.tables, prefix)
+}
+
+// listLiveKeysWithPrefix returns active, unexpired keys starting with prefix, newest first.
+func listLiveKeysWithPrefix(db querier, t tableNames, prefix string) ([]string, error) {
+	query := `SELECT key
+FROM ` + t.kv + `
+WHERE ` + prefixCondition + ` AND ` + liveCondition + `
+ORDER BY ` + lastWritten + ` DESC;`
+
+	return queryStrings(db, query, append(prefixArgs(prefix), nowMillis())...)
+}
+
+// DeletePrefix soft-deletes every active, unexpired key that starts with
+// prefix in a single statement and returns the number of keys deleted.
+//
+// Matching follows the same rules as ListKeysWithPrefix, so an empty prefix
+// deletes every key.
+//
+// Example:
+//
+//	n, err := client.DeletePrefix("tenant:42:")
+func (c *CacheClient) DeletePrefix(prefix string) (int64, error) {
+	query := `UPDATE ` + c.tables.kv + `
+SET is_active = 0
+WHERE ` + prefixCondition + ` AND ` + liveCondition + `;`
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+	defer c.mem.purge()
+
+	result, err := c.exec(db, query, append(prefixArgs(prefix), nowMillis())...)
+	if err != nil {
+		return 0, fmt.Errorf("exec failed: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected failed: %w", err)
+	}
+	return n, nil
+}
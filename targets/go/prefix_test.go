@@ -0,0 +1,123 @@
+package squeakyv
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestListKeysWithPrefix(t *testing.T) {
+	client := newTestClient(t)
+
+	for _, key := range []string{"user:1:profile", "user:2:profile", "users", "group:1", "User:3"} {
+		if err := client.Set(key, []byte("value")); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	keys, err := client.ListKeysWithPrefix("user:")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	sort.Strings(keys)
+	expected := []string{"user:1:profile", "user:2:profile"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+}
+
+func TestListKeysWithPrefixEscaping(t *testing.T) {
+	client := newTestClient(t)
+
+	for _, key := range []string{"a_b:1", "axb:1", "50%:1", "50x:1", `c\d:1`, `c\\d:1`} {
+		if err := client.Set(key, []byte("value")); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	cases := map[string][]string{
+		"a_b": {"a_b:1"},
+		"50%": {"50%:1"},
+		`c\d`: {`c\d:1`},
+	}
+	for prefix, expected := range cases {
+		keys, err := client.ListKeysWithPrefix(prefix)
+		if err != nil {
+			t.Fatalf("Failed to list keys for %q: %v", prefix, err)
+		}
+		if !reflect.DeepEqual(keys, expected) {
+			t.Errorf("Prefix %q: expected %v, got %v", prefix, expected, keys)
+		}
+	}
+}
+
+func TestListKeysWithPrefixFiltersInactive(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("ns:live", []byte("value"))
+	client.Set("ns:deleted", []byte("value"))
+	client.Delete("ns:deleted")
+	client.SetWithTTL("ns:expired", []byte("value"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	keys, err := client.ListKeysWithPrefix("ns:")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"ns:live"}) {
+		t.Errorf("Expected only ns:live, got %v", keys)
+	}
+}
+
+func TestListKeysWithEmptyPrefix(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("value"))
+	client.Set("b", []byte("value"))
+
+	all, err := client.ListKeys()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	keys, err := client.ListKeysWithPrefix("")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if !reflect.DeepEqual(keys, all) {
+		t.Errorf("Expected %v, got %v", all, keys)
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("tenant_42:a", []byte("1"))
+	client.Set("tenant_42:b", []byte("2"))
+	client.Set("tenant_42:c", []byte("3"))
+	client.Delete("tenant_42:c")
+	client.Set("tenantX42:a", []byte("4")) // would match an unescaped _
+	client.Set("other", []byte("5"))
+
+	n, err := client.DeletePrefix("tenant_42:")
+	if err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 keys deleted, got %d", n)
+	}
+
+	keys, err := client.ListKeysWithPrefix("tenant_42:")
+	if err != nil {
+		t.Fatalf("ListKeysWithPrefix failed: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Expected no keys left under prefix, got %v", keys)
+	}
+
+	keys, _ = client.ListKeys()
+	sort.Strings(keys)
+	if expected := []string{"other", "tenantX42:a"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+}
@@ -0,0 +1,75 @@
+//go:build squeakyv_protobuf
+
+package squeakyv
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtoCodec is a Codec for protocol buffer messages, using proto.Marshal and
+// proto.Unmarshal. Values passed to it must implement proto.Message.
+//
+// It is only built with the squeakyv_protobuf tag, so that builds without it
+// don't compile the protobuf module, though go.mod requires it.
+type ProtoCodec struct{}
+
+// Marshal implements Codec.
+func (ProtoCodec) Marshal(v any) ([]byte, error) {
+	m, err := protoMessage(v)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal implements Codec.
+func (ProtoCodec) Unmarshal(data []byte, v any) error {
+	m, err := protoMessage(v)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// protoMessage returns v as a proto.Message, failing for nil messages, which
+// cannot be told apart from empty ones once serialized.
+func protoMessage(v any) (proto.Message, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a proto.Message", v)
+	}
+	if m == nil || !m.ProtoReflect().IsValid() {
+		return nil, fmt.Errorf("nil %T message", v)
+	}
+	return m, nil
+}
+
+// SetProto stores the serialization of m for a key. m must not be nil. A
+// message with every field at its default serializes to an empty value,
+// which is stored as such and read back by GetProto as present.
+//
+// Example:
+//
+//	err := client.SetProto("user:1", &pb.User{Name: "alice"})
+func (c *CacheClient) SetProto(key string, m proto.Message) error {
+	return c.setEncoded(ProtoCodec{}, key, m)
+}
+
+// GetProto unmarshals the value for a key into m, which must be a non-nil
+// message, and reports whether the key exists. A missing, deleted or expired
+// key returns false and a nil error, leaving m untouched; an empty stored
+// value returns true and resets m to its defaults. A value that fails to
+// unmarshal returns true and a *DecodeError naming the key.
+//
+// Example:
+//
+//	var user pb.User
+//	found, err := client.GetProto("user:1", &user)
+func (c *CacheClient) GetProto(key string, m proto.Message) (bool, error) {
+	if _, err := protoMessage(m); err != nil {
+		return false, fmt.Errorf("failed to decode value of key %q: %w", key, err)
+	}
+	return c.getDecoded(ProtoCodec{}, key, m)
+}
@@ -0,0 +1,92 @@
+//go:build squeakyv_protobuf
+
+package squeakyv
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestSetGetProto(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.SetProto("key", wrapperspb.String("hello")); err != nil {
+		t.Fatalf("SetProto failed: %v", err)
+	}
+
+	got := &wrapperspb.StringValue{}
+	found, err := client.GetProto("key", got)
+	if err != nil || !found {
+		t.Fatalf("GetProto failed: found=%v err=%v", found, err)
+	}
+	if got.GetValue() != "hello" {
+		t.Errorf("Expected hello, got %q", got.GetValue())
+	}
+}
+
+func TestProtoEmptyMessage(t *testing.T) {
+	client := newTestClient(t)
+
+	// A default message serializes to no bytes but is still a stored value.
+	if err := client.SetProto("key", wrapperspb.String("")); err != nil {
+		t.Fatalf("SetProto failed: %v", err)
+	}
+	if exists, _ := client.Exists("key"); !exists {
+		t.Fatal("Expected empty message to be stored")
+	}
+
+	got := wrapperspb.String("stale")
+	found, err := client.GetProto("key", got)
+	if err != nil || !found {
+		t.Fatalf("GetProto failed: found=%v err=%v", found, err)
+	}
+	if got.GetValue() != "" {
+		t.Errorf("Expected message reset to defaults, got %q", got.GetValue())
+	}
+}
+
+func TestProtoMissing(t *testing.T) {
+	client := newTestClient(t)
+
+	got := wrapperspb.String("unchanged")
+	found, err := client.GetProto("missing", got)
+	if err != nil || found {
+		t.Fatalf("Expected false, nil, got %v, %v", found, err)
+	}
+	if got.GetValue() != "unchanged" {
+		t.Errorf("Expected message untouched, got %q", got.GetValue())
+	}
+}
+
+func TestProtoNilMessage(t *testing.T) {
+	client := newTestClient(t)
+
+	var nilValue *wrapperspb.StringValue
+	if err := client.SetProto("key", nilValue); err == nil {
+		t.Error("Expected SetProto to reject a nil message")
+	}
+	if err := client.SetProto("key", nil); err == nil {
+		t.Error("Expected SetProto to reject a nil interface")
+	}
+	if exists, _ := client.Exists("key"); exists {
+		t.Error("Expected nothing written")
+	}
+
+	client.SetProto("key", wrapperspb.String("hello"))
+	if _, err := client.GetProto("key", nilValue); err == nil {
+		t.Error("Expected GetProto to reject a nil message")
+	}
+}
+
+func TestProtoInvalid(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("key", []byte{0xff, 0xff, 0xff})
+
+	found, err := client.GetProto("key", &wrapperspb.StringValue{})
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Key != "key" || !found {
+		t.Errorf("Expected *DecodeError for key, got found=%v err=%v", found, err)
+	}
+}
@@ -0,0 +1,106 @@
+package squeakyv
+
+import (
+	"fmt"
+	"time"
+)
+
+// PruneVersions physically deletes all but the newest keep inactive versions
+// of a key and returns the number of rows removed. The active version is never
+// pruned, even when keep is zero. Changes older than WithChangeRetention are
+// pruned from the change feed too.
+//
+// Example:
+//
+//	n, err := client.PruneVersions("config", 10)
+func (c *CacheClient) PruneVersions(key string, keep int) (int64, error) {
+	if keep < 0 {
+		return 0, fmt.Errorf("invalid keep %d: must not be negative", keep)
+	}
+
+	query := `DELETE FROM ` + c.tables.kv + `
+WHERE key = ? AND is_active = 0 AND rowid NOT IN (
+  SELECT rowid FROM ` + c.tables.kv + `
+  WHERE key = ? AND is_active = 0
+  ORDER BY rowid DESC
+  LIMIT ?
+);`
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	result, err := c.exec(db, query, key, key, keep)
+	if err != nil {
+		return 0, fmt.Errorf("exec failed: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected failed: %w", err)
+	}
+	return n, c.pruneOldChanges(db)
+}
+
+// PruneAllVersions applies PruneVersions to every key in the database and
+// returns the total number of rows removed, and prunes the change feed as
+// PruneVersions does.
+//
+// Rows are deleted in batches (see WithSweepBatchSize), each in its own
+// transaction, so concurrent writers are never blocked for long.
+func (c *CacheClient) PruneAllVersions(keep int) (int64, error) {
+	if keep < 0 {
+		return 0, fmt.Errorf("invalid keep %d: must not be negative", keep)
+	}
+
+	query := `DELETE FROM ` + c.tables.kv + `
+WHERE rowid IN (
+  SELECT rowid FROM (
+    SELECT rowid, ROW_NUMBER() OVER (PARTITION BY key ORDER BY rowid DESC) AS rank
+    FROM ` + c.tables.kv + `
+    WHERE is_active = 0
+  )
+  WHERE rank > ?
+  LIMIT ?
+);`
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	n, err := c.deleteInBatches(db, query, keep)
+	if err != nil {
+		return n, err
+	}
+	return n, c.pruneOldChanges(db)
+}
+
+// PruneOlderThan physically deletes every inactive version written before
+// cutoff, across all keys, and returns the number of rows removed.
+//
+// Active versions are never removed, however old, and a deleted key may be
+// left with no history at all. Rows are deleted in batches as in
+// PruneAllVersions.
+//
+// Example:
+//
+//	n, err := client.PruneOlderThan(time.Now().AddDate(0, 0, -90))
+func (c *CacheClient) PruneOlderThan(cutoff time.Time) (int64, error) {
+	query := `DELETE FROM ` + c.tables.kv + `
+WHERE rowid IN (
+  SELECT rowid FROM ` + c.tables.kv + `
+  WHERE is_active = 0 AND inserted_at < ?
+  LIMIT ?
+);`
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	return c.deleteInBatches(db, query, cutoff.UnixMilli())
+}
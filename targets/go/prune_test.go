@@ -0,0 +1,129 @@
+package squeakyv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPruneVersions(t *testing.T) {
+	client := newTestClient(t)
+
+	for _, v := range []string{"v1", "v2", "v3", "v4", "v5"} {
+		client.Set("key", []byte(v))
+	}
+	client.Set("other", []byte("a"))
+	client.Set("other", []byte("b"))
+
+	n, err := client.PruneVersions("key", 2)
+	if err != nil {
+		t.Fatalf("PruneVersions failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 rows removed, got %d", n)
+	}
+
+	versions, _ := client.History("key")
+	expected := []string{"v5", "v4", "v3"}
+	if len(versions) != len(expected) {
+		t.Fatalf("Expected %d versions, got %d", len(expected), len(versions))
+	}
+	for i, v := range versions {
+		if string(v.Value) != expected[i] {
+			t.Errorf("Version %d: expected %s, got %s", i, expected[i], v.Value)
+		}
+	}
+
+	// Other keys are untouched
+	if n := countRows(t, client, "other"); n != 2 {
+		t.Errorf("Expected 2 rows for other, got %d", n)
+	}
+}
+
+func TestPruneVersionsKeepsActive(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("v1"))
+	client.Set("key", []byte("v2"))
+
+	if _, err := client.PruneVersions("key", 0); err != nil {
+		t.Fatalf("PruneVersions failed: %v", err)
+	}
+	value, _ := client.Get("key")
+	if string(value) != "v2" {
+		t.Errorf("Expected active version to survive, got %s", value)
+	}
+	if n := countRows(t, client, "key"); n != 1 {
+		t.Errorf("Expected 1 row, got %d", n)
+	}
+
+	if _, err := client.PruneVersions("key", -1); err == nil {
+		t.Error("Expected error for negative keep")
+	}
+}
+
+func TestPruneAllVersions(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithSweepBatchSize(3))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 5; i++ {
+		client.Set("a", []byte{byte(i)})
+		client.Set("b", []byte{byte(i)})
+	}
+	client.Delete("b")
+
+	n, err := client.PruneAllVersions(1)
+	if err != nil {
+		t.Fatalf("PruneAllVersions failed: %v", err)
+	}
+	// a: 4 inactive, keep 1; b: 5 inactive, keep 1
+	if n != 7 {
+		t.Errorf("Expected 7 rows removed, got %d", n)
+	}
+	if rows := countRows(t, client, "a"); rows != 2 {
+		t.Errorf("Expected 2 rows for a, got %d", rows)
+	}
+	if rows := countRows(t, client, "b"); rows != 1 {
+		t.Errorf("Expected 1 row for b, got %d", rows)
+	}
+}
+
+func TestPruneOlderThan(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("old1"))
+	client.Set("key", []byte("old2"))
+	client.Set("gone", []byte("old"))
+	client.Delete("gone")
+	client.Set("stale", []byte("active but old"))
+
+	time.Sleep(5 * time.Millisecond)
+	cutoff := time.Now()
+	time.Sleep(5 * time.Millisecond)
+
+	client.Set("key", []byte("new1"))
+	client.Set("key", []byte("new2"))
+
+	n, err := client.PruneOlderThan(cutoff)
+	if err != nil {
+		t.Fatalf("PruneOlderThan failed: %v", err)
+	}
+	// old1, old2 and the deleted "gone" row
+	if n != 3 {
+		t.Errorf("Expected 3 rows removed, got %d", n)
+	}
+
+	versions, _ := client.History("key")
+	if len(versions) != 2 || string(versions[1].Value) != "new1" {
+		t.Errorf("Expected only versions newer than cutoff, got %v", versions)
+	}
+	if versions, _ := client.History("gone"); len(versions) != 0 {
+		t.Errorf("Expected deleted key to have no history, got %v", versions)
+	}
+	value, _ := client.Get("stale")
+	if string(value) != "active but old" {
+		t.Errorf("Expected old active version to survive, got %s", value)
+	}
+}
@@ -0,0 +1,174 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// The queries of the YesQL specification, written by hand for the client's
+// table and extended with Go-only columns such as expires_at. operations.go
+// embeds only the generated schema.
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so every query below can
+// run standalone or inside a transaction.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// getLiveVersion returns the current value for key and its expiry, which is
+// invalid (NULL) if it never expires, or an error wrapping ErrKeyNotFound if
+// the key is absent, or ErrChecksumMismatch if its value is corrupt. Present
+// values are never nil.
+//
+// An active version whose expiry has passed is treated as absent and is
+// soft-deleted on the spot, so ListKeys and later reads agree with this one.
+func getLiveVersion(db querier, t tableNames, key string) ([]byte, sql.NullInt64, error) {
+	query := `SELECT rowid, value, expires_at, checksum
+FROM ` + t.kv + `
+WHERE key = ? AND is_active = 1;`
+
+	var (
+		version   int64
+		value     []byte
+		expiresAt sql.NullInt64
+		checksum  sql.NullInt64
+	)
+	err := db.QueryRow(query, key).Scan(&version, &value, &expiresAt, &checksum)
+	if err == sql.ErrNoRows {
+		return nil, sql.NullInt64{}, keyNotFound(key)
+	}
+	if err != nil {
+		return nil, sql.NullInt64{}, fmt.Errorf("query failed: %w", err)
+	}
+
+	now := nowMillis()
+	if expiresAt.Valid && expiresAt.Int64 <= now {
+		if err := expireKey(db, t, key, now); err != nil {
+			return nil, sql.NullInt64{}, err
+		}
+		return nil, sql.NullInt64{}, &expiredError{key: key}
+	}
+	if err := verifyChecksum(key, version, value, checksum); err != nil {
+		return nil, sql.NullInt64{}, err
+	}
+	if value == nil {
+		// The driver scans an empty BLOB as nil
+		value = []byte{}
+	}
+	return value, expiresAt, nil
+}
+
+// readLiveValue returns the value of key if it is active and unexpired at
+// now, or an error wrapping ErrKeyNotFound, or ErrChecksumMismatch if the
+// value is corrupt. Unlike getLiveVersion it never
+// writes, so it is safe inside read-only transactions.
+func readLiveValue(db querier, t tableNames, key string, now int64) ([]byte, error) {
+	value, _, err := readLiveVersion(db, t, key, now)
+	return value, err
+}
+
+// readLiveVersion is like readLiveValue but also returns the version's expiry,
+// which is invalid (NULL) if the version never expires.
+func readLiveVersion(db querier, t tableNames, key string, now int64) ([]byte, sql.NullInt64, error) {
+	query := `SELECT rowid, value, expires_at, checksum
+FROM ` + t.kv + `
+WHERE key = ? AND ` + liveCondition + `;`
+
+	var (
+		version   int64
+		value     []byte
+		expiresAt sql.NullInt64
+		checksum  sql.NullInt64
+	)
+	err := db.QueryRow(query, key, now).Scan(&version, &value, &expiresAt, &checksum)
+	if err == sql.ErrNoRows {
+		return nil, sql.NullInt64{}, keyNotFound(key)
+	}
+	if err != nil {
+		return nil, sql.NullInt64{}, fmt.Errorf("query failed: %w", err)
+	}
+	if err := verifyChecksum(key, version, value, checksum); err != nil {
+		return nil, sql.NullInt64{}, err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, expiresAt, nil
+}
+
+// insertVersion writes a new version of key with the given expiry (NULL for
+// none); the kv_swap_active trigger retires the previous version.
+func insertVersion(db querier, t tableNames, key string, value []byte, expiresAt sql.NullInt64) error {
+	query := `INSERT INTO ` + t.kv + ` (key, value, expires_at, checksum)
+VALUES (?, ?, ?, ?);`
+
+	if _, err := db.Exec(query, key, value, expiresAt, checksumOf(value)); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
+
+// deleteKey soft-deletes the active version of key, if any. It is the
+// delete_key query of the YesQL, run against the client's table.
+func deleteKey(db querier, t tableNames, key string) error {
+	query := `UPDATE ` + t.kv + `
+SET is_active = 0
+WHERE key = ? AND is_active = 1;`
+
+	if _, err := db.Exec(query, key); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
+
+// expireKey soft-deletes the active version of key if it expired at or before now.
+//
+// The expiry condition is re-checked in the UPDATE so that a fresh version
+// written concurrently is never retired, and repeating the call is harmless.
+func expireKey(db querier, t tableNames, key string, now int64) error {
+	query := `UPDATE ` + t.kv + `
+SET is_active = 0
+WHERE key = ? AND is_active = 1 AND expires_at IS NOT NULL AND expires_at <= ?;`
+
+	if _, err := db.Exec(query, key, now); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
+
+// listLiveKeys returns all keys active and unexpired at now, newest first.
+func listLiveKeys(db querier, t tableNames, now int64) ([]string, error) {
+	query := `SELECT key
+FROM ` + t.kv + `
+WHERE ` + liveCondition + `
+ORDER BY ` + lastWritten + ` DESC;`
+
+	return queryStrings(db, query, now)
+}
+
+// queryStrings runs a query selecting a single text column and returns the
+// values in row order.
+func queryStrings(db querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var results []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		results = append(results, value)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,123 @@
+package squeakyv
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newReadOnlyFixture writes a small database at a fresh path, closes it, and
+// returns the path.
+func newReadOnlyFixture(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Set("key", []byte("value"))
+	client.SetWithTTL("expired", []byte("gone"), time.Millisecond)
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	return path
+}
+
+func TestReadOnlyLeavesFileUntouched(t *testing.T) {
+	path := newReadOnlyFixture(t)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read database file: %v", err)
+	}
+
+	client, err := NewCacheClient(path, WithReadOnly())
+	if err != nil {
+		t.Fatalf("Failed to open read-only client: %v", err)
+	}
+
+	if value, err := client.Get("key"); err != nil || string(value) != "value" {
+		t.Errorf("Expected value, got %q, %v", value, err)
+	}
+	// Reading an expired key must not soft-delete it
+	if value, err := client.Get("expired"); err != nil || value != nil {
+		t.Errorf("Expected expired key to read as missing, got %q, %v", value, err)
+	}
+
+	if err := client.Set("key", []byte("rogue")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Set, got %v", err)
+	}
+	if err := client.Delete("key"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Delete, got %v", err)
+	}
+	if _, err := client.Increment("n", 1); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Increment, got %v", err)
+	}
+	if err := client.WithTransaction(func(tx *Tx) error { return nil }); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from WithTransaction, got %v", err)
+	}
+	if err := client.Clear(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from Clear, got %v", err)
+	}
+	if _, err := client.SweepNow(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from SweepNow, got %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read database file: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("Expected database file to be byte-identical after read-only use")
+	}
+}
+
+func TestReadOnlyMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.db")
+
+	if _, err := NewCacheClient(path, WithReadOnly()); !errors.Is(err, ErrDatabaseNotExist) {
+		t.Errorf("Expected a not-exist error, got %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected read-only open not to create the file")
+	}
+
+	if _, err := NewCacheClient(":memory:", WithReadOnly()); err == nil {
+		t.Error("Expected read-only :memory: to be rejected")
+	}
+}
+
+func TestReadOnlyUninitializedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.db")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	if _, err := NewCacheClient(path, WithReadOnly()); err == nil {
+		t.Error("Expected an uninitialized database to be rejected")
+	}
+}
+
+func TestImmutable(t *testing.T) {
+	path := newReadOnlyFixture(t)
+
+	client, err := NewCacheClient(path, WithImmutable())
+	if err != nil {
+		t.Fatalf("Failed to open immutable client: %v", err)
+	}
+	defer client.Close()
+
+	if value, _ := client.Get("key"); string(value) != "value" {
+		t.Errorf("Expected value, got %q", value)
+	}
+	if err := client.Set("key", nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
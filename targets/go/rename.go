@@ -0,0 +1,196 @@
+package squeakyv
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+)
+
+// Rename moves a key, together with its entire version history, to a new
+// name in a single transaction. Afterwards oldKey behaves as if it had never
+// been written, and the history of newKey includes every version written
+// under oldKey.
+//
+// Returns an error wrapping ErrKeyNotFound if oldKey has no live value, and
+// one wrapping ErrKeyExists if newKey already has a live value. If newKey has
+// only history (it was deleted or expired), the two histories are merged.
+// With WithCaseInsensitiveKeys, renaming a key to a different casing of
+// itself changes the casing of every version. A pin set by Pin or PinHard
+// moves to newKey.
+//
+// Example:
+//
+//	err := client.Rename("user:123", "user:v2:123")
+func (c *CacheClient) Rename(oldKey, newKey string) error {
+	if oldKey == newKey {
+		return nil
+	}
+	if err := c.validateKey(newKey); err != nil {
+		return err
+	}
+	recase := c.opts.caseInsensitiveKeys && foldKey(oldKey) == foldKey(newKey)
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+	defer c.invalidate(oldKey, newKey)
+
+	return c.withTx(db, func(tx *sql.Tx) error {
+		now := nowMillis()
+
+		exists, err := liveKeyExists(tx, c.tables, oldKey, now)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return keyNotFound(oldKey)
+		}
+
+		if !recase {
+			exists, err = liveKeyExists(tx, c.tables, newKey, now)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return keyExists(newKey)
+			}
+			// Retire an expired but still active version of newKey so the
+			// moved active row doesn't collide with it.
+			if err := expireKey(tx, c.tables, newKey, now); err != nil {
+				return err
+			}
+		}
+
+		if err := c.resealVersions(tx, oldKey, newKey); err != nil {
+			return err
+		}
+		query := `UPDATE ` + c.tables.kv + `
+SET key = ?
+WHERE key = ?;`
+		if _, err := tx.Exec(query, newKey, oldKey); err != nil {
+			return fmt.Errorf("exec failed: %w", err)
+		}
+
+		// The pin, stored by key name, moves with the key.
+		query = `UPDATE OR REPLACE ` + c.tables.pins + `
+SET key = ?
+WHERE key = ?;`
+		if _, err := tx.Exec(query, c.watchKey(newKey), c.watchKey(oldKey)); err != nil {
+			return fmt.Errorf("exec failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// resealVersions moves the versions of oldKey whose encrypted values are
+// bound to it to newKey, re-encrypted for newKey. Rename moves the others.
+func (c *CacheClient) resealVersions(tx *sql.Tx, oldKey, newKey string) error {
+	if !c.usesEnvelopes() {
+		return nil
+	}
+
+	query := `SELECT rowid, value
+FROM ` + c.tables.kv + `
+WHERE key = ? AND substr(CAST(value AS BLOB), 1, 4) = ` + valueMagicHex + `;`
+	rows, err := tx.Query(query, oldKey)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	type version struct {
+		id    int64
+		value []byte
+	}
+	var moved []version
+	for rows.Next() {
+		var v version
+		if err := rows.Scan(&v.id, &v.value); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		sealed, err := c.resealStored(oldKey, newKey, v.value)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if !bytes.Equal(sealed, v.value) {
+			moved = append(moved, version{id: v.id, value: sealed})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	query = `UPDATE ` + c.tables.kv + `
+SET key = ?, value = ?, checksum = ?
+WHERE rowid = ?;`
+	for _, v := range moved {
+		if _, err := tx.Exec(query, newKey, v.value, checksumOf(v.value), v.id); err != nil {
+			return fmt.Errorf("exec failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// Copy writes the current value of src to dst as a new version, following the
+// same rules as Set: any existing version of dst is retired into its history.
+// The copy never expires, even if src does.
+//
+// The value is copied inside SQLite in a single statement, so large values
+// never pass through Go and the copy is atomic. Values in a database with
+// envelopes (see WithCompressor) are instead copied in a transaction, since
+// encrypted values must be re-encrypted for dst. Returns an error wrapping
+// ErrKeyNotFound if src has no live value.
+//
+// Example:
+//
+//	err := client.Copy("config:staging", "config:prod")
+func (c *CacheClient) Copy(src, dst string) error {
+	query := `INSERT INTO ` + c.tables.kv + ` (key, value, checksum)
+SELECT ?, value, checksum
+FROM ` + c.tables.kv + `
+WHERE key = ? AND ` + liveCondition + `;`
+
+	if err := c.validateKey(dst); err != nil {
+		return err
+	}
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+	defer c.invalidate(dst)
+
+	if c.usesEnvelopes() {
+		return c.withTx(db, func(tx *sql.Tx) error {
+			stored, err := readLiveValue(tx, c.tables, src, nowMillis())
+			if err != nil {
+				return err
+			}
+			if stored, err = c.resealStored(src, dst, stored); err != nil {
+				return err
+			}
+			return insertVersion(tx, c.tables, dst, stored, sql.NullInt64{})
+		})
+	}
+
+	var result sql.Result
+	err = c.writeEvicting(db, nil, func(db querier) error {
+		result, err = db.Exec(query, dst, src, nowMillis())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected failed: %w", err)
+	}
+	if n == 0 {
+		return keyNotFound(src)
+	}
+	return nil
+}
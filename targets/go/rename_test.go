@@ -0,0 +1,164 @@
+package squeakyv
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRename(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("old", []byte("v1"))
+	client.Set("old", []byte("v2"))
+
+	if err := client.Rename("old", "new"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	value, err := client.Get("new")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value) != "v2" {
+		t.Errorf("Expected v2 under new key, got %s", value)
+	}
+	if value, _ := client.Get("old"); value != nil {
+		t.Errorf("Expected old key to be gone, got %s", value)
+	}
+
+	info, err := client.Stat("new")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Versions != 2 {
+		t.Errorf("Expected history to move with the key, got %d versions", info.Versions)
+	}
+	versions, err := client.History("new")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(versions) != 2 || string(versions[1].Value) != "v1" {
+		t.Errorf("Expected history of new key to include v1, got %v", versions)
+	}
+	if _, err := client.Stat("old"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected no history left under old key, got %v", err)
+	}
+}
+
+func TestRenameErrors(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.Rename("missing", "new"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	client.Set("a", []byte("a"))
+	client.Set("b", []byte("b"))
+	if err := client.Rename("a", "b"); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("Expected ErrKeyExists, got %v", err)
+	}
+
+	// Nothing moved on failure
+	value, _ := client.Get("a")
+	if string(value) != "a" {
+		t.Errorf("Expected a to be untouched, got %s", value)
+	}
+}
+
+func TestRenameOntoDeletedOrExpiredKey(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("deleted", []byte("old"))
+	client.Delete("deleted")
+	client.SetWithTTL("expired", []byte("old"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	client.Set("src1", []byte("new"))
+	client.Set("src2", []byte("new"))
+
+	if err := client.Rename("src1", "deleted"); err != nil {
+		t.Fatalf("Rename onto deleted key failed: %v", err)
+	}
+	if err := client.Rename("src2", "expired"); err != nil {
+		t.Fatalf("Rename onto expired key failed: %v", err)
+	}
+
+	for _, key := range []string{"deleted", "expired"} {
+		value, _ := client.Get(key)
+		if string(value) != "new" {
+			t.Errorf("Expected %s to hold new value, got %s", key, value)
+		}
+		info, err := client.Stat(key)
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if info.Versions != 2 {
+			t.Errorf("Expected merged history for %s, got %d versions", key, info.Versions)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("staging", []byte("new config"))
+	client.Set("prod", []byte("old config"))
+
+	if err := client.Copy("staging", "prod"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	for _, key := range []string{"staging", "prod"} {
+		value, _ := client.Get(key)
+		if string(value) != "new config" {
+			t.Errorf("Expected %s to hold new config, got %s", key, value)
+		}
+	}
+
+	info, err := client.Stat("prod")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Versions != 2 {
+		t.Errorf("Expected overwritten value kept as history, got %d versions", info.Versions)
+	}
+
+	if err := client.Copy("missing", "prod"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestCopyOntoItself(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("value"))
+	if err := client.Copy("key", "key"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	value, _ := client.Get("key")
+	if string(value) != "value" {
+		t.Errorf("Expected value, got %s", value)
+	}
+	if n := countRows(t, client, "key"); n != 2 {
+		t.Errorf("Expected 2 versions, got %d", n)
+	}
+}
+
+func TestCopyDropsExpiry(t *testing.T) {
+	client := newTestClient(t)
+
+	client.SetWithTTL("src", []byte("value"), time.Hour)
+	if err := client.Copy("src", "dst"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	_, ok, err := client.TTL("dst")
+	if err != nil {
+		t.Fatalf("TTL failed: %v", err)
+	}
+	if ok {
+		t.Error("Expected copy to have no expiry")
+	}
+}
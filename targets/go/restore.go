@@ -0,0 +1,271 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// restoreSchema is the name the backup database is attached under by Restore.
+const restoreSchema = "squeakyv_restore"
+
+// restoreTables lists the Go-only tables Restore replaces along with kv,
+// emptied when the backup was written by another language target. They are
+// copied after kv, whose triggers would otherwise clear the restored pins and
+// tombstones.
+func restoreTables(t tableNames) []struct{ name, columns string } {
+	return []struct{ name, columns string }{
+		{t.pins, "key, hard"},
+		{t.tombstones, "key, expires_at"},
+		{t.meta, "name, value"},
+	}
+}
+
+// restoreColumns lists the kv columns Restore copies. Required columns must
+// exist in the backup; the others are Go-only and copied as NULL when the
+// backup was written by another language target.
+var restoreColumns = []struct {
+	name     string
+	required bool
+}{
+	{"inserted_at", true},
+	{"is_active", true},
+	{"key", true},
+	{"value", true},
+	{"expires_at", false},
+	{"deactivated_at", false},
+	{"checksum", false},
+	{"accessed_at", false},
+	{"touched_at", false},
+	{"ttl", false},
+}
+
+// Restore replaces the entire contents of the cache, history included, with
+// those of the backup database at srcPath, such as one written by Backup or
+// VacuumInto. Version IDs are preserved.
+//
+// Pins, negative cache entries and settings such as the encryption
+// verification record are restored too. The change feed records the restore
+// as the deletion of every old key and the setting of every restored one;
+// changes recorded before it are pruned, since they describe contents that
+// are gone.
+//
+// The backup is checked for a compatible kv table before anything is changed,
+// and the swap happens in a single transaction: readers see either the old
+// contents or the restored ones, never a mix. Writes from this client wait
+// until the restore is done.
+//
+// Example:
+//
+//	if err := client.Restore("/backups/cache.db"); err != nil {
+//		return err
+//	}
+func (c *CacheClient) Restore(srcPath string) error {
+	var envelopes bool
+	err := c.withAttached(srcPath, restoreSchema, func(ctx context.Context, conn *sql.Conn) error {
+		columns, err := attachedColumns(ctx, conn, c.tables, restoreSchema)
+		if err != nil {
+			return fmt.Errorf("invalid backup: %w", err)
+		}
+		return c.retry(func() error {
+			return withConnTx(ctx, conn, func(tx *sql.Tx) error {
+				if err := restoreFrom(tx, c.tables, columns); err != nil {
+					return err
+				}
+				envelopes, err = c.restoreEnvelopes(tx)
+				return err
+			})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if envelopes {
+		c.envelopes.Store(true)
+	}
+	return nil
+}
+
+// restoreEnvelopes marks the client's database as storing values in
+// envelopes after restoreFrom, which replaces the mark with the backup's, if
+// the client transforms values or its database was marked before. It reports
+// whether the database is marked.
+func (c *CacheClient) restoreEnvelopes(tx *sql.Tx) (bool, error) {
+	if c.transformsValues() {
+		if err := markEnvelopes(tx, c.tables); err != nil {
+			return false, fmt.Errorf("failed to mark value envelopes: %w", err)
+		}
+		return true, nil
+	}
+	_, err := readMeta(tx, c.tables, valueEnvelopesName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// restoreFrom replaces main.kv and the restoreTables with those of the
+// attached backup, whose kv table has the given columns.
+func restoreFrom(tx *sql.Tx, t tableNames, columns map[string]bool) error {
+	var targets, sources []string
+	for _, col := range restoreColumns {
+		targets = append(targets, col.name)
+		if columns[col.name] {
+			sources = append(sources, col.name)
+		} else if col.required {
+			return fmt.Errorf("invalid backup: %s table has no %s column", t.kv, col.name)
+		} else {
+			sources = append(sources, "NULL")
+		}
+	}
+
+	// Inactive rows go in first: kv_swap_active would otherwise retire an
+	// active row as soon as an older version of the same key was inserted.
+	insert := `INSERT INTO main.` + t.kv + ` (rowid, ` + strings.Join(targets, ", ") + `)
+SELECT rowid, ` + strings.Join(sources, ", ") + `
+FROM ` + restoreSchema + `.` + t.kv + `
+ORDER BY is_active, rowid;`
+
+	// The changes the restore records are kept.
+	first, err := queryLastChangeSeq(tx, t)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM main.` + t.kv + `;`); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	if _, err := tx.Exec(insert); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+
+	for _, table := range restoreTables(t) {
+		if err := restoreTable(tx, t, table.name, table.columns); err != nil {
+			return err
+		}
+	}
+	_, err = pruneChangesBefore(tx, t, first+1)
+	return err
+}
+
+// restoreTable replaces the given columns of main.table with those of the
+// backup's, if it has the table. The change feed's prune point in kv_meta
+// belongs to the client's own feed and is kept.
+func restoreTable(tx *sql.Tx, t tableNames, table, columns string) error {
+	keep := ""
+	if table == t.meta {
+		keep = ` WHERE name <> '` + changesPrunedName + `'`
+	}
+	if _, err := tx.Exec(`DELETE FROM main.` + table + keep + `;`); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM ` + restoreSchema + `.sqlite_master WHERE type = 'table' AND name = ?);`
+	if err := tx.QueryRow(query, table).Scan(&exists); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+	insert := `INSERT INTO main.` + table + ` (` + columns + `)
+SELECT ` + columns + ` FROM ` + restoreSchema + `.` + table + keep + `;`
+	if _, err := tx.Exec(insert); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
+
+// withAttached attaches the database at path under schema on a connection
+// pinned for the duration of fn, since ATTACH is per connection, and detaches
+// it afterwards. Writes from this client wait until fn returns.
+func (c *CacheClient) withAttached(path, schema string, fn func(ctx context.Context, conn *sql.Conn) error) error {
+	// ATTACH would silently create a missing file.
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	defer c.mem.purge()
+
+	return attachTo(db, path, schema, fn)
+}
+
+// attachTo attaches the database at path under schema on a connection of db
+// for the duration of fn.
+func attachTo(db *sql.DB, path, schema string, fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `ATTACH DATABASE ? AS `+schema+`;`, path); err != nil {
+		return fmt.Errorf("failed to attach database: %w", err)
+	}
+	defer conn.ExecContext(ctx, `DETACH DATABASE `+schema+`;`)
+
+	return fn(ctx, conn)
+}
+
+// withConnTx runs fn in a transaction on conn, committing if fn returns nil
+// and rolling back otherwise. The caller must hold c.writeMu, as withAttached
+// does.
+func withConnTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin failed: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// attachedColumns returns the set of columns of the kv table in the attached
+// database schema, failing if it isn't a database or has no kv table.
+func attachedColumns(ctx context.Context, conn *sql.Conn, t tableNames, schema string) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT name FROM pragma_table_info(?, ?);`, t.kv, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no %s table", t.kv)
+	}
+	return columns, nil
+}
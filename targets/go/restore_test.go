@@ -0,0 +1,168 @@
+package squeakyv
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRestore(t *testing.T) {
+	client := newFileClient(t)
+
+	client.Set("a", []byte("a1"))
+	client.Set("a", []byte("a2"))
+	client.Set("b", []byte("b1"))
+	client.Delete("b")
+	client.Set("c", []byte("c1"))
+
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	if err := client.Backup(backup, nil); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	before, _ := client.History("a")
+
+	client.Set("a", []byte("a3"))
+	client.Set("d", []byte("d1"))
+	client.Delete("c")
+
+	if err := client.Restore(backup); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	keys, _ := client.ListKeys()
+	sort.Strings(keys)
+	if expected := []string{"a", "c"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+	if value, _ := client.Get("a"); string(value) != "a2" {
+		t.Errorf("Expected a2, got %s", value)
+	}
+	after, _ := client.History("a")
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("Expected history to match the backup, got %v want %v", after, before)
+	}
+	if deleted, _ := client.ListDeletedKeys(); !reflect.DeepEqual(deleted, []string{"b"}) {
+		t.Errorf("Expected b to be deleted, got %v", deleted)
+	}
+
+	// The restored cache keeps working
+	client.Set("a", []byte("a4"))
+	if value, _ := client.Get("a"); string(value) != "a4" {
+		t.Errorf("Expected a4, got %s", value)
+	}
+}
+
+func TestRestoreKeepsActiveRowWithOlderID(t *testing.T) {
+	client := newTestClient(t)
+
+	// Renaming onto a deleted key merges histories, leaving the active
+	// version with a lower ID than an inactive one.
+	client.Set("key", []byte("live"))
+	client.Set("dst", []byte("history"))
+	client.Delete("dst")
+	if err := client.Rename("key", "dst"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	if err := client.VacuumInto(backup); err != nil {
+		t.Fatalf("VacuumInto failed: %v", err)
+	}
+	client.ClearHard()
+
+	if err := client.Restore(backup); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if value, _ := client.Get("dst"); string(value) != "live" {
+		t.Errorf("Expected live, got %s", value)
+	}
+}
+
+func TestRestoreRejectsInvalidBackup(t *testing.T) {
+	client := newFileClient(t)
+	client.Set("key", []byte("value"))
+
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "garbage.db")
+	os.WriteFile(garbage, []byte("definitely not an sqlite database file"), 0o644)
+
+	other := filepath.Join(dir, "other.db")
+	db, err := sql.Open(driverName, other)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.Exec(`CREATE TABLE unrelated (x INTEGER);`)
+	db.Close()
+
+	for _, path := range []string{filepath.Join(dir, "missing.db"), garbage, other} {
+		if err := client.Restore(path); err == nil {
+			t.Errorf("Expected error restoring %s", filepath.Base(path))
+		}
+	}
+
+	if value, _ := client.Get("key"); string(value) != "value" {
+		t.Errorf("Expected contents untouched, got %s", value)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.db")); !os.IsNotExist(err) {
+		t.Error("Expected Restore not to create the missing backup")
+	}
+}
+
+func TestRestoreSideTables(t *testing.T) {
+	client := newFileClient(t)
+	client.Set("a", []byte("a1"))
+	client.Set("b", []byte("b1"))
+	client.Pin("a")
+
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	if err := client.Backup(backup, nil); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	client.Unpin("a")
+	client.Pin("b")
+	_, resume, _ := client.ChangesSince(0, 100)
+
+	if err := client.Restore(backup); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if pinned, _ := client.ListPinned(); !reflect.DeepEqual(pinned, []string{"a"}) {
+		t.Errorf("Expected the backup's pins, got %v", pinned)
+	}
+
+	// Changes from before the restore are gone; the restore's own are kept.
+	if _, _, err := client.ChangesSince(0, 100); !errors.Is(err, ErrChangesPruned) {
+		t.Errorf("Expected ErrChangesPruned, got %v", err)
+	}
+	changes, _, err := client.ChangesSince(resume, 100)
+	if err != nil {
+		t.Fatalf("ChangesSince failed: %v", err)
+	}
+	if len(changes) != 4 {
+		t.Errorf("Expected the restore recorded as 2 deletes and 2 sets, got %v", changeList(changes))
+	}
+}
+
+func TestRestoreEnvelopes(t *testing.T) {
+	dir := t.TempDir()
+	compressed := newTestClientAt(t, filepath.Join(dir, "compressed.db"), WithCompressor(GzipCompressor{}))
+	compressed.Set("key", compressible)
+	backup := filepath.Join(dir, "backup.db")
+	if err := compressed.Backup(backup, nil); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	// A client of an unmarked database takes the backup's mark.
+	client := newTestClientAt(t, filepath.Join(dir, "plain.db"))
+	if err := client.Restore(backup); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got, _ := client.Get("key"); !bytes.Equal(got, compressible) {
+		t.Errorf("Expected the compressed value decoded, got %d bytes", len(got))
+	}
+}
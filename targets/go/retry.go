@@ -0,0 +1,102 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrBusy is matched, via errors.Is, by the BusyError returned when a write
+// keeps failing because another connection holds the database lock.
+var ErrBusy = errors.New("squeakyv: database is busy")
+
+// BusyError reports that a write gave up after repeated SQLITE_BUSY or
+// SQLITE_LOCKED results. It wraps the last driver error, and errors.Is
+// matches it against ErrBusy, so lock contention can be told apart from other
+// failures such as corruption.
+type BusyError struct {
+	// Attempts is how many times the write was tried.
+	Attempts int
+	// Elapsed is the time spent trying, including backoff.
+	Elapsed time.Duration
+	// Err is the error from the last attempt.
+	Err error
+}
+
+// Error implements error.
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("%v after %d attempts in %v: %v", ErrBusy, e.Attempts, e.Elapsed.Round(time.Millisecond), e.Err)
+}
+
+// Unwrap returns the error from the last attempt.
+func (e *BusyError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrBusy.
+func (e *BusyError) Is(target error) bool {
+	return target == ErrBusy
+}
+
+const (
+	// retryBaseDelay and retryMaxDelay bound the exponential backoff between
+	// attempts; each delay is jittered down to half its nominal value.
+	retryBaseDelay = 2 * time.Millisecond
+	retryMaxDelay  = 100 * time.Millisecond
+)
+
+// retry runs op, running it again with jittered exponential backoff while it
+// fails with a busy or locked error, up to the limits set by WithRetry.
+//
+// op must be safe to repeat: a write transaction is retried as a whole, after
+// its previous attempt has been rolled back.
+func (c *CacheClient) retry(op func() error) error {
+	return c.retryWaiting(nil, op)
+}
+
+// retryWaiting is retry, adding to *wait, unless wait is nil, the time spent
+// waiting for the database lock: attempts that failed with a busy error and
+// the backoff between attempts.
+func (c *CacheClient) retryWaiting(wait *time.Duration, op func() error) error {
+	start := time.Now()
+	delay := retryBaseDelay
+
+	var waited time.Duration
+	if wait != nil {
+		defer func() { *wait += waited }()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !isBusy(err) {
+			return err
+		}
+
+		elapsed := time.Since(start)
+		sleep := delay/2 + rand.N(delay/2+1)
+		if attempt >= c.opts.retryMaxAttempts || elapsed+sleep > c.opts.retryMaxElapsed {
+			waited = elapsed
+			busy := &BusyError{Attempts: attempt, Elapsed: elapsed, Err: err}
+			c.logBusy(busy)
+			return busy
+		}
+		c.logRetry(attempt, sleep, err)
+
+		time.Sleep(sleep)
+		waited = time.Since(start)
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// exec executes a single write statement on db, retrying on contention.
+func (c *CacheClient) exec(db querier, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := c.retry(func() error {
+		var err error
+		result, err = db.Exec(query, args...)
+		return err
+	})
+	return result, err
+}
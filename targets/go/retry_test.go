@@ -0,0 +1,159 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockDatabase takes the write lock on the database at path from a separate
+// client and returns a function that releases it.
+func lockDatabase(t *testing.T, path string) func() {
+	t.Helper()
+	holder, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	conn, err := holder.db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), `BEGIN IMMEDIATE;`); err != nil {
+		t.Fatalf("Failed to lock database: %v", err)
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			conn.ExecContext(context.Background(), `ROLLBACK;`)
+			conn.Close()
+			holder.Close()
+		})
+	}
+	t.Cleanup(release)
+	return release
+}
+
+func TestWithBusyTimeout(t *testing.T) {
+	client, err := NewCacheClient(filepath.Join(t.TempDir(), "test.db"), WithBusyTimeout(1500*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if got := queryPragma(t, client, "busy_timeout"); got != "1500" {
+		t.Errorf("Expected busy_timeout 1500, got %s", got)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path, WithBusyTimeout(time.Millisecond), WithRetry(3, time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	release := lockDatabase(t, path)
+
+	err = client.Set("key", []byte("value"))
+	if !errors.Is(err, ErrBusy) {
+		t.Fatalf("Expected ErrBusy, got %v", err)
+	}
+	var busy *BusyError
+	if !errors.As(err, &busy) {
+		t.Fatalf("Expected *BusyError, got %T", err)
+	}
+	if busy.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", busy.Attempts)
+	}
+	if !isBusy(busy.Err) {
+		t.Errorf("Expected the driver error to be wrapped, got %v", busy.Err)
+	}
+
+	// Transactions report contention the same way
+	if _, err := client.GetSet("key", []byte("value")); !errors.Is(err, ErrBusy) {
+		t.Errorf("Expected ErrBusy from GetSet, got %v", err)
+	}
+
+	release()
+	if err := client.Set("key", []byte("value")); err != nil {
+		t.Fatalf("Set failed after lock was released: %v", err)
+	}
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path, WithBusyTimeout(20*time.Millisecond), WithRetry(1000, 100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	lockDatabase(t, path)
+
+	start := time.Now()
+	err = client.Delete("key")
+	var busy *BusyError
+	if !errors.As(err, &busy) {
+		t.Fatalf("Expected *BusyError, got %v", err)
+	}
+	if busy.Attempts >= 1000 {
+		t.Errorf("Expected max elapsed to stop retries early, got %d attempts", busy.Attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected to give up after about 100ms, took %v", elapsed)
+	}
+}
+
+func TestRetrySucceedsOnceLockIsReleased(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path, WithBusyTimeout(time.Millisecond), WithRetry(1000, 10*time.Second))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("counter", []byte("1"))
+	release := lockDatabase(t, path)
+	time.AfterFunc(50*time.Millisecond, release)
+
+	n, err := client.Increment("counter", 1)
+	if err != nil {
+		t.Fatalf("Increment failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2, got %d", n)
+	}
+}
+
+func TestRetryDoesNotRetryOtherErrors(t *testing.T) {
+	client := newTestClient(t)
+
+	calls := 0
+	want := errors.New("boom")
+	err := client.retry(func() error {
+		calls++
+		return want
+	})
+	if err != want {
+		t.Errorf("Expected error to be returned unchanged, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+
+	// A failed transaction is not retried either
+	calls = 0
+	err = client.WithTransaction(func(tx *Tx) error {
+		calls++
+		return sql.ErrTxDone
+	})
+	if !errors.Is(err, sql.ErrTxDone) || calls != 1 {
+		t.Errorf("Expected a single failed call, got %d calls and %v", calls, err)
+	}
+}
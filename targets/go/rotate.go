@@ -0,0 +1,275 @@
+package squeakyv
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// rotationProgressName is the kv_meta entry recording how far an interrupted
+// RotateEncryptionKey got: the fingerprints of the old and new keys followed
+// by the rowid of the last row processed, big-endian.
+const rotationProgressName = "rotation_progress"
+
+// RotateEncryptionKey re-encrypts every value encrypted with oldKey, history
+// included, with newKey, and returns how many rows it rewrote. Values written
+// before values were bound to their key are rewritten bound to it. Values
+// encrypted with other keys, and unencrypted values, are left alone. Finally
+// the database's verification record is re-encrypted, so that afterwards
+// NewCacheClient only accepts newKey (see WithEncryption). If oldKey cannot
+// decrypt the verification record, nothing is changed and the error wraps
+// ErrDecrypt.
+//
+// Rows are rewritten in batched transactions of WithSweepBatchSize rows,
+// so writers are never blocked for long. Progress is recorded with each
+// batch: if rotation is interrupted, calling RotateEncryptionKey again with
+// the same keys resumes where it stopped, and the count covers only the
+// rows rewritten by that call.
+//
+// Rotation does not change the keys the client itself uses. To rotate without
+// downtime, first configure every client with WithEncryptionKeys(newKey,
+// oldKey), so that new writes use newKey while old values stay readable, then
+// rotate, then drop oldKey.
+//
+// Example:
+//
+//	n, err := client.RotateEncryptionKey(oldKey, newKey)
+//	if err != nil {
+//		return err
+//	}
+//	log.Printf("re-encrypted %d versions", n)
+func (c *CacheClient) RotateEncryptionKey(oldKey, newKey []byte) (int64, error) {
+	oldK, err := newEncryptionKey(oldKey)
+	if err != nil {
+		return 0, err
+	}
+	newK, err := newEncryptionKey(newKey)
+	if err != nil {
+		return 0, err
+	}
+	if oldK.fingerprint == newK.fingerprint {
+		return 0, errors.New("invalid key rotation: old and new keys are the same")
+	}
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	r := &rotation{
+		old:       oldK,
+		new:       newK,
+		batchSize: c.opts.sweepBatchSize,
+		tables:    c.tables,
+		foldKeys:  c.opts.caseInsensitiveKeys,
+	}
+	if err := r.resume(db); err != nil {
+		return 0, err
+	}
+	if err := r.check(db); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for {
+		var (
+			n    int64
+			done bool
+		)
+		err := c.withTx(db, func(tx *sql.Tx) error {
+			var err error
+			n, done, err = r.batch(tx)
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+		r.commit()
+		total += n
+		if done {
+			break
+		}
+	}
+
+	err = c.withTx(db, func(tx *sql.Tx) error {
+		return r.finish(tx)
+	})
+	return total, err
+}
+
+// rotation is the state of one RotateEncryptionKey call.
+type rotation struct {
+	old, new  *encryptionKey
+	batchSize int
+	tables    tableNames
+	// foldKeys is set with WithCaseInsensitiveKeys; see authKey.
+	foldKeys bool
+
+	// after is the rowid of the last row processed by a committed batch, and
+	// pendingAfter that of the batch being written.
+	after, pendingAfter int64
+	// resumed is set if an interrupted rotation between the same keys is
+	// being resumed.
+	resumed bool
+}
+
+// resume starts the rotation after the last row recorded by an interrupted
+// rotation between the same keys, if any.
+func (r *rotation) resume(db querier) error {
+	record, err := readMeta(db, r.tables, rotationProgressName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(record) != 2*fingerprintSize+8 ||
+		!bytes.Equal(record[:fingerprintSize], r.old.fingerprint[:]) ||
+		!bytes.Equal(record[fingerprintSize:2*fingerprintSize], r.new.fingerprint[:]) {
+		// Progress of a rotation between other keys; start over.
+		return nil
+	}
+	r.after = int64(binary.BigEndian.Uint64(record[2*fingerprintSize:]))
+	r.resumed = true
+	return nil
+}
+
+// check verifies, before any row is rewritten, that the database's
+// verification record opens with the old key, or with the new one when
+// resuming. Otherwise a mistyped old key would rotate nothing and then seal
+// the record with the new key, locking out the key the values still use.
+func (r *rotation) check(db querier) error {
+	record, err := readMeta(db, r.tables, encryptionCheckName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	keys := []*encryptionKey{r.old}
+	if r.resumed {
+		keys = append(keys, r.new)
+	}
+	kind, id, payload, ok := parseLayer(record)
+	if ok && kind == layerEncrypted && (id == aesGCMID || id == aesGCMKeyID) {
+		for _, k := range keys {
+			if !bytes.HasPrefix(payload, k.fingerprint[:]) {
+				continue
+			}
+			plaintext, err := k.open(encryptionCheckName, encryptionCheckName, id, payload)
+			if err == nil && bytes.Equal(plaintext, encryptionCheckPlaintext) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: the old key is not this database's encryption key", ErrDecrypt)
+}
+
+// batch re-encrypts the next batch of rows and records the progress,
+// returning how many rows it rewrote and whether there were no rows left.
+func (r *rotation) batch(tx *sql.Tx) (int64, bool, error) {
+	r.pendingAfter = r.after
+
+	query := `SELECT rowid, key, value
+FROM ` + r.tables.kv + `
+WHERE rowid > ?
+ORDER BY rowid
+LIMIT ?;`
+
+	rows, err := tx.Query(query, r.after, r.batchSize)
+	if err != nil {
+		return 0, false, fmt.Errorf("query failed: %w", err)
+	}
+	type row struct {
+		id    int64
+		key   string
+		value []byte
+	}
+	var batch []row
+	for rows.Next() {
+		var rw row
+		if err := rows.Scan(&rw.id, &rw.key, &rw.value); err != nil {
+			rows.Close()
+			return 0, false, fmt.Errorf("scan failed: %w", err)
+		}
+		batch = append(batch, rw)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, false, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	if len(batch) == 0 {
+		return 0, true, nil
+	}
+
+	var n int64
+	for _, rw := range batch {
+		kind, id, payload, ok := parseLayer(rw.value)
+		if !ok || kind != layerEncrypted || (id != aesGCMID && id != aesGCMKeyID) ||
+			!bytes.HasPrefix(payload, r.old.fingerprint[:]) {
+			continue
+		}
+		auth := authKey(rw.key, r.foldKeys)
+		plaintext, err := r.old.open(rw.key, auth, id, payload)
+		if err != nil {
+			return 0, false, fmt.Errorf("version %d: %w", rw.id, err)
+		}
+		sealed, err := r.new.seal(auth, plaintext)
+		if err != nil {
+			return 0, false, err
+		}
+		query := `UPDATE ` + r.tables.kv + `
+SET value = ?, checksum = ?
+WHERE rowid = ?;`
+		if _, err := tx.Exec(query, sealed, checksumOf(sealed), rw.id); err != nil {
+			return 0, false, fmt.Errorf("exec failed: %w", err)
+		}
+		n++
+	}
+
+	r.pendingAfter = batch[len(batch)-1].id
+	progress := make([]byte, 0, 2*fingerprintSize+8)
+	progress = append(progress, r.old.fingerprint[:]...)
+	progress = append(progress, r.new.fingerprint[:]...)
+	progress = binary.BigEndian.AppendUint64(progress, uint64(r.pendingAfter))
+	if err := replaceMeta(tx, r.tables, rotationProgressName, progress); err != nil {
+		return 0, false, err
+	}
+	return n, false, nil
+}
+
+// commit moves the rotation past the batch just committed.
+func (r *rotation) commit() {
+	r.after = r.pendingAfter
+}
+
+// finish re-encrypts the verification record with the new key and clears
+// the progress record, once every batch has committed.
+func (r *rotation) finish(tx *sql.Tx) error {
+	sealed, err := r.new.seal(encryptionCheckName, encryptionCheckPlaintext)
+	if err != nil {
+		return err
+	}
+	if err := replaceMeta(tx, r.tables, encryptionCheckName, sealed); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM `+r.tables.meta+` WHERE name = ?;`, rotationProgressName); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
+
+// replaceMeta stores a kv_meta entry, replacing any existing one called name.
+func replaceMeta(db querier, t tableNames, name string, value []byte) error {
+	query := `INSERT OR REPLACE INTO ` + t.meta + ` (name, value)
+VALUES (?, ?);`
+
+	if _, err := db.Exec(query, name, value); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
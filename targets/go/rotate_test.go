@@ -0,0 +1,158 @@
+package squeakyv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptionFallbackKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	oldKey, newKey := testKey(1), testKey(2)
+
+	old := newTestClientAt(t, path, WithEncryption(oldKey))
+	old.Set("a", []byte("one"))
+
+	both := newTestClientAt(t, path, WithEncryptionKeys(newKey, oldKey))
+	if got, err := both.Get("a"); err != nil || string(got) != "one" {
+		t.Errorf("Expected fallback key to decrypt, got %q, %v", got, err)
+	}
+	both.Set("b", []byte("two"))
+
+	// New writes use the primary key, which the old client lacks.
+	if _, err := old.Get("b"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt with the old key alone, got %v", err)
+	}
+	if _, err := NewCacheClient(path, WithEncryptionKeys(newKey, testKey(3))); err == nil {
+		t.Error("Expected open to fail without the database's key")
+	}
+}
+
+func TestRotateEncryptionKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	oldKey, newKey := testKey(1), testKey(2)
+
+	old := newTestClientAt(t, path, WithEncryption(oldKey))
+	old.Set("a", []byte("one"))
+	old.Set("a", []byte("two"))
+	old.Set("b", []byte("three"))
+	old.Delete("b")
+
+	client := newTestClientAt(t, path, WithEncryptionKeys(newKey, oldKey))
+	client.Set("c", []byte("four"))
+
+	// Every old-key version is rewritten; c already uses the new key.
+	n, err := client.RotateEncryptionKey(oldKey, newKey)
+	if err != nil {
+		t.Fatalf("RotateEncryptionKey failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 rows rewritten, got %d", n)
+	}
+	if n, _ := client.RotateEncryptionKey(oldKey, newKey); n != 0 {
+		t.Errorf("Expected a second rotation to rewrite nothing, got %d", n)
+	}
+
+	rotated := newTestClientAt(t, path, WithEncryption(newKey))
+	if got, _ := rotated.Get("a"); string(got) != "two" {
+		t.Errorf("Expected two, got %q", got)
+	}
+	if versions, _ := rotated.History("b"); len(versions) != 1 || string(versions[0].Value) != "three" {
+		t.Errorf("Expected rotated history, got %+v", versions)
+	}
+	if got, _ := rotated.Get("c"); string(got) != "four" {
+		t.Errorf("Expected four, got %q", got)
+	}
+
+	if _, err := NewCacheClient(path, WithEncryption(oldKey)); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected the old key to be rejected, got %v", err)
+	}
+}
+
+func TestRotateEncryptionKeyResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	oldKey, newKey := testKey(1), testKey(2)
+
+	client := newTestClientAt(t, path, WithEncryption(oldKey), WithSweepBatchSize(3))
+	for i := range 10 {
+		client.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+
+	// Corrupt a row in the third batch so rotation stops there.
+	bad := rawValue(t, client, "key7")
+	bad[len(bad)-1] ^= 0xff
+	if _, err := client.db.Exec(`UPDATE kv SET value = ? WHERE key = 'key7';`, bad); err != nil {
+		t.Fatalf("Failed to corrupt value: %v", err)
+	}
+
+	n, err := client.RotateEncryptionKey(oldKey, newKey)
+	if !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("Expected ErrDecrypt, got %v", err)
+	}
+	if n != 6 {
+		t.Errorf("Expected the first two batches committed, got %d rows", n)
+	}
+
+	if _, err := client.db.Exec(`DELETE FROM kv WHERE key = 'key7';`); err != nil {
+		t.Fatalf("Failed to delete value: %v", err)
+	}
+	n, err = client.RotateEncryptionKey(oldKey, newKey)
+	if err != nil {
+		t.Fatalf("RotateEncryptionKey failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected the remaining 3 rows rewritten, got %d", n)
+	}
+
+	rotated := newTestClientAt(t, path, WithEncryption(newKey))
+	for i := range 10 {
+		if i == 7 {
+			continue
+		}
+		got, err := rotated.Get(fmt.Sprintf("key%d", i))
+		if err != nil || !bytes.Equal(got, []byte("value")) {
+			t.Errorf("Expected key%d readable with the new key, got %q, %v", i, got, err)
+		}
+	}
+}
+
+func TestRotateEncryptionKeyErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	client := newTestClientAt(t, path, WithEncryption(testKey(1)))
+
+	if _, err := client.RotateEncryptionKey(testKey(1), testKey(1)); err == nil {
+		t.Error("Expected an error for identical keys")
+	}
+	if _, err := client.RotateEncryptionKey([]byte("short"), testKey(2)); err == nil {
+		t.Error("Expected an error for an invalid key")
+	}
+
+	ro := newTestClientAt(t, path, WithEncryption(testKey(1)), WithReadOnly())
+	if _, err := ro.RotateEncryptionKey(testKey(1), testKey(2)); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestRotateEncryptionKeyWrongOldKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	key := testKey(1)
+	client := newTestClientAt(t, path, WithEncryption(key))
+	client.Set("a", []byte("one"))
+
+	n, err := client.RotateEncryptionKey(testKey(3), testKey(2))
+	if !errors.Is(err, ErrDecrypt) || n != 0 {
+		t.Fatalf("Expected ErrDecrypt and no rows, got %d, %v", n, err)
+	}
+	client.Close()
+
+	// The database still opens with its real key, and only with it.
+	if _, err := NewCacheClient(path, WithEncryption(testKey(2))); err == nil {
+		t.Error("Expected the new key to be rejected")
+	}
+	reopened := newTestClientAt(t, path, WithEncryption(key))
+	if got, err := reopened.Get("a"); err != nil || string(got) != "one" {
+		t.Errorf("Expected one, got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,387 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+)
+
+// salvageBatchSize is how many rows Salvage reads per query while the table
+// reads cleanly.
+const salvageBatchSize = 500
+
+// SalvageStats reports the outcome of Salvage.
+type SalvageStats struct {
+	// Recovered is the number of versions copied, history included.
+	Recovered int64
+	// Skipped is the number of versions that could not be recovered: rows
+	// that failed to read or failed their checksum, plus an estimate, from
+	// the version IDs on either side, of the rows in stretches of the table
+	// too damaged to read at all. Versions hard-deleted before the damage
+	// can make the estimate too high.
+	Skipped int64
+}
+
+// Salvage copies every readable version of the damaged database at srcPath,
+// history included, into a new database at destPath, skipping what cannot be
+// read instead of failing. Version IDs, timestamps, expiries and checksums are
+// preserved and values are copied as stored, so the copy opens with the same
+// options, such as WithEncryption, as the original.
+//
+// The source is opened read-only and is neither checked against nor migrated
+// to the current schema, so even a database NewCacheClient would refuse can
+// be salvaged; columns it lacks are left empty in the copy. Of opts, only
+// WithTableName, WithCaseInsensitiveKeys and WithBusyTimeout apply. destPath
+// must not exist. An error is returned only if the source cannot be opened or
+// the copy cannot be written; damage to the source is reported in the
+// returned stats.
+//
+// Example:
+//
+//	stats, err := squeakyv.Salvage("cache.db", "cache-salvaged.db")
+//	if err != nil {
+//		return err
+//	}
+//	log.Printf("recovered %d versions, lost %d", stats.Recovered, stats.Skipped)
+func Salvage(srcPath, destPath string, opts ...Option) (SalvageStats, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := checkTableName(o.table); err != nil {
+		return SalvageStats{}, err
+	}
+	if isMemoryPath(srcPath) || isMemoryPath(destPath) {
+		return SalvageStats{}, errors.New("salvage: source and destination must be files")
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		return SalvageStats{}, fmt.Errorf("salvage: %s already exists", destPath)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return SalvageStats{}, err
+	}
+
+	db, err := openDB(srcPath, options{readOnly: true, table: o.table, busyTimeout: o.busyTimeout})
+	if err != nil {
+		return SalvageStats{}, fmt.Errorf("salvage: %w", err)
+	}
+	defer db.Close()
+	columns, err := salvageColumns(db, newTableNames(o.table))
+	if err != nil {
+		return SalvageStats{}, fmt.Errorf("salvage: %w", err)
+	}
+
+	dstOpts := []Option{WithTableName(o.table)}
+	if o.caseInsensitiveKeys {
+		dstOpts = append(dstOpts, WithCaseInsensitiveKeys())
+	}
+	dst, err := NewCacheClient(destPath, dstOpts...)
+	if err != nil {
+		return SalvageStats{}, fmt.Errorf("salvage: %w", err)
+	}
+	defer dst.Close()
+
+	s := &salvager{src: db, columns: columns, dst: dst, active: make(map[string]int64)}
+	if err := s.copyRows(); err != nil {
+		return s.stats, err
+	}
+	if err := s.activate(); err != nil {
+		return s.stats, err
+	}
+	s.copyMeta()
+	return s.stats, nil
+}
+
+// salvageOptional lists the kv columns Salvage copies when the source has
+// them, in the order salvageRow scans them.
+var salvageOptional = []string{"expires_at", "deactivated_at", "checksum", "accessed_at", "touched_at", "ttl"}
+
+// salvageColumns returns the columns Salvage selects from the source's kv
+// table, with NULL in place of the optional ones it lacks, failing if the
+// source has no kv table or its schema can't be read.
+func salvageColumns(db *sql.DB, t tableNames) (string, error) {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?);`, t.kv)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect table %s: %w", t.kv, err)
+	}
+	defer rows.Close()
+
+	have := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return "", fmt.Errorf("scan failed: %w", err)
+		}
+		have[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to inspect table %s: %w", t.kv, err)
+	}
+	if len(have) == 0 {
+		return "", fmt.Errorf("no %s table", t.kv)
+	}
+
+	columns := "rowid, inserted_at, is_active, key, value"
+	for _, name := range salvageOptional {
+		if !have[name] {
+			name = "NULL"
+		}
+		columns += ", " + name
+	}
+	return columns, nil
+}
+
+// salvageRow is a kv row read by Salvage.
+type salvageRow struct {
+	id            int64
+	insertedAt    int64
+	active        bool
+	key           string
+	value         []byte
+	expiresAt     sql.NullInt64
+	deactivatedAt sql.NullInt64
+	checksum      sql.NullInt64
+	accessedAt    sql.NullInt64
+	touchedAt     sql.NullInt64
+	ttl           sql.NullInt64
+}
+
+// salvager holds the state of one Salvage call.
+type salvager struct {
+	src *sql.DB
+	// columns is the source's select list, from salvageColumns.
+	columns string
+	dst     *CacheClient
+	stats   SalvageStats
+	// active maps each key to its newest active version copied. Rows are
+	// copied inactive and activated at the end, since kv_swap_active would
+	// otherwise retire them as their key's history is copied.
+	active map[string]int64
+}
+
+// copyRows copies every readable row of the source, in version order.
+// Batches that fail to read are halved until the first unreadable row is
+// isolated, which is then skipped.
+func (s *salvager) copyRows() error {
+	maxID, err := s.maxID()
+	if err != nil {
+		// The end of the table is damaged; probe until nothing reads.
+		maxID = math.MaxInt64 - 1
+	}
+
+	var after int64
+	limit := salvageBatchSize
+	for {
+		rows, err := s.read(after, limit)
+		if err != nil {
+			if limit > 1 {
+				limit /= 2
+				continue
+			}
+			resume, ok := s.skipDamaged(after, maxID)
+			if !ok {
+				return nil
+			}
+			after, limit = resume, salvageBatchSize
+			continue
+		}
+
+		if err := s.write(rows); err != nil {
+			return err
+		}
+		if len(rows) < limit {
+			return nil
+		}
+		after, limit = rows[len(rows)-1].id, salvageBatchSize
+	}
+}
+
+// maxID returns the largest version ID in the source.
+func (s *salvager) maxID() (int64, error) {
+	var id sql.NullInt64
+	if err := s.src.QueryRow(`SELECT MAX(rowid) FROM ` + s.dst.tables.kv + `;`).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id.Int64, nil
+}
+
+// read returns up to limit rows with IDs above after, failing if any of them
+// cannot be read.
+func (s *salvager) read(after int64, limit int) ([]salvageRow, error) {
+	query := `SELECT ` + s.columns + `
+FROM ` + s.dst.tables.kv + `
+WHERE rowid > ?
+ORDER BY rowid
+LIMIT ?;`
+
+	rows, err := s.src.Query(query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []salvageRow
+	for rows.Next() {
+		var r salvageRow
+		if err := rows.Scan(&r.id, &r.insertedAt, &r.active, &r.key, &r.value, &r.expiresAt, &r.deactivatedAt, &r.checksum, &r.accessedAt, &r.touchedAt, &r.ttl); err != nil {
+			return nil, err
+		}
+		if r.value == nil {
+			r.value = []byte{}
+		}
+		batch = append(batch, r)
+	}
+	return batch, rows.Err()
+}
+
+// nextID returns the first version ID at or above from, reporting false if
+// there is none.
+func (s *salvager) nextID(from int64) (int64, bool, error) {
+	var id int64
+	err := s.src.QueryRow(`SELECT rowid FROM `+s.dst.tables.kv+` WHERE rowid >= ? ORDER BY rowid LIMIT 1;`, from).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+// skipDamaged is called when the row after the given ID cannot be read. It
+// counts the damage as skipped and returns the ID to resume reading after,
+// reporting false if nothing beyond it can be read.
+func (s *salvager) skipDamaged(after, maxID int64) (int64, bool) {
+	// Often only the row's value is unreadable and its ID still is.
+	if id, found, err := s.nextID(after + 1); err == nil {
+		if !found {
+			return 0, false
+		}
+		s.stats.Skipped++
+		return id, true
+	}
+
+	// Otherwise the page holding it is: find the first ID past the damage,
+	// doubling the distance and then bisecting.
+	failed, step := after+1, int64(1)
+	var readable int64
+	for {
+		probe := after + 1 + step
+		if probe > maxID || probe < after {
+			if maxID < math.MaxInt64-1 {
+				s.stats.Skipped += maxID - after
+			}
+			return 0, false
+		}
+		if _, _, err := s.nextID(probe); err == nil {
+			readable = probe
+			break
+		}
+		failed = probe
+		step *= 2
+	}
+	for readable-failed > 1 {
+		mid := failed + (readable-failed)/2
+		if _, _, err := s.nextID(mid); err == nil {
+			readable = mid
+		} else {
+			failed = mid
+		}
+	}
+
+	// IDs before readable lie in the damaged stretch.
+	s.stats.Skipped += readable - 1 - after
+	return readable - 1, true
+}
+
+// write copies a batch of rows into the destination, inactive.
+func (s *salvager) write(batch []salvageRow) error {
+	query := `INSERT INTO ` + s.dst.tables.kv + ` (rowid, inserted_at, is_active, key, value, expires_at, deactivated_at, checksum, accessed_at, touched_at, ttl)
+VALUES (?, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+	var recovered, skipped int64
+	err := s.dst.withTx(s.dst.db, func(tx *sql.Tx) error {
+		recovered, skipped = 0, 0
+		stmt, err := tx.Prepare(query)
+		if err != nil {
+			return fmt.Errorf("prepare failed: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, r := range batch {
+			if verifyChecksum(r.key, r.id, r.value, r.checksum) != nil {
+				skipped++
+				continue
+			}
+			if _, err := stmt.Exec(r.id, r.insertedAt, r.key, r.value, r.expiresAt, r.deactivatedAt, r.checksum, r.accessedAt, r.touchedAt, r.ttl); err != nil {
+				return fmt.Errorf("salvage: exec failed for version %d: %w", r.id, err)
+			}
+			recovered++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.stats.Recovered += recovered
+	s.stats.Skipped += skipped
+	for _, r := range batch {
+		if r.active && verifyChecksum(r.key, r.id, r.value, r.checksum) == nil {
+			s.active[r.key] = r.id
+		}
+	}
+	return nil
+}
+
+// activate marks the versions that were active in the source active again.
+func (s *salvager) activate() error {
+	query := `UPDATE ` + s.dst.tables.kv + `
+SET is_active = 1, deactivated_at = NULL
+WHERE rowid = ?;`
+
+	return s.dst.withTx(s.dst.db, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(query)
+		if err != nil {
+			return fmt.Errorf("prepare failed: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, id := range s.active {
+			if _, err := stmt.Exec(id); err != nil {
+				return fmt.Errorf("salvage: exec failed for version %d: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// copyMeta copies the readable kv_meta entries, such as the encryption
+// verification record, so the copy opens with the same options. Failures
+// are ignored: the entries can be rebuilt.
+func (s *salvager) copyMeta() {
+	rows, err := s.src.Query(`SELECT name, value FROM ` + s.dst.tables.meta + `;`)
+	if err != nil {
+		return
+	}
+	meta := make(map[string][]byte)
+	for rows.Next() {
+		var (
+			name  string
+			value []byte
+		)
+		if rows.Scan(&name, &value) != nil {
+			break
+		}
+		meta[name] = value
+	}
+	rows.Close()
+
+	// The copy records its own change feed.
+	delete(meta, changesPrunedName)
+	for name, value := range meta {
+		replaceMeta(s.dst.db, s.dst.tables, name, value)
+	}
+}
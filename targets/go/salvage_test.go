@@ -0,0 +1,172 @@
+package squeakyv
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// salvageValue is the distinctive value of version i in the salvage tests.
+func salvageValue(i int) []byte {
+	return []byte(fmt.Sprintf("salvage-value-%04d", i))
+}
+
+// writeSalvageSource writes n keys, each with an older version in history,
+// to a new database at path.
+func writeSalvageSource(t *testing.T, path string, n int) {
+	t.Helper()
+	client, err := NewCacheClient(path, WithJournalMode("DELETE"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	for i := range n {
+		client.Set(fmt.Sprintf("key%04d", i), []byte("old"))
+		client.Set(fmt.Sprintf("key%04d", i), salvageValue(i))
+	}
+}
+
+// damagePageOf overwrites the header of the page holding needle, making the
+// rows on that page unreadable.
+func damagePageOf(t *testing.T, path string, needle []byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read database: %v", err)
+	}
+	at := bytes.Index(data, needle)
+	if at < 0 {
+		t.Fatalf("Value %q not found in database file", needle)
+	}
+	page := at / 4096 * 4096
+	for i := range 8 {
+		data[page+i] = 0xff
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write database: %v", err)
+	}
+}
+
+func TestSalvageHealthy(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.db")
+	writeSalvageSource(t, src, 20)
+
+	dst := filepath.Join(dir, "dst.db")
+	stats, err := Salvage(src, dst)
+	if err != nil {
+		t.Fatalf("Salvage failed: %v", err)
+	}
+	if stats != (SalvageStats{Recovered: 40}) {
+		t.Errorf("Expected 40 versions recovered, got %+v", stats)
+	}
+
+	copied, err := NewCacheClient(dst)
+	if err != nil {
+		t.Fatalf("Failed to open salvaged database: %v", err)
+	}
+	defer copied.Close()
+	if got, _ := copied.Get("key0007"); !bytes.Equal(got, salvageValue(7)) {
+		t.Errorf("Expected the current value, got %q", got)
+	}
+	if versions, _ := copied.History("key0007"); len(versions) != 2 || string(versions[1].Value) != "old" {
+		t.Errorf("Expected history to be recovered, got %+v", versions)
+	}
+	if verify, _ := copied.VerifyAll(nil); verify.Verified != 40 {
+		t.Errorf("Expected checksums to be copied, got %+v", verify)
+	}
+
+	if _, err := Salvage(src, dst); err == nil {
+		t.Error("Expected an error for an existing destination")
+	}
+}
+
+func TestSalvageDamaged(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.db")
+	const n = 400
+	writeSalvageSource(t, src, n)
+	damagePageOf(t, src, salvageValue(n/2))
+	before, _ := os.ReadFile(src)
+
+	client, err := NewCacheClient(src, WithReadOnly())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	if _, err := client.Count(); err == nil {
+		t.Fatal("Expected the damaged database to fail a full scan")
+	}
+
+	stats, err := Salvage(src, filepath.Join(dir, "dst.db"))
+	if err != nil {
+		t.Fatalf("Salvage failed: %v", err)
+	}
+	if stats.Skipped == 0 || stats.Recovered < 2*n*3/4 {
+		t.Errorf("Expected most versions recovered and some skipped, got %+v", stats)
+	}
+	if stats.Recovered+stats.Skipped != 2*n {
+		t.Errorf("Expected recovered and skipped to account for %d versions, got %+v", 2*n, stats)
+	}
+
+	after, _ := os.ReadFile(src)
+	if !bytes.Equal(before, after) {
+		t.Error("Expected the source to be left untouched")
+	}
+
+	copied, err := NewCacheClient(filepath.Join(dir, "dst.db"))
+	if err != nil {
+		t.Fatalf("Failed to open salvaged database: %v", err)
+	}
+	defer copied.Close()
+	if problems, err := copied.IntegrityCheck(); err != nil || len(problems) != 0 {
+		t.Errorf("Expected a healthy copy, got %v, %v", problems, err)
+	}
+	for _, i := range []int{0, n - 1} {
+		if got, _ := copied.Get(fmt.Sprintf("key%04d", i)); !bytes.Equal(got, salvageValue(i)) {
+			t.Errorf("Expected key%04d recovered, got %q", i, got)
+		}
+	}
+}
+
+func TestSalvageOldSchema(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.db")
+	db, err := sql.Open(driverName, src)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.Exec(SchemaSQL)
+	db.Exec(`INSERT INTO kv (key, value) VALUES ('a', 'old'), ('a', 'new');`)
+	db.Close()
+	before, _ := os.ReadFile(src)
+
+	// Read-only clients refuse a database without the Go columns.
+	if _, err := NewCacheClient(src, WithReadOnly()); err == nil {
+		t.Fatal("Expected the old schema to be rejected")
+	}
+
+	dst := filepath.Join(dir, "dst.db")
+	stats, err := Salvage(src, dst)
+	if err != nil {
+		t.Fatalf("Salvage failed: %v", err)
+	}
+	if stats != (SalvageStats{Recovered: 2}) {
+		t.Errorf("Expected 2 versions recovered, got %+v", stats)
+	}
+	if after, _ := os.ReadFile(src); !bytes.Equal(before, after) {
+		t.Error("Expected the source to be left unmigrated")
+	}
+
+	copied := newTestClientAt(t, dst)
+	if got, _ := copied.Get("a"); string(got) != "new" {
+		t.Errorf("Expected the current value, got %q", got)
+	}
+
+	if _, err := Salvage(filepath.Join(dir, "missing.db"), filepath.Join(dir, "other.db")); err == nil {
+		t.Error("Expected an error for a missing source")
+	}
+}
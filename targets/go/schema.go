@@ -0,0 +1,509 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// columnMigration describes a column the Go target adds on top of the shared
+// schema in SchemaSQL.
+//
+// Added columns are always nullable (or carry a default) so that databases
+// written by the other language targets remain readable and writable by them.
+type columnMigration struct {
+	column string
+	decl   string
+}
+
+// goColumns lists the columns layered onto the shared schema, in the order
+// they were introduced.
+var goColumns = []columnMigration{
+	// UNIX expiry time (milliseconds); NULL means the row never expires
+	{column: "expires_at", decl: "INTEGER"},
+	// UNIX time (milliseconds) the row stopped being active; NULL while active
+	{column: "deactivated_at", decl: "INTEGER"},
+	// CRC32C of the value as stored; NULL for rows written without one, such
+	// as by older versions of this package or other language targets
+	{column: "checksum", decl: "INTEGER"},
+	// UNIX time (milliseconds) of the last read recorded by a client with
+	// WithMaxEntries, or of the last Touch; NULL if none was
+	{column: "accessed_at", decl: "INTEGER"},
+	// UNIX time (milliseconds) of the last Touch or Append; NULL if the row
+	// was never touched
+	{column: "touched_at", decl: "INTEGER"},
+	// TTL (milliseconds) expires_at was last set with by Expire; NULL if it
+	// was set when the row was written, and so is measured from inserted_at
+	{column: "ttl", decl: "INTEGER"},
+}
+
+// goSchemaSQL returns the idempotent statements that run after goColumns are
+// in place.
+func goSchemaSQL(t tableNames) string {
+	return `
+-- Settings the Go target keeps about the database, such as the encryption
+-- verification record
+CREATE TABLE IF NOT EXISTS ` + t.meta + ` (
+  name TEXT PRIMARY KEY,
+  value BLOB NOT NULL
+);
+
+-- Expiry scans
+CREATE INDEX IF NOT EXISTS ` + t.kv + `_expires_at ON ` + t.kv + `(expires_at) WHERE expires_at IS NOT NULL;
+
+-- Record when a row is retired, whether by delete or overwrite
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_stamp_deactivated
+AFTER UPDATE OF is_active ON ` + t.kv + `
+FOR EACH ROW
+WHEN OLD.is_active = 1 AND NEW.is_active = 0
+BEGIN
+  UPDATE ` + t.kv + ` SET deactivated_at = CAST(unixepoch('subsec') * 1000 AS INTEGER)
+  WHERE rowid = NEW.rowid;
+END;
+
+-- Drop the checksum of a value rewritten without updating it, so that writers
+-- unaware of the column never leave a stale one behind
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_clear_checksum
+AFTER UPDATE OF value ON ` + t.kv + `
+FOR EACH ROW
+WHEN NEW.value IS NOT OLD.value AND NEW.checksum IS OLD.checksum AND NEW.checksum IS NOT NULL
+BEGIN
+  UPDATE ` + t.kv + ` SET checksum = NULL
+  WHERE rowid = NEW.rowid;
+END;
+
+-- Change feed: one row per set or delete of a live key, by any writer. op is
+-- 1 for a set and 2 for a delete. AUTOINCREMENT keeps pruned sequence numbers
+-- from being reused. The triggers recording sets and soft deletes are in
+-- goTriggers.
+CREATE TABLE IF NOT EXISTS ` + t.changes + ` (
+  seq INTEGER PRIMARY KEY AUTOINCREMENT,
+  key TEXT NOT NULL,
+  op INTEGER NOT NULL,
+  changed_at INTEGER NOT NULL
+);
+
+-- Physically deleting a live version, as Clear does, deletes the key
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_changes_delete
+AFTER DELETE ON ` + t.kv + `
+FOR EACH ROW
+WHEN OLD.is_active = 1
+  AND (OLD.expires_at IS NULL OR OLD.expires_at > CAST(unixepoch('subsec') * 1000 AS INTEGER))
+BEGIN
+  INSERT INTO ` + t.changes + ` (key, op, changed_at)
+  VALUES (OLD.key, 2, CAST(unixepoch('subsec') * 1000 AS INTEGER));
+END;
+
+-- Renaming a live key, as Rename does, deletes the old key and sets the new
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_changes_rename
+AFTER UPDATE OF key ON ` + t.kv + `
+FOR EACH ROW
+WHEN OLD.is_active = 1 AND NEW.is_active = 1 AND NEW.key IS NOT OLD.key
+BEGIN
+  INSERT INTO ` + t.changes + ` (key, op, changed_at)
+  VALUES (OLD.key, 2, CAST(unixepoch('subsec') * 1000 AS INTEGER));
+  INSERT INTO ` + t.changes + ` (key, op, changed_at)
+  VALUES (NEW.key, 1, CAST(unixepoch('subsec') * 1000 AS INTEGER));
+END;
+
+-- Reactivating a retired version, as Undelete does, sets the key
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_changes_reactivate
+AFTER UPDATE OF is_active ON ` + t.kv + `
+FOR EACH ROW
+WHEN OLD.is_active = 0 AND NEW.is_active = 1
+BEGIN
+  INSERT INTO ` + t.changes + ` (key, op, changed_at)
+  VALUES (NEW.key, 1, CAST(unixepoch('subsec') * 1000 AS INTEGER));
+END;
+
+-- Number and total stored size of active rows, kept up to date by triggers so
+-- that WithMaxEntries and WithMaxBytes needn't compute them on every write.
+-- The triggers are in place before the totals are seeded, so no write is
+-- missed or counted twice.
+CREATE TABLE IF NOT EXISTS ` + t.usage + ` (
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  entries INTEGER NOT NULL,
+  bytes INTEGER NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_usage_insert
+AFTER INSERT ON ` + t.kv + `
+FOR EACH ROW
+WHEN NEW.is_active = 1
+BEGIN
+  UPDATE ` + t.usage + ` SET entries = entries + 1, bytes = bytes + length(NEW.value);
+END;
+
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_usage_update
+AFTER UPDATE OF is_active ON ` + t.kv + `
+FOR EACH ROW
+WHEN NEW.is_active IS NOT OLD.is_active
+BEGIN
+  UPDATE ` + t.usage + `
+  SET entries = entries + NEW.is_active - OLD.is_active,
+      bytes = bytes + NEW.is_active * length(NEW.value) - OLD.is_active * length(OLD.value);
+END;
+
+-- Values rewritten in place, as by RotateEncryptionKey
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_usage_rewrite
+AFTER UPDATE OF value ON ` + t.kv + `
+FOR EACH ROW
+WHEN OLD.is_active = 1 AND NEW.is_active = 1 AND length(NEW.value) IS NOT length(OLD.value)
+BEGIN
+  UPDATE ` + t.usage + ` SET bytes = bytes + length(NEW.value) - length(OLD.value);
+END;
+
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_usage_delete
+AFTER DELETE ON ` + t.kv + `
+FOR EACH ROW
+WHEN OLD.is_active = 1
+BEGIN
+  UPDATE ` + t.usage + ` SET entries = entries - 1, bytes = bytes - length(OLD.value);
+END;
+
+INSERT OR IGNORE INTO ` + t.usage + ` (id, entries, bytes)
+SELECT 1, COUNT(*), ifnull(SUM(length(value)), 0) FROM ` + t.kv + ` WHERE is_active = 1;
+
+-- Keys exempt from eviction (see Pin); hard is 1 for PinHard, whose keys
+-- never expire either. Keys are stored in lower case by clients with
+-- WithCaseInsensitiveKeys, and compared with the table's key column on the
+-- left so that its collation applies.
+CREATE TABLE IF NOT EXISTS ` + t.pins + ` (
+  key TEXT PRIMARY KEY,
+  hard INTEGER NOT NULL
+);
+
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_pins_delete
+AFTER DELETE ON ` + t.kv + `
+FOR EACH ROW
+WHEN OLD.is_active = 1
+BEGIN
+  DELETE FROM ` + t.pins + ` WHERE OLD.key = key;
+END;
+
+-- Hard-pinned keys never get an expiry, whoever writes them
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_pins_hard_insert
+AFTER INSERT ON ` + t.kv + `
+FOR EACH ROW
+WHEN NEW.is_active = 1 AND NEW.expires_at IS NOT NULL
+  AND EXISTS (SELECT 1 FROM ` + t.pins + ` WHERE NEW.key = key AND hard = 1)
+BEGIN
+  UPDATE ` + t.kv + ` SET expires_at = NULL WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_pins_hard_expire
+AFTER UPDATE OF expires_at ON ` + t.kv + `
+FOR EACH ROW
+WHEN NEW.is_active = 1 AND NEW.expires_at IS NOT NULL
+  AND EXISTS (SELECT 1 FROM ` + t.pins + ` WHERE NEW.key = key AND hard = 1)
+BEGIN
+  UPDATE ` + t.kv + ` SET expires_at = NULL WHERE rowid = NEW.rowid;
+END;
+
+-- Negative cache entries recorded by GetOrLoad with WithNegativeTTL: keys the
+-- loader reported as not found, until expires_at. Kept apart from the table
+-- so they can never be read as values. Keys are stored in lower case by
+-- clients with WithCaseInsensitiveKeys.
+CREATE TABLE IF NOT EXISTS ` + t.tombstones + ` (
+  key TEXT PRIMARY KEY,
+  expires_at INTEGER NOT NULL
+);
+
+-- A value written for a key replaces its tombstone
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_clear_tombstone
+AFTER INSERT ON ` + t.kv + `
+FOR EACH ROW
+BEGIN
+  DELETE FROM ` + t.tombstones + ` WHERE key IN (NEW.key, lower(NEW.key));
+END;
+`
+}
+
+// triggerMigration describes a trigger the Go target defines itself, in place
+// of any earlier definition under the same name.
+type triggerMigration struct {
+	name string
+	// current is a fragment of the trigger's SQL found in no earlier
+	// definition, by which an up-to-date trigger is recognized.
+	current string
+	create  string
+}
+
+// goTriggers returns the triggers migrateSchema replaces when their definition
+// is out of date, after goSchemaSQL has run.
+func goTriggers(t tableNames) []triggerMigration {
+	return []triggerMigration{
+		// Replaces the shared schema's kv_swap_active, which only clears
+		// is_active. Stamping deactivated_at in the same statement marks the
+		// version as overwritten, not deleted, for the triggers below, whatever
+		// order SQLite fires them in; kv_stamp_deactivated stamps the versions
+		// retired by any other statement afterwards.
+		{name: t.kv + "_swap_active", current: "deactivated_at", create: `
+CREATE TRIGGER ` + t.kv + `_swap_active
+BEFORE INSERT ON ` + t.kv + `
+FOR EACH ROW
+BEGIN
+  UPDATE ` + t.kv + ` SET is_active = 0, deactivated_at = CAST(unixepoch('subsec') * 1000 AS INTEGER)
+  WHERE key = NEW.key AND is_active = 1;
+END;`},
+		{name: t.kv + "_changes_insert", current: "WHEN NEW.is_active = 1", create: `
+CREATE TRIGGER ` + t.kv + `_changes_insert
+AFTER INSERT ON ` + t.kv + `
+FOR EACH ROW
+WHEN NEW.is_active = 1
+BEGIN
+  INSERT INTO ` + t.changes + ` (key, op, changed_at)
+  VALUES (NEW.key, 1, CAST(unixepoch('subsec') * 1000 AS INTEGER));
+END;`},
+		// Versions retired by kv_swap_active were overwritten, not deleted.
+		{name: t.kv + "_changes_deactivate", current: "NEW.deactivated_at IS NULL", create: `
+CREATE TRIGGER ` + t.kv + `_changes_deactivate
+AFTER UPDATE OF is_active ON ` + t.kv + `
+FOR EACH ROW
+WHEN OLD.is_active = 1 AND NEW.is_active = 0 AND NEW.deactivated_at IS NULL
+BEGIN
+  INSERT INTO ` + t.changes + ` (key, op, changed_at)
+  VALUES (OLD.key, 2, CAST(unixepoch('subsec') * 1000 AS INTEGER));
+END;`},
+		// Rewriting a live value in place, as Append and RotateEncryptionKey
+		// do, sets the key. Rename re-encrypting a value as it moves it is
+		// recorded by kv_changes_rename alone.
+		{name: t.kv + "_changes_rewrite", current: "NEW.key IS OLD.key", create: `
+CREATE TRIGGER ` + t.kv + `_changes_rewrite
+AFTER UPDATE OF value ON ` + t.kv + `
+FOR EACH ROW
+WHEN OLD.is_active = 1 AND NEW.is_active = 1 AND NEW.value IS NOT OLD.value
+  AND NEW.key IS OLD.key
+BEGIN
+  INSERT INTO ` + t.changes + ` (key, op, changed_at)
+  VALUES (NEW.key, 1, CAST(unixepoch('subsec') * 1000 AS INTEGER));
+END;`},
+		// A pin ends with its key, deleted or expired, but not with an overwrite
+		// of a live value.
+		{name: t.kv + "_pins_deactivate", current: "NEW.deactivated_at IS NULL", create: `
+CREATE TRIGGER ` + t.kv + `_pins_deactivate
+AFTER UPDATE OF is_active ON ` + t.kv + `
+FOR EACH ROW
+WHEN OLD.is_active = 1 AND NEW.is_active = 0
+  AND (NEW.deactivated_at IS NULL
+    OR OLD.expires_at <= CAST(unixepoch('subsec') * 1000 AS INTEGER))
+BEGIN
+  DELETE FROM ` + t.pins + ` WHERE OLD.key = key;
+END;`},
+	}
+}
+
+// checkKeyCollation verifies that the key column's collation matches the
+// WithCaseInsensitiveKeys option, since a table's collation is fixed when it
+// is created and the two modes must not be mixed.
+func checkKeyCollation(db *sql.DB, t tableNames, caseInsensitive bool) error {
+	// The unique index on active keys inherits the column's collation.
+	var coll sql.NullString
+	query := `SELECT coll FROM pragma_index_xinfo(?) WHERE name = 'key';`
+	if err := db.QueryRow(query, t.kv+"_active_key").Scan(&coll); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to inspect key collation: %w", err)
+	}
+
+	nocase := strings.EqualFold(coll.String, "NOCASE")
+	switch {
+	case caseInsensitive && !nocase:
+		return fmt.Errorf("database has case-sensitive keys; it cannot be opened with WithCaseInsensitiveKeys")
+	case !caseInsensitive && nocase:
+		return fmt.Errorf("database has case-insensitive keys; open it with WithCaseInsensitiveKeys")
+	}
+	return nil
+}
+
+// migrateSchema brings a database initialized with SchemaSQL up to date with
+// the columns, indexes and triggers used by this package.
+func migrateSchema(db *sql.DB, t tableNames) error {
+	for _, m := range goColumns {
+		exists, err := columnExists(db, t.kv, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", t.kv, m.column, m.decl)
+		if _, err := db.Exec(stmt); err != nil {
+			// Another process may have migrated the same file concurrently.
+			if exists, _ := columnExists(db, t.kv, m.column); exists {
+				continue
+			}
+			return fmt.Errorf("failed to add column %s.%s: %w", t.kv, m.column, err)
+		}
+	}
+
+	if err := migrateVersionIDs(db, t); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(goSchemaSQL(t)); err != nil {
+		return fmt.Errorf("failed to create indexes and triggers: %w", err)
+	}
+	// The earlier change feed triggers marked inserts in progress with these.
+	if _, err := db.Exec(`DROP TRIGGER IF EXISTS ` + t.kv + `_changes_insert_begin;
+DROP INDEX IF EXISTS ` + t.kv + `_changes_pending;`); err != nil {
+		return fmt.Errorf("failed to drop triggers: %w", err)
+	}
+
+	for _, m := range goTriggers(t) {
+		current, err := triggerCurrent(db, m)
+		if err != nil {
+			return err
+		}
+		if current {
+			continue
+		}
+		// Replacing the trigger in one transaction leaves no moment without it.
+		err = runTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + m.name + `;`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(m.create)
+			return err
+		})
+		if err != nil {
+			// Another process may have migrated the same file concurrently.
+			if current, _ := triggerCurrent(db, m); current {
+				continue
+			}
+			return fmt.Errorf("failed to create trigger %s: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// versionIDColumn is the INTEGER PRIMARY KEY migrateVersionIDs gives kv. As
+// an alias for the rowid, it keeps the rowids used as version IDs from being
+// renumbered by VACUUM.
+const versionIDColumn = "id"
+
+// migrateVersionIDs rebuilds kv with versionIDColumn, keeping every row's
+// rowid, unless it already has it. SQLite can't add a primary key to a table,
+// so the rows are copied into a new one, and the indexes and triggers dropped
+// with the old table, as well as the views, are recreated from their stored
+// SQL, all in one transaction.
+func migrateVersionIDs(db *sql.DB, t tableNames) error {
+	exists, err := columnExists(db, t.kv, versionIDColumn)
+	if err != nil || exists {
+		return err
+	}
+
+	err = runTx(db, func(tx *sql.Tx) error {
+		var create string
+		query := `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?;`
+		if err := tx.QueryRow(query, t.kv).Scan(&create); err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+		dependents, err := queryStrings(tx, `SELECT sql FROM sqlite_master
+WHERE (tbl_name = ? AND type IN ('index', 'trigger') OR type = 'view') AND sql IS NOT NULL;`, t.kv)
+		if err != nil {
+			return err
+		}
+		// Views would stop the table from being renamed while they refer to
+		// a missing one.
+		views, err := queryStrings(tx, `SELECT name FROM sqlite_master WHERE type = 'view';`)
+		if err != nil {
+			return err
+		}
+		columns, err := queryStrings(tx, `SELECT name FROM pragma_table_info(?);`, t.kv)
+		if err != nil {
+			return err
+		}
+
+		list := strings.Join(columns, ", ")
+		var stmts []string
+		for _, view := range views {
+			stmts = append(stmts, `DROP VIEW `+view+`;`)
+		}
+		stmts = append(stmts,
+			`CREATE TABLE `+t.kv+`_rebuild (`+versionIDColumn+` INTEGER PRIMARY KEY, `+create[strings.Index(create, "(")+1:]+`;`,
+			`INSERT INTO `+t.kv+`_rebuild (`+versionIDColumn+`, `+list+`) SELECT rowid, `+list+` FROM `+t.kv+`;`,
+			`DROP TABLE `+t.kv+`;`,
+			`ALTER TABLE `+t.kv+`_rebuild RENAME TO `+t.kv+`;`,
+		)
+		for _, stmt := range append(stmts, dependents...) {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("exec failed: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Another process may have migrated the same file concurrently.
+		if exists, _ := columnExists(db, t.kv, versionIDColumn); exists {
+			return nil
+		}
+		return fmt.Errorf("failed to add column %s.%s: %w", t.kv, versionIDColumn, err)
+	}
+	return nil
+}
+
+// triggerCurrent reports whether the trigger described by m exists with its
+// current definition.
+func triggerCurrent(db *sql.DB, m triggerMigration) (bool, error) {
+	var current bool
+	query := `SELECT EXISTS (
+  SELECT 1 FROM sqlite_master
+  WHERE type = 'trigger' AND name = ? AND instr(sql, ?) > 0
+);`
+	if err := db.QueryRow(query, m.name, m.current).Scan(&current); err != nil {
+		return false, fmt.Errorf("failed to inspect trigger %s: %w", m.name, err)
+	}
+	return current, nil
+}
+
+// checkSchema verifies, without writing, that the database already has every
+// column migrateSchema and migrateVersionIDs would add. Read-only clients
+// cannot migrate.
+func checkSchema(db *sql.DB, t tableNames) error {
+	for _, m := range goColumns {
+		exists, err := columnExists(db, t.kv, m.column)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("database has no column %s.%s; open it once without WithReadOnly to initialize it", t.kv, m.column)
+		}
+	}
+	exists, err := columnExists(db, t.kv, versionIDColumn)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("database has no column %s.%s; open it once without WithReadOnly to initialize it", t.kv, versionIDColumn)
+	}
+	return nil
+}
+
+// columnExists reports whether table already has the named column.
+func columnExists(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, fmt.Errorf("scan failed: %w", err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return false, nil
+}
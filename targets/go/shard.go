@@ -0,0 +1,279 @@
+package squeakyv
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+// ShardedClient spreads keys over several CacheClients, each with its own
+// database file, so that writes to different shards don't contend for the
+// same SQLite write lock.
+//
+// Every key lives in exactly one shard, chosen by hashing it (see
+// WithShardHash). Single-key operations go to that shard alone and behave as
+// the CacheClient methods of the same name. Operations on several keys are
+// split by shard: each shard's part is atomic, but the operation as a whole
+// is not, so a SetMany spanning shards can fail with some shards written.
+// Listings fan out to every shard and merge the results.
+//
+// The mapping from keys to shards depends on the number and order of paths,
+// so a sharded database must always be opened with the same paths in the
+// same order. A ShardedClient is safe for concurrent use.
+type ShardedClient struct {
+	shards []*CacheClient
+	hash   func(key string) uint64
+}
+
+// NewShardedClient opens a CacheClient for each of paths, with opts, and
+// returns a client sharding keys across them. If any shard fails to open, the
+// ones already opened are closed.
+//
+// Example:
+//
+//	client, err := squeakyv.NewShardedClient([]string{
+//		"/data/0/cache.db",
+//		"/data/1/cache.db",
+//		"/data/2/cache.db",
+//		"/data/3/cache.db",
+//	})
+func NewShardedClient(paths []string, opts ...Option) (*ShardedClient, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no shard paths given")
+	}
+	seen := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		if _, ok := seen[path]; ok && path != ":memory:" {
+			return nil, fmt.Errorf("duplicate shard path %q", path)
+		}
+		seen[path] = struct{}{}
+	}
+
+	s := &ShardedClient{shards: make([]*CacheClient, 0, len(paths))}
+	for _, path := range paths {
+		c, err := NewCacheClient(path, opts...)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("shard %q: %w", path, err)
+		}
+		s.shards = append(s.shards, c)
+	}
+
+	s.hash = s.shards[0].opts.shardHash
+	if s.hash == nil {
+		s.hash = fnvHash
+		if s.shards[0].opts.caseInsensitiveKeys {
+			s.hash = func(key string) uint64 { return fnvHash(foldKey(key)) }
+		}
+	}
+	return s, nil
+}
+
+// fnvHash is the default shard hash, 64-bit FNV-1a.
+func fnvHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// Shard returns the client holding key.
+func (s *ShardedClient) Shard(key string) *CacheClient {
+	return s.shards[s.hash(key)%uint64(len(s.shards))]
+}
+
+// Shards returns the client of every shard, in the order of the paths given
+// to NewShardedClient. They can be used for operations ShardedClient doesn't
+// provide, but must not be closed directly.
+func (s *ShardedClient) Shards() []*CacheClient {
+	return append([]*CacheClient(nil), s.shards...)
+}
+
+// Get retrieves the value for a key, returning nil if it doesn't exist. See
+// CacheClient.Get.
+func (s *ShardedClient) Get(key string) ([]byte, error) {
+	return s.Shard(key).Get(key)
+}
+
+// GetStrict retrieves the value for a key, returning an error wrapping
+// ErrKeyNotFound if it doesn't exist. See CacheClient.GetStrict.
+func (s *ShardedClient) GetStrict(key string) ([]byte, error) {
+	return s.Shard(key).GetStrict(key)
+}
+
+// Set stores a value for a key. See CacheClient.Set.
+func (s *ShardedClient) Set(key string, value []byte) error {
+	return s.Shard(key).Set(key, value)
+}
+
+// SetWithTTL stores a value for a key that expires after ttl. See
+// CacheClient.SetWithTTL.
+func (s *ShardedClient) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return s.Shard(key).SetWithTTL(key, value, ttl)
+}
+
+// Delete soft-deletes a key. See CacheClient.Delete.
+func (s *ShardedClient) Delete(key string) error {
+	return s.Shard(key).Delete(key)
+}
+
+// Exists reports whether a key has a live value. See CacheClient.Exists.
+func (s *ShardedClient) Exists(key string) (bool, error) {
+	return s.Shard(key).Exists(key)
+}
+
+// GetMany retrieves the values for several keys, with one GetMany per shard
+// involved. See CacheClient.GetMany.
+func (s *ShardedClient) GetMany(keys []string) (map[string][]byte, error) {
+	byShard := make(map[*CacheClient][]string)
+	for _, key := range keys {
+		shard := s.Shard(key)
+		byShard[shard] = append(byShard[shard], key)
+	}
+
+	results := make(map[string][]byte, len(keys))
+	for shard, keys := range byShard {
+		values, err := shard.GetMany(keys)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			results[key] = value
+		}
+	}
+	return results, nil
+}
+
+// SetMany stores several values, with one SetMany per shard involved. The
+// values stored in each shard are written atomically, but SetMany as a whole
+// is not atomic: if it fails, shards written before the failure keep their
+// values. See CacheClient.SetMany.
+func (s *ShardedClient) SetMany(items map[string][]byte) error {
+	byShard := make(map[*CacheClient]map[string][]byte)
+	for key, value := range items {
+		shard := s.Shard(key)
+		if byShard[shard] == nil {
+			byShard[shard] = make(map[string][]byte)
+		}
+		byShard[shard][key] = value
+	}
+
+	for _, shard := range s.shards {
+		if items, ok := byShard[shard]; ok {
+			if err := shard.SetMany(items); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteMany soft-deletes several keys, with one DeleteMany per shard
+// involved, and returns how many were present. As with SetMany, only each
+// shard's part is atomic. See CacheClient.DeleteMany.
+func (s *ShardedClient) DeleteMany(keys []string) (int, error) {
+	byShard := make(map[*CacheClient][]string)
+	for _, key := range keys {
+		shard := s.Shard(key)
+		byShard[shard] = append(byShard[shard], key)
+	}
+
+	total := 0
+	for _, shard := range s.shards {
+		if keys, ok := byShard[shard]; ok {
+			n, err := shard.DeleteMany(keys)
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+// ListKeys returns the live keys of every shard, ordered by insertion time
+// (newest first) as CacheClient.ListKeys orders them. The shards are read one
+// after another, not from a single snapshot.
+func (s *ShardedClient) ListKeys() ([]string, error) {
+	var all []writtenKey
+	for _, shard := range s.shards {
+		keys, err := shard.listWrittenKeys()
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, keys...)
+	}
+
+	sort.SliceStable(all, func(i, j int) bool { return all[i].writtenAt > all[j].writtenAt })
+	keys := make([]string, len(all))
+	for i, k := range all {
+		keys[i] = k.key
+	}
+	return keys, nil
+}
+
+// Count returns the number of keys with a live value across every shard.
+func (s *ShardedClient) Count() (int, error) {
+	total := 0
+	for _, shard := range s.shards {
+		n, err := shard.Count()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Close closes every shard, returning their errors joined. Calling Close more
+// than once is safe.
+func (s *ShardedClient) Close() error {
+	var errs []error
+	for _, shard := range s.shards {
+		if err := shard.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writtenKey is a live key with the time its value was written, or last
+// touched.
+type writtenKey struct {
+	key       string
+	writtenAt int64
+}
+
+// listWrittenKeys returns the live keys with their write times, newest first.
+func (c *CacheClient) listWrittenKeys() ([]writtenKey, error) {
+	query := `SELECT key, ` + lastWritten + `
+FROM ` + c.tables.kv + `
+WHERE ` + liveCondition + `
+ORDER BY ` + lastWritten + ` DESC;`
+
+	db, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	rows, err := db.Query(query, nowMillis())
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []writtenKey
+	for rows.Next() {
+		var k writtenKey
+		if err := rows.Scan(&k.key, &k.writtenAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration failed: %w", err)
+	}
+	return keys, nil
+}
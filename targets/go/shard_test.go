@@ -0,0 +1,197 @@
+package squeakyv
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newShardedTestClient returns a ShardedClient over n file databases.
+func newShardedTestClient(t testing.TB, n int, opts ...Option) *ShardedClient {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("shard%d.db", i))
+	}
+	client, err := NewShardedClient(paths, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create sharded client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestShardedClient(t *testing.T) {
+	client := newShardedTestClient(t, 4)
+
+	for i := 0; i < 40; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if err := client.Set(key, []byte(key)); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	for i := 0; i < 40; i++ {
+		key := fmt.Sprintf("key%d", i)
+		value, err := client.Get(key)
+		if err != nil || string(value) != key {
+			t.Errorf("Expected %q for %q, got %q, %v", key, key, value, err)
+		}
+		if value, _ := client.Shard(key).Get(key); string(value) != key {
+			t.Errorf("Expected %q to be stored in its shard", key)
+		}
+	}
+
+	used := 0
+	for _, shard := range client.Shards() {
+		if n, _ := shard.Count(); n > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Errorf("Expected keys spread over several shards, got %d", used)
+	}
+
+	if n, err := client.Count(); err != nil || n != 40 {
+		t.Errorf("Expected a count of 40, got %d, %v", n, err)
+	}
+
+	client.Delete("key0")
+	if exists, _ := client.Exists("key0"); exists {
+		t.Error("Expected key0 to be deleted")
+	}
+	if _, err := client.GetStrict("key0"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestShardedClientBatches(t *testing.T) {
+	client := newShardedTestClient(t, 3)
+
+	items := make(map[string][]byte)
+	for i := 0; i < 30; i++ {
+		items[fmt.Sprintf("key%d", i)] = []byte(strconv.Itoa(i))
+	}
+	if err := client.SetMany(items); err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+
+	values, err := client.GetMany([]string{"key1", "key2", "key29", "missing"})
+	if err != nil {
+		t.Fatalf("GetMany failed: %v", err)
+	}
+	if len(values) != 3 || string(values["key29"]) != "29" {
+		t.Errorf("Expected 3 values, got %q", values)
+	}
+
+	n, err := client.DeleteMany([]string{"key1", "key2", "missing"})
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 keys deleted, got %d, %v", n, err)
+	}
+	if count, _ := client.Count(); count != 28 {
+		t.Errorf("Expected 28 keys left, got %d", count)
+	}
+}
+
+func TestShardedClientListKeys(t *testing.T) {
+	client := newShardedTestClient(t, 3)
+
+	var want []string
+	for i := 0; i < 6; i++ {
+		key := fmt.Sprintf("key%d", i)
+		client.Set(key, []byte("v"))
+		want = append([]string{key}, want...)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	keys, err := client.ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys failed: %v", err)
+	}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected keys newest first %v, got %v", want, keys)
+	}
+}
+
+func TestShardedClientHash(t *testing.T) {
+	client := newShardedTestClient(t, 2, WithShardHash(func(key string) uint64 {
+		if strings.HasPrefix(key, "b") {
+			return 1
+		}
+		return 0
+	}))
+
+	client.Set("apple", []byte("1"))
+	client.Set("banana", []byte("2"))
+
+	shards := client.Shards()
+	if exists, _ := shards[0].Exists("apple"); !exists {
+		t.Error("Expected apple in shard 0")
+	}
+	if exists, _ := shards[1].Exists("banana"); !exists {
+		t.Error("Expected banana in shard 1")
+	}
+}
+
+func TestShardedClientCaseInsensitive(t *testing.T) {
+	client := newShardedTestClient(t, 4, WithCaseInsensitiveKeys())
+
+	for i := 0; i < 20; i++ {
+		client.Set(fmt.Sprintf("Key%d", i), []byte("v"))
+	}
+	for i := 0; i < 20; i++ {
+		if value, _ := client.Get(fmt.Sprintf("KEY%d", i)); string(value) != "v" {
+			t.Errorf("Expected KEY%d to find Key%d", i, i)
+		}
+	}
+}
+
+func TestNewShardedClientErrors(t *testing.T) {
+	if _, err := NewShardedClient(nil); err == nil {
+		t.Error("Expected an error for no paths")
+	}
+	path := filepath.Join(t.TempDir(), "shard.db")
+	if _, err := NewShardedClient([]string{path, path}); err == nil {
+		t.Error("Expected an error for duplicate paths")
+	}
+
+	client, err := NewShardedClient([]string{":memory:", ":memory:"})
+	if err != nil {
+		t.Fatalf("Expected several in-memory shards to be allowed, got %v", err)
+	}
+	client.Close()
+	if err := client.Set("key", []byte("v")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+// BenchmarkShardedSet measures concurrent Set throughput as the number of
+// shards, each a separate file with its own write lock, grows. Throughput
+// scales only with cores and disk bandwidth to spare: run it with -cpu 4 or
+// more, ideally with the shards on separate disks.
+func BenchmarkShardedSet(b *testing.B) {
+	for _, n := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("shards=%d", n), func(b *testing.B) {
+			client := newShardedTestClient(b, n)
+			value := []byte("value")
+			var next atomic.Int64
+
+			b.SetParallelism(4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					key := "key" + strconv.FormatInt(next.Add(1), 10)
+					if err := client.Set(key, value); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
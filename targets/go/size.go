@@ -0,0 +1,101 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SizeInfo summarizes how much data a cache holds.
+type SizeInfo struct {
+	// ActiveBytes is the total size of all live values.
+	ActiveBytes int64
+	// HistoryBytes is the total size of all retained versions that are not
+	// live: overwritten, deleted, and expired values.
+	HistoryBytes int64
+	// ActiveKeys is the number of keys with a live value.
+	ActiveKeys int64
+	// TotalVersions is the number of stored versions, live or not.
+	TotalVersions int64
+}
+
+// Size reports how many bytes of values are live versus retained as history,
+// computed with a single aggregate query. Value sizes are measured in SQLite,
+// as stored (compressed values count their compressed size); no value is
+// read into memory.
+//
+// Example:
+//
+//	info, err := client.Size()
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Printf("%d keys, %d bytes live, %d bytes history\n",
+//		info.ActiveKeys, info.ActiveBytes, info.HistoryBytes)
+func (c *CacheClient) Size() (SizeInfo, error) {
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return SizeInfo{}, err
+	}
+	defer c.release()
+
+	query := `SELECT
+  COALESCE(SUM(CASE WHEN ` + liveCondition + ` THEN length(value) END), 0),
+  COALESCE(SUM(CASE WHEN ` + liveCondition + ` THEN 0 ELSE length(value) END), 0),
+  COUNT(CASE WHEN ` + liveCondition + ` THEN 1 END),
+  COUNT(*)
+FROM ` + c.tables.kv + `;`
+
+	now := nowMillis()
+	var info SizeInfo
+	err = db.QueryRow(query, now, now, now).Scan(
+		&info.ActiveBytes, &info.HistoryBytes, &info.ActiveKeys, &info.TotalVersions,
+	)
+	if err != nil {
+		return SizeInfo{}, fmt.Errorf("query failed: %w", err)
+	}
+	return info, nil
+}
+
+// SizeOf returns the size in bytes of a key's current value, as stored,
+// without reading the value. Returns an error wrapping ErrKeyNotFound if the
+// key doesn't exist.
+func (c *CacheClient) SizeOf(key string) (int64, error) {
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	query := `SELECT length(value)
+FROM ` + c.tables.kv + `
+WHERE key = ? AND ` + liveCondition + `;`
+
+	var size int64
+	err = db.QueryRow(query, key, nowMillis()).Scan(&size)
+	if err == sql.ErrNoRows {
+		return 0, keyNotFound(key)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+	return size, nil
+}
+
+// FileSize returns the size in bytes of the main database file, excluding any
+// WAL, from its page count, which SQLite keeps in the file header. In-memory
+// databases report the size they would have as a file.
+func (c *CacheClient) FileSize() (int64, error) {
+	db, err := c.acquire()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	query := `SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size();`
+
+	var size int64
+	if err := db.QueryRow(query).Scan(&size); err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+	return size, nil
+}
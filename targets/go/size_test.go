@@ -0,0 +1,82 @@
+package squeakyv
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSize(t *testing.T) {
+	client := newTestClient(t)
+
+	info, err := client.Size()
+	if err != nil {
+		t.Fatalf("Size failed: %v", err)
+	}
+	if info != (SizeInfo{}) {
+		t.Errorf("Expected zero size for empty cache, got %+v", info)
+	}
+
+	client.Set("a", []byte("12345"))   // 5 live
+	client.Set("b", []byte("123"))     // 3, overwritten below
+	client.Set("b", []byte("1234567")) // 7 live
+	client.Set("c", []byte("12"))      // 2, deleted below
+	client.Delete("c")
+
+	info, err = client.Size()
+	if err != nil {
+		t.Fatalf("Size failed: %v", err)
+	}
+	expected := SizeInfo{
+		ActiveBytes:   12,
+		HistoryBytes:  5,
+		ActiveKeys:    2,
+		TotalVersions: 4,
+	}
+	if info != expected {
+		t.Errorf("Expected %+v, got %+v", expected, info)
+	}
+}
+
+func TestSizeOf(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte{0x00, 0x01, 0x02})
+	client.Set("empty", []byte{})
+
+	size, err := client.SizeOf("key")
+	if err != nil {
+		t.Fatalf("SizeOf failed: %v", err)
+	}
+	if size != 3 {
+		t.Errorf("Expected size 3, got %d", size)
+	}
+
+	size, err = client.SizeOf("empty")
+	if err != nil {
+		t.Fatalf("SizeOf failed: %v", err)
+	}
+	if size != 0 {
+		t.Errorf("Expected size 0, got %d", size)
+	}
+
+	if _, err := client.SizeOf("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestFileSize(t *testing.T) {
+	client := newTestClient(t)
+
+	before, err := client.FileSize()
+	if err != nil {
+		t.Fatalf("FileSize failed: %v", err)
+	}
+	client.Set("a", make([]byte, 64<<10))
+	after, err := client.FileSize()
+	if err != nil {
+		t.Fatalf("FileSize failed: %v", err)
+	}
+	if before <= 0 || after < before+64<<10 {
+		t.Errorf("Expected the file to grow by the value, got %d then %d", before, after)
+	}
+}
@@ -0,0 +1,39 @@
+package squeakyv
+
+import "time"
+
+// SlowOp describes an operation that took longer than the threshold set with
+// WithSlowOpThreshold.
+type SlowOp struct {
+	// Op names the operation: "get", "set", "delete", "list_keys",
+	// "set_with_ttl", "get_many", "set_many" or "delete_many".
+	Op string
+	// Key is the key operated on, or empty for operations on several keys.
+	Key string
+	// Keys is the number of keys operated on, or listed by list_keys.
+	Keys int
+	// Duration is how long the operation took.
+	Duration time.Duration
+	// LockWait is the part of Duration spent waiting for the database lock:
+	// on write attempts that failed because another connection held it, in
+	// backoff between retries, and, for batch writes, behind the client's
+	// other write transactions. Time SQLite's busy handler spends waiting
+	// before an attempt succeeds cannot be observed and counts as executing.
+	LockWait time.Duration
+}
+
+// Executing returns the part of Duration not spent waiting for the lock.
+func (s SlowOp) Executing() time.Duration {
+	return s.Duration - s.LockWait
+}
+
+// reportSlow calls the WithSlowOpThreshold callback if the operation started
+// at start has taken longer than the threshold.
+func (c *CacheClient) reportSlow(op, key string, n int, start time.Time, wait time.Duration) {
+	if c.opts.slowOp == nil {
+		return
+	}
+	if elapsed := time.Since(start); elapsed > c.opts.slowOpThreshold {
+		c.opts.slowOp(SlowOp{Op: op, Key: key, Keys: n, Duration: elapsed, LockWait: wait})
+	}
+}
@@ -0,0 +1,99 @@
+package squeakyv
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowOpRecorder collects the SlowOps reported to it.
+type slowOpRecorder struct {
+	mu  sync.Mutex
+	ops []SlowOp
+}
+
+func (r *slowOpRecorder) record(op SlowOp) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops = append(r.ops, op)
+}
+
+func TestWithSlowOpThreshold(t *testing.T) {
+	rec := &slowOpRecorder{}
+	client, err := NewCacheClient(":memory:", WithSlowOpThreshold(time.Nanosecond, rec.record))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("a", []byte("value"))
+	client.Get("a")
+	client.GetMany([]string{"a", "b"})
+	client.ListKeys()
+
+	want := []SlowOp{
+		{Op: "set", Key: "a", Keys: 1},
+		{Op: "get", Key: "a", Keys: 1},
+		{Op: "get_many", Keys: 2},
+		{Op: "list_keys", Keys: 1},
+	}
+	if len(rec.ops) != len(want) {
+		t.Fatalf("Expected %d slow operations, got %+v", len(want), rec.ops)
+	}
+	for i, w := range want {
+		got := rec.ops[i]
+		if got.Op != w.Op || got.Key != w.Key || got.Keys != w.Keys || got.Duration <= 0 || got.LockWait != 0 {
+			t.Errorf("Expected %+v, got %+v", w, got)
+		}
+	}
+}
+
+func TestWithSlowOpThresholdFast(t *testing.T) {
+	rec := &slowOpRecorder{}
+	client, err := NewCacheClient(":memory:", WithSlowOpThreshold(time.Minute, rec.record))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("a", []byte("value"))
+	client.Get("a")
+	if len(rec.ops) != 0 {
+		t.Errorf("Expected no slow operations, got %+v", rec.ops)
+	}
+}
+
+func TestWithSlowOpThresholdLockWait(t *testing.T) {
+	rec := &slowOpRecorder{}
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path,
+		WithBusyTimeout(time.Millisecond),
+		WithRetry(1000, 10*time.Second),
+		WithSlowOpThreshold(20*time.Millisecond, rec.record),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	for _, write := range []func() error{
+		func() error { return client.Set("a", []byte("value")) },
+		func() error { return client.SetMany(map[string][]byte{"b": []byte("value")}) },
+	} {
+		release := lockDatabase(t, path)
+		time.AfterFunc(50*time.Millisecond, release)
+		if err := write(); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	if len(rec.ops) != 2 {
+		t.Fatalf("Expected both writes reported, got %+v", rec.ops)
+	}
+	for _, op := range rec.ops {
+		if op.LockWait < 40*time.Millisecond || op.LockWait > op.Duration || op.Executing() < 0 {
+			t.Errorf("Expected most of %s to be spent waiting for the lock, got %+v", op.Op, op)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Snapshot is a read-only, point-in-time view of a cache, created by
+// CacheClient.Snapshot. Reads through a Snapshot never observe writes made
+// after it was taken, and keys are considered expired as of that moment.
+//
+// A Snapshot holds an open read transaction on its own connection until it is
+// closed, so always call Close. In WAL journal mode, the default for
+// file-backed caches, writers are not blocked; with a rollback journal an open
+// snapshot makes writers wait.
+type Snapshot struct {
+	client *CacheClient
+	now    int64
+
+	// mu guards tx, which is nil once the snapshot is closed.
+	mu sync.RWMutex
+	tx *sql.Tx
+}
+
+// Snapshot starts a consistent read-only view of the cache.
+//
+// Snapshots are not supported on in-memory databases, whose single connection
+// would be held for the snapshot's whole lifetime. Closing the client closes
+// any snapshots still open.
+//
+// Example:
+//
+//	snap, err := client.Snapshot()
+//	if err != nil {
+//		return err
+//	}
+//	defer snap.Close()
+//
+//	err = snap.ForEach(func(key string, value []byte) error {
+//		return export(key, value)
+//	})
+func (c *CacheClient) Snapshot() (*Snapshot, error) {
+	if isMemoryPath(c.path) {
+		return nil, errors.New("snapshots are not supported on in-memory databases")
+	}
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin failed: %w", err)
+	}
+	// A deferred transaction only pins its read snapshot on first read.
+	var one int
+	if err := tx.QueryRow(`SELECT 1 FROM ` + c.tables.kv + ` LIMIT 1;`).Scan(&one); err != nil && err != sql.ErrNoRows {
+		tx.Rollback()
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	s := &Snapshot{client: c, now: nowMillis(), tx: tx}
+
+	c.snapMu.Lock()
+	if c.snapshots == nil {
+		c.snapshots = make(map[*Snapshot]struct{})
+	}
+	c.snapshots[s] = struct{}{}
+	c.snapMu.Unlock()
+
+	return s, nil
+}
+
+// Get retrieves the value for a key as of the snapshot, returning nil if the
+// key didn't exist. See CacheClient.Get.
+func (s *Snapshot) Get(key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.tx == nil {
+		return nil, ErrClosed
+	}
+	stored, err := readLiveValue(s.tx, s.client.tables, key, s.now)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.client.decodeStored(key, stored)
+}
+
+// ListKeys returns the keys that were active when the snapshot was taken,
+// newest first. See CacheClient.ListKeys.
+func (s *Snapshot) ListKeys() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.tx == nil {
+		return nil, ErrClosed
+	}
+	return listLiveKeys(s.tx, s.client.tables, s.now)
+}
+
+// ForEach calls fn for every key and value in the snapshot, in ascending key
+// order, stopping at the first error returned by fn. See CacheClient.ForEach.
+//
+// Unlike CacheClient.ForEach, fn may freely use the client, including writes,
+// because the snapshot reads on its own connection.
+func (s *Snapshot) ForEach(fn func(key string, value []byte) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.tx == nil {
+		return ErrClosed
+	}
+	return forEachLive(s.tx, s.client.tables, s.now, s.client.decodeEach(fn))
+}
+
+// Close ends the snapshot's read transaction and releases its connection.
+// Calling Close more than once is safe.
+func (s *Snapshot) Close() error {
+	err := s.close()
+
+	s.client.snapMu.Lock()
+	delete(s.client.snapshots, s)
+	s.client.snapMu.Unlock()
+
+	return err
+}
+
+// close rolls back the read transaction without unregistering the snapshot.
+func (s *Snapshot) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tx == nil {
+		return nil
+	}
+	err := s.tx.Rollback()
+	s.tx = nil
+	if err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+	return nil
+}
+
+// closeSnapshots closes every snapshot still open on the client.
+func (c *CacheClient) closeSnapshots() {
+	c.snapMu.Lock()
+	snapshots := c.snapshots
+	c.snapshots = nil
+	c.snapMu.Unlock()
+
+	for s := range snapshots {
+		s.close()
+	}
+}
@@ -0,0 +1,150 @@
+package squeakyv
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// newWALClient opens a file-backed client in WAL journal mode, so that open
+// snapshots do not block writers.
+func newWALClient(t *testing.T) *CacheClient {
+	t.Helper()
+	return newTestClientAt(t, filepath.Join(t.TempDir(), "test.db"), WithJournalMode("WAL"))
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	client := newWALClient(t)
+
+	client.Set("a", []byte("old"))
+	client.Set("b", []byte("value"))
+
+	snap, err := client.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+	defer snap.Close()
+
+	// Writes after the snapshot must not be visible through it
+	if err := client.Set("a", []byte("new")); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if err := client.Delete("b"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if err := client.Set("c", []byte("value")); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	value, err := snap.Get("a")
+	if err != nil {
+		t.Fatalf("Failed to get from snapshot: %v", err)
+	}
+	if string(value) != "old" {
+		t.Errorf("Expected snapshot to see old value, got %s", value)
+	}
+
+	keys, err := snap.ListKeys()
+	if err != nil {
+		t.Fatalf("Failed to list snapshot keys: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("Expected 2 keys in snapshot, got %v", keys)
+	}
+
+	var seen []string
+	err = snap.ForEach(func(key string, value []byte) error {
+		seen = append(seen, key+"="+string(value))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEach failed: %v", err)
+	}
+	if len(seen) != 2 || seen[0] != "a=old" || seen[1] != "b=value" {
+		t.Errorf("Expected [a=old b=value], got %v", seen)
+	}
+
+	// The live client sees the new state
+	value, err = client.Get("a")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if string(value) != "new" {
+		t.Errorf("Expected client to see new value, got %s", value)
+	}
+}
+
+func TestSnapshotForEachCanWrite(t *testing.T) {
+	client := newWALClient(t)
+
+	client.Set("a", []byte("1"))
+	client.Set("b", []byte("2"))
+
+	snap, err := client.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+	defer snap.Close()
+
+	err = snap.ForEach(func(key string, value []byte) error {
+		return client.Set("copy:"+key, value)
+	})
+	if err != nil {
+		t.Fatalf("ForEach failed: %v", err)
+	}
+
+	keys, err := client.ListKeysWithPrefix("copy:")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("Expected 2 copied keys, got %v", keys)
+	}
+}
+
+func TestSnapshotClose(t *testing.T) {
+	client := newWALClient(t)
+
+	snap, err := client.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+	if err := snap.Close(); err != nil {
+		t.Fatalf("Failed to close snapshot: %v", err)
+	}
+	if err := snap.Close(); err != nil {
+		t.Errorf("Second close failed: %v", err)
+	}
+	if _, err := snap.Get("a"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestClientCloseClosesSnapshots(t *testing.T) {
+	client, err := NewCacheClient(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	snap, err := client.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Failed to close client: %v", err)
+	}
+	if _, err := snap.ListKeys(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from snapshot after client close, got %v", err)
+	}
+	if err := snap.Close(); err != nil {
+		t.Errorf("Closing a snapshot after the client failed: %v", err)
+	}
+}
+
+func TestSnapshotMemoryUnsupported(t *testing.T) {
+	client := newTestClient(t)
+
+	if _, err := client.Snapshot(); err == nil {
+		t.Error("Expected error for snapshot on :memory: database")
+	}
+}
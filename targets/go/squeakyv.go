@@ -22,27 +22,70 @@
 package squeakyv
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"sync"
-
-	_ "github.com/mattn/go-sqlite3"
+	"sync/atomic"
+	"time"
 )
 
 // CacheClient provides thread-safe access to a SQLite-backed key-value cache.
 //
 // Each CacheClient maintains a single database connection. The client is safe
 // for concurrent use by multiple goroutines thanks to SQLite's internal locking.
+// Once closed, every operation returns ErrClosed.
 type CacheClient struct {
-	db   *sql.DB
-	path string
-	mu   sync.Mutex
+	db      *sql.DB
+	path    string
+	opts    options
+	keys    *keyring
+	sweeper *sweeper
+	// tables names the client's table and those named after it.
+	tables tableNames
+	// envelopes is set once the database is known to store values in
+	// envelopes (see valueEnvelopesName).
+	envelopes *atomic.Bool
+
+	// mirrorQueue replays writes onto a MirrorAsync mirror.
+	mirrorQueue *mirrorQueue
+
+	// mu guards db: operations hold the read lock for their duration and
+	// Close takes the write lock, so Close waits for in-flight operations.
+	mu sync.RWMutex
+
+	// writeMu serializes write transactions started by withTx.
+	writeMu sync.Mutex
+
+	// snapMu guards snapshots, the snapshots still open on this client.
+	snapMu    sync.Mutex
+	snapshots map[*Snapshot]struct{}
+
+	watches watchHub
+	loads   loadGroup
+	// mem is the WithMemoryCache cache, or nil.
+	mem *memCache
+	// behind buffers writes under WithWriteBehind, or is nil.
+	behind *writeBehind
+	// access notes the keys read under WithMaxEntries or WithMaxBytes, or
+	// is nil.
+	access *accessLog
+	// evictions delivers evictions to WithEvictionCallback, or is nil.
+	evictions *evictionQueue
+
+	stats stats
 }
 
 // NewCacheClient creates a new cache client with the specified database path.
 //
 // Use ":memory:" for an in-memory cache, or provide a file path for persistent storage.
+// SQLite URI filenames are accepted too; see NewSharedMemoryClient for an
+// in-memory cache shared by several clients.
 // The database schema is automatically initialized if it doesn't exist.
+// Behavior can be customized with Option values such as WithSweepInterval.
 //
 // Example:
 //
@@ -51,32 +94,182 @@ type CacheClient struct {
 //		return err
 //	}
 //	defer client.Close()
-func NewCacheClient(path string) (*CacheClient, error) {
-	db, err := sql.Open("sqlite3", path)
+func NewCacheClient(path string, opts ...Option) (*CacheClient, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := checkTableName(o.table); err != nil {
+		return nil, err
+	}
+	if o.mirror != nil && o.mirrorMode != MirrorSync && o.mirrorMode != MirrorAsync {
+		return nil, fmt.Errorf("invalid mirror mode %d", o.mirrorMode)
+	}
+	if o.memCache && o.memCacheEntries <= 0 && o.memCacheBytes <= 0 {
+		return nil, errors.New("memory cache needs an entry or byte limit")
+	}
+	if o.writeBehind {
+		if o.writeBehindBatch <= 0 || o.writeBehindDelay < 0 {
+			return nil, fmt.Errorf("invalid write-behind batch %d or delay %v", o.writeBehindBatch, o.writeBehindDelay)
+		}
+		if o.readOnly {
+			return nil, errors.New("write-behind can't be used with a read-only client")
+		}
+	}
+	if o.maxEntries < 0 || o.maxBytes < 0 {
+		return nil, fmt.Errorf("invalid max entries %d or bytes %d", o.maxEntries, o.maxBytes)
+	}
+	if o.evicts() && o.readOnly {
+		return nil, errors.New("max entries and bytes can't be used with a read-only client")
+	}
+	keys, err := newKeyring(o)
+	if err != nil {
+		return nil, err
+	}
+	if o.readOnly || o.mustExist {
+		if err := checkExists(path); err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+	}
+	if err := prepareFile(path, o); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	tables := newTableNames(o.table)
+	db, err := openClientDB(path, o)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// For :memory: databases, limit to single connection to share the same in-memory DB
-	if path == ":memory:" {
-		db.SetMaxOpenConns(1)
+	if err := initSchema(db, tables, o); err != nil {
+		closeClientDB(path, o, db)
+		return nil, err
+	}
+	if err := checkJournalMode(db, o); err != nil {
+		closeClientDB(path, o, db)
+		return nil, err
+	}
+	if err := checkEncryption(db, tables, keys, o.readOnly); err != nil {
+		closeClientDB(path, o, db)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Initialize schema
-	if _, err := db.Exec(SchemaSQL); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	envelopes, err := openEnvelopes(db, tables, path, o, o.compressor != nil || keys != nil)
+	if err != nil {
+		closeClientDB(path, o, db)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	return &CacheClient{
-		db:   db,
-		path: path,
-	}, nil
+	c := &CacheClient{
+		db:        db,
+		path:      path,
+		opts:      o,
+		tables:    tables,
+		keys:      keys,
+		envelopes: envelopes,
+	}
+	if o.sweepInterval > 0 && !o.readOnly {
+		c.sweeper = startSweeper(c, o.sweepInterval)
+	}
+	if o.mirror != nil && o.mirrorMode == MirrorAsync {
+		c.mirrorQueue = startMirrorQueue(o.mirror, o.mirrorDropped, c.logMirrorRetry)
+	}
+	if o.memCache {
+		c.mem = newMemCache(o.memCacheEntries, o.memCacheBytes)
+		c.mem.startRefresh(c)
+	}
+	if o.writeBehind {
+		c.behind = newWriteBehind(o.writeBehindBatch, o.writeBehindDelay)
+		c.behind.start(c)
+	}
+	if o.evicts() {
+		c.access = newAccessLog()
+	}
+	if o.evictionCallback != nil {
+		c.evictions = startEvictionQueue(o.evictionCallback)
+	}
+	return c, nil
+}
+
+// openClientDB opens the database handle for a new client. Clients of the
+// same shared in-memory database share one handle; see sharedMemory.
+func openClientDB(path string, o options) (*sql.DB, error) {
+	open := func() (*sql.DB, error) {
+		db, err := openDB(path, o)
+		if err != nil {
+			return nil, err
+		}
+		// Each connection to a private in-memory database gets its own
+		// database, and a shared one lives only while a connection is
+		// open, so keep to a single connection that is never recycled.
+		if isMemoryPath(path) {
+			db.SetMaxOpenConns(1)
+		}
+		return db, nil
+	}
+
+	if name, ok := sharedMemoryName(path); ok {
+		return acquireShared(sharedKey{name, o.table}, open)
+	}
+	return open()
+}
+
+// closeClientDB closes a handle returned by openClientDB.
+func closeClientDB(path string, o options, db *sql.DB) error {
+	if name, ok := sharedMemoryName(path); ok {
+		return releaseShared(sharedKey{name, o.table})
+	}
+	return db.Close()
+}
+
+// checkExists fails unless there is an existing database file at path, for
+// options that must not create one. Without it, SQLite would create an empty
+// file or fail with an opaque "unable to open database file".
+func checkExists(path string) error {
+	if isMemoryPath(path) {
+		return errors.New("an existing database file is required, not an in-memory database")
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w at %s", ErrDatabaseNotExist, path)
+		}
+		return err
+	}
+	return nil
+}
+
+// initSchema initializes and migrates the schema, or for read-only clients
+// checks that this has already been done.
+func initSchema(db *sql.DB, t tableNames, o options) error {
+	if o.readOnly {
+		if err := checkSchema(db, t); err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+	} else {
+		if _, err := db.Exec(sharedSchemaSQL(t, o.caseInsensitiveKeys)); err != nil {
+			return fmt.Errorf("failed to initialize schema: %w", err)
+		}
+		if err := migrateSchema(db, t); err != nil {
+			return fmt.Errorf("failed to migrate schema: %w", err)
+		}
+		if o.evicts() {
+			if _, err := db.Exec(lruSchemaSQL(t)); err != nil {
+				return fmt.Errorf("failed to create eviction index: %w", err)
+			}
+		}
+	}
+
+	if err := checkKeyCollation(db, t, o.caseInsensitiveKeys); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	return nil
 }
 
 // Get retrieves the value for a key.
 //
-// Returns nil if the key doesn't exist. The returned byte slice should not be modified.
+// Returns nil if the key doesn't exist or has expired. An expired key is
+// soft-deleted as a side effect, unless the client is read-only. The returned
+// byte slice should not be modified.
 //
 // Example:
 //
@@ -88,7 +281,70 @@ func NewCacheClient(path string) (*CacheClient, error) {
 //		fmt.Println("Key not found")
 //	}
 func (c *CacheClient) Get(key string) ([]byte, error) {
-	return _getCurrentValue(c.db, key)
+	return c.GetContext(context.Background(), key)
+}
+
+// GetContext is Get with a context, which is checked before the lookup starts
+// and carries the parent of its span under WithTracerProvider. It does not
+// interrupt a lookup in progress.
+func (c *CacheClient) GetContext(ctx context.Context, key string) (value []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	_, span := c.startSpan(ctx, "get", key)
+	start := c.opStart()
+	defer func() {
+		span.SetBool(attrHit, value != nil)
+		span.SetInt(attrValueSize, len(value))
+		span.End(err)
+		c.logOp(ctx, "get", key, start, len(value), err)
+		c.reportSlow("get", key, 1, start, 0)
+	}()
+
+	value, err = c.GetStrict(key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// GetStrict retrieves the value for a key, returning an error wrapping
+// ErrKeyNotFound if the key doesn't exist, was deleted, or has expired.
+//
+// Unlike Get, a present but empty value is returned as a non-nil empty slice,
+// so the result is never nil when err is nil.
+//
+// Example:
+//
+//	value, err := client.GetStrict("mykey")
+//	if errors.Is(err, squeakyv.ErrKeyNotFound) {
+//		fmt.Println("Key not found")
+//	}
+func (c *CacheClient) GetStrict(key string) (value []byte, err error) {
+	defer func() {
+		c.stats.recordGet(value, err)
+		if err == nil {
+			c.access.record(c.watchKey(key), nowMillis())
+		}
+		var expired *expiredError
+		if errors.As(err, &expired) {
+			c.evictions.add(eviction{key: expired.key, reason: Expired})
+		}
+	}()
+
+	db, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	if value, ok, err := c.readBehind(key); ok {
+		return value, err
+	}
+	if c.mem != nil {
+		return c.readCached(db, key)
+	}
+	return c.readValue(db, key)
 }
 
 // Set stores a value for a key.
@@ -100,7 +356,53 @@ func (c *CacheClient) Get(key string) ([]byte, error) {
 //
 //	err := client.Set("mykey", []byte("myvalue"))
 func (c *CacheClient) Set(key string, value []byte) error {
-	return _setValue(c.db, key, value)
+	return c.SetContext(context.Background(), key, value)
+}
+
+// SetContext is Set with a context, used as GetContext uses it.
+func (c *CacheClient) SetContext(ctx context.Context, key string, value []byte) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, span := c.startSpan(ctx, "set", key)
+	span.SetInt(attrValueSize, len(value))
+	start := c.opStart()
+	var wait time.Duration
+	defer func() {
+		span.End(err)
+		c.logOp(ctx, "set", key, start, len(value), err)
+		c.reportSlow("set", key, 1, start, wait)
+	}()
+
+	return c.set(key, value, &wait)
+}
+
+// set implements Set, adding the time spent waiting for the database lock to
+// *wait.
+func (c *CacheClient) set(key string, value []byte, wait *time.Duration) (err error) {
+	defer func() { c.stats.recordSet(1, int64(len(value)), err) }()
+
+	if c.behind != nil {
+		return c.bufferWrite(key, WatchSet, value)
+	}
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	stored, err := c.encodeStored(key, value)
+	if err != nil {
+		return err
+	}
+	err = c.writeEvicting(db, wait, func(db querier) error {
+		return insertVersion(db, c.tables, key, stored, sql.NullInt64{})
+	})
+	if err != nil {
+		return err
+	}
+	c.notify(key, WatchSet, value)
+	return c.mirror(mirrorWrite{key: key, op: WatchSet, value: value})
 }
 
 // Delete removes a key (soft delete - marks as inactive).
@@ -112,10 +414,48 @@ func (c *CacheClient) Set(key string, value []byte) error {
 //
 //	err := client.Delete("mykey")
 func (c *CacheClient) Delete(key string) error {
-	return _deleteKey(c.db, key)
+	return c.DeleteContext(context.Background(), key)
 }
 
-// ListKeys returns all active keys, ordered by insertion time (newest first).
+// DeleteContext is Delete with a context, used as GetContext uses it.
+func (c *CacheClient) DeleteContext(ctx context.Context, key string) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, span := c.startSpan(ctx, "delete", key)
+	start := c.opStart()
+	var wait time.Duration
+	defer func() {
+		span.End(err)
+		c.logOp(ctx, "delete", key, start, 0, err)
+		c.reportSlow("delete", key, 1, start, wait)
+	}()
+
+	return c.delete(key, &wait)
+}
+
+// delete implements Delete, adding the time spent waiting for the database
+// lock to *wait.
+func (c *CacheClient) delete(key string, wait *time.Duration) (err error) {
+	defer func() { c.stats.recordDelete(1, err) }()
+
+	if c.behind != nil {
+		return c.bufferWrite(key, WatchDelete, nil)
+	}
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	if err := c.retryWaiting(wait, func() error { return deleteKey(db, c.tables, key) }); err != nil {
+		return err
+	}
+	c.notify(key, WatchDelete, nil)
+	return c.mirror(mirrorWrite{key: key, op: WatchDelete})
+}
+
+// ListKeys returns all active, unexpired keys, ordered by insertion time (newest first).
 //
 // Example:
 //
@@ -127,24 +467,136 @@ func (c *CacheClient) Delete(key string) error {
 //		fmt.Println(key)
 //	}
 func (c *CacheClient) ListKeys() ([]string, error) {
-	return _listActiveKeys(c.db)
+	return c.ListKeysContext(context.Background())
+}
+
+// ListKeysContext is ListKeys with a context, used as GetContext uses it.
+func (c *CacheClient) ListKeysContext(ctx context.Context) (keys []string, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	_, span := c.startSpan(ctx, "list_keys", "")
+	start := c.opStart()
+	defer func() {
+		span.SetInt(attrKeyCount, len(keys))
+		span.End(err)
+		c.logBatchOp(ctx, "list_keys", len(keys), start, 0, err)
+		c.reportSlow("list_keys", "", len(keys), start, 0)
+	}()
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	return listLiveKeys(db, c.tables, nowMillis())
 }
 
 // Close closes the database connection.
 //
-// After calling Close, the client should not be used.
+// Close stops the background sweeper and memory cache refresh, if any, waits
+// for in-flight operations to finish, writes the Sets and Deletes buffered by
+// WithWriteBehind and the reads noted for eviction, replays the writes still
+// queued for a MirrorAsync mirror, closes any open snapshots, and then closes
+// the database. It returns the errors of buffered writes that failed along
+// with any error closing the database. After Close, every operation returns
+// ErrClosed. Calling Close more than once is safe.
 func (c *CacheClient) Close() error {
+	c.unpublishExpvar()
+	c.watches.close()
+	if c.sweeper != nil {
+		c.sweeper.shutdown()
+	}
+	c.mem.shutdown()
+	c.behind.shutdown()
+	// After the lock is released, once Close's own writes have evicted.
+	defer c.evictions.shutdown()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.db != nil {
-		err := c.db.Close()
+		flushErr := c.drainWriteBehind(c.db)
+		c.flushAccess(c.db)
+		if c.mirrorQueue != nil {
+			c.mirrorQueue.shutdown()
+		}
+		c.closeSnapshots()
+		err := closeClientDB(c.path, c.opts, c.db)
 		c.db = nil
-		return err
+		return errors.Join(flushErr, err)
 	}
 	return nil
 }
 
+// acquire returns the database handle for one operation, holding the read
+// lock until release is called. It returns ErrClosed once the client is closed.
+func (c *CacheClient) acquire() (*sql.DB, error) {
+	c.mu.RLock()
+	if c.db == nil {
+		c.mu.RUnlock()
+		return nil, ErrClosed
+	}
+	return c.db, nil
+}
+
+// acquireFlushed is acquire for reads that must see the Sets and Deletes
+// buffered by WithWriteBehind, which it writes first.
+func (c *CacheClient) acquireFlushed() (*sql.DB, error) {
+	c.flushBehind()
+	return c.acquire()
+}
+
+// acquireWrite is acquire for operations that modify the database, after
+// writing any Sets and Deletes buffered by WithWriteBehind. On a read-only
+// client it returns ErrReadOnly without touching the database.
+func (c *CacheClient) acquireWrite() (*sql.DB, error) {
+	c.flushBehind()
+	db, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.readOnly {
+		c.release()
+		return nil, ErrReadOnly
+	}
+	return db, nil
+}
+
+// readValue returns the decoded live value of key for Get and GetStrict.
+// Expired versions are soft-deleted on the spot unless the client is
+// read-only.
+func (c *CacheClient) readValue(db querier, key string) ([]byte, error) {
+	value, _, err := c.readVersion(db, key)
+	return value, err
+}
+
+// readVersion is like readValue but also returns the version's expiry, which
+// is invalid (NULL) if the version never expires.
+func (c *CacheClient) readVersion(db querier, key string) ([]byte, sql.NullInt64, error) {
+	var (
+		stored    []byte
+		expiresAt sql.NullInt64
+		err       error
+	)
+	if c.opts.readOnly {
+		stored, expiresAt, err = readLiveVersion(db, c.tables, key, nowMillis())
+	} else {
+		stored, expiresAt, err = getLiveVersion(db, c.tables, key)
+	}
+	if err != nil {
+		return nil, sql.NullInt64{}, err
+	}
+	value, err := c.decodeStored(key, stored)
+	return value, expiresAt, err
+}
+
+// release ends an operation started with acquire.
+func (c *CacheClient) release() {
+	c.mu.RUnlock()
+}
+
 // Path returns the database file path used by this client.
 func (c *CacheClient) Path() string {
 	return c.path
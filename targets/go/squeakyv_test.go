@@ -2,12 +2,34 @@ package squeakyv
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 )
 
+// newTestClient opens an in-memory client with opts that is closed when the
+// test ends.
+func newTestClient(t testing.TB, opts ...Option) *CacheClient {
+	t.Helper()
+	return newTestClientAt(t, ":memory:", opts...)
+}
+
+// newTestClientAt opens a client for path with opts that is closed when the
+// test ends.
+func newTestClientAt(t testing.TB, path string, opts ...Option) *CacheClient {
+	t.Helper()
+	client, err := NewCacheClient(path, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
 func TestNewCacheClient(t *testing.T) {
 	client, err := NewCacheClient(":memory:")
 	if err != nil {
@@ -371,3 +393,91 @@ func ExampleCacheClient() {
 
 	// Output: myvalue
 }
+
+func TestGetStrict(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.Set("key", []byte("value")); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	value, err := client.GetStrict("key")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if !bytes.Equal(value, []byte("value")) {
+		t.Errorf("Expected value, got %s", value)
+	}
+
+	_, err = client.GetStrict("missing")
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Expected ErrKeyNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected error to name the key, got %q", err)
+	}
+
+	client.Delete("key")
+	if _, err := client.GetStrict("key"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound after delete, got %v", err)
+	}
+}
+
+func TestGetStrictEmptyValue(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.Set("empty", []byte{}); err != nil {
+		t.Fatalf("Failed to set empty value: %v", err)
+	}
+
+	value, err := client.GetStrict("empty")
+	if err != nil {
+		t.Fatalf("Failed to get empty value: %v", err)
+	}
+	if value == nil || len(value) != 0 {
+		t.Errorf("Expected non-nil empty slice, got %#v", value)
+	}
+}
+
+func TestWithMustExist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "typo.db")
+
+	_, err := NewCacheClient(path, WithMustExist())
+	if !errors.Is(err, ErrDatabaseNotExist) {
+		t.Fatalf("Expected ErrDatabaseNotExist, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "database does not exist at "+path) {
+		t.Errorf("Expected error to name the path, got %q", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected no file to be created")
+	}
+
+	if _, err := NewCacheClient(":memory:", WithMustExist()); err == nil {
+		t.Error("Expected :memory: to be rejected")
+	}
+
+	// An existing database opens normally
+	client, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Set("key", []byte("value"))
+	client.Close()
+
+	client, err = NewCacheClient(path, WithMustExist())
+	if err != nil {
+		t.Fatalf("Failed to open existing database: %v", err)
+	}
+	defer client.Close()
+	if value, _ := client.Get("key"); string(value) != "value" {
+		t.Errorf("Expected value, got %q", value)
+	}
+
+	// The option does not stop Clone from creating its destination
+	clone, err := client.Clone(":memory:")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	clone.Close()
+}
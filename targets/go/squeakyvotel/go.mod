@@ -0,0 +1,22 @@
+module github.com/squeakyv/squeakyv/squeakyvotel
+
+go 1.23.0
+
+require (
+	github.com/squeakyv/squeakyv v0.0.0
+	go.opentelemetry.io/otel v1.34.0
+	go.opentelemetry.io/otel/sdk v1.34.0
+	go.opentelemetry.io/otel/trace v1.34.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.22 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.34.0 // indirect
+	golang.org/x/sys v0.34.0 // indirect
+)
+
+replace github.com/squeakyv/squeakyv => ../
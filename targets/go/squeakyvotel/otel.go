@@ -0,0 +1,105 @@
+// Package squeakyvotel traces squeakyv clients with OpenTelemetry. It is a
+// module of its own so that OpenTelemetry is not a dependency of every user
+// of squeakyv.
+package squeakyvotel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/squeakyv/squeakyv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans the tracer creates.
+const tracerName = "github.com/squeakyv/squeakyv"
+
+// TraceKeys controls how keys are recorded on spans.
+type TraceKeys int
+
+const (
+	// TraceKeysHashed records the hex SHA-256 of each key as
+	// squeakyv.key_hash, so spans touching the same key can be correlated
+	// without revealing it. This is the default.
+	TraceKeysHashed TraceKeys = iota
+	// TraceKeysOmitted records no key attribute.
+	TraceKeysOmitted
+	// TraceKeysPlain records each key as squeakyv.key.
+	TraceKeysPlain
+)
+
+// WithTracerProvider creates an OpenTelemetry span for every Get, Set, Delete
+// and ListKeys, named "squeakyv.get" and so on. Spans record the database
+// path, the key's hash, the value size, and for Get whether the key was
+// found. Use the Context variants of the methods, such as GetContext, to
+// parent the spans under the caller's. Use NewTracer to record keys
+// differently.
+//
+// Example:
+//
+//	client, err := squeakyv.NewCacheClient("cache.db",
+//		squeakyvotel.WithTracerProvider(otel.GetTracerProvider()))
+func WithTracerProvider(tp trace.TracerProvider) squeakyv.Option {
+	return squeakyv.WithTracer(NewTracer(tp, TraceKeysHashed))
+}
+
+// NewTracer returns a squeakyv.Tracer creating the spans of
+// WithTracerProvider, recording keys as keys says:
+//
+//	squeakyv.WithTracer(squeakyvotel.NewTracer(tp, squeakyvotel.TraceKeysPlain))
+func NewTracer(tp trace.TracerProvider, keys TraceKeys) squeakyv.Tracer {
+	return &tracer{tracer: tp.Tracer(tracerName), keys: keys}
+}
+
+// tracer implements squeakyv.Tracer.
+type tracer struct {
+	tracer trace.Tracer
+	keys   TraceKeys
+}
+
+// Start implements squeakyv.Tracer.
+func (t *tracer) Start(ctx context.Context, op, path, key string) (context.Context, squeakyv.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "sqlite"),
+		attribute.String("squeakyv.path", path),
+	}
+	if op != "list_keys" {
+		switch t.keys {
+		case TraceKeysHashed:
+			sum := sha256.Sum256([]byte(key))
+			attrs = append(attrs, attribute.String("squeakyv.key_hash", hex.EncodeToString(sum[:])))
+		case TraceKeysPlain:
+			attrs = append(attrs, attribute.String("squeakyv.key", key))
+		}
+	}
+
+	ctx, s := t.tracer.Start(ctx, "squeakyv."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, span{s}
+}
+
+// span adapts a trace.Span to squeakyv.Span.
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetInt(name string, value int) {
+	s.span.SetAttributes(attribute.Int(name, value))
+}
+
+func (s span) SetBool(name string, value bool) {
+	s.span.SetAttributes(attribute.Bool(name, value))
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
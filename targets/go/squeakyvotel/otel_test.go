@@ -0,0 +1,83 @@
+package squeakyvotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/squeakyv/squeakyv"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttrs returns the attributes of a recorded span by key.
+func spanAttrs(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range s.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestWithTracerProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	client, err := squeakyv.NewCacheClient(":memory:", WithTracerProvider(tp))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	client.SetContext(ctx, "a", []byte("value"))
+	client.GetContext(ctx, "a")
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	set, get := spans[0], spans[1]
+	if set.Name() != "squeakyv.set" || get.Name() != "squeakyv.get" {
+		t.Errorf("Expected set and get spans, got %s and %s", set.Name(), get.Name())
+	}
+	if get.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Expected the span to be parented under the context's span")
+	}
+
+	attrs := spanAttrs(get)
+	if attrs["squeakyv.hit"].AsBool() != true || attrs["squeakyv.value_size"].AsInt64() != 5 {
+		t.Errorf("Expected hit and size attributes, got %v", attrs)
+	}
+	if attrs["squeakyv.path"].AsString() != ":memory:" {
+		t.Errorf("Expected the path attribute, got %v", attrs)
+	}
+	if _, ok := attrs["squeakyv.key"]; ok || len(attrs["squeakyv.key_hash"].AsString()) != 64 {
+		t.Errorf("Expected only a hashed key by default, got %v", attrs)
+	}
+}
+
+func TestNewTracer(t *testing.T) {
+	for mode, want := range map[TraceKeys]attribute.Key{
+		TraceKeysPlain:   "squeakyv.key",
+		TraceKeysOmitted: "",
+	} {
+		recorder := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+		client, err := squeakyv.NewCacheClient(":memory:", squeakyv.WithTracer(NewTracer(tp, mode)))
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		client.Get("secret")
+		client.Close()
+
+		attrs := spanAttrs(recorder.Ended()[0])
+		_, hashed := attrs["squeakyv.key_hash"]
+		_, plain := attrs["squeakyv.key"]
+		if hashed || plain != (want != "") {
+			t.Errorf("Mode %d: unexpected key attributes %v", mode, attrs)
+		}
+	}
+}
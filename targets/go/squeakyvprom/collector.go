@@ -0,0 +1,149 @@
+// Package squeakyvprom exposes squeakyv client metrics to Prometheus. It is a
+// module of its own so that the Prometheus client library is not a
+// dependency of every user of squeakyv.
+package squeakyvprom
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/squeakyv/squeakyv"
+)
+
+// gaugeTTL is how long a Collector reuses the active key count and database
+// size before querying them again.
+const gaugeTTL = 15 * time.Second
+
+// Collector is a prometheus.Collector exposing a client's Stats counters,
+// its active key count and the size of its database, each labeled with the
+// client's path.
+type Collector struct {
+	client *squeakyv.CacheClient
+
+	counters []counter
+	errors   *prometheus.Desc
+	keys     *prometheus.Desc
+	size     *prometheus.Desc
+
+	// mu guards the cached gauge values.
+	mu        sync.Mutex
+	gaugesAt  time.Time
+	keyCount  int64
+	sizeBytes int64
+	gaugesErr error
+}
+
+// counter pairs a counter's description with the CacheStats field it
+// reports.
+type counter struct {
+	desc  *prometheus.Desc
+	value func(squeakyv.CacheStats) int64
+}
+
+// NewCollector returns a Collector for client, ready to register:
+//
+//	prometheus.MustRegister(squeakyvprom.NewCollector(client))
+//
+// Counters are read from Stats on every scrape, which costs a few atomic
+// loads. The active key count and database size come from queries whose
+// results are reused for 15 seconds, so frequent scrapes don't each count
+// the table; they are omitted if the queries fail. Register one Collector per
+// client; clients with different paths can share a registry.
+func NewCollector(client *squeakyv.CacheClient) *Collector {
+	labels := prometheus.Labels{"path": client.Path()}
+	newCounter := func(name, help string, value func(squeakyv.CacheStats) int64) counter {
+		return counter{
+			desc:  prometheus.NewDesc("squeakyv_"+name, help, nil, labels),
+			value: value,
+		}
+	}
+
+	return &Collector{
+		client: client,
+		counters: []counter{
+			newCounter("gets_total", "Keys looked up.",
+				func(s squeakyv.CacheStats) int64 { return s.Gets }),
+			newCounter("hits_total", "Lookups that found a live value.",
+				func(s squeakyv.CacheStats) int64 { return s.Hits }),
+			newCounter("misses_total", "Lookups of missing, deleted or expired keys.",
+				func(s squeakyv.CacheStats) int64 { return s.Misses }),
+			newCounter("sets_total", "Values written.",
+				func(s squeakyv.CacheStats) int64 { return s.Sets }),
+			newCounter("deletes_total", "Keys deleted.",
+				func(s squeakyv.CacheStats) int64 { return s.Deletes }),
+			newCounter("read_bytes_total", "Bytes of values returned by lookups.",
+				func(s squeakyv.CacheStats) int64 { return s.BytesRead }),
+			newCounter("written_bytes_total", "Bytes of values written.",
+				func(s squeakyv.CacheStats) int64 { return s.BytesWritten }),
+		},
+		errors: prometheus.NewDesc("squeakyv_errors_total",
+			"Failed calls, by operation.", []string{"op"}, labels),
+		keys: prometheus.NewDesc("squeakyv_active_keys",
+			"Keys with a live value.", nil, labels),
+		size: prometheus.NewDesc("squeakyv_database_size_bytes",
+			"Size of the main database file, excluding any WAL.", nil, labels),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, counter := range c.counters {
+		ch <- counter.desc
+	}
+	ch <- c.errors
+	ch <- c.keys
+	ch <- c.size
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.Stats()
+	for _, counter := range c.counters {
+		ch <- prometheus.MustNewConstMetric(counter.desc, prometheus.CounterValue, float64(counter.value(stats)))
+	}
+	for op, n := range map[string]int64{
+		"get":    stats.GetErrors,
+		"set":    stats.SetErrors,
+		"delete": stats.DeleteErrors,
+	} {
+		ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(n), op)
+	}
+
+	// The gauges are left out, rather than failing the scrape, once the
+	// client is closed.
+	keys, size, err := c.gauges()
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.keys, prometheus.GaugeValue, float64(keys))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(size))
+}
+
+// gauges returns the active key count and database size, querying them again
+// once the cached values are older than gaugeTTL.
+func (c *Collector) gauges() (keys, size int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.gaugesAt) < gaugeTTL {
+		return c.keyCount, c.sizeBytes, c.gaugesErr
+	}
+	c.keyCount, c.sizeBytes, c.gaugesErr = c.queryGauges()
+	c.gaugesAt = time.Now()
+	return c.keyCount, c.sizeBytes, c.gaugesErr
+}
+
+// queryGauges counts the active keys and reads the database size.
+func (c *Collector) queryGauges() (keys, size int64, err error) {
+	n, err := c.client.Count()
+	if err != nil {
+		return 0, 0, fmt.Errorf("squeakyv collector: %w", err)
+	}
+	size, err = c.client.FileSize()
+	if err != nil {
+		return 0, 0, fmt.Errorf("squeakyv collector: %w", err)
+	}
+	return int64(n), size, nil
+}
@@ -0,0 +1,84 @@
+package squeakyvprom
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/squeakyv/squeakyv"
+)
+
+// gatherMetrics returns the value of each metric gathered from reg, keyed by
+// name and, for squeakyv_errors_total, operation.
+func gatherMetrics(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "op" {
+					name += ":" + label.GetValue()
+				}
+			}
+			values[name] = metricValue(m)
+		}
+	}
+	return values
+}
+
+func metricValue(m *dto.Metric) float64 {
+	if m.GetCounter() != nil {
+		return m.GetCounter().GetValue()
+	}
+	return m.GetGauge().GetValue()
+}
+
+func TestCollector(t *testing.T) {
+	client, err := squeakyv.NewCacheClient(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Set("a", []byte("value"))
+	client.Get("a")
+	client.Get("missing")
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(NewCollector(client)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	values := gatherMetrics(t, reg)
+	for name, want := range map[string]float64{
+		"squeakyv_gets_total":          2,
+		"squeakyv_hits_total":          1,
+		"squeakyv_misses_total":        1,
+		"squeakyv_sets_total":          1,
+		"squeakyv_written_bytes_total": 5,
+		"squeakyv_errors_total:get":    0,
+		"squeakyv_active_keys":         1,
+	} {
+		if values[name] != want {
+			t.Errorf("Expected %s = %v, got %v", name, want, values[name])
+		}
+	}
+	if values["squeakyv_database_size_bytes"] <= 0 {
+		t.Errorf("Expected a database size, got %v", values["squeakyv_database_size_bytes"])
+	}
+
+	// The key count is cached between scrapes; counters are not.
+	client.Set("b", []byte("value"))
+	values = gatherMetrics(t, reg)
+	if values["squeakyv_active_keys"] != 1 || values["squeakyv_sets_total"] != 2 {
+		t.Errorf("Expected a cached key count and a fresh counter, got %v", values)
+	}
+
+	client.Close()
+	if _, err := reg.Gather(); err != nil {
+		t.Errorf("Expected scrapes of a closed client to succeed, got %v", err)
+	}
+}
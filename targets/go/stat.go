@@ -0,0 +1,92 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// KeyInfo describes a key and its version history, as returned by Stat.
+type KeyInfo struct {
+	Key string
+	// CreatedAt is when the oldest retained version was written.
+	CreatedAt time.Time
+	// UpdatedAt is when the current version (or, for a deleted key, the most
+	// recent version) was written.
+	UpdatedAt time.Time
+	// Versions is the number of retained versions, including the current one.
+	Versions int64
+	// Deleted is true when the key has no live version: it was deleted or
+	// has expired.
+	Deleted bool
+	// Size is the size in bytes of the version UpdatedAt refers to, as
+	// stored: compressed if written with WithCompressor.
+	Size int64
+	// ExpiresAt is when that version expires, or the zero Time if it never does.
+	ExpiresAt time.Time
+}
+
+// Stat returns metadata about a key without reading its value.
+//
+// Deleted and expired keys are still reported, with Deleted set, as long as
+// any version is retained. Returns an error wrapping ErrKeyNotFound if the key
+// was never written (or its history has been purged).
+//
+// Example:
+//
+//	info, err := client.Stat("config")
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Printf("%d versions, last written %v\n", info.Versions, info.UpdatedAt)
+func (c *CacheClient) Stat(key string) (*KeyInfo, error) {
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	return statKey(db, c.tables, key, nowMillis())
+}
+
+// statKey gathers KeyInfo for key as of now.
+func statKey(db querier, t tableNames, key string, now int64) (*KeyInfo, error) {
+	summary := `SELECT MIN(inserted_at), COUNT(*), COALESCE(MAX(` + liveCondition + `), 0)
+FROM ` + t.kv + `
+WHERE key = ?;`
+
+	var (
+		createdAt sql.NullInt64
+		live      bool
+		info      = KeyInfo{Key: key}
+	)
+	if err := db.QueryRow(summary, now, key).Scan(&createdAt, &info.Versions, &live); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	if info.Versions == 0 {
+		return nil, keyNotFound(key)
+	}
+	info.CreatedAt = time.UnixMilli(createdAt.Int64)
+	info.Deleted = !live
+
+	// The active row is current even if a newer-looking row exists, as after
+	// a restore; otherwise the most recently inserted row stands in.
+	latest := `SELECT inserted_at, length(value), expires_at
+FROM ` + t.kv + `
+WHERE key = ?
+ORDER BY is_active DESC, rowid DESC
+LIMIT 1;`
+
+	var (
+		updatedAt int64
+		expiresAt sql.NullInt64
+	)
+	if err := db.QueryRow(latest, key).Scan(&updatedAt, &info.Size, &expiresAt); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	info.UpdatedAt = time.UnixMilli(updatedAt)
+	if expiresAt.Valid {
+		info.ExpiresAt = time.UnixMilli(expiresAt.Int64)
+	}
+	return &info, nil
+}
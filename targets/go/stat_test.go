@@ -0,0 +1,70 @@
+package squeakyv
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStat(t *testing.T) {
+	client := newTestClient(t)
+
+	before := time.Now().Add(-time.Second)
+	client.Set("key", []byte("v1"))
+	time.Sleep(5 * time.Millisecond)
+	if err := client.SetWithTTL("key", []byte("value2"), time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	info, err := client.Stat("key")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Key != "key" {
+		t.Errorf("Expected key name, got %q", info.Key)
+	}
+	if info.Versions != 2 {
+		t.Errorf("Expected 2 versions, got %d", info.Versions)
+	}
+	if info.Deleted {
+		t.Error("Expected key not to be deleted")
+	}
+	if info.Size != 6 {
+		t.Errorf("Expected size 6, got %d", info.Size)
+	}
+	if info.CreatedAt.Before(before) || !info.UpdatedAt.After(info.CreatedAt) {
+		t.Errorf("Unexpected timestamps: created %v, updated %v", info.CreatedAt, info.UpdatedAt)
+	}
+	if remaining := time.Until(info.ExpiresAt); remaining < 59*time.Minute || remaining > time.Hour {
+		t.Errorf("Expected expiry about an hour away, got %v", info.ExpiresAt)
+	}
+}
+
+func TestStatDeletedKey(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("value"))
+	client.Delete("key")
+
+	info, err := client.Stat("key")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if !info.Deleted {
+		t.Error("Expected key to be reported as deleted")
+	}
+	if info.Versions != 1 || info.Size != 5 {
+		t.Errorf("Unexpected info for deleted key: %+v", info)
+	}
+	if !info.ExpiresAt.IsZero() {
+		t.Errorf("Expected no expiry, got %v", info.ExpiresAt)
+	}
+}
+
+func TestStatMissingKey(t *testing.T) {
+	client := newTestClient(t)
+
+	if _, err := client.Stat("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package squeakyv
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// CacheStats holds cumulative operation counters for a client, as returned by
+// Stats.
+//
+// Reads are counted by Get, GetStrict and GetMany, and by the typed getters
+// built on them such as GetJSON; writes by Set, SetWithTTL and SetMany and the
+// typed setters; deletes by Delete and DeleteMany. Other operations, including
+// those inside WithTransaction, are not counted. Batch operations count each
+// key they are given, and byte counts are of values as passed to or returned
+// by the client, before compression or encryption.
+type CacheStats struct {
+	// Gets is the number of keys looked up.
+	Gets int64
+	// Hits is the number of lookups that found a live value.
+	Hits int64
+	// Misses is the number of lookups of missing, deleted or expired keys.
+	Misses int64
+	// Sets is the number of values written.
+	Sets int64
+	// Deletes is the number of keys passed to Delete and DeleteMany.
+	Deletes int64
+	// BytesRead is the total size of the values returned by hits.
+	BytesRead int64
+	// BytesWritten is the total size of the values written.
+	BytesWritten int64
+	// GetErrors, SetErrors and DeleteErrors count the calls that failed with
+	// an error other than a miss. Keys in failed calls are not counted above.
+	GetErrors    int64
+	SetErrors    int64
+	DeleteErrors int64
+
+	// StoredBytes is the total size of the database's live values as stored,
+	// after compression or encryption, which WithMaxBytes bounds. Unlike the
+	// counters above it is read from the database, and covers every client's
+	// writes; it is 0 unless WithMaxEntries or WithMaxBytes is set.
+	StoredBytes int64
+}
+
+// HitRatio returns Hits as a fraction of Hits and Misses, or 0 before the
+// first lookup.
+func (s CacheStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// stats holds a client's counters. They are updated with atomics so that
+// counting adds no lock contention.
+type stats struct {
+	gets, hits, misses      atomic.Int64
+	sets, deletes           atomic.Int64
+	bytesRead, bytesWritten atomic.Int64
+	getErrors, setErrors    atomic.Int64
+	deleteErrors            atomic.Int64
+}
+
+// recordGet counts a single-key lookup and its outcome.
+func (s *stats) recordGet(value []byte, err error) {
+	s.recordRead(int64(len(value)), err)
+}
+
+// recordRead is recordGet for a lookup that read size bytes.
+func (s *stats) recordRead(size int64, err error) {
+	switch {
+	case err == nil:
+		s.gets.Add(1)
+		s.hits.Add(1)
+		s.bytesRead.Add(size)
+	case errors.Is(err, ErrKeyNotFound):
+		s.gets.Add(1)
+		s.misses.Add(1)
+	default:
+		s.getErrors.Add(1)
+	}
+}
+
+// recordGetMany counts the lookups of a GetMany call.
+func (s *stats) recordGetMany(keys []string, results map[string][]byte, err error) {
+	if err != nil {
+		s.getErrors.Add(1)
+		return
+	}
+	var hits, read int64
+	for _, key := range keys {
+		if value, ok := results[key]; ok {
+			hits++
+			read += int64(len(value))
+		}
+	}
+	s.gets.Add(int64(len(keys)))
+	s.hits.Add(hits)
+	s.misses.Add(int64(len(keys)) - hits)
+	s.bytesRead.Add(read)
+}
+
+// recordSet counts n values of size bytes in total written by one call.
+func (s *stats) recordSet(n int, size int64, err error) {
+	if err != nil {
+		s.setErrors.Add(1)
+		return
+	}
+	s.sets.Add(int64(n))
+	s.bytesWritten.Add(size)
+}
+
+// recordDelete counts n keys deleted by one call.
+func (s *stats) recordDelete(n int, err error) {
+	if err != nil {
+		s.deleteErrors.Add(1)
+		return
+	}
+	s.deletes.Add(int64(n))
+}
+
+// Stats returns the client's operation counters, accumulated since it was
+// created or since the last ResetStats. The counters live in memory only and
+// are not shared between clients of the same database; StoredBytes is the
+// exception.
+//
+// Each counter is read atomically, but the snapshot as a whole is not:
+// operations completing meanwhile may be reflected in some counters and not
+// yet in others.
+//
+// Example:
+//
+//	s := client.Stats()
+//	log.Printf("hit ratio %.2f over %d gets", s.HitRatio(), s.Gets)
+func (c *CacheClient) Stats() CacheStats {
+	s := &c.stats
+	return CacheStats{
+		Gets:         s.gets.Load(),
+		Hits:         s.hits.Load(),
+		Misses:       s.misses.Load(),
+		Sets:         s.sets.Load(),
+		Deletes:      s.deletes.Load(),
+		BytesRead:    s.bytesRead.Load(),
+		BytesWritten: s.bytesWritten.Load(),
+		GetErrors:    s.getErrors.Load(),
+		SetErrors:    s.setErrors.Load(),
+		DeleteErrors: s.deleteErrors.Load(),
+		StoredBytes:  c.storedBytes(),
+	}
+}
+
+// storedBytes returns the total stored size of the live values kept in
+// kv_usage for a client that evicts, or 0 for other clients or on error.
+func (c *CacheClient) storedBytes() int64 {
+	if !c.opts.evicts() {
+		return 0
+	}
+	db, err := c.acquire()
+	if err != nil {
+		return 0
+	}
+	defer c.release()
+
+	u, err := readUsage(db, c.tables)
+	if err != nil {
+		return 0
+	}
+	return u.bytes
+}
+
+// ResetStats sets every counter returned by Stats back to zero.
+func (c *CacheClient) ResetStats() {
+	s := &c.stats
+	for _, counter := range []*atomic.Int64{
+		&s.gets, &s.hits, &s.misses, &s.sets, &s.deletes,
+		&s.bytesRead, &s.bytesWritten, &s.getErrors, &s.setErrors, &s.deleteErrors,
+	} {
+		counter.Store(0)
+	}
+}
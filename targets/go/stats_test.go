@@ -0,0 +1,52 @@
+package squeakyv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("12345"))
+	client.SetWithTTL("b", []byte("123"), time.Hour)
+	client.SetMany(map[string][]byte{"c": []byte("1"), "d": []byte("12")})
+	client.Get("a")
+	client.Get("missing")
+	client.GetStrict("missing")
+	client.GetMany([]string{"b", "c", "nope"})
+	client.Delete("a")
+	client.DeleteMany([]string{"b", "nope"})
+	client.Set("", []byte("x"))
+
+	want := CacheStats{
+		Gets:         6,
+		Hits:         3,
+		Misses:       3,
+		Sets:         4,
+		Deletes:      3,
+		BytesRead:    9,
+		BytesWritten: 11,
+		SetErrors:    1,
+	}
+	if got := client.Stats(); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if ratio := client.Stats().HitRatio(); ratio != 0.5 {
+		t.Errorf("Expected a hit ratio of 0.5, got %v", ratio)
+	}
+
+	client.ResetStats()
+	if got := client.Stats(); got != (CacheStats{}) {
+		t.Errorf("Expected zeroed stats after ResetStats, got %+v", got)
+	}
+	if ratio := client.Stats().HitRatio(); ratio != 0 {
+		t.Errorf("Expected a hit ratio of 0 without lookups, got %v", ratio)
+	}
+
+	client.Close()
+	client.Get("c")
+	if got := client.Stats(); got.GetErrors != 1 || got.Gets != 0 {
+		t.Errorf("Expected a closed client's Get to count as an error, got %+v", got)
+	}
+}
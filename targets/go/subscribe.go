@@ -0,0 +1,164 @@
+package squeakyv
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// subscribeBuffer is how many undelivered events a subscription holds before
+// it overflows.
+const subscribeBuffer = 1024
+
+// ChangeEvent is a change recorded in the database's change feed, delivered
+// by Subscribe, ChangesSince and PollChanges.
+type ChangeEvent struct {
+	// Seq is the change's position in the feed. Sequence numbers increase
+	// with every change and are never reused, even across restarts.
+	Seq int64
+	// Key is the key that changed, as written.
+	Key string
+	// Op is the kind of change.
+	Op WatchOp
+	// Time is when the change was written, to the millisecond.
+	Time time.Time
+	// Err is nil except on the last event of a subscription that lost events,
+	// when it wraps ErrSubscriptionOverflow or the error that prevented
+	// reading the feed. Seq is then that of the last change delivered, so
+	// that ChangesSince(Seq, ...) resumes where the subscription stopped, and
+	// the other fields are empty.
+	Err error
+}
+
+// Subscribe returns a channel delivering, in order, every change recorded in
+// the change feed (see ChangesSince) after the call, until ctx is cancelled
+// or the client is closed, when the channel is closed.
+//
+// The feed is read whenever this client publishes a change to Watch, so
+// changes are delivered as soon as such a write commits. Changes the feed
+// records in between, such as writes in WithTransaction or by other
+// processes, are delivered along with the next one; use PollChanges to follow
+// other processes promptly.
+//
+// Writers never wait for subscribers. A subscription buffers up to 1024
+// undelivered events. If a change would exceed that, the subscription ends
+// instead: it delivers a last event whose Err wraps ErrSubscriptionOverflow
+// and whose Seq is that of the last change delivered, then its channel is
+// closed. A receiver that must not miss changes can catch up from there with
+// ChangesSince.
+//
+// Example:
+//
+//	events, err := client.Subscribe(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	for ev := range events {
+//		if ev.Err != nil {
+//			invalidateAll()
+//			continue
+//		}
+//		invalidate(ev.Key)
+//	}
+func (c *CacheClient) Subscribe(ctx context.Context) (<-chan ChangeEvent, error) {
+	db, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	return c.watches.subscribe(ctx, func() (int64, error) { return queryLastChangeSeq(db, c.tables) })
+}
+
+// readNewChanges reads up to limit changes recorded after seq from the feed.
+// It is called while an operation holds the database.
+func (c *CacheClient) readNewChanges(seq int64, limit int) ([]ChangeEvent, error) {
+	return readChanges(c.db, c.tables, seq, limit)
+}
+
+// subscriber is one subscription. Its channel has room for one more event
+// than subscribeBuffer so that an overflow can always be reported.
+type subscriber struct {
+	ch chan ChangeEvent
+	// seq is the sequence number of the last change delivered, or of the
+	// latest change when the subscription started.
+	seq  int64
+	stop func() bool
+}
+
+// subscribe registers a subscription to the changes after the one numbered by
+// last, ending when ctx is done.
+func (h *watchHub) subscribe(ctx context.Context, last func() (int64, error)) (<-chan ChangeEvent, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+	h.init()
+
+	seq, err := last()
+	if err != nil {
+		return nil, err
+	}
+	if len(h.subscribers) == 0 {
+		h.seq = seq
+	}
+
+	s := &subscriber{ch: make(chan ChangeEvent, subscribeBuffer+1), seq: seq}
+	h.subscribers[s] = struct{}{}
+	h.count.Add(1)
+	s.stop = context.AfterFunc(ctx, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.unsubscribeLocked(s)
+	})
+	return s.ch, nil
+}
+
+// unsubscribeLocked ends the subscription s, if it hasn't ended, closing its
+// channel. h.mu must be held.
+func (h *watchHub) unsubscribeLocked(s *subscriber) {
+	if _, ok := h.subscribers[s]; !ok {
+		return
+	}
+	s.stop()
+	delete(h.subscribers, s)
+	h.count.Add(-1)
+	close(s.ch)
+}
+
+// broadcast delivers the changes recorded since the last broadcast, read with
+// read, to every subscription, ending those that are full. If the feed can't
+// be read, every subscription ends. h.mu must be held.
+func (h *watchHub) broadcast(read func(seq int64, limit int) ([]ChangeEvent, error)) {
+	for len(h.subscribers) > 0 {
+		changes, err := read(h.seq, subscribeBuffer+1)
+		if err != nil {
+			for s := range h.subscribers {
+				s.ch <- ChangeEvent{Seq: s.seq, Err: err}
+				h.unsubscribeLocked(s)
+			}
+			return
+		}
+
+		for _, ev := range changes {
+			h.seq = ev.Seq
+			for s := range h.subscribers {
+				if ev.Seq <= s.seq {
+					continue
+				}
+				if len(s.ch) < subscribeBuffer {
+					s.ch <- ev
+					s.seq = ev.Seq
+					continue
+				}
+				s.ch <- ChangeEvent{Seq: s.seq, Err: fmt.Errorf("%w: %d events undelivered", ErrSubscriptionOverflow, len(s.ch))}
+				h.unsubscribeLocked(s)
+			}
+		}
+		if len(changes) <= subscribeBuffer {
+			return
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package squeakyv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// nextChange receives the next event from events, failing the test if none
+// arrives in time or the channel is closed.
+func nextChange(t *testing.T, events <-chan ChangeEvent) ChangeEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("Expected an event, channel closed")
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return ChangeEvent{}
+}
+
+// expectChangesClosed fails the test unless events is closed without further
+// events.
+func expectChangesClosed(t *testing.T, events <-chan ChangeEvent) {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if ok {
+			t.Fatalf("Expected the channel to be closed, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the channel to close")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("b", []byte("2"))
+	events, err := client.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	before := time.Now().Truncate(time.Millisecond)
+	client.Set("a", []byte("1"))
+	client.Delete("b")
+	client.SetMany(map[string][]byte{"c": []byte("3")})
+
+	want := []struct {
+		key string
+		op  WatchOp
+	}{{"a", WatchSet}, {"b", WatchDelete}, {"c", WatchSet}}
+	var last int64
+	for _, w := range want {
+		ev := nextChange(t, events)
+		if ev.Key != w.key || ev.Op != w.op || ev.Err != nil {
+			t.Errorf("Expected %s %s, got %+v", w.op, w.key, ev)
+		}
+		if ev.Seq <= last {
+			t.Errorf("Expected sequence numbers to increase, got %d after %d", ev.Seq, last)
+		}
+		if ev.Time.Before(before) {
+			t.Errorf("Expected the event time to be after %v, got %v", before, ev.Time)
+		}
+		last = ev.Seq
+	}
+}
+
+func TestSubscribeEnds(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled, err := client.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	open, err := client.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	cancel()
+	expectChangesClosed(t, cancelled)
+
+	client.Close()
+	expectChangesClosed(t, open)
+	if client.watches.count.Load() != 0 {
+		t.Errorf("Expected no subscriptions left, got %d", client.watches.count.Load())
+	}
+	if _, err := client.Subscribe(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestSubscribeOverflow(t *testing.T) {
+	client := newTestClient(t)
+	events, err := client.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	items := make(map[string][]byte, subscribeBuffer+5)
+	for i := 0; i < subscribeBuffer+5; i++ {
+		items[fmt.Sprintf("key%d", i)] = []byte("v")
+	}
+	if err := client.SetMany(items); err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+
+	var delivered, last ChangeEvent
+	n := 0
+	for ev := range events {
+		delivered, last = last, ev
+		n++
+	}
+	if n != subscribeBuffer+1 {
+		t.Errorf("Expected %d events, got %d", subscribeBuffer+1, n)
+	}
+	if !errors.Is(last.Err, ErrSubscriptionOverflow) {
+		t.Fatalf("Expected the last event to report an overflow, got %+v", last)
+	}
+	if last.Seq != delivered.Seq || last.Key != "" {
+		t.Errorf("Expected the overflow event to carry only the last delivered sequence number %d, got %+v", delivered.Seq, last)
+	}
+
+	// The feed picks up where the subscription stopped.
+	missed, _, err := client.ChangesSince(last.Seq, len(items))
+	if err != nil {
+		t.Fatalf("ChangesSince failed: %v", err)
+	}
+	if len(missed) != len(items)-subscribeBuffer {
+		t.Errorf("Expected %d missed changes, got %d", len(items)-subscribeBuffer, len(missed))
+	}
+}
+
+func TestSubscribeSequenceSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	lastSeq := func() int64 {
+		client, err := NewCacheClient(path)
+		if err != nil {
+			t.Fatalf("NewCacheClient failed: %v", err)
+		}
+		defer client.Close()
+
+		events, err := client.Subscribe(context.Background())
+		if err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+		client.Set("key", []byte("value"))
+		return nextChange(t, events).Seq
+	}
+
+	first := lastSeq()
+	if second := lastSeq(); second <= first {
+		t.Errorf("Expected the sequence to continue after reopening, got %d after %d", second, first)
+	}
+}
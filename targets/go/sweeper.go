@@ -0,0 +1,162 @@
+package squeakyv
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sweeper periodically purges expired rows on behalf of a CacheClient.
+type sweeper struct {
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// startSweeper launches a goroutine that calls SweepNow every interval until
+// the returned sweeper is shut down.
+func startSweeper(c *CacheClient, interval time.Duration) *sweeper {
+	s := &sweeper{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				// Failures are retried on the next tick.
+				c.SweepNow()
+			}
+		}
+	}()
+
+	return s
+}
+
+// shutdown stops the sweeper goroutine and waits for it to exit. It is safe
+// to call more than once.
+func (s *sweeper) shutdown() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// SweepNow physically deletes every row whose expiry has passed and returns
+// the number of rows removed. Expired WithNegativeTTL tombstones, and changes
+// older than WithChangeRetention, are discarded too, without being counted.
+//
+// Rows are deleted in batches (see WithSweepBatchSize), each in its own
+// transaction, so concurrent writers are never blocked for long. Expired
+// history versions are removed along with expired active versions.
+//
+// The background sweeper enabled by WithSweepInterval calls SweepNow; it can
+// also be called directly, for example from tests.
+func (c *CacheClient) SweepNow() (int, error) {
+	query := `DELETE FROM ` + c.tables.kv + `
+WHERE rowid IN (
+  SELECT rowid FROM ` + c.tables.kv + `
+  WHERE expires_at IS NOT NULL AND expires_at <= ?
+  LIMIT ?
+);`
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer c.release()
+
+	now := nowMillis()
+	var n int64
+	if c.evictions != nil {
+		n, err = c.sweepReporting(db, query, now)
+	} else {
+		n, err = c.deleteInBatches(db, query, now)
+	}
+	if err != nil {
+		return int(n), err
+	}
+	if _, err := c.exec(db, `DELETE FROM `+c.tables.tombstones+` WHERE expires_at <= ?;`, now); err != nil {
+		return int(n), fmt.Errorf("exec failed: %w", err)
+	}
+	if err := c.pruneOldChanges(db); err != nil {
+		return int(n), err
+	}
+	return int(n), nil
+}
+
+// sweepReporting is deleteInBatches for SweepNow's query under
+// WithEvictionCallback, queueing the keys of the live versions it removes.
+func (c *CacheClient) sweepReporting(db querier, query string, now int64) (int64, error) {
+	query = strings.TrimSuffix(query, ";") + "\nRETURNING key, is_active;"
+
+	var total int64
+	for {
+		var (
+			n       int64
+			expired []eviction
+		)
+		err := c.retry(func() error {
+			n, expired = 0, nil
+			rows, err := db.Query(query, now, c.opts.sweepBatchSize)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				var (
+					key    string
+					active bool
+				)
+				if err := rows.Scan(&key, &active); err != nil {
+					return err
+				}
+				n++
+				if active {
+					expired = append(expired, eviction{key: key, reason: Expired})
+				}
+			}
+			return rows.Err()
+		})
+		if err != nil {
+			return total, fmt.Errorf("exec failed: %w", err)
+		}
+		total += n
+		c.evictions.add(expired...)
+		if n < int64(c.opts.sweepBatchSize) {
+			return total, nil
+		}
+	}
+}
+
+// deleteInBatches repeatedly executes a DELETE whose final parameter is a
+// LIMIT, appending the configured batch size to args, until a batch removes
+// fewer rows than the limit. Each batch runs in its own implicit transaction.
+// It returns the total number of rows removed, including on error.
+func (c *CacheClient) deleteInBatches(db querier, query string, args ...interface{}) (int64, error) {
+	args = append(args, c.opts.sweepBatchSize)
+
+	var total int64
+	for {
+		result, err := c.exec(db, query, args...)
+		if err != nil {
+			return total, fmt.Errorf("exec failed: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, fmt.Errorf("rows affected failed: %w", err)
+		}
+		total += n
+		if n < int64(c.opts.sweepBatchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package squeakyv
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// countRows returns the number of physical rows stored for key, active or not.
+func countRows(t *testing.T, client *CacheClient, key string) int {
+	t.Helper()
+	var n int
+	if err := client.db.QueryRow(`SELECT COUNT(*) FROM kv WHERE key = ?`, key).Scan(&n); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	return n
+}
+
+func TestSweepNow(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.SetWithTTL("expiring", []byte("value"), 10*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if err := client.Set("durable", []byte("value")); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if err := client.SetWithTTL("later", []byte("value"), time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	n, err := client.SweepNow()
+	if err != nil {
+		t.Fatalf("Failed to sweep: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 row swept, got %d", n)
+	}
+	if rows := countRows(t, client, "expiring"); rows != 0 {
+		t.Errorf("Expected expired rows to be purged, found %d", rows)
+	}
+
+	for _, key := range []string{"durable", "later"} {
+		value, err := client.Get(key)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", key, err)
+		}
+		if !bytes.Equal(value, []byte("value")) {
+			t.Errorf("Expected %s to survive the sweep, got %v", key, value)
+		}
+	}
+}
+
+func TestSweepNowBatches(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithSweepBatchSize(7))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 50; i++ {
+		if err := client.SetWithTTL("key", []byte{byte(i)}, 5*time.Millisecond); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	n, err := client.SweepNow()
+	if err != nil {
+		t.Fatalf("Failed to sweep: %v", err)
+	}
+	if n != 50 {
+		t.Errorf("Expected 50 rows swept, got %d", n)
+	}
+}
+
+func TestBackgroundSweeper(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithSweepInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.SetWithTTL("key", []byte("value"), 5*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for countRows(t, client, "key") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Background sweeper did not purge expired row")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCloseStopsSweeper(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithSweepInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- client.Close() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Failed to close client: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return while the sweeper was running")
+	}
+}
@@ -0,0 +1,133 @@
+package squeakyv
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// defaultTable is the table of the shared schema in SchemaSQL.
+const defaultTable = "kv"
+
+// tableName matches the names accepted by WithTableName. Names are
+// interpolated into SQL, so anything else is rejected.
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// tableSuffixes lists the suffixes of the tables, view, indexes and triggers
+// named after a client's table. A table whose name ends in one could collide
+// with those of another table in the same file.
+var tableSuffixes = schemaSuffixes()
+
+// schemaObject matches a statement creating or dropping a schema object,
+// capturing the object's name.
+var schemaObject = regexp.MustCompile(`(?i)\b(?:CREATE|DROP)\s+(?:UNIQUE\s+)?(?:TABLE|INDEX|TRIGGER|VIEW)\s+(?:IF\s+(?:NOT\s+)?EXISTS\s+)?(\w+)`)
+
+// schemaSuffixes returns the suffixes for tableSuffixes, read from the names
+// of the objects in the schema of defaultTable, so that an object added to
+// the schema is reserved without being listed here.
+func schemaSuffixes() []string {
+	t := newTableNames(defaultTable)
+	schema := []string{sharedSchemaSQL(t, false), goSchemaSQL(t), lruSchemaSQL(t)}
+	for _, trigger := range goTriggers(t) {
+		schema = append(schema, trigger.create)
+	}
+
+	// Objects that only exist while migrating an older schema.
+	suffixes := []string{"_rebuild", "_changes_insert_begin", "_changes_pending"}
+	for _, sql := range schema {
+		for _, match := range schemaObject.FindAllStringSubmatch(sql, -1) {
+			suffix, ok := strings.CutPrefix(match[1], defaultTable)
+			if ok && suffix != "" && !slices.Contains(suffixes, suffix) {
+				suffixes = append(suffixes, suffix)
+			}
+		}
+	}
+	return suffixes
+}
+
+// checkTableName reports whether name may be used with WithTableName.
+func checkTableName(name string) error {
+	lower := strings.ToLower(name)
+	if !tableName.MatchString(name) || strings.HasPrefix(lower, "sqlite_") || lower == "__metadata__" {
+		return fmt.Errorf("invalid table name %q", name)
+	}
+	for _, suffix := range tableSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return fmt.Errorf("invalid table name %q: names ending in %q are reserved for the objects named after another table", name, suffix)
+		}
+	}
+	return nil
+}
+
+// tableNames names a client's table and the tables and view named after it.
+// Every query builds its SQL from these, and the indexes and triggers are
+// named after kv the same way.
+type tableNames struct {
+	kv         string
+	meta       string
+	changes    string
+	pins       string
+	tombstones string
+	usage      string
+	current    string
+}
+
+// newTableNames returns the names for the table called name.
+func newTableNames(name string) tableNames {
+	return tableNames{
+		kv:         name,
+		meta:       name + "_meta",
+		changes:    name + "_changes",
+		pins:       name + "_pins",
+		tombstones: name + "_tombstones",
+		usage:      name + "_usage",
+		current:    name + "_current",
+	}
+}
+
+// sharedSchemaSQL returns SchemaSQL for the table t.kv, with the key column
+// declared COLLATE NOCASE if caseInsensitive is set. Every comparison of
+// keys, including the unique index on active keys and the swap trigger, then
+// ignores ASCII case. TestSharedSchemaSQL checks that it stays in step with
+// the generated SchemaSQL.
+func sharedSchemaSQL(t tableNames, caseInsensitive bool) string {
+	key := "key TEXT NOT NULL,"
+	if caseInsensitive {
+		key = "key TEXT NOT NULL COLLATE NOCASE,"
+	}
+	return `
+CREATE TABLE IF NOT EXISTS __metadata__ (
+  key TEXT NOT NULL PRIMARY KEY,
+  value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ` + t.kv + ` (
+  inserted_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+  is_active INTEGER NOT NULL DEFAULT (1) CHECK (is_active IN (0,1)),
+  ` + key + `
+  value BLOB NOT NULL
+);
+
+INSERT OR IGNORE INTO __metadata__ (key, value) VALUES ('schema_version', '1.0.0');
+INSERT OR IGNORE INTO __metadata__ (key, value) VALUES ('schema_tree_ish', 'git-hash-abc123');
+INSERT OR IGNORE INTO __metadata__ (key, value) VALUES ('creation_date', strftime('%Y-%m-%dT%H:%M:%f', 'now'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS ` + t.kv + `_active_key ON ` + t.kv + `(key) WHERE is_active = 1;
+
+CREATE INDEX IF NOT EXISTS ` + t.kv + `_key_time ON ` + t.kv + `(key, inserted_at);
+
+CREATE TRIGGER IF NOT EXISTS ` + t.kv + `_swap_active
+BEFORE INSERT ON ` + t.kv + `
+FOR EACH ROW
+BEGIN
+  UPDATE ` + t.kv + ` SET is_active = 0
+  WHERE key = NEW.key AND is_active = 1;
+END;
+
+CREATE VIEW IF NOT EXISTS ` + t.current + ` AS
+  SELECT key, value, inserted_at
+  FROM ` + t.kv + `
+  WHERE is_active = 1;
+`
+}
@@ -0,0 +1,165 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithTableName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	open := func(opts ...Option) *CacheClient {
+		t.Helper()
+		client, err := NewCacheClient(path, opts...)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+	sessions := open(WithTableName("sessions"))
+	users := open(WithTableName("users"))
+	plain := open()
+
+	sessions.Set("key", []byte("s1"))
+	sessions.Set("key", []byte("s2"))
+	users.Set("key", []byte("u"))
+	plain.Set("other", []byte("p"))
+
+	if value, _ := sessions.Get("key"); string(value) != "s2" {
+		t.Errorf("Expected s2, got %q", value)
+	}
+	if value, _ := users.Get("key"); string(value) != "u" {
+		t.Errorf("Expected u, got %q", value)
+	}
+	if keys, _ := plain.ListKeys(); len(keys) != 1 || keys[0] != "other" {
+		t.Errorf("Expected only the default table's key, got %v", keys)
+	}
+	if versions, _ := sessions.History("key"); len(versions) != 2 {
+		t.Errorf("Expected 2 versions in sessions, got %d", len(versions))
+	}
+
+	// Each table gets its own triggers, indexes and view
+	var n int
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE name IN ('sessions_swap_active', 'sessions_stamp_deactivated', 'sessions_current', 'sessions_expires_at')`
+	if err := plain.db.QueryRow(query).Scan(&n); err != nil {
+		t.Fatalf("Failed to query schema: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("Expected 4 schema objects for sessions, got %d", n)
+	}
+}
+
+func TestWithTableNameDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Set("key", []byte("value"))
+	client.Close()
+
+	client, err = NewCacheClient(path, WithTableName("kv"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	if value, _ := client.Get("key"); string(value) != "value" {
+		t.Errorf("Expected existing data in the default table, got %q", value)
+	}
+}
+
+func TestWithTableNameInvalid(t *testing.T) {
+	for _, name := range []string{"", "1abc", "a-b", "kv; DROP TABLE kv", "sqlite_stat1", "SQLITE_x"} {
+		if _, err := NewCacheClient(":memory:", WithTableName(name)); err == nil {
+			t.Errorf("Expected table name %q to be rejected", name)
+		}
+	}
+}
+
+func TestWithTableNameClone(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithTableName("sessions"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	client.Set("key", []byte("value"))
+
+	clone, err := client.Clone(":memory:")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer clone.Close()
+	if value, _ := clone.Get("key"); string(value) != "value" {
+		t.Errorf("Expected value in clone, got %q", value)
+	}
+}
+
+func TestWithTableNameDerivedClash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	client, err := NewCacheClient(path, WithTableName("cache"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	// cache_meta is the settings table of cache
+	if other, err := NewCacheClient(path, WithTableName("cache_meta")); err == nil {
+		other.Close()
+		t.Fatal("Expected a table name ending in _meta to be rejected")
+	}
+	if err := client.Set("key", []byte("value")); err != nil {
+		t.Errorf("Set failed: %v", err)
+	}
+	var n int
+	if err := client.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE tbl_name = 'kv'`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("Expected no objects on the default table, got %d, %v", n, err)
+	}
+	for _, name := range []string{
+		"a_changes", "a_PINS", "a_current", "a_active_key", "a_lru", "__metadata__",
+		"a_changes_insert", "a_usage_insert", "a_changes_rename", "a_changes_rewrite", "a_pins_hard_expire",
+	} {
+		if err := checkTableName(name); err == nil {
+			t.Errorf("Expected table name %q to be rejected", name)
+		}
+	}
+	for _, name := range []string{"metadata", "changes", "kv_log", "sessions"} {
+		if err := checkTableName(name); err != nil {
+			t.Errorf("Expected table name %q to be accepted, got %v", name, err)
+		}
+	}
+}
+
+func TestSharedSchemaSQL(t *testing.T) {
+	// The schema built for the default table must create the same objects,
+	// with the same columns, as the generated SchemaSQL.
+	describe := func(schema string) string {
+		t.Helper()
+		db, err := sql.Open(driverName, ":memory:")
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+		db.SetMaxOpenConns(1)
+		if _, err := db.Exec(schema); err != nil {
+			t.Fatalf("Failed to create schema: %v", err)
+		}
+		objects, err := queryStrings(db, `SELECT type || ' ' || name || ' ' || tbl_name FROM sqlite_master ORDER BY name;`)
+		if err != nil {
+			t.Fatalf("Failed to list schema: %v", err)
+		}
+		columns, err := queryStrings(db, `SELECT name || ' ' || type || ' ' || "notnull" || ' ' || ifnull(dflt_value, '') || ' ' || pk
+FROM pragma_table_info('kv') ORDER BY cid;`)
+		if err != nil {
+			t.Fatalf("Failed to list columns: %v", err)
+		}
+		return fmt.Sprint(objects, columns)
+	}
+
+	want := describe(SchemaSQL)
+	if got := describe(sharedSchemaSQL(newTableNames(defaultTable), false)); got != want {
+		t.Errorf("sharedSchemaSQL differs from SchemaSQL:\n got %s\nwant %s", got, want)
+	}
+}
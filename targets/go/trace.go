@@ -0,0 +1,56 @@
+package squeakyv
+
+import "context"
+
+// Tracer starts a span for each traced client operation: Get, Set, Delete and
+// ListKeys and their Context variants. Set one with WithTracer.
+//
+// The OpenTelemetry implementation lives in its own module,
+// github.com/squeakyv/squeakyv/squeakyvotel, so the core package does not
+// depend on OpenTelemetry.
+type Tracer interface {
+	// Start starts the span for op, a name such as "get", on the database
+	// at path, returning ctx with the span attached. Key is empty for
+	// operations on no single key, such as "list_keys".
+	Start(ctx context.Context, op, path, key string) (context.Context, Span)
+}
+
+// Span is a traced operation in progress. The client sets the attributes
+// squeakyv.value_size (the value's size in bytes), squeakyv.hit (whether Get
+// found the key) and squeakyv.key_count (how many keys ListKeys returned).
+type Span interface {
+	SetInt(name string, value int)
+	SetBool(name string, value bool)
+	// End ends the span, recording err if the operation failed.
+	End(err error)
+}
+
+// WithTracer traces the client's operations with t.
+func WithTracer(t Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// noopSpan is the span of operations on clients without a tracer.
+type noopSpan struct{}
+
+func (noopSpan) SetInt(string, int)   {}
+func (noopSpan) SetBool(string, bool) {}
+func (noopSpan) End(error)            {}
+
+// Span attributes set by the core; the tracer adds the path and key.
+const (
+	attrValueSize = "squeakyv.value_size"
+	attrHit       = "squeakyv.hit"
+	attrKeyCount  = "squeakyv.key_count"
+)
+
+// startSpan starts the span for op on key, or a no-op span if the client has
+// no tracer.
+func (c *CacheClient) startSpan(ctx context.Context, op, key string) (context.Context, Span) {
+	if c.opts.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return c.opts.tracer.Start(ctx, op, c.path, key)
+}
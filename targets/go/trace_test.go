@@ -0,0 +1,97 @@
+package squeakyv
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// recordedSpan is a span captured by recordingTracer.
+type recordedSpan struct {
+	op, path, key string
+	attrs         map[string]any
+	err           error
+	ended         bool
+}
+
+func (s *recordedSpan) SetInt(name string, value int)   { s.attrs[name] = value }
+func (s *recordedSpan) SetBool(name string, value bool) { s.attrs[name] = value }
+func (s *recordedSpan) End(err error)                   { s.err, s.ended = err, true }
+
+// recordingTracer records every span it starts.
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, op, path, key string) (context.Context, Span) {
+	s := &recordedSpan{op: op, path: path, key: key, attrs: make(map[string]any)}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestTracing(t *testing.T) {
+	rec := &recordingTracer{}
+	client, err := NewCacheClient(":memory:", WithTracer(rec))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("a", []byte("value"))
+	client.Get("a")
+	client.Get("missing")
+	client.ListKeys()
+	client.Delete("a")
+	client.Set("", []byte("x"))
+
+	want := []struct {
+		op    string
+		key   string
+		attrs map[string]any
+		fails bool
+	}{
+		{"set", "a", map[string]any{attrValueSize: 5}, false},
+		{"get", "a", map[string]any{attrHit: true, attrValueSize: 5}, false},
+		{"get", "missing", map[string]any{attrHit: false, attrValueSize: 0}, false},
+		{"list_keys", "", map[string]any{attrKeyCount: 1}, false},
+		{"delete", "a", map[string]any{}, false},
+		{"set", "", map[string]any{attrValueSize: 1}, true},
+	}
+	if len(rec.spans) != len(want) {
+		t.Fatalf("Expected %d spans, got %d", len(want), len(rec.spans))
+	}
+	for i, w := range want {
+		s := rec.spans[i]
+		if s.op != w.op || s.key != w.key || s.path != ":memory:" || !s.ended || (s.err != nil) != w.fails {
+			t.Errorf("Span %d: expected %s of %q, got %+v", i, w.op, w.key, s)
+		}
+		for name, value := range w.attrs {
+			if s.attrs[name] != value {
+				t.Errorf("Span %d: expected %s = %v, got %v", i, name, value, s.attrs[name])
+			}
+		}
+	}
+}
+
+func TestContextMethodsCanceled(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("a", []byte("value"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.GetContext(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected GetContext to fail with context.Canceled, got %v", err)
+	}
+	if err := client.SetContext(ctx, "a", []byte("new")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected SetContext to fail with context.Canceled, got %v", err)
+	}
+	if err := client.DeleteContext(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected DeleteContext to fail with context.Canceled, got %v", err)
+	}
+	if _, err := client.ListKeysContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected ListKeysContext to fail with context.Canceled, got %v", err)
+	}
+	if got, _ := client.Get("a"); string(got) != "value" {
+		t.Errorf("Expected canceled calls to leave the value, got %q", got)
+	}
+}
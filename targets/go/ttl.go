@@ -0,0 +1,200 @@
+package squeakyv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// liveCondition restricts a query on kv to the active, unexpired version of a
+// key. It expects the current time in UNIX milliseconds as its only argument.
+const liveCondition = `is_active = 1 AND (expires_at IS NULL OR expires_at > ?)`
+
+// nowMillis returns the current time in the same unit as inserted_at and expires_at.
+func nowMillis() int64 {
+	return time.Now().UnixMilli()
+}
+
+// expiryMillis converts a ttl relative to now into an absolute expires_at value.
+func expiryMillis(ttl time.Duration) (int64, error) {
+	if ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl %v: must be positive", ttl)
+	}
+	return time.Now().Add(ttl).UnixMilli(), nil
+}
+
+// SetWithTTL stores a value for a key that expires after ttl.
+//
+// Versioning behaves exactly as in Set. A later Set without a TTL replaces the
+// expiring version with one that never expires.
+//
+// Example:
+//
+//	err := client.SetWithTTL("session", token, 30*time.Minute)
+func (c *CacheClient) SetWithTTL(key string, value []byte, ttl time.Duration) (err error) {
+	start := c.opStart()
+	var wait time.Duration
+	defer func() {
+		c.stats.recordSet(1, int64(len(value)), err)
+		c.logOp(context.Background(), "set_with_ttl", key, start, len(value), err)
+		c.reportSlow("set_with_ttl", key, 1, start, wait)
+	}()
+
+	expiresAt, err := expiryMillis(ttl)
+	if err != nil {
+		return err
+	}
+
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	stored, err := c.encodeStored(key, value)
+	if err != nil {
+		return err
+	}
+	err = c.writeEvicting(db, &wait, func(db querier) error {
+		return insertVersion(db, c.tables, key, stored, sql.NullInt64{Int64: expiresAt, Valid: true})
+	})
+	if err != nil {
+		return err
+	}
+	c.notify(key, WatchSet, value)
+	return c.mirror(mirrorWrite{key: key, op: WatchSet, value: value, expires: time.UnixMilli(expiresAt)})
+}
+
+// Expire sets or replaces the expiry of an existing key so that it expires
+// after ttl.
+//
+// Only the current active version is affected; no new version is written.
+// Returns an error wrapping ErrKeyNotFound if the key does not exist.
+//
+// Example:
+//
+//	err := client.Expire("mykey", time.Hour)
+func (c *CacheClient) Expire(key string, ttl time.Duration) error {
+	expiresAt, err := expiryMillis(ttl)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE ` + c.tables.kv + `
+SET expires_at = ?, ttl = ?
+WHERE key = ? AND ` + liveCondition + `;`
+	return c.updateLive(key, query, expiresAt, ttl.Milliseconds(), key, nowMillis())
+}
+
+// Persist removes the expiry from an existing key so that it never expires.
+//
+// Only the current active version is affected; no new version is written.
+// Returns an error wrapping ErrKeyNotFound if the key does not exist.
+func (c *CacheClient) Persist(key string) error {
+	query := `UPDATE ` + c.tables.kv + `
+SET expires_at = NULL, ttl = NULL
+WHERE key = ? AND ` + liveCondition + `;`
+	return c.updateLive(key, query, key, nowMillis())
+}
+
+// TTL returns the remaining lifetime of a key.
+//
+// The boolean result is false when the key exists but has no expiry, in which
+// case the duration is zero. Returns an error wrapping ErrKeyNotFound if the
+// key does not exist.
+//
+// Example:
+//
+//	remaining, ok, err := client.TTL("session")
+//	if err != nil {
+//		return err
+//	}
+//	if !ok {
+//		fmt.Println("session never expires")
+//	}
+func (c *CacheClient) TTL(key string) (time.Duration, bool, error) {
+	query := `SELECT expires_at
+FROM ` + c.tables.kv + `
+WHERE key = ? AND ` + liveCondition + `;`
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return 0, false, err
+	}
+	defer c.release()
+
+	now := nowMillis()
+	var expiresAt sql.NullInt64
+	err = db.QueryRow(query, key, now).Scan(&expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, false, keyNotFound(key)
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("query failed: %w", err)
+	}
+	if !expiresAt.Valid {
+		return 0, false, nil
+	}
+	return time.Duration(expiresAt.Int64-now) * time.Millisecond, true, nil
+}
+
+// lastWritten is the SQL expression ordering keys for ListKeys, newest
+// first: the later of the version's write and its last Touch.
+const lastWritten = `max(inserted_at, ifnull(touched_at, 0))`
+
+// Touch marks a key as recently used, without creating a new version or
+// copying the value: ListKeys lists it as if it had just been written, and
+// WithMaxEntries and WithMaxBytes evict it as if it had just been read. The
+// version keeps its write time, as reported by Stat and History.
+//
+// If the client was created with WithSlidingExpiry, an expiring key also has
+// its expiry pushed out so it keeps its TTL, as last set by SetWithTTL or
+// Expire, measured from now. Returns an error wrapping ErrKeyNotFound if the
+// key doesn't exist, was deleted, or has expired.
+//
+// Example:
+//
+//	if err := client.Touch("report"); errors.Is(err, squeakyv.ErrKeyNotFound) {
+//		return regenerateReport()
+//	}
+func (c *CacheClient) Touch(key string) error {
+	// SET expressions see the row's old values. Without a TTL set by Expire,
+	// the TTL is the one the version was written with.
+	query := `UPDATE ` + c.tables.kv + `
+SET touched_at = ?,
+    accessed_at = max(ifnull(accessed_at, 0), ?),
+    expires_at = CASE
+      WHEN ? AND expires_at IS NOT NULL THEN ? + ifnull(ttl, expires_at - inserted_at)
+      ELSE expires_at
+    END,
+    ttl = ifnull(ttl, expires_at - inserted_at)
+WHERE key = ? AND ` + liveCondition + `;`
+
+	now := nowMillis()
+	return c.updateLive(key, query, now, now, c.opts.slidingExpiry, now, key, now)
+}
+
+// updateLive executes an UPDATE on the live version of key, reporting a
+// not-found error if no row was changed.
+func (c *CacheClient) updateLive(key, query string, args ...interface{}) error {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+	defer c.invalidate(key)
+
+	result, err := c.exec(db, query, args...)
+	if err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected failed: %w", err)
+	}
+	if n == 0 {
+		return keyNotFound(key)
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package squeakyv
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetWithTTL(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.SetWithTTL("key", []byte("value"), time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	remaining, ok, err := client.TTL("key")
+	if err != nil {
+		t.Fatalf("Failed to read TTL: %v", err)
+	}
+	if !ok {
+		t.Fatal("Expected key to have an expiry")
+	}
+	if remaining <= 59*time.Minute || remaining > time.Hour {
+		t.Errorf("Expected remaining TTL close to 1h, got %v", remaining)
+	}
+
+	if err := client.SetWithTTL("key", []byte("value"), 0); err == nil {
+		t.Error("Expected error for non-positive ttl")
+	}
+}
+
+func TestTTLWithoutExpiry(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.Set("key", []byte("value")); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	remaining, ok, err := client.TTL("key")
+	if err != nil {
+		t.Fatalf("Failed to read TTL: %v", err)
+	}
+	if ok || remaining != 0 {
+		t.Errorf("Expected no expiry, got %v (ok=%v)", remaining, ok)
+	}
+}
+
+func TestExpireAndPersist(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.Set("key", []byte("value")); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	if err := client.Expire("key", time.Minute); err != nil {
+		t.Fatalf("Failed to expire key: %v", err)
+	}
+	if _, ok, err := client.TTL("key"); err != nil || !ok {
+		t.Fatalf("Expected expiry after Expire, got ok=%v err=%v", ok, err)
+	}
+
+	if err := client.Persist("key"); err != nil {
+		t.Fatalf("Failed to persist key: %v", err)
+	}
+	if _, ok, err := client.TTL("key"); err != nil || ok {
+		t.Fatalf("Expected no expiry after Persist, got ok=%v err=%v", ok, err)
+	}
+
+	// Neither operation may create a new version
+	keys, err := client.ListKeys()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Errorf("Expected 1 key, got %d", len(keys))
+	}
+}
+
+func TestTTLOperationsOnMissingKey(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.Expire("missing", time.Minute); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expire: expected ErrKeyNotFound, got %v", err)
+	}
+	if err := client.Persist("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Persist: expected ErrKeyNotFound, got %v", err)
+	}
+	if _, _, err := client.TTL("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("TTL: expected ErrKeyNotFound, got %v", err)
+	}
+
+	// Deleted keys count as missing
+	client.Set("deleted", []byte("value"))
+	client.Delete("deleted")
+	if err := client.Expire("deleted", time.Minute); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expire on deleted key: expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestTTLOperationsOnExpiredKey(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.SetWithTTL("key", []byte("value"), 10*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, _, err := client.TTL("key"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("TTL: expected ErrKeyNotFound, got %v", err)
+	}
+	if err := client.Persist("key"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Persist: expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestSchemaMigrationIsIdempotent(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	for i := 0; i < 2; i++ {
+		client, err := NewCacheClient(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to open database (attempt %d): %v", i+1, err)
+		}
+		if err := client.SetWithTTL("key", []byte("value"), time.Hour); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+		client.Close()
+	}
+}
+
+func TestGetExpiredKey(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.SetWithTTL("key", []byte("value"), 10*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	value, err := client.Get("key")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if string(value) != "value" {
+		t.Fatalf("Expected value before expiry, got %v", value)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	value, err = client.Get("key")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if value != nil {
+		t.Errorf("Expected nil for expired key, got %v", value)
+	}
+
+	// The expired version is soft-deleted, not purged
+	var active int
+	if err := client.db.QueryRow(`SELECT COUNT(*) FROM kv WHERE key = ? AND is_active = 1`, "key").Scan(&active); err != nil {
+		t.Fatalf("Failed to count active rows: %v", err)
+	}
+	if active != 0 {
+		t.Errorf("Expected expired row to be inactive, found %d active", active)
+	}
+	if rows := countRows(t, client, "key"); rows != 1 {
+		t.Errorf("Expected expired row to be retained as history, found %d rows", rows)
+	}
+}
+
+func TestListKeysSkipsExpired(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("durable", []byte("value"))
+	if err := client.SetWithTTL("expiring", []byte("value"), 10*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// No Get has touched the expired key yet
+	keys, err := client.ListKeys()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "durable" {
+		t.Errorf("Expected only durable key, got %v", keys)
+	}
+}
+
+func TestConcurrentGetExpiredKey(t *testing.T) {
+	client, err := NewCacheClient(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.SetWithTTL("key", []byte("value"), 10*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := client.Get("key")
+			if err != nil {
+				t.Errorf("Failed to get value: %v", err)
+			}
+			if value != nil {
+				t.Errorf("Expected nil for expired key, got %v", value)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if rows := countRows(t, client, "key"); rows != 1 {
+		t.Errorf("Expected a single retained row, found %d", rows)
+	}
+}
+
+func TestSetAfterExpiry(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.SetWithTTL("key", []byte("old"), 10*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if err := client.Set("key", []byte("new")); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	value, err := client.Get("key")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if string(value) != "new" {
+		t.Errorf("Expected new value, got %q", value)
+	}
+}
+
+func TestTouch(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("value"))
+	time.Sleep(5 * time.Millisecond)
+	client.Set("b", []byte("value"))
+
+	before, err := client.Stat("a")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if err := client.Touch("a"); err != nil {
+		t.Fatalf("Touch failed: %v", err)
+	}
+
+	after, err := client.Stat("a")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if after.Versions != 1 {
+		t.Errorf("Touch must not create a version, got %d versions", after.Versions)
+	}
+	if !after.CreatedAt.Equal(before.CreatedAt) || !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Errorf("Expected write times unchanged: before %+v, after %+v", before, after)
+	}
+
+	// a is now listed as the most recently written key
+	keys, err := client.ListKeys()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if keys[0] != "a" {
+		t.Errorf("Expected touched key first, got %v", keys)
+	}
+}
+
+func TestTouchMissingKey(t *testing.T) {
+	client := newTestClient(t)
+
+	if err := client.Touch("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	client.Set("deleted", []byte("value"))
+	client.Delete("deleted")
+	if err := client.Touch("deleted"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for deleted key, got %v", err)
+	}
+}
+
+func TestTouchExpiry(t *testing.T) {
+	fixed := newTestClient(t)
+	sliding, err := NewCacheClient(":memory:", WithSlidingExpiry())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer sliding.Close()
+
+	for _, client := range []*CacheClient{fixed, sliding} {
+		if err := client.SetWithTTL("key", []byte("value"), 200*time.Millisecond); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	for _, client := range []*CacheClient{fixed, sliding} {
+		if err := client.Touch("key"); err != nil {
+			t.Fatalf("Touch failed: %v", err)
+		}
+	}
+
+	remaining, _, err := fixed.TTL("key")
+	if err != nil {
+		t.Fatalf("TTL failed: %v", err)
+	}
+	if remaining > 150*time.Millisecond {
+		t.Errorf("Expected fixed expiry to be unchanged, got %v remaining", remaining)
+	}
+
+	remaining, _, err = sliding.TTL("key")
+	if err != nil {
+		t.Fatalf("TTL failed: %v", err)
+	}
+	if remaining < 150*time.Millisecond {
+		t.Errorf("Expected sliding expiry to be extended, got %v remaining", remaining)
+	}
+}
+
+func TestTouchAfterExpire(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithSlidingExpiry())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.SetWithTTL("key", []byte("value"), time.Hour)
+	time.Sleep(5 * time.Millisecond)
+	if err := client.Expire("key", 200*time.Millisecond); err != nil {
+		t.Fatalf("Expire failed: %v", err)
+	}
+
+	// The TTL set by Expire is the one restarted, every time.
+	for i := 0; i < 2; i++ {
+		time.Sleep(50 * time.Millisecond)
+		if err := client.Touch("key"); err != nil {
+			t.Fatalf("Touch failed: %v", err)
+		}
+		remaining, _, err := client.TTL("key")
+		if err != nil {
+			t.Fatalf("TTL failed: %v", err)
+		}
+		if remaining < 150*time.Millisecond || remaining > 200*time.Millisecond {
+			t.Errorf("Expected about 200ms remaining, got %v", remaining)
+		}
+	}
+}
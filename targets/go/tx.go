@@ -0,0 +1,254 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// savepointName matches the savepoint names accepted by Tx.Savepoint. Names
+// are interpolated into SQL, so anything else is rejected.
+var savepointName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// Tx is a transaction-scoped view of a CacheClient, passed to the function
+// given to WithTransaction.
+//
+// Its methods behave like the CacheClient methods of the same name but run
+// inside the enclosing transaction. A Tx must not be used after the function
+// it was passed to returns.
+type Tx struct {
+	tx     *sql.Tx
+	client *CacheClient
+
+	// depth counts active nested WithTransaction calls, used to name savepoints.
+	depth int
+	// written lists the keys set or deleted, to invalidate in the memory
+	// cache once the transaction ends.
+	written []string
+}
+
+// WithTransaction runs fn inside a single write transaction. The transaction
+// commits if fn returns nil and rolls back if fn returns an error or panics;
+// a panic is re-raised after the rollback.
+//
+// Read-modify-write sequences performed through tx are atomic with respect to
+// other writers. If the transaction fails because another process holds the
+// database lock, it is rolled back and fn is called again (see WithRetry), so
+// fn should not have side effects outside tx. fn must only use tx: calling
+// methods on the client itself from within fn is not supported and may
+// deadlock. To nest transactional sections, call tx.WithTransaction, which
+// uses a savepoint.
+//
+// Example:
+//
+//	err := client.WithTransaction(func(tx *squeakyv.Tx) error {
+//		value, err := tx.Get("counter")
+//		if err != nil {
+//			return err
+//		}
+//		return tx.Set("counter", append(value, '!'))
+//	})
+func (c *CacheClient) WithTransaction(fn func(tx *Tx) error) error {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	var written []string
+	defer func() { c.invalidate(written...) }()
+
+	return c.withTx(db, func(tx *sql.Tx) error {
+		t := &Tx{tx: tx, client: c}
+		defer func() { written = append(written, t.written...) }()
+		return fn(t)
+	})
+}
+
+// WithTransaction runs fn as a nested section of the enclosing transaction,
+// backed by a SQLite savepoint.
+//
+// If fn returns an error or panics, only the work done inside fn is undone and
+// the enclosing transaction can continue; the error (or panic) is passed on
+// to the caller. If fn returns nil, its work becomes part of the enclosing
+// transaction and is committed or rolled back with it.
+func (t *Tx) WithTransaction(fn func(tx *Tx) error) (err error) {
+	t.depth++
+	defer func() { t.depth-- }()
+
+	name := fmt.Sprintf("squeakyv_nested_%d", t.depth)
+	if err := t.Savepoint(name); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			t.rollbackSavepoint(name)
+			panic(p)
+		}
+	}()
+
+	if err := fn(t); err != nil {
+		if rbErr := t.rollbackSavepoint(name); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return t.Release(name)
+}
+
+// Savepoint marks a point within the transaction that RollbackTo can return
+// to. Savepoints nest; reusing a name shadows the earlier savepoint until it
+// is released.
+//
+// The name must start with a letter or underscore, contain only letters,
+// digits and underscores, and be at most 64 characters long.
+//
+// Example:
+//
+//	if err := tx.Savepoint("batch"); err != nil {
+//		return err
+//	}
+//	if err := writeBatch(tx); err != nil {
+//		// Discard the batch but keep earlier work
+//		if err := tx.RollbackTo("batch"); err != nil {
+//			return err
+//		}
+//	}
+//	return tx.Release("batch")
+func (t *Tx) Savepoint(name string) error {
+	return t.execSavepoint("SAVEPOINT ", name)
+}
+
+// RollbackTo undoes all work done since the named savepoint. The savepoint
+// itself stays active, so it can be rolled back to again; call Release to
+// remove it.
+func (t *Tx) RollbackTo(name string) error {
+	return t.execSavepoint("ROLLBACK TO ", name)
+}
+
+// Release removes the named savepoint and every savepoint created after it,
+// merging their work into the enclosing transaction.
+func (t *Tx) Release(name string) error {
+	return t.execSavepoint("RELEASE ", name)
+}
+
+// execSavepoint validates name and executes the savepoint statement prefix + name.
+func (t *Tx) execSavepoint(prefix, name string) error {
+	if !savepointName.MatchString(name) {
+		return fmt.Errorf("invalid savepoint name %q", name)
+	}
+	if _, err := t.tx.Exec(prefix + name); err != nil {
+		return fmt.Errorf("%sfailed: %w", prefix, err)
+	}
+	return nil
+}
+
+// rollbackSavepoint undoes the work since the named savepoint and removes it.
+func (t *Tx) rollbackSavepoint(name string) error {
+	if err := t.RollbackTo(name); err != nil {
+		return err
+	}
+	return t.Release(name)
+}
+
+// Get retrieves the value for a key within the transaction, returning nil if
+// the key doesn't exist. See CacheClient.Get.
+func (t *Tx) Get(key string) ([]byte, error) {
+	value, err := t.client.readValue(t.tx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// GetStrict retrieves the value for a key within the transaction, returning an
+// error wrapping ErrKeyNotFound if the key doesn't exist. See CacheClient.GetStrict.
+func (t *Tx) GetStrict(key string) ([]byte, error) {
+	return t.client.readValue(t.tx, key)
+}
+
+// Set stores a value for a key within the transaction. See CacheClient.Set.
+func (t *Tx) Set(key string, value []byte) error {
+	stored, err := t.client.encodeStored(key, value)
+	if err != nil {
+		return err
+	}
+	t.written = append(t.written, key)
+	return insertVersion(t.tx, t.client.tables, key, stored, sql.NullInt64{})
+}
+
+// Delete soft-deletes a key within the transaction. See CacheClient.Delete.
+func (t *Tx) Delete(key string) error {
+	t.written = append(t.written, key)
+	return deleteKey(t.tx, t.client.tables, key)
+}
+
+// ListKeys returns all active, unexpired keys as seen by the transaction,
+// newest first. See CacheClient.ListKeys.
+func (t *Tx) ListKeys() ([]string, error) {
+	return listLiveKeys(t.tx, t.client.tables, nowMillis())
+}
+
+// withTx runs fn inside a write transaction on db, committing if fn returns
+// nil and rolling back if it returns an error or panics.
+//
+// Write transactions from the same client are serialized so that a
+// read-modify-write sequence never has to upgrade its lock while another
+// connection of the pool holds one. A transaction that fails on contention
+// with another process is rolled back and run again, so fn must not leave
+// state behind that a repeated call would double count.
+func (c *CacheClient) withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	return c.withTxWaiting(db, nil, fn)
+}
+
+// withTxWaiting is withTx, adding to *wait, unless wait is nil, the time
+// spent waiting for the database lock, including for the client's other
+// write transactions to finish. Under WithMaxEntries or WithMaxBytes the
+// transaction also evicts keys over the limits (see evictingTx).
+func (c *CacheClient) withTxWaiting(db *sql.DB, wait *time.Duration, fn func(tx *sql.Tx) error) (err error) {
+	var evicted []eviction
+	defer func() { c.notifyEvicted(evicted) }()
+
+	if wait != nil {
+		start := time.Now()
+		c.writeMu.Lock()
+		*wait += time.Since(start)
+	} else {
+		c.writeMu.Lock()
+	}
+	defer c.writeMu.Unlock()
+
+	if c.access != nil {
+		evicted, err = c.evictingTx(db, wait, fn)
+		return err
+	}
+	return c.retryWaiting(wait, func() error { return runTx(db, fn) })
+}
+
+// runTx runs fn inside a transaction on db, committing if fn returns nil and
+// rolling back if it returns an error or panics.
+func runTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin failed: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package squeakyv
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestWithTransactionCommit(t *testing.T) {
+	client := newTestClient(t)
+
+	err := client.WithTransaction(func(tx *Tx) error {
+		if err := tx.Set("key1", []byte("value1")); err != nil {
+			return err
+		}
+		if err := tx.Set("key2", []byte("value2")); err != nil {
+			return err
+		}
+
+		// Writes are visible inside the transaction
+		value, err := tx.Get("key1")
+		if err != nil {
+			return err
+		}
+		if string(value) != "value1" {
+			t.Errorf("Expected value1 inside transaction, got %s", value)
+		}
+		return tx.Delete("key2")
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	keys, err := client.ListKeys()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "key1" {
+		t.Errorf("Expected only key1 after commit, got %v", keys)
+	}
+}
+
+func TestWithTransactionRollback(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("key", []byte("original"))
+
+	sentinel := errors.New("abort")
+	err := client.WithTransaction(func(tx *Tx) error {
+		if err := tx.Set("key", []byte("changed")); err != nil {
+			return err
+		}
+		return sentinel
+	})
+	if !errors.Is(err, sentinel) {
+		t.Fatalf("Expected callback error, got %v", err)
+	}
+
+	value, err := client.Get("key")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if string(value) != "original" {
+		t.Errorf("Expected original after rollback, got %s", value)
+	}
+}
+
+func TestWithTransactionPanic(t *testing.T) {
+	client := newTestClient(t)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic to propagate")
+			}
+		}()
+		client.WithTransaction(func(tx *Tx) error {
+			tx.Set("key", []byte("value"))
+			panic("boom")
+		})
+	}()
+
+	value, err := client.Get("key")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if value != nil {
+		t.Errorf("Expected rollback after panic, got %s", value)
+	}
+
+	// The client must remain usable
+	if err := client.Set("key", []byte("value")); err != nil {
+		t.Errorf("Failed to set after panic: %v", err)
+	}
+}
+
+func TestNestedTransaction(t *testing.T) {
+	client := newTestClient(t)
+
+	sentinel := errors.New("abort inner")
+	err := client.WithTransaction(func(tx *Tx) error {
+		if err := tx.Set("outer", []byte("value")); err != nil {
+			return err
+		}
+
+		err := tx.WithTransaction(func(tx *Tx) error {
+			if err := tx.Set("inner", []byte("value")); err != nil {
+				return err
+			}
+			return sentinel
+		})
+		if !errors.Is(err, sentinel) {
+			t.Errorf("Expected inner error, got %v", err)
+		}
+
+		return tx.WithTransaction(func(tx *Tx) error {
+			return tx.Set("inner2", []byte("value"))
+		})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	values, err := client.GetMany([]string{"outer", "inner", "inner2"})
+	if err != nil {
+		t.Fatalf("Failed to get values: %v", err)
+	}
+	if _, ok := values["inner"]; ok {
+		t.Error("Rolled back inner write was committed")
+	}
+	if _, ok := values["outer"]; !ok {
+		t.Error("Outer write was lost")
+	}
+	if _, ok := values["inner2"]; !ok {
+		t.Error("Released inner write was lost")
+	}
+}
+
+func TestWithTransactionAtomicIncrement(t *testing.T) {
+	client, err := NewCacheClient(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("counter", []byte("0"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				err := client.WithTransaction(func(tx *Tx) error {
+					value, err := tx.Get("counter")
+					if err != nil {
+						return err
+					}
+					n, err := strconv.Atoi(string(value))
+					if err != nil {
+						return err
+					}
+					return tx.Set("counter", []byte(fmt.Sprint(n+1)))
+				})
+				if err != nil {
+					t.Errorf("Transaction failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := client.Get("counter")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if string(value) != "100" {
+		t.Errorf("Expected counter 100, got %s", value)
+	}
+}
+
+func TestWithTransactionAfterClose(t *testing.T) {
+	client, err := NewCacheClient(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.Close()
+
+	err = client.WithTransaction(func(tx *Tx) error { return nil })
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestSavepointRollbackTo(t *testing.T) {
+	client := newTestClient(t)
+
+	err := client.WithTransaction(func(tx *Tx) error {
+		if err := tx.Set("before", []byte("value")); err != nil {
+			return err
+		}
+
+		if err := tx.Savepoint("batch"); err != nil {
+			return err
+		}
+		if err := tx.Set("batch1", []byte("value")); err != nil {
+			return err
+		}
+		// A nil value violates the NOT NULL constraint
+		if err := tx.Set("batch2", nil); err == nil {
+			t.Error("Expected failed insert")
+		}
+		if err := tx.RollbackTo("batch"); err != nil {
+			return err
+		}
+		if err := tx.Release("batch"); err != nil {
+			return err
+		}
+
+		return tx.Set("after", []byte("value"))
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	values, err := client.GetMany([]string{"before", "batch1", "batch2", "after"})
+	if err != nil {
+		t.Fatalf("Failed to get values: %v", err)
+	}
+	if len(values) != 2 {
+		t.Errorf("Expected only before and after to be committed, got %v", values)
+	}
+	if _, ok := values["batch1"]; ok {
+		t.Error("Rolled back write batch1 was committed")
+	}
+}
+
+func TestSavepointRelease(t *testing.T) {
+	client := newTestClient(t)
+
+	err := client.WithTransaction(func(tx *Tx) error {
+		if err := tx.Savepoint("sp"); err != nil {
+			return err
+		}
+		if err := tx.Set("key", []byte("value")); err != nil {
+			return err
+		}
+		return tx.Release("sp")
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	value, err := client.Get("key")
+	if err != nil {
+		t.Fatalf("Failed to get value: %v", err)
+	}
+	if string(value) != "value" {
+		t.Errorf("Expected released work to be committed, got %s", value)
+	}
+}
+
+func TestSavepointInvalidName(t *testing.T) {
+	client := newTestClient(t)
+
+	names := []string{"", "1abc", "a b", "sp; DROP TABLE kv", "sp--", `"quoted"`}
+	err := client.WithTransaction(func(tx *Tx) error {
+		for _, name := range names {
+			if err := tx.Savepoint(name); err == nil {
+				t.Errorf("Expected error for savepoint name %q", name)
+			}
+			if err := tx.RollbackTo(name); err == nil {
+				t.Errorf("Expected error for rollback name %q", name)
+			}
+			if err := tx.Release(name); err == nil {
+				t.Errorf("Expected error for release name %q", name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+}
+
+func TestReleaseUnknownSavepoint(t *testing.T) {
+	client := newTestClient(t)
+
+	err := client.WithTransaction(func(tx *Tx) error {
+		return tx.Release("never_created")
+	})
+	if err == nil {
+		t.Error("Expected error releasing unknown savepoint")
+	}
+}
@@ -0,0 +1,126 @@
+package squeakyv
+
+import (
+	"errors"
+	"time"
+)
+
+// TypedClient is a view of a CacheClient that stores values of type T,
+// encoding and decoding them with a Codec. Create one with Typed.
+//
+// Keys and expiry behave exactly as on the underlying client, which can
+// still be used directly; a TypedClient holds no resources of its own.
+type TypedClient[T any] struct {
+	client *CacheClient
+	codec  Codec
+}
+
+// Typed returns a typed view of c whose values are encoded with codec, or
+// with the client's codec (see WithCodec) if codec is nil.
+//
+// Example:
+//
+//	type User struct {
+//		Name string
+//	}
+//
+//	users := squeakyv.Typed[User](client, nil)
+//	err := users.Set("user:1", User{Name: "alice"})
+//	user, found, err := users.Get("user:1")
+func Typed[T any](c *CacheClient, codec Codec) *TypedClient[T] {
+	if codec == nil {
+		codec = c.opts.codec
+	}
+	return &TypedClient[T]{client: c, codec: codec}
+}
+
+// Get retrieves and decodes the value for a key. A missing, deleted or
+// expired key returns the zero value of T and found=false. A value that
+// fails to decode returns a *DecodeError naming the key.
+func (t *TypedClient[T]) Get(key string) (value T, found bool, err error) {
+	data, err := t.client.GetStrict(key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+	value, err = t.decode(key, data)
+	if err != nil {
+		return value, false, err
+	}
+	return value, true, nil
+}
+
+// Set encodes and stores a value for a key. See CacheClient.Set.
+func (t *TypedClient[T]) Set(key string, value T) error {
+	data, err := t.encode(key, value)
+	if err != nil {
+		return err
+	}
+	return t.client.Set(key, data)
+}
+
+// SetWithTTL encodes and stores a value for a key that expires after ttl.
+// See CacheClient.SetWithTTL.
+func (t *TypedClient[T]) SetWithTTL(key string, value T, ttl time.Duration) error {
+	data, err := t.encode(key, value)
+	if err != nil {
+		return err
+	}
+	return t.client.SetWithTTL(key, data, ttl)
+}
+
+// GetMany retrieves and decodes the values for several keys. The result
+// contains only keys that exist. If any value fails to decode, GetMany
+// returns a *DecodeError for it and no results. See CacheClient.GetMany.
+func (t *TypedClient[T]) GetMany(keys []string) (map[string]T, error) {
+	raw, err := t.client.GetMany(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]T, len(raw))
+	for key, data := range raw {
+		value, err := t.decode(key, data)
+		if err != nil {
+			return nil, err
+		}
+		results[key] = value
+	}
+	return results, nil
+}
+
+// SetMany encodes and stores several values in a single transaction. Nothing
+// is written if any value fails to encode. See CacheClient.SetMany.
+func (t *TypedClient[T]) SetMany(items map[string]T) error {
+	raw := make(map[string][]byte, len(items))
+	for key, value := range items {
+		data, err := t.encode(key, value)
+		if err != nil {
+			return err
+		}
+		raw[key] = data
+	}
+	return t.client.SetMany(raw)
+}
+
+// Delete soft-deletes a key. See CacheClient.Delete.
+func (t *TypedClient[T]) Delete(key string) error {
+	return t.client.Delete(key)
+}
+
+// encode marshals value with the codec.
+func (t *TypedClient[T]) encode(key string, value T) ([]byte, error) {
+	return encodeValue(t.codec, key, value)
+}
+
+// decode unmarshals data with the codec.
+func (t *TypedClient[T]) decode(key string, data []byte) (T, error) {
+	var value T
+	if err := t.codec.Unmarshal(data, &value); err != nil {
+		var zero T
+		return zero, &DecodeError{Key: key, Err: err}
+	}
+	return value, nil
+}
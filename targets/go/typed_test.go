@@ -0,0 +1,150 @@
+package squeakyv
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+type typedUser struct {
+	Name  string
+	Age   int
+	Tags  []string
+	Attrs map[string]float64
+	Blob  []byte
+}
+
+func TestTypedStruct(t *testing.T) {
+	client := newTestClient(t)
+	users := Typed[typedUser](client, nil)
+
+	want := typedUser{Name: "alice", Age: 30, Tags: []string{"admin"}, Attrs: map[string]float64{"score": 1.5}}
+	if err := users.Set("user:1", want); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	got, found, err := users.Get("user:1")
+	if err != nil || !found {
+		t.Fatalf("Get failed: found=%v err=%v", found, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// Stored as JSON by default
+	if raw, _ := client.Get("user:1"); !strings.Contains(string(raw), `"Name":"alice"`) {
+		t.Errorf("Expected JSON, got %s", raw)
+	}
+}
+
+func TestTypedSliceAndMap(t *testing.T) {
+	client := newTestClient(t)
+
+	lists := Typed[[]int](client, nil)
+	lists.Set("list", []int{1, 2, 3})
+	if got, _, _ := lists.Get("list"); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("Expected [1 2 3], got %v", got)
+	}
+
+	maps := Typed[map[string]string](client, JSONCodec{})
+	maps.Set("map", map[string]string{"a": "b"})
+	if got, _, _ := maps.Get("map"); !reflect.DeepEqual(got, map[string]string{"a": "b"}) {
+		t.Errorf("Expected map[a:b], got %v", got)
+	}
+}
+
+func TestTypedMissing(t *testing.T) {
+	client := newTestClient(t)
+	users := Typed[typedUser](client, nil)
+
+	got, found, err := users.Get("missing")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if found {
+		t.Error("Expected found=false")
+	}
+	if !reflect.DeepEqual(got, typedUser{}) {
+		t.Errorf("Expected zero value, got %+v", got)
+	}
+}
+
+func TestTypedDecodeError(t *testing.T) {
+	client := newTestClient(t)
+	client.Set("bad", []byte("not json"))
+	numbers := Typed[int](client, nil)
+
+	_, found, err := numbers.Get("bad")
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected *DecodeError, got %v", err)
+	}
+	if decodeErr.Key != "bad" || found {
+		t.Errorf("Expected error for key bad and found=false, got %q, %v", decodeErr.Key, found)
+	}
+
+	client.Set("good", []byte("1"))
+	if _, err := numbers.GetMany([]string{"good", "bad"}); !errors.As(err, &decodeErr) {
+		t.Errorf("Expected GetMany to report the decode error, got %v", err)
+	}
+}
+
+func TestTypedEncodeError(t *testing.T) {
+	client := newTestClient(t)
+	funcs := Typed[func()](client, nil)
+
+	err := funcs.Set("f", func() {})
+	if err == nil || !strings.Contains(err.Error(), `"f"`) {
+		t.Errorf("Expected an encode error naming the key, got %v", err)
+	}
+	if err := funcs.SetMany(map[string]func(){"g": func() {}}); err == nil {
+		t.Error("Expected SetMany to fail")
+	}
+	if n, _ := client.Count(); n != 0 {
+		t.Errorf("Expected nothing written, got %d keys", n)
+	}
+}
+
+func TestTypedMany(t *testing.T) {
+	client := newTestClient(t)
+	counts := Typed[int](client, nil)
+
+	if err := counts.SetMany(map[string]int{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+	got, err := counts.GetMany([]string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatalf("GetMany failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("Expected map[a:1 b:2], got %v", got)
+	}
+
+	counts.Delete("a")
+	if _, found, _ := counts.Get("a"); found {
+		t.Error("Expected a to be deleted")
+	}
+}
+
+func TestTypedRoundTrip(t *testing.T) {
+	client := newTestClient(t)
+	users := Typed[typedUser](client, nil)
+
+	roundTrip := func(want typedUser) bool {
+		if err := users.Set("user", want); err != nil {
+			t.Logf("Set failed: %v", err)
+			return false
+		}
+		got, found, err := users.Get("user")
+		if err != nil || !found {
+			t.Logf("Get failed: found=%v err=%v", found, err)
+			return false
+		}
+		return reflect.DeepEqual(got, want)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,61 @@
+package squeakyv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Vacuum rebuilds the database file, returning the space freed by deleted,
+// pruned and compacted rows to the file system.
+//
+// VACUUM needs exclusive access, so Vacuum waits for the client's write
+// transactions to finish and fails if any Snapshot is open. Other processes
+// holding transactions on the file make it fail with a busy error. On ":memory:"
+// databases it simply defragments memory.
+func (c *CacheClient) Vacuum() error {
+	db, err := c.acquireWrite()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.snapMu.Lock()
+	open := len(c.snapshots)
+	c.snapMu.Unlock()
+	if open > 0 {
+		return fmt.Errorf("cannot vacuum while %d snapshot(s) are open", open)
+	}
+
+	if _, err := db.Exec(`VACUUM;`); err != nil {
+		return fmt.Errorf("vacuum failed: %w", err)
+	}
+	return nil
+}
+
+// VacuumInto writes a compacted, consistent copy of the database to a new
+// file at path, which must not already exist (or must be empty). The source
+// is not modified, so this doubles as a cheap online backup and works on
+// ":memory:" databases too.
+//
+// Example:
+//
+//	err := client.VacuumInto("/backups/cache-2024-01-01.db")
+func (c *CacheClient) VacuumInto(path string) error {
+	if path == "" {
+		return errors.New("vacuum into: empty path")
+	}
+
+	db, err := c.acquireFlushed()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	if _, err := db.Exec(`VACUUM INTO ?;`, path); err != nil {
+		return fmt.Errorf("vacuum failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package squeakyv
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestVacuum(t *testing.T) {
+	for _, name := range []string{"memory", "file"} {
+		t.Run(name, func(t *testing.T) {
+			var client *CacheClient
+			if name == "memory" {
+				client = newTestClient(t)
+			} else {
+				client = newFileClient(t)
+			}
+
+			client.Set("a", []byte("1"))
+			client.Set("b", []byte("2"))
+			client.ClearHard()
+			client.Set("c", []byte("3"))
+
+			if err := client.Vacuum(); err != nil {
+				t.Fatalf("Vacuum failed: %v", err)
+			}
+			value, _ := client.Get("c")
+			if string(value) != "3" {
+				t.Errorf("Expected 3 after vacuum, got %s", value)
+			}
+		})
+	}
+}
+
+func TestVacuumWithOpenSnapshot(t *testing.T) {
+	client := newWALClient(t)
+	client.Set("a", []byte("1"))
+
+	snap, err := client.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if err := client.Vacuum(); err == nil {
+		t.Error("Expected error while a snapshot is open")
+	}
+	snap.Close()
+
+	if err := client.Vacuum(); err != nil {
+		t.Errorf("Vacuum failed after closing snapshot: %v", err)
+	}
+}
+
+func TestVacuumInto(t *testing.T) {
+	client := newTestClient(t)
+
+	client.Set("a", []byte("1"))
+	client.Set("b", []byte("2"))
+	client.Set("b", []byte("3"))
+	client.Delete("a")
+	client.Set("c", []byte("4"))
+
+	path := filepath.Join(t.TempDir(), "copy.db")
+	if err := client.VacuumInto(path); err != nil {
+		t.Fatalf("VacuumInto failed: %v", err)
+	}
+
+	copied, err := NewCacheClient(path)
+	if err != nil {
+		t.Fatalf("Failed to open copy: %v", err)
+	}
+	defer copied.Close()
+
+	keys, err := copied.ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys failed: %v", err)
+	}
+	sort.Strings(keys)
+	if expected := []string{"b", "c"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+	if value, _ := copied.Get("b"); string(value) != "3" {
+		t.Errorf("Expected b=3 in copy, got %s", value)
+	}
+
+	// The target must not already hold a database
+	if err := client.VacuumInto(path); err == nil {
+		t.Error("Expected error when the target already exists")
+	}
+}
+
+func TestVacuumKeepsVersionIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	db, err := sql.Open(driverName, path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.Exec(SchemaSQL)
+	db.Exec(`INSERT INTO kv (key, value) VALUES ('gone', 'x'), ('a', '1'), ('a', '2'), ('b', '1');`)
+	db.Exec(`DELETE FROM kv WHERE key = 'gone';`)
+	db.Close()
+
+	// Opening the database gives kv an INTEGER PRIMARY KEY, keeping the IDs.
+	client := newTestClientAt(t, path)
+	ids := func() []int64 {
+		var ids []int64
+		for _, key := range []string{"a", "b"} {
+			versions, _ := client.History(key)
+			for _, v := range versions {
+				ids = append(ids, v.ID)
+			}
+		}
+		return ids
+	}
+	if got, want := ids(), []int64{3, 2, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected IDs %v after migration, got %v", want, got)
+	}
+	var pk string
+	client.db.QueryRow(`SELECT name FROM pragma_table_info('kv') WHERE pk = 1;`).Scan(&pk)
+	if pk != versionIDColumn {
+		t.Errorf("Expected %s as the primary key, got %q", versionIDColumn, pk)
+	}
+
+	if err := client.Vacuum(); err != nil {
+		t.Fatalf("Vacuum failed: %v", err)
+	}
+	if got, want := ids(), []int64{3, 2, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected IDs %v after vacuum, got %v", want, got)
+	}
+	client.Set("a", []byte("3"))
+	if value, _ := client.Get("a"); string(value) != "3" {
+		t.Errorf("Expected the triggers recreated, got %s", value)
+	}
+	if versions, _ := client.History("a"); len(versions) != 3 || versions[0].ID != 5 {
+		t.Errorf("Expected a new version with ID 5, got %+v", versions)
+	}
+}
@@ -0,0 +1,276 @@
+package squeakyv
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// valueMagic starts every value stored in an envelope, as values written
+// through a value transform such as WithCompressor are. It is followed by a
+// layer kind, a byte whose meaning depends on the kind (such as a compressor
+// ID), and the layer's payload.
+//
+// Envelopes are only looked for in databases marked as using them (see
+// valueEnvelopesName); in others every value is returned as stored, whatever
+// its first bytes. Once a database is marked, every client wraps the values
+// it writes, with or without transforms of its own, so a plain value is never
+// mistaken for an envelope.
+var valueMagic = []byte{0xff, 'S', 'Q', 'V'}
+
+// valueEnvelopesName is the kv_meta entry marking a database whose values
+// are stored in envelopes. It is written by the first client with a value
+// transform to open the database, and never removed.
+const valueEnvelopesName = "value_envelopes"
+
+// valueMagicHex is valueMagic as an SQL blob literal.
+const valueMagicHex = `X'FF535156'`
+
+// Value layer kinds.
+const (
+	// layerCompressed holds a value compressed by the Compressor whose ID
+	// follows the kind. Every transformed value has one, uncompressed if the
+	// client has no compressor, so it is always the innermost layer.
+	layerCompressed byte = 'z'
+	// layerEncrypted holds a compressed layer encrypted in the format whose
+	// ID follows the kind.
+	layerEncrypted byte = 'e'
+)
+
+// transformsValues reports whether the client stores values through a
+// transform, or at least in envelopes. If so, SQL that reads or rewrites
+// value bytes in place, such as Append's concatenation, must not be used.
+func (c *CacheClient) transformsValues() bool {
+	return c.opts.compressor != nil || c.keys != nil || c.envelopes.Load()
+}
+
+// wrapsValue reports whether value must be stored in an envelope. A value
+// beginning with valueMagic makes a client that hasn't seen the database
+// marked check again, in case another process has marked it since.
+func (c *CacheClient) wrapsValue(value []byte) bool {
+	return c.transformsValues() || (bytes.HasPrefix(value, valueMagic) && c.usesEnvelopes())
+}
+
+// usesEnvelopes reports whether the database is marked as storing values in
+// envelopes. Only files are checked again: an in-memory database is marked by
+// its own clients, which share the mark (see openEnvelopes), and a query
+// outside the transaction holding its only connection would never return.
+func (c *CacheClient) usesEnvelopes() bool {
+	if c.envelopes.Load() {
+		return true
+	}
+	if isMemoryPath(c.path) {
+		return false
+	}
+	if _, err := readMeta(c.db, c.tables, valueEnvelopesName); err != nil {
+		return false
+	}
+	c.envelopes.Store(true)
+	return true
+}
+
+// remarkEnvelopes marks the client's database as storing values in envelopes
+// after its contents were replaced by a copy, which drops the mark, if the
+// copy's source was marked (copied) or the client was.
+func (c *CacheClient) remarkEnvelopes(copied bool) error {
+	if !copied && !c.envelopes.Load() {
+		return nil
+	}
+	if !c.opts.readOnly {
+		err := runTx(c.db, func(tx *sql.Tx) error {
+			return markEnvelopes(tx, c.tables)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark value envelopes: %w", err)
+		}
+	}
+	c.envelopes.Store(true)
+	return nil
+}
+
+// markEnvelopes marks the database as storing values in envelopes, unless
+// it already is. Values stored before then that begin with valueMagic are
+// wrapped first, so they aren't taken for envelopes afterwards.
+func markEnvelopes(tx *sql.Tx, t tableNames) error {
+	if _, err := readMeta(tx, t, valueEnvelopesName); err == nil {
+		return nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	type lookalike struct {
+		rowid    int64
+		value    []byte
+		checksum sql.NullInt64
+	}
+	query := `SELECT rowid, value, checksum
+FROM ` + t.kv + `
+WHERE substr(CAST(value AS BLOB), 1, 4) = ` + valueMagicHex + `;`
+
+	rows, err := tx.Query(query)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+	var found []lookalike
+	for rows.Next() {
+		var l lookalike
+		if err := rows.Scan(&l.rowid, &l.value, &l.checksum); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		found = append(found, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration failed: %w", err)
+	}
+
+	update := `UPDATE ` + t.kv + ` SET value = ?, checksum = ? WHERE rowid = ?;`
+	for _, l := range found {
+		stored := appendLayer(layerCompressed, uncompressedID, l.value)
+		if l.checksum.Valid {
+			l.checksum.Int64 = checksumOf(stored)
+		}
+		if _, err := tx.Exec(update, stored, l.checksum, l.rowid); err != nil {
+			return fmt.Errorf("exec failed: %w", err)
+		}
+	}
+	return insertMeta(tx, t, valueEnvelopesName, []byte{1})
+}
+
+// openEnvelopes returns the mark of a database storing values in envelopes,
+// for a new client of it, marking the database first if the client
+// transforms values. Clients of one shared in-memory database share the
+// returned flag.
+func openEnvelopes(db *sql.DB, t tableNames, path string, o options, transforms bool) (*atomic.Bool, error) {
+	flag := new(atomic.Bool)
+	if name, ok := sharedMemoryName(path); ok {
+		flag = sharedEnvelopes(sharedKey{name, o.table})
+	}
+	if transforms && !o.readOnly {
+		err := runTx(db, func(tx *sql.Tx) error {
+			return markEnvelopes(tx, t)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to mark value envelopes: %w", err)
+		}
+		flag.Store(true)
+		return flag, nil
+	}
+	_, err := readMeta(db, t, valueEnvelopesName)
+	if err == nil {
+		flag.Store(true)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return flag, nil
+}
+
+// encodeStored returns value in the form it is stored under key. It is the
+// last step before every write of a new value, so it also enforces the key
+// rules, WithMaxValueSize and WithMaxBytes.
+func (c *CacheClient) encodeStored(key string, value []byte) ([]byte, error) {
+	if err := c.validateKey(key); err != nil {
+		return nil, err
+	}
+	if err := c.checkValueSize(key, int64(len(value))); err != nil {
+		return nil, err
+	}
+	stored := value
+	if c.wrapsValue(value) {
+		var err error
+		if stored, err = compressValue(c.opts.compressor, key, value); err != nil {
+			return nil, err
+		}
+		if c.keys != nil {
+			if stored, err = c.keys.seal(key, stored); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// A value that can't fit in WithMaxBytes on its own would evict everything.
+	if c.opts.maxBytes > 0 && int64(len(stored)) > c.opts.maxBytes {
+		return nil, &ValueTooLargeError{Key: key, Size: len(stored), Limit: int(c.opts.maxBytes)}
+	}
+	return stored, nil
+}
+
+// checkValueSize rejects a value of size bytes for key if it exceeds
+// WithMaxValueSize.
+func (c *CacheClient) checkValueSize(key string, size int64) error {
+	if c.opts.maxValueSize > 0 && size > c.opts.maxValueSize {
+		return &ValueTooLargeError{Key: key, Size: int(size), Limit: int(c.opts.maxValueSize)}
+	}
+	return nil
+}
+
+// decodeStored returns the value stored under key, reversing encodeStored. In
+// a database marked as using envelopes, it decodes every layer it
+// recognizes, whatever the client's own options, so values written under a
+// different configuration still read back as long as what they need, such as
+// their compressor, is available.
+func (c *CacheClient) decodeStored(key string, stored []byte) ([]byte, error) {
+	kind, id, payload, ok := parseLayer(stored)
+	if !ok || !c.usesEnvelopes() {
+		return stored, nil
+	}
+	if kind == layerEncrypted {
+		plaintext, err := c.keys.open(key, id, payload)
+		if err != nil {
+			return nil, err
+		}
+		if kind, id, payload, ok = parseLayer(plaintext); !ok || kind != layerCompressed {
+			return nil, decryptError(key, "invalid plaintext")
+		}
+	}
+	switch kind {
+	case layerCompressed:
+		return decompressValue(c.opts.compressor, key, id, payload)
+	default:
+		return nil, fmt.Errorf("failed to decode stored value of key %q: unknown layer %q", key, kind)
+	}
+}
+
+// resealStored returns a version's stored value, as read, once moved from the
+// key from to the key to: encrypted values are bound to their key, so they are
+// re-encrypted for the new one.
+func (c *CacheClient) resealStored(from, to string, stored []byte) ([]byte, error) {
+	if !bytes.HasPrefix(stored, valueMagic) || !c.usesEnvelopes() {
+		return stored, nil
+	}
+	return c.keys.reseal(from, to, stored)
+}
+
+// decodeEach wraps fn so that it receives decoded values.
+func (c *CacheClient) decodeEach(fn func(key string, value []byte) error) func(key string, value []byte) error {
+	if fn == nil {
+		return nil
+	}
+	return func(key string, stored []byte) error {
+		value, err := c.decodeStored(key, stored)
+		if err != nil {
+			return err
+		}
+		return fn(key, value)
+	}
+}
+
+// appendLayer returns payload wrapped in a layer of the given kind and id.
+func appendLayer(kind, id byte, payload []byte) []byte {
+	stored := make([]byte, 0, len(valueMagic)+2+len(payload))
+	stored = append(stored, valueMagic...)
+	stored = append(stored, kind, id)
+	return append(stored, payload...)
+}
+
+// parseLayer splits a value stored with appendLayer, reporting false if it
+// has no envelope.
+func parseLayer(stored []byte) (kind, id byte, payload []byte, ok bool) {
+	if len(stored) < len(valueMagic)+2 || !bytes.HasPrefix(stored, valueMagic) {
+		return 0, 0, nil, false
+	}
+	header := stored[len(valueMagic):]
+	return header[0], header[1], header[2:], true
+}
@@ -0,0 +1,79 @@
+package squeakyv
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxValueSize(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithMaxValueSize(8))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	big := []byte("123456789")
+	err = client.Set("big", big)
+	var tooLarge *ValueTooLargeError
+	if !errors.As(err, &tooLarge) || !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("Expected *ValueTooLargeError, got %v", err)
+	}
+	if tooLarge.Key != "big" || tooLarge.Size != 9 || tooLarge.Limit != 8 {
+		t.Errorf("Unexpected error fields %+v", tooLarge)
+	}
+	if err := client.Set("ok", []byte("12345678")); err != nil {
+		t.Errorf("Expected a value at the limit to be accepted, got %v", err)
+	}
+
+	writes := map[string]func() error{
+		"SetWithTTL": func() error { return client.SetWithTTL("big", big, time.Hour) },
+		"SetMany":    func() error { return client.SetMany(map[string][]byte{"a": []byte("a"), "big": big}) },
+		"SetNX":      func() error { _, err := client.SetNX("big", big); return err },
+		"GetSet":     func() error { _, err := client.GetSet("big", big); return err },
+		"CompareAndSwap": func() error {
+			_, err := client.CompareAndSwap("ok", []byte("12345678"), big)
+			return err
+		},
+		"Append": func() error { _, err := client.Append("ok", []byte("9")); return err },
+		"Tx": func() error {
+			return client.WithTransaction(func(tx *Tx) error { return tx.Set("big", big) })
+		},
+		"Import": func() error {
+			_, err := client.Import(strings.NewReader(`{"key":"big","value":"MTIzNDU2Nzg5"}`+"\n"), ImportOptions{})
+			return err
+		},
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, ErrValueTooLarge) {
+			t.Errorf("Expected %s to fail with ErrValueTooLarge, got %v", name, err)
+		}
+	}
+
+	// Nothing was written.
+	if keys, _ := client.ListKeys(); len(keys) != 1 {
+		t.Errorf("Expected only ok, got %v", keys)
+	}
+	if got, _ := client.Get("ok"); !bytes.Equal(got, []byte("12345678")) {
+		t.Errorf("Expected ok unchanged, got %q", got)
+	}
+}
+
+func TestMaxValueSizeCompressed(t *testing.T) {
+	// The limit applies to the value as given, not as stored.
+	client, err := NewCacheClient(":memory:", WithMaxValueSize(100), WithCompressor(GzipCompressor{}))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Set("key", compressible); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge, got %v", err)
+	}
+	client.Set("log", bytes.Repeat([]byte("x"), 100))
+	if _, err := client.Append("log", []byte("y")); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge from Append, got %v", err)
+	}
+}
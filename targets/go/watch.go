@@ -0,0 +1,334 @@
+package squeakyv
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// watchBuffer is how many undelivered events a watch holds before it starts
+// dropping the oldest.
+const watchBuffer = 64
+
+// WatchOp is the kind of change a WatchEvent reports.
+type WatchOp int
+
+const (
+	// WatchSet reports a new value.
+	WatchSet WatchOp = iota + 1
+	// WatchDelete reports a deleted key.
+	WatchDelete
+)
+
+// String returns "set" or "delete".
+func (op WatchOp) String() string {
+	switch op {
+	case WatchSet:
+		return "set"
+	case WatchDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// WatchEvent is a change delivered by Watch or WatchPrefix.
+type WatchEvent struct {
+	// Key is the key that changed, as written.
+	Key string
+	// Op is the kind of change.
+	Op WatchOp
+	// Value is the new value for WatchSet, and nil for WatchDelete. It is
+	// shared by every watch receiving the event and must not be modified.
+	Value []byte
+	// Missed is how many events for the watch were dropped just before this
+	// one because the receiver fell behind.
+	Missed int
+}
+
+// Watch returns a channel delivering an event every time this client sets or
+// deletes key, until ctx is cancelled or the client is closed, when the
+// channel is closed. With WithCaseInsensitiveKeys, writes to any casing of
+// key are delivered.
+//
+// Events are published after the write commits, by Set, SetWithTTL, SetMany,
+// Delete, DeleteMany, GetDel, GetSet, SetNX, CompareAndSwap and
+// CompareAndDelete, and through a Namespace. Delete and DeleteMany publish a
+// delete even if the key had no live value. Other writes, such as those in
+// WithTransaction, Append, Increment, Rename, expiry and bulk deletes, and
+// writes by other clients or processes, are not seen.
+//
+// Writers never wait for watchers. Each watch buffers up to 64 undelivered
+// events; beyond that the oldest is dropped, so a slow receiver always ends
+// up with the latest change, and the next event delivered counts the drops
+// in Missed. Events from concurrent writes to the same key may be delivered
+// in either order.
+//
+// Example:
+//
+//	events, err := client.Watch(ctx, "config")
+//	if err != nil {
+//		return err
+//	}
+//	for ev := range events {
+//		if ev.Op == squeakyv.WatchSet {
+//			reload(ev.Value)
+//		}
+//	}
+func (c *CacheClient) Watch(ctx context.Context, key string) (<-chan WatchEvent, error) {
+	return c.watches.add(ctx, watchTarget{key: c.watchKey(key)})
+}
+
+// WatchPrefix is Watch for every key starting with prefix, or every key if
+// prefix is empty. As with ListKeysWithPrefix, the prefix is matched
+// case-sensitively even with WithCaseInsensitiveKeys. Events carry the full
+// key. A write matching several watches, exact or prefix, is delivered to
+// each of them.
+//
+// Example:
+//
+//	events, err := client.WatchPrefix(ctx, "users:")
+//	if err != nil {
+//		return err
+//	}
+//	for ev := range events {
+//		log.Printf("%s %s", ev.Op, ev.Key)
+//	}
+func (c *CacheClient) WatchPrefix(ctx context.Context, prefix string) (<-chan WatchEvent, error) {
+	return c.watches.add(ctx, watchTarget{key: prefix, prefix: true})
+}
+
+// watchKey returns key in the form watches are indexed by.
+func (c *CacheClient) watchKey(key string) string {
+	if c.opts.caseInsensitiveKeys {
+		return foldKey(key)
+	}
+	return key
+}
+
+// notify publishes a change of key to its watchers. value is copied, so the
+// caller keeps ownership of it.
+func (c *CacheClient) notify(key string, op WatchOp, value []byte) {
+	c.invalidate(key)
+	if c.watches.count.Load() == 0 {
+		return
+	}
+	c.watches.publish(c.watchKey(key), WatchEvent{Key: key, Op: op, Value: value}, c.readNewChanges)
+}
+
+// watchTarget is what a watch matches: a key, or with prefix set, every key
+// starting with key.
+type watchTarget struct {
+	key    string
+	prefix bool
+}
+
+// watchHub tracks a client's watches and subscriptions.
+type watchHub struct {
+	// count is the number of watches and subscriptions, read without the lock
+	// so writes cost nothing while nobody watches.
+	count atomic.Int32
+
+	mu          sync.Mutex
+	byKey       map[string]map[*watcher]struct{}
+	byPrefix    map[string]map[*watcher]struct{}
+	subscribers map[*subscriber]struct{}
+	// seq is the sequence number of the last change read for subscriptions.
+	seq    int64
+	closed bool
+	// done is closed, and running waited for, when the client closes.
+	done    chan struct{}
+	running sync.WaitGroup
+}
+
+// init allocates the hub's maps on first use. h.mu must be held.
+func (h *watchHub) init() {
+	if h.done == nil {
+		h.byKey = make(map[string]map[*watcher]struct{})
+		h.byPrefix = make(map[string]map[*watcher]struct{})
+		h.subscribers = make(map[*subscriber]struct{})
+		h.done = make(chan struct{})
+	}
+}
+
+// watchers returns the watches registered for targets like target.
+func (h *watchHub) watchers(target watchTarget) map[string]map[*watcher]struct{} {
+	if target.prefix {
+		return h.byPrefix
+	}
+	return h.byKey
+}
+
+// add registers a watch of target.
+func (h *watchHub) add(ctx context.Context, target watchTarget) (<-chan WatchEvent, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+	h.init()
+	watchers := h.watchers(target)
+	if watchers[target.key] == nil {
+		watchers[target.key] = make(map[*watcher]struct{})
+	}
+
+	w := &watcher{
+		wake: make(chan struct{}, 1),
+		out:  make(chan WatchEvent),
+	}
+	watchers[target.key][w] = struct{}{}
+	h.count.Add(1)
+
+	h.running.Add(1)
+	go func() {
+		defer h.running.Done()
+		w.run(ctx, h.done)
+		h.remove(target, w)
+	}()
+	return w.out, nil
+}
+
+// remove unregisters the watch w of target.
+func (h *watchHub) remove(target watchTarget, w *watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	watchers := h.watchers(target)
+	if _, ok := watchers[target.key][w]; !ok {
+		return
+	}
+	delete(watchers[target.key], w)
+	if len(watchers[target.key]) == 0 {
+		delete(watchers, target.key)
+	}
+	h.count.Add(-1)
+}
+
+// publish queues ev for the watches of key, the form of ev.Key that watches
+// are indexed by, and for the prefix watches matching ev.Key. Subscriptions
+// are sent the changes read from the feed with read.
+func (h *watchHub) publish(key string, ev WatchEvent, read func(seq int64, limit int) ([]ChangeEvent, error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	if len(h.subscribers) > 0 {
+		h.broadcast(read)
+	}
+
+	cloned := false
+	deliver := func(watchers map[*watcher]struct{}) {
+		if !cloned {
+			ev.Value = bytes.Clone(ev.Value)
+			cloned = true
+		}
+		for w := range watchers {
+			w.enqueue(ev)
+		}
+	}
+
+	if watchers := h.byKey[key]; len(watchers) > 0 {
+		deliver(watchers)
+	}
+	for prefix, watchers := range h.byPrefix {
+		if strings.HasPrefix(ev.Key, prefix) {
+			deliver(watchers)
+		}
+	}
+}
+
+// close ends every watch and subscription, returning once their channels are
+// closed.
+func (h *watchHub) close() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.closed = true
+	if h.done != nil {
+		close(h.done)
+	}
+	for s := range h.subscribers {
+		h.unsubscribeLocked(s)
+	}
+	h.mu.Unlock()
+
+	h.running.Wait()
+}
+
+// watcher is one watch: a bounded queue of events drained into out by run.
+type watcher struct {
+	mu    sync.Mutex
+	queue []WatchEvent
+	// wake is signaled when an event is queued.
+	wake chan struct{}
+	out  chan WatchEvent
+}
+
+// enqueue queues ev, dropping the oldest queued event if the queue is full.
+// It never blocks on the receiver.
+func (w *watcher) enqueue(ev WatchEvent) {
+	w.mu.Lock()
+	if len(w.queue) == watchBuffer {
+		dropped := w.queue[0]
+		w.queue = w.queue[1:]
+		if len(w.queue) > 0 {
+			w.queue[0].Missed += 1 + dropped.Missed
+		} else {
+			ev.Missed += 1 + dropped.Missed
+		}
+	}
+	w.queue = append(w.queue, ev)
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes and returns the oldest queued event.
+func (w *watcher) next() (WatchEvent, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.queue) == 0 {
+		return WatchEvent{}, false
+	}
+	ev := w.queue[0]
+	w.queue = w.queue[1:]
+	return ev, true
+}
+
+// run delivers queued events to out until ctx is cancelled or done is
+// closed, then closes out.
+func (w *watcher) run(ctx context.Context, done <-chan struct{}) {
+	defer close(w.out)
+
+	for {
+		ev, ok := w.next()
+		if !ok {
+			select {
+			case <-w.wake:
+				continue
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			}
+		}
+
+		select {
+		case w.out <- ev:
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		}
+	}
+}
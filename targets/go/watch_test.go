@@ -0,0 +1,236 @@
+package squeakyv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// nextEvent receives the next event from events, failing the test if none
+// arrives in time or the channel is closed.
+func nextEvent(t *testing.T, events <-chan WatchEvent) WatchEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("Expected an event, channel closed")
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return WatchEvent{}
+}
+
+// expectClosed fails the test unless events is closed without further events.
+func expectClosed(t *testing.T, events <-chan WatchEvent) {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if ok {
+			t.Fatalf("Expected the channel to be closed, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the channel to close")
+	}
+}
+
+// expectNoEvent fails the test if an event is pending on events.
+func expectNoEvent(t *testing.T, events <-chan WatchEvent) {
+	t.Helper()
+	select {
+	case ev := <-events:
+		t.Fatalf("Expected no event, got %+v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestWatch(t *testing.T) {
+	client := newTestClient(t)
+	events, err := client.Watch(context.Background(), "config")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	value := []byte("v1")
+	client.Set("config", value)
+	value[0] = 'X'
+	client.Set("other", []byte("ignored"))
+	if ev := nextEvent(t, events); ev.Key != "config" || ev.Op != WatchSet || string(ev.Value) != "v1" || ev.Missed != 0 {
+		t.Errorf("Expected a set of v1, got %+v", ev)
+	}
+
+	client.Delete("config")
+	if ev := nextEvent(t, events); ev.Op != WatchDelete || ev.Value != nil {
+		t.Errorf("Expected a tombstone, got %+v", ev)
+	}
+
+	client.SetMany(map[string][]byte{"config": []byte("v2"), "other": []byte("x")})
+	client.SetWithTTL("config", []byte("v3"), time.Hour)
+	client.CompareAndSwap("config", []byte("wrong"), []byte("never"))
+	client.CompareAndSwap("config", []byte("v3"), []byte("v4"))
+	client.GetDel("config")
+	for _, want := range []string{"v2", "v3", "v4", ""} {
+		ev := nextEvent(t, events)
+		if string(ev.Value) != want || (want == "") != (ev.Op == WatchDelete) {
+			t.Errorf("Expected %q, got %+v", want, ev)
+		}
+	}
+	expectNoEvent(t, events)
+}
+
+func TestWatchCancel(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := client.Watch(ctx, "key")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	cancel()
+	expectClosed(t, events)
+	client.Set("key", []byte("value"))
+	deadline := time.Now().Add(time.Second)
+	for client.watches.count.Load() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := client.watches.count.Load(); n != 0 {
+		t.Errorf("Expected the watch to be removed, %d remain", n)
+	}
+}
+
+func TestWatchClose(t *testing.T) {
+	client, err := NewCacheClient(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	events, err := client.Watch(context.Background(), "key")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	client.Close()
+	expectClosed(t, events)
+	if _, err := client.Watch(context.Background(), "key"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestWatchSlowReceiver(t *testing.T) {
+	client := newTestClient(t)
+	events, err := client.Watch(context.Background(), "key")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	// Nothing is received while writing, so writes must not block.
+	const n = 3 * watchBuffer
+	for i := range n {
+		client.Set("key", []byte(fmt.Sprint(i)))
+	}
+
+	var received, accounted int
+	var last WatchEvent
+	for accounted < n {
+		last = nextEvent(t, events)
+		received++
+		accounted += 1 + last.Missed
+	}
+	if accounted != n || string(last.Value) != fmt.Sprint(n-1) {
+		t.Errorf("Expected the latest value after %d events, got %+v after %d", n, last, accounted)
+	}
+	if received > watchBuffer+1 {
+		t.Errorf("Expected older events to be dropped, received %d", received)
+	}
+	expectNoEvent(t, events)
+}
+
+func TestWatchCaseInsensitive(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithCaseInsensitiveKeys())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	events, err := client.Watch(context.Background(), "Config")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	client.Set("CONFIG", []byte("v"))
+	if ev := nextEvent(t, events); ev.Key != "CONFIG" {
+		t.Errorf("Expected the write's casing, got %+v", ev)
+	}
+}
+
+func TestWatchPrefix(t *testing.T) {
+	client := newTestClient(t)
+	users, err := client.WatchPrefix(context.Background(), "users:")
+	if err != nil {
+		t.Fatalf("WatchPrefix failed: %v", err)
+	}
+	all, err := client.WatchPrefix(context.Background(), "")
+	if err != nil {
+		t.Fatalf("WatchPrefix failed: %v", err)
+	}
+	alice, err := client.Watch(context.Background(), "users:alice")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	client.Set("users:alice", []byte("a"))
+	client.Set("groups:admin", []byte("g"))
+	client.Delete("users:bob")
+
+	for name, events := range map[string]<-chan WatchEvent{"prefix": users, "exact": alice, "all": all} {
+		if ev := nextEvent(t, events); ev.Key != "users:alice" || ev.Op != WatchSet || string(ev.Value) != "a" {
+			t.Errorf("Expected the %s watch to see the set of users:alice, got %+v", name, ev)
+		}
+	}
+	if ev := nextEvent(t, all); ev.Key != "groups:admin" {
+		t.Errorf("Expected the catch-all watch to see groups:admin, got %+v", ev)
+	}
+	for name, events := range map[string]<-chan WatchEvent{"prefix": users, "all": all} {
+		if ev := nextEvent(t, events); ev.Key != "users:bob" || ev.Op != WatchDelete {
+			t.Errorf("Expected the %s watch to see the delete of users:bob, got %+v", name, ev)
+		}
+	}
+	expectNoEvent(t, users)
+	expectNoEvent(t, alice)
+	expectNoEvent(t, all)
+}
+
+func TestWatchPrefixUnsubscribe(t *testing.T) {
+	client := newTestClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var channels []<-chan WatchEvent
+	for _, prefix := range []string{"a:", "a:", "b:"} {
+		events, err := client.WatchPrefix(ctx, prefix)
+		if err != nil {
+			t.Fatalf("WatchPrefix failed: %v", err)
+		}
+		channels = append(channels, events)
+	}
+	exact, err := client.Watch(ctx, "a:1")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	channels = append(channels, exact)
+
+	cancel()
+	for _, events := range channels {
+		expectClosed(t, events)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for client.watches.count.Load() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	client.watches.mu.Lock()
+	defer client.watches.mu.Unlock()
+	if n := client.watches.count.Load(); n != 0 || len(client.watches.byPrefix) != 0 || len(client.watches.byKey) != 0 {
+		t.Errorf("Expected every watch removed, got %d left, %v, %v", n, client.watches.byPrefix, client.watches.byKey)
+	}
+}
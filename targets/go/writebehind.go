@@ -0,0 +1,376 @@
+package squeakyv
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// WriteBehindDrop describes a buffered write that never reached the database,
+// passed to the WithWriteBehindDropped callback.
+type WriteBehindDrop struct {
+	// Key is the key written.
+	Key string
+	// Op is the kind of write.
+	Op WatchOp
+	// Err is the error that failed the write's batch.
+	Err error
+}
+
+// behindWrite is a Set or Delete buffered by WithWriteBehind.
+type behindWrite struct {
+	seq uint64
+	key string
+	op  WatchOp
+	// value is the value set, returned by reads; stored is value as written
+	// to the database.
+	value  []byte
+	stored []byte
+	at     time.Time
+}
+
+// writeBehind buffers a client's Sets and Deletes and writes them in batches.
+// Its methods do nothing on a nil *writeBehind.
+type writeBehind struct {
+	maxBatch int
+	maxDelay time.Duration
+
+	mu sync.Mutex
+	// queue holds the writes no flush has taken yet, oldest first.
+	queue []behindWrite
+	// latest is the newest buffered write of each key, by watchKey, until
+	// it has been written or dropped.
+	latest map[string]behindWrite
+	// enqueued and written are the sequence numbers of the last write
+	// buffered and of the last one written or dropped.
+	enqueued uint64
+	written  uint64
+
+	// flushing holds a token while a batch is taken and written, so batches
+	// commit in the order they were buffered.
+	flushing chan struct{}
+	// kick wakes the flusher when the queue stops being empty or fills up.
+	kick     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newWriteBehind returns an empty buffer flushing batches of up to maxBatch
+// writes, at the latest maxDelay after the oldest was buffered.
+func newWriteBehind(maxBatch int, maxDelay time.Duration) *writeBehind {
+	return &writeBehind{
+		maxBatch: maxBatch,
+		maxDelay: maxDelay,
+		latest:   make(map[string]behindWrite),
+		flushing: make(chan struct{}, 1),
+		kick:     make(chan struct{}, 1),
+	}
+}
+
+// add buffers w for the key cacheKey.
+func (b *writeBehind) add(cacheKey string, w behindWrite) {
+	b.mu.Lock()
+	b.enqueued++
+	w.seq = b.enqueued
+	w.at = time.Now()
+	b.queue = append(b.queue, w)
+	b.latest[cacheKey] = w
+	n := len(b.queue)
+	b.mu.Unlock()
+
+	if n == 1 || n >= b.maxBatch {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// lookup returns the newest buffered write of the key cacheKey, if any.
+func (b *writeBehind) lookup(cacheKey string) (behindWrite, bool) {
+	if b == nil {
+		return behindWrite{}, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, ok := b.latest[cacheKey]
+	return w, ok
+}
+
+// due reports how long to wait before the next batch should be written, and
+// false if nothing is buffered.
+func (b *writeBehind) due() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.queue) == 0 {
+		return 0, false
+	}
+	if len(b.queue) >= b.maxBatch {
+		return 0, true
+	}
+	return max(b.maxDelay-time.Since(b.queue[0].at), 0), true
+}
+
+// take removes and returns the oldest batch of buffered writes. The caller
+// must hold the flushing token and call finish once the batch is done.
+func (b *writeBehind) take() []behindWrite {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := min(len(b.queue), b.maxBatch)
+	batch := b.queue[:n:n]
+	b.queue = b.queue[n:]
+	if len(b.queue) == 0 {
+		b.queue = nil
+	}
+	return batch
+}
+
+// finish stops reads from seeing the writes of batch, which have been
+// written or dropped. keyOf maps a key to its key in latest.
+func (b *writeBehind) finish(batch []behindWrite, keyOf func(string) string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, w := range batch {
+		k := keyOf(w.key)
+		if b.latest[k].seq == w.seq {
+			delete(b.latest, k)
+		}
+	}
+	b.written = batch[len(batch)-1].seq
+}
+
+// progress returns the sequence numbers of the last write buffered and of the
+// last one written or dropped.
+func (b *writeBehind) progress() (enqueued, written uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.enqueued, b.written
+}
+
+// start launches the goroutine writing batches as they become due, until
+// shutdown is called.
+func (b *writeBehind) start(c *CacheClient) {
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+
+	go func() {
+		defer close(b.done)
+
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-b.kick:
+			}
+
+			for {
+				wait, ok := b.due()
+				if !ok {
+					break
+				}
+				if wait > 0 {
+					timer := time.NewTimer(wait)
+					select {
+					case <-b.stop:
+						timer.Stop()
+						return
+					case <-b.kick:
+					case <-timer.C:
+					}
+					timer.Stop()
+					continue
+				}
+				if !c.flushDue() {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// shutdown stops the flusher goroutine and waits for it to exit. Writes still
+// buffered are left for drainWriteBehind. It is safe to call more than once.
+func (b *writeBehind) shutdown() {
+	if b == nil {
+		return
+	}
+	b.stopOnce.Do(func() { close(b.stop) })
+	<-b.done
+}
+
+// flushDue writes one batch for the flusher goroutine, reporting false if the
+// client is closed.
+func (c *CacheClient) flushDue() bool {
+	db, err := c.acquire()
+	if err != nil {
+		return false
+	}
+	defer c.release()
+
+	c.behind.flushing <- struct{}{}
+	defer func() { <-c.behind.flushing }()
+
+	if err := c.writeBehindBatch(db); err != nil {
+		c.logWriteBehindFailure(err)
+	}
+	return true
+}
+
+// writeBehindBatch writes the oldest batch of buffered writes in a single
+// transaction, then notifies watchers and the mirror of them, or reports them
+// to WithWriteBehindDropped if the transaction fails. The caller must hold the
+// flushing token.
+func (c *CacheClient) writeBehindBatch(db *sql.DB) error {
+	batch := c.behind.take()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	err := c.withTx(db, func(tx *sql.Tx) error {
+		for _, w := range batch {
+			var err error
+			if w.op == WatchSet {
+				err = insertVersion(tx, c.tables, w.key, w.stored, sql.NullInt64{})
+			} else {
+				err = deleteKey(tx, c.tables, w.key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.behind.finish(batch, c.watchKey)
+		if fn := c.opts.writeBehindDropped; fn != nil {
+			for _, w := range batch {
+				fn(WriteBehindDrop{Key: w.key, Op: w.op, Err: err})
+			}
+		}
+		return err
+	}
+
+	// Notify before finishing, so that the memory cache is invalidated
+	// before reads stop seeing the buffered writes.
+	writes := make([]mirrorWrite, len(batch))
+	for i, w := range batch {
+		c.notify(w.key, w.op, w.value)
+		writes[i] = mirrorWrite{key: w.key, op: w.op, value: w.value}
+	}
+	c.behind.finish(batch, c.watchKey)
+	return c.mirror(writes...)
+}
+
+// drainWriteBehind writes every buffered write, for Close. The client's
+// write lock must be held.
+func (c *CacheClient) drainWriteBehind(db *sql.DB) error {
+	if c.behind == nil {
+		return nil
+	}
+	var errs []error
+	for {
+		if _, ok := c.behind.due(); !ok {
+			return errors.Join(errs...)
+		}
+		if err := c.writeBehindBatch(db); err != nil {
+			errs = append(errs, err)
+		}
+	}
+}
+
+// Flush writes every Set and Delete buffered by WithWriteBehind when it is
+// called, and waits for them to commit. It returns the errors of the batches
+// it wrote, whose writes are also reported to WithWriteBehindDropped, or
+// ctx.Err() if ctx is done first; the writes are still flushed later in that
+// case. Without WithWriteBehind, Flush does nothing.
+//
+// Example:
+//
+//	if err := client.Flush(ctx); err != nil {
+//		return err
+//	}
+func (c *CacheClient) Flush(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db, err := c.acquire()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	if c.behind == nil {
+		return nil
+	}
+
+	target, _ := c.behind.progress()
+	var errs []error
+	for {
+		if _, written := c.behind.progress(); written >= target {
+			return errors.Join(errs...)
+		}
+		select {
+		case c.behind.flushing <- struct{}{}:
+		case <-ctx.Done():
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+		err := c.writeBehindBatch(db)
+		<-c.behind.flushing
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+}
+
+// flushBehind flushes the writes buffered by WithWriteBehind before another
+// write, so that it can't overtake them. Failures are reported as Flush
+// reports them, and don't stop the write.
+func (c *CacheClient) flushBehind() {
+	if c.behind != nil {
+		c.Flush(context.Background())
+	}
+}
+
+// bufferWrite buffers a Set (with value) or Delete of key under
+// WithWriteBehind.
+func (c *CacheClient) bufferWrite(key string, op WatchOp, value []byte) error {
+	_, err := c.acquire()
+	if err != nil {
+		return err
+	}
+	defer c.release()
+
+	w := behindWrite{key: key, op: op}
+	if op == WatchSet {
+		// The caller may reuse value once Set returns.
+		w.value = bytes.Clone(value)
+		if w.value == nil {
+			w.value = []byte{}
+		}
+		if w.stored, err = c.encodeStored(key, w.value); err != nil {
+			return err
+		}
+	}
+	c.behind.add(c.watchKey(key), w)
+	return nil
+}
+
+// readBehind returns the value of key buffered by WithWriteBehind, if any,
+// reporting false if there is no buffered write of key.
+func (c *CacheClient) readBehind(key string) ([]byte, bool, error) {
+	w, ok := c.behind.lookup(c.watchKey(key))
+	if !ok {
+		return nil, false, nil
+	}
+	if w.op == WatchDelete {
+		return nil, true, keyNotFound(key)
+	}
+	return bytes.Clone(w.value), true, nil
+}
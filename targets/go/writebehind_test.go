@@ -0,0 +1,361 @@
+package squeakyv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// storedCount returns the number of live keys in client's database, ignoring
+// buffered writes.
+func storedCount(t *testing.T, client *CacheClient) int {
+	t.Helper()
+	var n int
+	query := `SELECT COUNT(*) FROM kv WHERE ` + liveCondition + `;`
+	if err := client.db.QueryRow(query, nowMillis()).Scan(&n); err != nil {
+		t.Fatalf("Failed to count keys: %v", err)
+	}
+	return n
+}
+
+func TestWriteBehind(t *testing.T) {
+	client := newTestClient(t, WithWriteBehind(100, time.Hour))
+
+	value := []byte("v1")
+	if err := client.Set("key", value); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	value[0] = 'X'
+	if got, _ := client.Get("key"); string(got) != "v1" {
+		t.Errorf("Expected Get to see the buffered write, got %q", got)
+	}
+	if n := storedCount(t, client); n != 0 {
+		t.Errorf("Expected nothing written before a flush, got %d keys", n)
+	}
+
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if n := storedCount(t, client); n != 1 {
+		t.Errorf("Expected the write after a flush, got %d keys", n)
+	}
+
+	client.Delete("key")
+	if _, err := client.GetStrict("key"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected GetStrict to see the buffered delete, got %v", err)
+	}
+	if n := storedCount(t, client); n != 1 {
+		t.Errorf("Expected the delete buffered, got %d keys", n)
+	}
+	if exists, _ := client.Exists("key"); exists {
+		t.Error("Expected Exists to see the buffered delete")
+	}
+
+	client.Set("empty", nil)
+	if got, err := client.GetStrict("empty"); got == nil || err != nil {
+		t.Errorf("Expected a buffered empty value to be non-nil, got %v, %v", got, err)
+	}
+}
+
+func TestWriteBehindReads(t *testing.T) {
+	client := newTestClient(t, WithWriteBehind(100, time.Hour))
+
+	client.Set("a", []byte("v"))
+	client.Set("b", []byte("v"))
+	client.Delete("a")
+	if keys, err := client.ListKeys(); err != nil || len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("Expected ListKeys to see the buffered writes, got %v, %v", keys, err)
+	}
+
+	client.Set("c", []byte("v"))
+	if results, err := client.GetMany([]string{"a", "c"}); err != nil || len(results) != 1 || string(results["c"]) != "v" {
+		t.Errorf("Expected GetMany to see the buffered write, got %v, %v", results, err)
+	}
+	client.Set("d", []byte("v"))
+	if exists, err := client.ExistsMany([]string{"a", "d"}); err != nil || exists["a"] || !exists["d"] {
+		t.Errorf("Expected ExistsMany to see the buffered writes, got %v, %v", exists, err)
+	}
+	client.Set("d", []byte("v2"))
+	if info, err := client.Stat("d"); err != nil || info.Versions != 2 {
+		t.Errorf("Expected Stat to see the buffered write, got %+v, %v", info, err)
+	}
+	client.Set("e", []byte("v"))
+	if keys, err := client.ListKeysWithPrefix("e"); err != nil || len(keys) != 1 {
+		t.Errorf("Expected ListKeysWithPrefix to see the buffered write, got %v, %v", keys, err)
+	}
+
+	client.Set("f", []byte("v"))
+	if n, err := client.Count(); err != nil || n != 5 {
+		t.Errorf("Expected Count to see the buffered write, got %d, %v", n, err)
+	}
+	client.Set("g", []byte("v"))
+	if keys, _, err := client.ListKeysPage(100, ""); err != nil || len(keys) != 6 {
+		t.Errorf("Expected ListKeysPage to see the buffered write, got %v, %v", keys, err)
+	}
+	client.Set("h", []byte("v"))
+	var items int
+	for _, err := range client.ItemsErr() {
+		if err != nil {
+			t.Fatalf("ItemsErr failed: %v", err)
+		}
+		items++
+	}
+	if items != 7 {
+		t.Errorf("Expected ItemsErr to see the buffered write, got %d items", items)
+	}
+	client.Set("i", []byte("v"))
+	var buf bytes.Buffer
+	if err := client.Export(&buf, ExportOptions{}); err != nil || !bytes.Contains(buf.Bytes(), []byte(`"i"`)) {
+		t.Errorf("Expected Export to see the buffered write, got %s, %v", buf.Bytes(), err)
+	}
+
+	client.Set("j", []byte("v"))
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	if err := client.Backup(backup, nil); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if value, _ := newTestClientAt(t, backup).Get("j"); string(value) != "v" {
+		t.Errorf("Expected Backup to include the buffered write, got %q", value)
+	}
+}
+
+func TestWriteBehindBatches(t *testing.T) {
+	client := newTestClient(t, WithWriteBehind(100, time.Hour))
+
+	for i := 0; i < 250; i++ {
+		client.Set(fmt.Sprintf("key%d", i), []byte("v"))
+	}
+
+	// Full batches are written without waiting for the delay.
+	deadline := time.Now().Add(5 * time.Second)
+	for storedCount(t, client) < 200 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for full batches, got %d keys", storedCount(t, client))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := storedCount(t, client); n != 200 {
+		t.Errorf("Expected the partial batch to wait, got %d keys", n)
+	}
+
+	client.Flush(context.Background())
+	if n := storedCount(t, client); n != 250 {
+		t.Errorf("Expected every key after Flush, got %d", n)
+	}
+
+	// Each buffered write is its own version.
+	client.Set("key0", []byte("a"))
+	client.Set("key0", []byte("b"))
+	client.Flush(context.Background())
+	if versions, _ := client.History("key0"); len(versions) != 3 {
+		t.Errorf("Expected 3 versions, got %d", len(versions))
+	}
+}
+
+func TestWriteBehindDelay(t *testing.T) {
+	client, err := NewCacheClient(":memory:", WithWriteBehind(1000, 20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.Set("key", []byte("v"))
+	deadline := time.Now().Add(5 * time.Second)
+	for storedCount(t, client) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the delay to flush the write")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWriteBehindCloseFlushes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	client, err := NewCacheClient(path, WithWriteBehind(1000, time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	for i := 0; i < 2500; i++ {
+		client.Set(fmt.Sprintf("key%d", i), []byte("v"))
+	}
+	client.Delete("key0")
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := client.Set("late", []byte("v")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+
+	reopened := newTestClientAt(t, path)
+	if n := storedCount(t, reopened); n != 2499 {
+		t.Errorf("Expected every buffered write after Close, got %d keys", n)
+	}
+}
+
+func TestWriteBehindOrder(t *testing.T) {
+	client := newTestClient(t, WithWriteBehind(100, time.Hour))
+
+	client.Set("key", []byte("buffered"))
+	if ok, err := client.SetNX("key", []byte("direct")); err != nil || ok {
+		t.Errorf("Expected SetNX to see the buffered write, got %v, %v", ok, err)
+	}
+
+	client.Set("counter", []byte("41"))
+	if n, err := client.Increment("counter", 1); err != nil || n != 42 {
+		t.Errorf("Expected Increment to apply to the buffered value, got %d, %v", n, err)
+	}
+
+	client.Set("ttl", []byte("buffered"))
+	client.SetWithTTL("ttl", []byte("direct"), time.Hour)
+	client.Flush(context.Background())
+	if got, _ := client.Get("ttl"); string(got) != "direct" {
+		t.Errorf("Expected the later direct write to win, got %q", got)
+	}
+}
+
+func TestWriteBehindDropped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	var (
+		mu      sync.Mutex
+		dropped []WriteBehindDrop
+	)
+	client := newTestClientAt(t, path, WithWriteBehind(100, time.Hour), WithBusyTimeout(time.Millisecond), WithRetry(1, time.Millisecond), WithWriteBehindDropped(func(d WriteBehindDrop) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped = append(dropped, d)
+	}))
+
+	release := lockDatabase(t, path)
+	client.Set("a", []byte("1"))
+	client.Delete("b")
+	if err := client.Flush(context.Background()); err == nil {
+		t.Fatal("Expected Flush to fail while the database is locked")
+	}
+	release()
+
+	mu.Lock()
+	if len(dropped) != 2 || dropped[0].Key != "a" || dropped[1].Op != WatchDelete || dropped[0].Err == nil {
+		t.Errorf("Expected both writes reported with the batch's error, got %+v", dropped)
+	}
+	mu.Unlock()
+	if got, _ := client.Get("a"); got != nil {
+		t.Errorf("Expected a dropped write to stop being read, got %q", got)
+	}
+
+	// Later writes are unaffected.
+	client.Set("c", []byte("3"))
+	if err := client.Flush(context.Background()); err != nil {
+		t.Errorf("Expected Flush to succeed once the database is free, got %v", err)
+	}
+}
+
+func TestWriteBehindFlushCancelled(t *testing.T) {
+	client := newTestClient(t, WithWriteBehind(100, time.Hour))
+	client.Set("key", []byte("v"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.Flush(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if got, _ := client.Get("key"); string(got) != "v" {
+		t.Errorf("Expected the write to stay buffered, got %q", got)
+	}
+}
+
+func TestWriteBehindMemoryCache(t *testing.T) {
+	client := newTestClient(t, WithWriteBehind(100, time.Hour), WithMemoryCache(100, 0))
+
+	client.Set("key", []byte("v1"))
+	client.Flush(context.Background())
+	client.Get("key")
+
+	client.Set("key", []byte("v2"))
+	if got, _ := client.Get("key"); string(got) != "v2" {
+		t.Errorf("Expected the buffered write over the cached value, got %q", got)
+	}
+	client.Flush(context.Background())
+	if got, _ := client.Get("key"); string(got) != "v2" {
+		t.Errorf("Expected the flushed write to replace the cached value, got %q", got)
+	}
+}
+
+func TestWriteBehindWatch(t *testing.T) {
+	client := newTestClient(t, WithWriteBehind(100, time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.Watch(ctx, "key")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	client.Set("key", []byte("v"))
+	select {
+	case ev := <-events:
+		t.Fatalf("Expected no event before the write is flushed, got %+v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	client.Flush(context.Background())
+	select {
+	case ev := <-events:
+		if ev.Op != WatchSet || string(ev.Value) != "v" {
+			t.Errorf("Expected a set event, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected an event once the write is flushed")
+	}
+}
+
+func TestWriteBehindInvalid(t *testing.T) {
+	if _, err := NewCacheClient(":memory:", WithWriteBehind(0, time.Second)); err == nil {
+		t.Error("Expected an error for a batch size of 0")
+	}
+	if _, err := NewCacheClient(":memory:", WithWriteBehind(10, -time.Second)); err == nil {
+		t.Error("Expected an error for a negative delay")
+	}
+	path := filepath.Join(t.TempDir(), "cache.db")
+	newTestClientAt(t, path)
+	if _, err := NewCacheClient(path, WithReadOnly(), WithWriteBehind(10, time.Second)); err == nil {
+		t.Error("Expected an error for a read-only client")
+	}
+}
+
+// BenchmarkSetWriteBehind compares small Sets committed one at a time with
+// Sets buffered by WithWriteBehind.
+func BenchmarkSetWriteBehind(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []Option
+	}{
+		{"direct", nil},
+		{"write-behind", []Option{WithWriteBehind(500, 10*time.Millisecond)}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			client, err := NewCacheClient(filepath.Join(b.TempDir(), "bench.db"), bench.opts...)
+			if err != nil {
+				b.Fatalf("Failed to create client: %v", err)
+			}
+			defer client.Close()
+
+			value := []byte("value")
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := client.Set("key"+strconv.Itoa(i), value); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err := client.Flush(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}